@@ -0,0 +1,61 @@
+// apply.go
+package controllers
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+)
+
+// applyStatus writes an object's status with server-side apply as
+// FieldManager. Only the status is sent, so the operator owns exactly the
+// status fields it sets and drops the ones it stops setting. The object's
+// resourceVersion is sent along and a concurrent change fails with a
+// conflict instead of being overwritten.
+func (r *PodRestartReconciler) applyStatus(ctx context.Context, obj client.Object, status interface{}) error {
+	gvk, err := apiutil.GVKForObject(obj, r.Scheme)
+	if err != nil {
+		return err
+	}
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(status)
+	if err != nil {
+		return err
+	}
+	u := &unstructured.Unstructured{Object: map[string]interface{}{"status": content}}
+	u.SetGroupVersionKind(gvk)
+	u.SetNamespace(obj.GetNamespace())
+	u.SetName(obj.GetName())
+	u.SetResourceVersion(obj.GetResourceVersion())
+	if err := r.Status().Patch(ctx, u, client.Apply, client.FieldOwner(FieldManager), client.ForceOwnership); err != nil {
+		return err
+	}
+	obj.SetResourceVersion(u.GetResourceVersion())
+	return nil
+}
+
+// applyPodAnnotations sets annotations on a pod with server-side apply as
+// FieldManager, leaving annotations of other managers alone. The pod's UID
+// is sent along so a replacement with the same name is never annotated.
+func applyPodAnnotations(ctx context.Context, writer client.Writer, pod *corev1.Pod, annotations map[string]string) error {
+	u := &unstructured.Unstructured{}
+	u.SetAPIVersion("v1")
+	u.SetKind("Pod")
+	u.SetNamespace(pod.Namespace)
+	u.SetName(pod.Name)
+	u.SetUID(pod.UID)
+	u.SetAnnotations(annotations)
+	if err := writer.Patch(ctx, u, client.Apply, client.FieldOwner(FieldManager), client.ForceOwnership); err != nil {
+		return err
+	}
+	if pod.Annotations == nil {
+		pod.Annotations = map[string]string{}
+	}
+	for k, v := range annotations {
+		pod.Annotations[k] = v
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+// audit.go
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	operatorv1alpha1 "github.com/example/pod-restart-operator/api/v1alpha1"
+)
+
+// Audit decisions
+const (
+	AuditDecisionRestart = "Restart"
+	AuditDecisionSkip    = "Skip"
+	// AuditDecisionNotify is recorded for triggers that only notify
+	AuditDecisionNotify = "Notify"
+	// AuditDecisionRetryBackoff is recorded while a failed restart waits for its retry
+	AuditDecisionRetryBackoff = "RetryBackoff"
+	// AuditDecisionShuttingDown is recorded for restarts left to the next
+	// operator instance because shutdown began
+	AuditDecisionShuttingDown = "ShuttingDown"
+)
+
+// AuditRecord is a machine readable record of a single restart decision
+type AuditRecord struct {
+	Time         time.Time      `json:"time"`
+	PodRestart   AuditObjectRef `json:"podRestart"`
+	Pod          string         `json:"pod"`
+	Workload     string         `json:"workload,omitempty"`
+	Decision     string         `json:"decision"`
+	Trigger      string         `json:"trigger,omitempty"`
+	Reason       string         `json:"reason,omitempty"`
+	EvidenceHash string         `json:"evidenceHash,omitempty"`
+	Action       string         `json:"action,omitempty"`
+	Identity     string         `json:"identity,omitempty"`
+	Result       string         `json:"result"`
+	Error        string         `json:"error,omitempty"`
+	Inputs       AuditInputs    `json:"inputs"`
+}
+
+// AuditObjectRef identifies the PodRestart that made a decision
+type AuditObjectRef struct {
+	Namespace  string `json:"namespace"`
+	Name       string `json:"name"`
+	UID        string `json:"uid"`
+	Generation int64  `json:"generation"`
+}
+
+// AuditInputs captures the configuration the decision was based on
+type AuditInputs struct {
+	ErrorPatterns          []string `json:"errorPatterns,omitempty"`
+	MetricConditions       int      `json:"metricConditions"`
+	MinTimeBetweenRestarts string   `json:"minTimeBetweenRestarts,omitempty"`
+	RestartBudget          string   `json:"restartBudget,omitempty"`
+	RestartsInWindow       int32    `json:"restartsInWindow"`
+}
+
+// AuditSink receives audit records. Implementations must be safe for concurrent use.
+type AuditSink interface {
+	Write(ctx context.Context, record AuditRecord) error
+}
+
+// jsonAuditSink writes one JSON document per line to a writer
+type jsonAuditSink struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// NewJSONAuditSink returns an AuditSink that writes newline delimited JSON to w
+func NewJSONAuditSink(w io.Writer) AuditSink {
+	return &jsonAuditSink{enc: json.NewEncoder(w)}
+}
+
+// Write implements AuditSink
+func (s *jsonAuditSink) Write(_ context.Context, record AuditRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.enc.Encode(record)
+}
+
+// newAuditRecord fills in the fields common to every decision about a pod
+func newAuditRecord(pr *operatorv1alpha1.PodRestart, pod *corev1.Pod, decision string) AuditRecord {
+	inputs := AuditInputs{
+		ErrorPatterns:    errorPatternRegexes(pr),
+		MetricConditions: len(pr.Spec.MetricConditions),
+		RestartsInWindow: pr.Status.RestartsInWindow,
+	}
+	if pr.Spec.MinTimeBetweenRestarts != nil {
+		inputs.MinTimeBetweenRestarts = pr.Spec.MinTimeBetweenRestarts.Duration.String()
+	}
+	if b := pr.Spec.RestartBudget; b != nil {
+		inputs.RestartBudget = strconv.Itoa(int(b.MaxRestarts)) + "/" + b.Window.Duration.String()
+	}
+
+	return AuditRecord{
+		Time: time.Now().UTC(),
+		PodRestart: AuditObjectRef{
+			Namespace:  pr.Namespace,
+			Name:       pr.Name,
+			UID:        string(pr.UID),
+			Generation: pr.Generation,
+		},
+		Pod:      pod.Name,
+		Workload: workloadFor(pod).String(),
+		Decision: decision,
+		Inputs:   inputs,
+	}
+}
+
+// auditTriggered records a decision about a triggered pod that did not
+// start a restart
+func (r *PodRestartReconciler) auditTriggered(ctx context.Context, pr *operatorv1alpha1.PodRestart, pod *corev1.Pod, result *triggerResult, decision, outcome, message string) {
+	record := newAuditRecord(pr, pod, decision)
+	record.Trigger = result.Trigger
+	record.Reason = result.Reason
+	record.EvidenceHash = evidenceHash(result)
+	record.Result = outcome
+	record.Error = message
+	r.audit(ctx, record)
+}
+
+// evidenceHash fingerprints the evidence behind a trigger without storing it
+func evidenceHash(result *triggerResult) string {
+	if result == nil {
+		return ""
+	}
+	h := sha256.New()
+	h.Write([]byte(result.Trigger))
+	h.Write([]byte{0})
+	h.Write([]byte(result.Reason))
+	h.Write([]byte{0})
+	h.Write([]byte(result.MatchedLine))
+	if result.MetricValue != nil {
+		h.Write([]byte{0})
+		h.Write([]byte(strconv.FormatFloat(*result.MetricValue, 'g', -1, 64)))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// audit writes a record to the configured sink, if any
+func (r *PodRestartReconciler) audit(ctx context.Context, record AuditRecord) {
+	if r.Audit == nil {
+		return
+	}
+	if err := r.Audit.Write(ctx, record); err != nil {
+		r.Log.Error(err, "Failed to write audit record", "pod", record.Pod, "decision", record.Decision)
+	}
+}
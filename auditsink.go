@@ -0,0 +1,159 @@
+// auditsink.go
+package controllers
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+const (
+	defaultAuditBatchSize     = 100
+	defaultAuditFlushInterval = 30 * time.Second
+	auditQueueSize            = 10000
+	auditUploadRetries        = 5
+	auditInitialBackoff       = time.Second
+	auditMaxBackoff           = time.Minute
+)
+
+// AuditUploader persists a batch of audit records to long term storage
+type AuditUploader interface {
+	Upload(ctx context.Context, records []AuditRecord) error
+}
+
+// BatchingAuditSink buffers audit records and ships them to an AuditUploader
+// in batches, retrying failed uploads with exponential backoff. It must be
+// added to the manager so that it is started and flushed on shutdown.
+type BatchingAuditSink struct {
+	uploader      AuditUploader
+	batchSize     int
+	flushInterval time.Duration
+	queue         chan AuditRecord
+	log           logr.Logger
+}
+
+// NewBatchingAuditSink creates a batching sink. Zero values select the defaults.
+func NewBatchingAuditSink(uploader AuditUploader, batchSize int, flushInterval time.Duration, log logr.Logger) *BatchingAuditSink {
+	if batchSize <= 0 {
+		batchSize = defaultAuditBatchSize
+	}
+	if flushInterval <= 0 {
+		flushInterval = defaultAuditFlushInterval
+	}
+	return &BatchingAuditSink{
+		uploader:      uploader,
+		batchSize:     batchSize,
+		flushInterval: flushInterval,
+		queue:         make(chan AuditRecord, auditQueueSize),
+		log:           log,
+	}
+}
+
+// Write implements AuditSink. It never blocks the reconcile; records are
+// dropped with an error when the queue is full.
+func (s *BatchingAuditSink) Write(_ context.Context, record AuditRecord) error {
+	select {
+	case s.queue <- record:
+		return nil
+	default:
+		return errors.New("audit queue full, record dropped")
+	}
+}
+
+// Start implements manager.Runnable
+func (s *BatchingAuditSink) Start(ctx context.Context) error {
+	ticker := time.NewTicker(s.flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]AuditRecord, 0, s.batchSize)
+	for {
+		select {
+		case record := <-s.queue:
+			batch = append(batch, record)
+			if len(batch) >= s.batchSize {
+				s.flush(ctx, batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			if len(batch) > 0 {
+				s.flush(ctx, batch)
+				batch = batch[:0]
+			}
+		case <-ctx.Done():
+			// Drain whatever is queued and make a final attempt with a fresh
+			// context, since the manager context is already cancelled
+			for len(s.queue) > 0 {
+				batch = append(batch, <-s.queue)
+			}
+			if len(batch) > 0 {
+				shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+				s.flush(shutdownCtx, batch)
+				cancel()
+			}
+			return nil
+		}
+	}
+}
+
+// flush uploads a batch, retrying with exponential backoff
+func (s *BatchingAuditSink) flush(ctx context.Context, batch []AuditRecord) {
+	records := make([]AuditRecord, len(batch))
+	copy(records, batch)
+
+	backoff := auditInitialBackoff
+	for attempt := 1; ; attempt++ {
+		err := s.uploader.Upload(ctx, records)
+		if err == nil {
+			return
+		}
+		if attempt >= auditUploadRetries {
+			s.log.Error(err, "Dropping audit batch after repeated upload failures", "records", len(records))
+			return
+		}
+		s.log.Error(err, "Failed to upload audit batch, retrying", "attempt", attempt, "backoff", backoff)
+
+		select {
+		case <-ctx.Done():
+			s.log.Error(ctx.Err(), "Dropping audit batch on shutdown", "records", len(records))
+			return
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > auditMaxBackoff {
+			backoff = auditMaxBackoff
+		}
+	}
+}
+
+// multiAuditSink fans records out to several sinks
+type multiAuditSink []AuditSink
+
+// NewMultiAuditSink returns a sink writing to every non-nil sink
+func NewMultiAuditSink(sinks ...AuditSink) AuditSink {
+	var m multiAuditSink
+	for _, s := range sinks {
+		if s != nil {
+			m = append(m, s)
+		}
+	}
+	switch len(m) {
+	case 0:
+		return nil
+	case 1:
+		return m[0]
+	}
+	return m
+}
+
+// Write implements AuditSink
+func (m multiAuditSink) Write(ctx context.Context, record AuditRecord) error {
+	var errs []error
+	for _, s := range m {
+		if err := s.Write(ctx, record); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,146 @@
+// auditupload.go
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"k8s.io/apimachinery/pkg/util/rand"
+)
+
+// S3AuditUploader writes each batch as a newline delimited JSON object to an
+// S3 compatible bucket. GCS is supported through its S3 interoperability
+// endpoint (https://storage.googleapis.com) with HMAC credentials.
+type S3AuditUploader struct {
+	client *s3.Client
+	bucket string
+	prefix string
+}
+
+// NewS3AuditUploader creates an uploader using the default AWS credential
+// chain. endpoint may be empty to use AWS S3.
+func NewS3AuditUploader(ctx context.Context, bucket, prefix, endpoint string) (*S3AuditUploader, error) {
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("loading AWS config: %w", err)
+	}
+	client := s3.NewFromConfig(cfg, func(o *s3.Options) {
+		if endpoint != "" {
+			o.BaseEndpoint = aws.String(endpoint)
+			o.UsePathStyle = true
+		}
+	})
+	return &S3AuditUploader{client: client, bucket: bucket, prefix: prefix}, nil
+}
+
+// Upload implements AuditUploader. Objects are partitioned by day so they can
+// be queried with tools like Athena or BigQuery external tables.
+func (u *S3AuditUploader) Upload(ctx context.Context, records []AuditRecord) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, record := range records {
+		if err := enc.Encode(record); err != nil {
+			return err
+		}
+	}
+
+	now := time.Now().UTC()
+	key := path.Join(u.prefix, now.Format("2006/01/02"), fmt.Sprintf("%s-%s.ndjson", now.Format("150405"), rand.String(8)))
+	_, err := u.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(u.bucket),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(buf.Bytes()),
+		ContentType: aws.String("application/x-ndjson"),
+	})
+	return err
+}
+
+// SQLAuditUploader inserts audit records into a SQL table
+type SQLAuditUploader struct {
+	db     *sql.DB
+	driver string
+	table  string
+}
+
+// NewSQLAuditUploader opens the database and creates the audit table if needed.
+// The driver must be registered by the caller, e.g. "pgx" or "mysql".
+func NewSQLAuditUploader(ctx context.Context, driver, dsn, table string) (*SQLAuditUploader, error) {
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("connecting to audit database: %w", err)
+	}
+
+	u := &SQLAuditUploader{db: db, driver: driver, table: table}
+	ddl := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	time TIMESTAMP NOT NULL,
+	namespace VARCHAR(253) NOT NULL,
+	podrestart VARCHAR(253) NOT NULL,
+	pod VARCHAR(253) NOT NULL,
+	decision VARCHAR(32) NOT NULL,
+	trigger_name VARCHAR(64),
+	result VARCHAR(64) NOT NULL,
+	evidence_hash VARCHAR(64),
+	record TEXT NOT NULL
+)`, table)
+	if _, err := db.ExecContext(ctx, ddl); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("creating audit table: %w", err)
+	}
+	return u, nil
+}
+
+// Upload implements AuditUploader. A batch is inserted in a single transaction.
+func (u *SQLAuditUploader) Upload(ctx context.Context, records []AuditRecord) error {
+	tx, err := u.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, fmt.Sprintf(
+		"INSERT INTO %s (time, namespace, podrestart, pod, decision, trigger_name, result, evidence_hash, record) VALUES (%s)",
+		u.table, u.placeholders(9)))
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, record := range records {
+		raw, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+		if _, err := stmt.ExecContext(ctx, record.Time, record.PodRestart.Namespace, record.PodRestart.Name,
+			record.Pod, record.Decision, record.Trigger, record.Result, record.EvidenceHash, string(raw)); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// placeholders returns n bind parameters in the syntax of the driver
+func (u *SQLAuditUploader) placeholders(n int) string {
+	params := make([]string, n)
+	for i := range params {
+		switch u.driver {
+		case "pgx", "postgres":
+			params[i] = fmt.Sprintf("$%d", i+1)
+		default:
+			params[i] = "?"
+		}
+	}
+	return strings.Join(params, ", ")
+}
@@ -0,0 +1,178 @@
+// autoscaling.go
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	operatorv1alpha1 "github.com/example/pod-restart-operator/api/v1alpha1"
+)
+
+// defaultScalingSettleTime is how long restarts stay deferred after a scale event
+const defaultScalingSettleTime = 3 * time.Minute
+
+// scaledObjectListGVK is read through unstructured objects so KEDA is only
+// consulted on clusters where it is installed
+var scaledObjectListGVK = schema.GroupVersionKind{Group: "keda.sh", Version: "v1alpha1", Kind: "ScaledObjectList"}
+
+// scalingSettings returns whether restarts are deferred during scaling and
+// for how long after a scale event
+func scalingSettings(pr *operatorv1alpha1.PodRestart) (bool, time.Duration) {
+	settle := defaultScalingSettleTime
+	spec := pr.Spec.Autoscaling
+	if spec == nil {
+		return true, settle
+	}
+	if spec.SettleTime != nil {
+		settle = spec.SettleTime.Duration
+	}
+	return spec.DeferDuringScaling == nil || *spec.DeferDuringScaling, settle
+}
+
+// scalingTracker answers whether a workload is being scaled, remembering the
+// answer for the rest of the pass so pods of one workload share the lookups
+type scalingTracker struct {
+	reader    client.Reader
+	namespace string
+	settle    time.Duration
+	now       time.Time
+	seen      map[workloadRef]string
+}
+
+func newScalingTracker(cluster *clusterTarget, namespace string, settle time.Duration) *scalingTracker {
+	return &scalingTracker{
+		reader:    cluster.lookups,
+		namespace: namespace,
+		settle:    settle,
+		now:       time.Now(),
+		seen:      make(map[workloadRef]string),
+	}
+}
+
+// scaling returns a description of the scaling activity on the workload, or
+// an empty string when it is not being scaled
+func (t *scalingTracker) scaling(ctx context.Context, workload workloadRef) (string, error) {
+	if workload.Kind == "Pod" {
+		return "", nil
+	}
+	if reason, ok := t.seen[workload]; ok {
+		return reason, nil
+	}
+
+	reason, err := t.hpaScaling(ctx, workload)
+	if err == nil && reason == "" {
+		reason, err = t.kedaScaling(ctx, workload)
+	}
+	if err != nil {
+		return "", err
+	}
+	t.seen[workload] = reason
+	return reason, nil
+}
+
+// hpaScaling checks the HorizontalPodAutoscalers targeting the workload for a
+// pending replica change or a recent scale event
+func (t *scalingTracker) hpaScaling(ctx context.Context, workload workloadRef) (string, error) {
+	var hpas autoscalingv2.HorizontalPodAutoscalerList
+	if err := t.reader.List(ctx, &hpas, client.InNamespace(t.namespace)); err != nil {
+		return "", fmt.Errorf("listing HorizontalPodAutoscalers: %w", err)
+	}
+	for _, hpa := range hpas.Items {
+		ref := hpa.Spec.ScaleTargetRef
+		if ref.Kind != workload.Kind || ref.Name != workload.Name {
+			continue
+		}
+		if hpa.Status.DesiredReplicas != hpa.Status.CurrentReplicas {
+			return fmt.Sprintf("HorizontalPodAutoscaler %s is scaling from %d to %d replicas",
+				hpa.Name, hpa.Status.CurrentReplicas, hpa.Status.DesiredReplicas), nil
+		}
+		if last := hpa.Status.LastScaleTime; last != nil && t.now.Sub(last.Time) < t.settle {
+			return fmt.Sprintf("HorizontalPodAutoscaler %s scaled %s ago",
+				hpa.Name, t.now.Sub(last.Time).Round(time.Second)), nil
+		}
+		return t.replicasSettling(ctx, workload, hpa.Name)
+	}
+	return "", nil
+}
+
+// replicasSettling checks whether the pods of an autoscaled workload have
+// caught up with its replica count. A single unready pod, usually the one
+// about to be restarted, does not count as scaling.
+func (t *scalingTracker) replicasSettling(ctx context.Context, workload workloadRef, hpa string) (string, error) {
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion(workload.APIVersion)
+	obj.SetKind(workload.Kind)
+	if err := t.reader.Get(ctx, client.ObjectKey{Namespace: t.namespace, Name: workload.Name}, obj); err != nil {
+		return "", fmt.Errorf("getting %s: %w", workload, err)
+	}
+	desired, found, _ := unstructured.NestedInt64(obj.Object, "spec", "replicas")
+	if !found {
+		return "", nil
+	}
+	current, _, _ := unstructured.NestedInt64(obj.Object, "status", "replicas")
+	ready, _, _ := unstructured.NestedInt64(obj.Object, "status", "readyReplicas")
+	switch {
+	case current != desired:
+		return fmt.Sprintf("%s scaled by HorizontalPodAutoscaler %s has %d of %d replicas",
+			workload, hpa, current, desired), nil
+	case ready < desired-1:
+		return fmt.Sprintf("%s scaled by HorizontalPodAutoscaler %s has %d of %d replicas ready",
+			workload, hpa, ready, desired), nil
+	}
+	return "", nil
+}
+
+// kedaScaling checks the KEDA ScaledObjects targeting the workload for a
+// recent activation or deactivation, which KEDA performs itself rather than
+// through its HorizontalPodAutoscaler
+func (t *scalingTracker) kedaScaling(ctx context.Context, workload workloadRef) (string, error) {
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(scaledObjectListGVK)
+	if err := t.reader.List(ctx, list, client.InNamespace(t.namespace)); err != nil {
+		if meta.IsNoMatchError(err) {
+			return "", nil
+		}
+		return "", fmt.Errorf("listing ScaledObjects: %w", err)
+	}
+	for _, so := range list.Items {
+		name, _, _ := unstructured.NestedString(so.Object, "spec", "scaleTargetRef", "name")
+		kind, _, _ := unstructured.NestedString(so.Object, "spec", "scaleTargetRef", "kind")
+		if kind == "" {
+			kind = "Deployment"
+		}
+		if kind != workload.Kind || name != workload.Name {
+			continue
+		}
+		conditions, _, _ := unstructured.NestedSlice(so.Object, "status", "conditions")
+		for _, c := range conditions {
+			cond, ok := c.(map[string]interface{})
+			if !ok || cond["type"] != "Active" {
+				continue
+			}
+			stamp, _ := cond["lastTransitionTime"].(string)
+			changed, err := time.Parse(time.RFC3339, stamp)
+			if err != nil {
+				continue
+			}
+			if since := t.now.Sub(changed); since < t.settle {
+				return fmt.Sprintf("ScaledObject %s became %s %s ago",
+					so.GetName(), activeState(cond["status"]), since.Round(time.Second)), nil
+			}
+		}
+	}
+	return "", nil
+}
+
+func activeState(status interface{}) string {
+	if status == "True" {
+		return "active"
+	}
+	return "inactive"
+}
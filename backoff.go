@@ -0,0 +1,103 @@
+// backoff.go
+package controllers
+
+import (
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+)
+
+const (
+	defaultBackoffBase    = 5 * time.Millisecond
+	defaultBackoffMax     = 5 * time.Minute
+	defaultReconcileQPS   = 10
+	defaultReconcileBurst = 100
+)
+
+// newRateLimiter returns the workqueue rate limiter: PodRestarts whose
+// reconcile fails back off exponentially per item, while the bucket bounds
+// the overall rate so a burst of failures cannot starve healthy PodRestarts
+func newRateLimiter(base, max time.Duration, qps float64, burst int) workqueue.RateLimiter {
+	if base <= 0 {
+		base = defaultBackoffBase
+	}
+	if max <= 0 {
+		max = defaultBackoffMax
+	}
+	if qps <= 0 {
+		qps = defaultReconcileQPS
+	}
+	if burst <= 0 {
+		burst = defaultReconcileBurst
+	}
+	return workqueue.NewMaxOfRateLimiter(
+		workqueue.NewItemExponentialFailureRateLimiter(base, max),
+		&workqueue.BucketRateLimiter{Limiter: rate.NewLimiter(rate.Limit(qps), burst)},
+	)
+}
+
+// requeueBackoff stretches the requeue interval of degraded PodRestarts.
+// Degraded passes do not return errors, so the workqueue rate limiter never
+// sees them; without this a broken metric endpoint would be queried at the
+// full check interval forever.
+type requeueBackoff struct {
+	mu       sync.Mutex
+	max      time.Duration
+	failures map[types.NamespacedName]int
+}
+
+func newRequeueBackoff(max time.Duration) *requeueBackoff {
+	if max <= 0 {
+		max = defaultBackoffMax
+	}
+	return &requeueBackoff{max: max, failures: map[types.NamespacedName]int{}}
+}
+
+// next returns the interval doubled for every consecutive degraded pass,
+// capped at the maximum backoff but never below the interval itself
+func (b *requeueBackoff) next(key types.NamespacedName, interval time.Duration) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	n := b.failures[key]
+	if n < 16 {
+		b.failures[key] = n + 1
+	}
+	d := interval << n
+	if d > b.max || d <= 0 {
+		d = b.max
+	}
+	if d < interval {
+		d = interval
+	}
+	return d
+}
+
+// failuresOf returns the number of consecutive degraded passes
+func (b *requeueBackoff) failuresOf(key types.NamespacedName) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.failures[key]
+}
+
+// restore sets the number of consecutive degraded passes from a checkpoint
+func (b *requeueBackoff) restore(key types.NamespacedName, n int) {
+	if n <= 0 {
+		return
+	}
+	if n > 16 {
+		n = 16
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures[key] = n
+}
+
+// reset clears the backoff once a pass completes without errors
+func (b *requeueBackoff) reset(key types.NamespacedName) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.failures, key)
+}
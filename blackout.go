@@ -0,0 +1,60 @@
+// blackout.go
+package controllers
+
+import (
+	"fmt"
+	"time"
+
+	operatorv1alpha1 "github.com/example/pod-restart-operator/api/v1alpha1"
+)
+
+// openBlackoutWindow returns a description of the blackout window of the
+// PodRestart that is open at now, or an empty string when none is.
+// Windows that do not parse were refused by the webhook and are ignored.
+func openBlackoutWindow(pr *operatorv1alpha1.PodRestart, now time.Time) string {
+	for i, window := range pr.Spec.BlackoutWindows {
+		if until, open := blackoutWindowOpen(window, now); open {
+			return fmt.Sprintf("Blackout window %d is open until %s", i, until.UTC().Format(time.RFC3339))
+		}
+	}
+	return ""
+}
+
+// blackoutWindowOpen reports whether window is open at now and when it
+// closes. A window may have opened on an earlier day, when it lasts past
+// midnight.
+func blackoutWindowOpen(window operatorv1alpha1.BlackoutWindow, now time.Time) (time.Time, bool) {
+	loc, err := time.LoadLocation(window.TimeZone)
+	if err != nil {
+		return time.Time{}, false
+	}
+	start, err := time.Parse(operatorv1alpha1.BlackoutWindowTimeFormat, window.Start)
+	if err != nil || window.Duration.Duration <= 0 {
+		return time.Time{}, false
+	}
+	local := now.In(loc)
+	for back := 0; back <= int(window.Duration.Duration/(24*time.Hour)); back++ {
+		day := local.AddDate(0, 0, -back)
+		opens := time.Date(day.Year(), day.Month(), day.Day(), start.Hour(), start.Minute(), 0, 0, loc)
+		if !blackoutWindowDay(window, opens.Weekday()) {
+			continue
+		}
+		closes := opens.Add(window.Duration.Duration)
+		if !local.Before(opens) && local.Before(closes) {
+			return closes, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func blackoutWindowDay(window operatorv1alpha1.BlackoutWindow, weekday time.Weekday) bool {
+	if len(window.Days) == 0 {
+		return true
+	}
+	for _, day := range window.Days {
+		if operatorv1alpha1.BlackoutWindowDays[day] == weekday {
+			return true
+		}
+	}
+	return false
+}
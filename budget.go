@@ -0,0 +1,77 @@
+// budget.go
+package controllers
+
+import (
+	"sort"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	operatorv1alpha1 "github.com/example/pod-restart-operator/api/v1alpha1"
+)
+
+// budgetExhausted reports whether maxRestarts restarts were performed within
+// the last window. A PodRestart without a budget is never exhausted.
+func budgetExhausted(pr *operatorv1alpha1.PodRestart, now time.Time) bool {
+	budget := pr.Spec.RestartBudget
+	if budget == nil {
+		return false
+	}
+	return restartsInWindow(pr, now) >= budget.MaxRestarts
+}
+
+// consumeBudget records a restart against the budget
+func consumeBudget(pr *operatorv1alpha1.PodRestart, now metav1.Time) {
+	if pr.Spec.RestartBudget == nil {
+		return
+	}
+	pr.Status.BudgetRestartTimes = append([]metav1.Time{now}, pr.Status.BudgetRestartTimes...)
+	refreshBudget(pr, now.Time)
+}
+
+// refreshBudget drops the restarts that slid out of the budget window and
+// updates RestartsInWindow. Only the newest maxRestarts restarts are kept,
+// since older ones leave the window before they can matter.
+func refreshBudget(pr *operatorv1alpha1.PodRestart, now time.Time) {
+	budget := pr.Spec.RestartBudget
+	if budget == nil {
+		pr.Status.BudgetRestartTimes = nil
+		pr.Status.RestartsInWindow = 0
+		return
+	}
+	times := pr.Status.BudgetRestartTimes[:0:0]
+	for _, t := range pr.Status.BudgetRestartTimes {
+		if now.Sub(t.Time) < budget.Window.Duration {
+			times = append(times, t)
+		}
+	}
+	sort.SliceStable(times, func(i, j int) bool { return times[i].After(times[j].Time) })
+	if len(times) > int(budget.MaxRestarts) {
+		times = times[:budget.MaxRestarts]
+	}
+	pr.Status.BudgetRestartTimes = times
+	pr.Status.RestartsInWindow = int32(len(times))
+}
+
+// restartsInWindow counts the restarts performed within the last window
+func restartsInWindow(pr *operatorv1alpha1.PodRestart, now time.Time) int32 {
+	var n int32
+	for _, t := range pr.Status.BudgetRestartTimes {
+		if now.Sub(t.Time) < pr.Spec.RestartBudget.Window.Duration {
+			n++
+		}
+	}
+	return n
+}
+
+// budgetFreesAt returns when the oldest restart in the window slides out of
+// it, freeing up the budget for another restart
+func budgetFreesAt(pr *operatorv1alpha1.PodRestart, now time.Time) time.Time {
+	var oldest time.Time
+	for _, t := range pr.Status.BudgetRestartTimes {
+		if now.Sub(t.Time) < pr.Spec.RestartBudget.Window.Duration && (oldest.IsZero() || t.Time.Before(oldest)) {
+			oldest = t.Time
+		}
+	}
+	return oldest.Add(pr.Spec.RestartBudget.Window.Duration)
+}
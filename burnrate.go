@@ -0,0 +1,222 @@
+// burnrate.go
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	operatorv1alpha1 "github.com/example/pod-restart-operator/api/v1alpha1"
+)
+
+// defaultBurnRateWindows are the fast and slow burn alerting windows
+// recommended by the SRE workbook for a 30 day SLO period
+var defaultBurnRateWindows = []operatorv1alpha1.BurnRateWindow{
+	{Long: metav1.Duration{Duration: time.Hour}, Short: metav1.Duration{Duration: 5 * time.Minute}, Factor: "14.4"},
+	{Long: metav1.Duration{Duration: 6 * time.Hour}, Short: metav1.Duration{Duration: 30 * time.Minute}, Factor: "6"},
+}
+
+// burnRatePass caches the burn rate evaluations of a single pass. An error
+// ratio describes a whole workload and every pod of it sees the same burn
+// rate, so each condition is queried once per workload and fires for one of
+// its pods only: restarting all of them at once would add to the outage
+// the SLO is already measuring.
+type burnRatePass struct {
+	mu      sync.Mutex
+	entries map[burnRateKey]*burnRateEntry
+}
+
+type burnRateKey struct {
+	namespace string
+	workload  string
+	condition string
+}
+
+// burnRateEntry is the evaluation of one condition for one workload
+type burnRateEntry struct {
+	once   sync.Once
+	result *triggerResult
+	err    error
+	// claimed is the pod the result fired for, or the error was reported
+	// for, guarded by burnRatePass.mu
+	claimed types.UID
+}
+
+type burnRatePassKey struct{}
+
+// withBurnRatePass makes the burn rate conditions checked through ctx share
+// a single evaluation per workload
+func withBurnRatePass(ctx context.Context) context.Context {
+	return context.WithValue(ctx, burnRatePassKey{}, &burnRatePass{entries: map[burnRateKey]*burnRateEntry{}})
+}
+
+// evaluate returns the cached evaluation of cond for the pod's workload,
+// evaluating it on first use. A firing result or an error is only returned
+// for the first pod of the workload asking for it.
+func (p *burnRatePass) evaluate(pod *corev1.Pod, cond operatorv1alpha1.BurnRateCondition, eval func() (*triggerResult, error)) (*triggerResult, error) {
+	key := burnRateKey{namespace: pod.Namespace, workload: workloadFor(pod).String(), condition: cond.Name}
+	p.mu.Lock()
+	entry, ok := p.entries[key]
+	if !ok {
+		entry = &burnRateEntry{}
+		p.entries[key] = entry
+	}
+	p.mu.Unlock()
+
+	entry.once.Do(func() { entry.result, entry.err = eval() })
+	if entry.err == nil && entry.result == nil {
+		return nil, nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if entry.claimed == "" {
+		entry.claimed = pod.UID
+	}
+	if entry.claimed != pod.UID {
+		return nil, nil
+	}
+	if entry.err != nil {
+		return nil, entry.err
+	}
+	result := *entry.result
+	return &result, nil
+}
+
+// checkBurnRates returns a result for the first burn rate condition that
+// fires. Conditions are evaluated once per workload and pass when ctx
+// carries a burnRatePass.
+func (r *PodRestartReconciler) checkBurnRates(ctx context.Context, pod *corev1.Pod, pr *operatorv1alpha1.PodRestart) *triggerResult {
+	pass, _ := ctx.Value(burnRatePassKey{}).(*burnRatePass)
+	for _, cond := range pr.Spec.BurnRates {
+		if cond.Provider == "" {
+			cond.Provider = r.defaultMetricProvider()
+		}
+		if cond.Provider == "" {
+			r.Log.Info("Skipping burn rate condition without a provider", "condition", cond.Name)
+			continue
+		}
+
+		eval := func() (*triggerResult, error) { return r.evaluateBurnRate(ctx, pod, cond) }
+		var result *triggerResult
+		var err error
+		if pass != nil {
+			result, err = pass.evaluate(pod, cond, eval)
+		} else {
+			result, err = eval()
+		}
+		if err != nil {
+			r.Log.Error(err, "Failed to evaluate burn rate condition",
+				"pod", pod.Name,
+				"condition", cond.Name,
+				"provider", cond.Provider)
+			r.Recorder.Eventf(pr, corev1.EventTypeWarning, EventReasonMetricQueryFailed,
+				"Failed to evaluate burn rate %s for pod %s using provider %s: %v", cond.Name, pod.Name, cond.Provider, err)
+			continue
+		}
+		if result != nil {
+			conditionMatchesTotal.WithLabelValues(pr.Namespace, pr.Name, cond.Name).Inc()
+			return result
+		}
+	}
+	return nil
+}
+
+// evaluateBurnRate checks the window pairs of a condition in order. A pair
+// fires when the burn rate over both its windows exceeds its factor; the
+// short window stops the condition from firing long after the burn ended.
+func (r *PodRestartReconciler) evaluateBurnRate(ctx context.Context, pod *corev1.Pod, cond operatorv1alpha1.BurnRateCondition) (*triggerResult, error) {
+	objective, err := strconv.ParseFloat(cond.Objective, 64)
+	if err != nil || objective <= 0 || objective >= 100 {
+		return nil, fmt.Errorf("invalid objective %q", cond.Objective)
+	}
+	budget := 1 - objective/100
+
+	windows := cond.Windows
+	if len(windows) == 0 {
+		windows = defaultBurnRateWindows
+	}
+	burnRate := func(window time.Duration) (float64, error) {
+		ratio, err := r.queryMetric(ctx, cond.Provider, pod, func(*operatorv1alpha1.MetricProvider) (string, error) {
+			return renderQuery(cond.ErrorRatioQuery, queryData{
+				Namespace: pod.Namespace,
+				Workload:  workloadFor(pod).Name,
+				Window:    promDuration(window),
+			})
+		})
+		return ratio / budget, err
+	}
+
+	for _, w := range windows {
+		factor, err := strconv.ParseFloat(w.Factor, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid factor %q", w.Factor)
+		}
+		long, err := burnRate(w.Long.Duration)
+		if err != nil {
+			return nil, err
+		}
+		if long < factor {
+			continue
+		}
+		short, err := burnRate(w.Short.Duration)
+		if err != nil {
+			return nil, err
+		}
+		if short < factor {
+			continue
+		}
+		return &triggerResult{
+			Trigger: TriggerBurnRate,
+			Name:    cond.Name,
+			Reason: fmt.Sprintf("Error budget of %s%% SLO %s is burning %.1fx over %s and %.1fx over %s (threshold %sx)",
+				cond.Objective, cond.Name, long, promDuration(w.Long.Duration), short, promDuration(w.Short.Duration), w.Factor),
+			MetricValue: &long,
+			NotifyOnly:  cond.Action == operatorv1alpha1.BurnRateNotify,
+		}, nil
+	}
+	return nil, nil
+}
+
+// promDuration formats a duration the way Prometheus range selectors and
+// recording rule names spell it, e.g. 5m or 6h
+func promDuration(d time.Duration) string {
+	switch {
+	case d%time.Hour == 0:
+		return fmt.Sprintf("%dh", d/time.Hour)
+	case d%time.Minute == 0:
+		return fmt.Sprintf("%dm", d/time.Minute)
+	default:
+		return fmt.Sprintf("%ds", d/time.Second)
+	}
+}
+
+// notifyTrigger reports a trigger that only notifies, without restarting the
+// pod. Burn rate conditions keep their own event so existing routes still
+// match; every other warning is a TriggerWarning.
+func (r *PodRestartReconciler) notifyTrigger(ctx context.Context, pr *operatorv1alpha1.PodRestart, pod *corev1.Pod, result *triggerResult) {
+	reason, event := EventReasonTriggerWarning, operatorv1alpha1.EventTriggerWarning
+	if result.Trigger == TriggerBurnRate {
+		reason, event = EventReasonBurnRateExceeded, operatorv1alpha1.EventBurnRateExceeded
+	}
+	triggerWarningsTotal.WithLabelValues(pr.Namespace, pr.Name, result.Trigger, result.Name).Inc()
+	r.recordAnnotatedPodEvent(pr, pod, map[string]string{AnnotationReasonCode: string(result.reasonCode())},
+		corev1.EventTypeWarning, reason, "%s", result.Reason)
+	r.notify(ctx, pr, Notification{
+		Event:       event,
+		Pod:         pod.Name,
+		Workload:    workloadFor(pod).String(),
+		Trigger:     result.Trigger,
+		TriggerName: result.Name,
+		ReasonCode:  result.reasonCode(),
+		Reason:      result.Reason,
+		MatchedLine: result.MatchedLine,
+		MetricValue: result.MetricValue,
+		Time:        time.Now(),
+	})
+}
@@ -0,0 +1,103 @@
+// checkpoint.go
+package controllers
+
+import (
+	"sort"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/types"
+
+	operatorv1alpha1 "github.com/example/pod-restart-operator/api/v1alpha1"
+)
+
+const (
+	// maxCheckpointCursors bounds the log cursors kept in status so a
+	// PodRestart targeting many pods stays well below the object size limit
+	maxCheckpointCursors = 1000
+
+	// maxCheckpointDedup bounds the deduplicated notifications kept in status
+	maxCheckpointDedup = 200
+)
+
+// stateRestores tracks the PodRestarts whose checkpoint was already loaded
+// by this operator process
+type stateRestores struct {
+	mu   sync.Mutex
+	done map[types.NamespacedName]bool
+}
+
+func newStateRestores() *stateRestores {
+	return &stateRestores{done: map[types.NamespacedName]bool{}}
+}
+
+// first reports whether key is seen for the first time and marks it
+func (s *stateRestores) first(key types.NamespacedName) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.done[key] {
+		return false
+	}
+	s.done[key] = true
+	return true
+}
+
+// keys returns the PodRestarts seen so far, sorted by name
+func (s *stateRestores) keys() []types.NamespacedName {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	keys := make([]types.NamespacedName, 0, len(s.done))
+	for key := range s.done {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].String() < keys[j].String()
+	})
+	return keys
+}
+
+// forget drops the mark of a deleted PodRestart
+func (s *stateRestores) forget(key types.NamespacedName) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.done, key)
+}
+
+// restoreState seeds the in-memory state of a PodRestart from the checkpoint
+// in its status the first time it is reconciled after an operator restart,
+// so backoff, log cursors, notification throttling and restart retries carry
+// over. Restarts in flight are not checkpointed: the checkpoint is written
+// with the status patch that ends the pass, after every restart of the pass
+// finished, and a process that dies mid-restart never writes it. The
+// restart's pod is then either gone or triggers again on the next pass.
+func (r *PodRestartReconciler) restoreState(pr *operatorv1alpha1.PodRestart) {
+	key := types.NamespacedName{Namespace: pr.Namespace, Name: pr.Name}
+	if !r.restores.first(key) {
+		return
+	}
+	cp := pr.Status.Checkpoint
+	if cp == nil {
+		return
+	}
+	r.backoff.restore(key, int(cp.DegradedPasses))
+	r.cursors.restore(key, cp.LogCursors)
+	r.throttle.restore(key, cp)
+	r.retries.restore(key, cp.RestartRetries)
+}
+
+// checkpointState writes the in-memory state of a PodRestart to its status
+func (r *PodRestartReconciler) checkpointState(pr *operatorv1alpha1.PodRestart) {
+	key := types.NamespacedName{Namespace: pr.Namespace, Name: pr.Name}
+	cp := &operatorv1alpha1.StateCheckpoint{
+		DegradedPasses: int32(r.backoff.failuresOf(key)),
+		LogCursors:     r.cursors.snapshot(key, maxCheckpointCursors),
+		Notifications:  r.throttle.snapshot(key),
+		RateLimits:     r.throttle.snapshotRateLimits(key),
+		Deduplicated:   r.throttle.snapshotDedup(key, maxCheckpointDedup),
+		RestartRetries: r.retries.checkpoint(key),
+	}
+	if cp.DegradedPasses == 0 && len(cp.LogCursors) == 0 && len(cp.Notifications) == 0 &&
+		len(cp.RateLimits) == 0 && len(cp.Deduplicated) == 0 && len(cp.RestartRetries) == 0 {
+		cp = nil
+	}
+	pr.Status.Checkpoint = cp
+}
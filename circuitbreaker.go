@@ -0,0 +1,52 @@
+// circuitbreaker.go
+package controllers
+
+import (
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	operatorv1alpha1 "github.com/example/pod-restart-operator/api/v1alpha1"
+)
+
+const (
+	// circuitBreakerFailures is the number of restarts of a PodRestart that
+	// fail in a row, whatever the pod, before its circuit breaker opens
+	circuitBreakerFailures = 5
+
+	// circuitBreakerOpenFor is how long an open circuit breaker blocks
+	// restarts. The next restart after that closes it again on success and
+	// reopens it on failure.
+	circuitBreakerOpenFor = 15 * time.Minute
+)
+
+// circuitOpen returns until when the circuit breaker of a PodRestart blocks
+// restarts, and whether it is open at now
+func circuitOpen(pr *operatorv1alpha1.PodRestart, now time.Time) (time.Time, bool) {
+	until := pr.Status.CircuitOpenUntil
+	if until == nil || !now.Before(until.Time) {
+		return time.Time{}, false
+	}
+	return until.Time, true
+}
+
+// tripCircuit counts a failed restart and reports whether it opened the
+// circuit breaker
+func tripCircuit(pr *operatorv1alpha1.PodRestart, now time.Time) bool {
+	pr.Status.ConsecutiveRestartFailures++
+	if pr.Status.ConsecutiveRestartFailures < circuitBreakerFailures {
+		return false
+	}
+	if _, open := circuitOpen(pr, now); open {
+		return false
+	}
+	until := metav1.NewTime(now.Add(circuitBreakerOpenFor))
+	pr.Status.CircuitOpenUntil = &until
+	return true
+}
+
+// resetCircuit closes the circuit breaker after a successful restart
+func resetCircuit(pr *operatorv1alpha1.PodRestart) {
+	pr.Status.ConsecutiveRestartFailures = 0
+	pr.Status.CircuitOpenUntil = nil
+}
@@ -0,0 +1,67 @@
+// lintcmd.go
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	operatorv1alpha1 "github.com/example/pod-restart-operator/api/v1alpha1"
+)
+
+// errLintFailed is returned once every file was checked and a problem was found
+var errLintFailed = errors.New("lint failed")
+
+func runLint(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("lint", flag.ContinueOnError)
+	strict := fs.Bool("strict", false, "Fail on warnings as well as errors.")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: kubectl podrestart lint [flags] FILE...")
+		fmt.Fprintln(fs.Output())
+		fmt.Fprintln(fs.Output(), "Checks the PodRestarts in each file without contacting a cluster: everything")
+		fmt.Fprintln(fs.Output(), "the admission webhook and CRD schema validate, plus metric thresholds, query")
+		fmt.Fprintln(fs.Output(), "templates and restart budget sanity. Use - to read stdin. Exits non-zero when")
+		fmt.Fprintln(fs.Output(), "an error, or with --strict a warning, is found.")
+		fmt.Fprintln(fs.Output())
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errors.New("no file given")
+	}
+
+	failed := false
+	for _, path := range fs.Args() {
+		podRestarts, err := loadPodRestarts(path)
+		if err != nil {
+			fmt.Fprintf(os.Stdout, "%s: error: %v\n", path, err)
+			failed = true
+			continue
+		}
+		for _, pr := range podRestarts {
+			result := operatorv1alpha1.Lint(pr)
+			name := pr.Name
+			if pr.Namespace != "" {
+				name = pr.Namespace + "/" + name
+			}
+			for _, e := range result.Errors {
+				fmt.Fprintf(os.Stdout, "%s: PodRestart %s: error: %s\n", path, name, e.Error())
+			}
+			for _, w := range result.Warnings {
+				fmt.Fprintf(os.Stdout, "%s: PodRestart %s: warning: %s\n", path, name, w)
+			}
+			if len(result.Errors) > 0 || (*strict && len(result.Warnings) > 0) {
+				failed = true
+			}
+		}
+	}
+	if failed {
+		return errLintFailed
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+// migratecmd.go
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	operatorv1alpha1 "github.com/example/pod-restart-operator/api/v1alpha1"
+	operatorv1beta1 "github.com/example/pod-restart-operator/api/v1beta1"
+)
+
+func runMigrate(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	var kube kubeFlags
+	kube.register(fs)
+	file := fs.String("f", "", "File holding the v1alpha1 PodRestart manifests to migrate, or - for stdin. Reads the PodRestarts of the cluster when unset.")
+	allNamespaces := fs.Bool("all-namespaces", false, "Migrate the PodRestarts of every namespace rather than the current one. Ignored with -f.")
+	fs.BoolVar(allNamespaces, "A", false, "Shorthand for --all-namespaces.")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: kubectl podrestart migrate [-f cr.yaml] [flags]")
+		fmt.Fprintln(fs.Output())
+		fmt.Fprintln(fs.Output(), "Prints v1alpha1 PodRestarts as v1beta1 manifests, read from a file or from the")
+		fmt.Fprintln(fs.Output(), "cluster. Status and server-set metadata are dropped, so the output can be")
+		fmt.Fprintln(fs.Output(), "committed and applied as is. Nothing is written to the cluster.")
+		fmt.Fprintln(fs.Output())
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return errors.New("unexpected arguments")
+	}
+
+	var podRestarts []*operatorv1alpha1.PodRestart
+	if *file != "" {
+		var err error
+		if podRestarts, err = loadPodRestarts(*file); err != nil {
+			return err
+		}
+	} else {
+		_, c, namespace, err := kube.connect()
+		if err != nil {
+			return err
+		}
+		var opts []client.ListOption
+		if !*allNamespaces {
+			opts = append(opts, client.InNamespace(namespace))
+		}
+		list := &operatorv1alpha1.PodRestartList{}
+		if err := c.List(ctx, list, opts...); err != nil {
+			return fmt.Errorf("listing PodRestarts: %w", err)
+		}
+		for i := range list.Items {
+			podRestarts = append(podRestarts, &list.Items[i])
+		}
+	}
+
+	for i, pr := range podRestarts {
+		out, err := migrate(pr)
+		if err != nil {
+			return fmt.Errorf("migrating PodRestart %s/%s: %w", pr.Namespace, pr.Name, err)
+		}
+		if i > 0 {
+			fmt.Fprintln(os.Stdout, "---")
+		}
+		if _, err := os.Stdout.Write(out); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrate converts a PodRestart to a v1beta1 manifest without status and
+// the metadata the API server sets
+func migrate(pr *operatorv1alpha1.PodRestart) ([]byte, error) {
+	converted := &operatorv1beta1.PodRestart{}
+	if err := converted.ConvertFrom(pr); err != nil {
+		return nil, err
+	}
+	converted.TypeMeta = metav1.TypeMeta{APIVersion: operatorv1beta1.GroupVersion.String(), Kind: "PodRestart"}
+	converted.ObjectMeta = metav1.ObjectMeta{Name: pr.Name, Namespace: pr.Namespace, Labels: pr.Labels}
+	for k, v := range pr.Annotations {
+		if k == corev1.LastAppliedConfigAnnotation {
+			continue
+		}
+		if converted.Annotations == nil {
+			converted.Annotations = map[string]string{}
+		}
+		converted.Annotations[k] = v
+	}
+
+	// Status is a struct, so it is dropped from the marshalled object rather
+	// than only zeroed
+	obj := map[string]interface{}{}
+	raw, err := yaml.Marshal(converted)
+	if err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(raw, &obj); err != nil {
+		return nil, err
+	}
+	delete(obj, "status")
+	if metadata, ok := obj["metadata"].(map[string]interface{}); ok {
+		delete(metadata, "creationTimestamp")
+	}
+	return yaml.Marshal(obj)
+}
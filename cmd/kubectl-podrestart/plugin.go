@@ -0,0 +1,259 @@
+// plugin.go
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	_ "k8s.io/client-go/plugin/pkg/client/auth"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	operatorv1alpha1 "github.com/example/pod-restart-operator/api/v1alpha1"
+	operatorv1beta1 "github.com/example/pod-restart-operator/api/v1beta1"
+	"github.com/example/pod-restart-operator/controllers"
+)
+
+var scheme = runtime.NewScheme()
+
+func init() {
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(operatorv1alpha1.AddToScheme(scheme))
+	utilruntime.Must(operatorv1beta1.AddToScheme(scheme))
+}
+
+// command is a subcommand of the plugin
+type command struct {
+	summary string
+	run     func(ctx context.Context, args []string) error
+}
+
+var commands = map[string]command{
+	"lint":     {summary: "Check PodRestart manifests offline, for use in CI", run: runLint},
+	"migrate":  {summary: "Print v1alpha1 PodRestarts as v1beta1 manifests", run: runMigrate},
+	"simulate": {summary: "Show which pods a PodRestart would restart, without restarting them", run: runSimulate},
+}
+
+func main() {
+	if len(os.Args) < 2 || os.Args[1] == "-h" || os.Args[1] == "--help" {
+		usage(os.Stderr)
+		os.Exit(2)
+	}
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", os.Args[1])
+		usage(os.Stderr)
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if err := cmd.run(ctx, os.Args[2:]); err != nil {
+		if !errors.Is(err, flag.ErrHelp) {
+			fmt.Fprintln(os.Stderr, "error:", err)
+		}
+		os.Exit(1)
+	}
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: kubectl podrestart <command> [flags]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "  %-10s %s\n", name, commands[name].summary)
+	}
+}
+
+// kubeFlags are the cluster connection flags shared by the commands
+type kubeFlags struct {
+	kubeconfig string
+	context    string
+	namespace  string
+}
+
+func (k *kubeFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&k.kubeconfig, "kubeconfig", "", "Path to the kubeconfig file. Defaults to the standard loading rules.")
+	fs.StringVar(&k.context, "context", "", "The kubeconfig context to use.")
+	fs.StringVar(&k.namespace, "namespace", "", "Namespace of PodRestarts that do not set one. Defaults to the context's namespace.")
+	fs.StringVar(&k.namespace, "n", "", "Shorthand for --namespace.")
+}
+
+// connect returns the rest config, a client and the default namespace
+func (k *kubeFlags) connect() (*rest.Config, client.Client, string, error) {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rules.ExplicitPath = k.kubeconfig
+	overrides := &clientcmd.ConfigOverrides{CurrentContext: k.context}
+	overrides.Context.Namespace = k.namespace
+	loader := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, overrides)
+
+	config, err := loader.ClientConfig()
+	if err != nil {
+		return nil, nil, "", err
+	}
+	config.UserAgent = "kubectl-podrestart"
+	namespace, _, err := loader.Namespace()
+	if err != nil {
+		return nil, nil, "", err
+	}
+	c, err := client.New(config, client.Options{Scheme: scheme})
+	if err != nil {
+		return nil, nil, "", err
+	}
+	return config, c, namespace, nil
+}
+
+// loadPodRestarts reads every PodRestart in a multi-document YAML or JSON
+// file, "-" being stdin. Documents of other kinds are ignored.
+func loadPodRestarts(path string) ([]*operatorv1alpha1.PodRestart, error) {
+	var in io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		in = f
+	}
+
+	var podRestarts []*operatorv1alpha1.PodRestart
+	decoder := utilyaml.NewYAMLOrJSONDecoder(in, 4096)
+	for doc := 1; ; doc++ {
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err != nil {
+			if errors.Is(err, io.EOF) {
+				return podRestarts, nil
+			}
+			return nil, fmt.Errorf("%s: document %d: %w", path, doc, err)
+		}
+		if len(raw) == 0 || string(raw) == "null" {
+			continue
+		}
+		pr := &operatorv1alpha1.PodRestart{}
+		if err := yaml.UnmarshalStrict(raw, pr); err != nil {
+			return nil, fmt.Errorf("%s: document %d: %w", path, doc, err)
+		}
+		if pr.Kind != "PodRestart" {
+			continue
+		}
+		podRestarts = append(podRestarts, pr)
+	}
+}
+
+func runSimulate(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("simulate", flag.ContinueOnError)
+	var kube kubeFlags
+	kube.register(fs)
+	file := fs.String("f", "", "File holding the PodRestart manifests to simulate, or - for stdin.")
+	output := fs.String("o", "table", "Output format: table or json.")
+	features := controllers.NewFeatureGates()
+	fs.Var(features, "feature-gates", "Feature gates to simulate with, as the operator's --feature-gates flag.")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: kubectl podrestart simulate -f cr.yaml [flags]")
+		fmt.Fprintln(fs.Output())
+		fmt.Fprintln(fs.Output(), "Runs the evaluation of each PodRestart against the live pods it selects, reading")
+		fmt.Fprintln(fs.Output(), "their logs and metrics, and prints which pods would be restarted and why.")
+		fmt.Fprintln(fs.Output(), "Nothing is written to the cluster.")
+		fmt.Fprintln(fs.Output())
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *file == "" {
+		fs.Usage()
+		return errors.New("-f is required")
+	}
+	if *output != "table" && *output != "json" {
+		return fmt.Errorf("unknown output format %q", *output)
+	}
+
+	podRestarts, err := loadPodRestarts(*file)
+	if err != nil {
+		return err
+	}
+	if len(podRestarts) == 0 {
+		return fmt.Errorf("%s holds no PodRestart", *file)
+	}
+	config, c, namespace, err := kube.connect()
+	if err != nil {
+		return err
+	}
+
+	type report struct {
+		PodRestart string                     `json:"podRestart"`
+		Pods       []controllers.SimulatedPod `json:"pods"`
+	}
+	var reports []report
+	for _, pr := range podRestarts {
+		if pr.Namespace == "" {
+			pr.Namespace = namespace
+		}
+		// An applied PodRestart's cooldown and budget carry over into the simulation
+		live := &operatorv1alpha1.PodRestart{}
+		if err := c.Get(ctx, client.ObjectKeyFromObject(pr), live); err == nil {
+			pr.Status = live.Status
+		} else if !apierrors.IsNotFound(err) {
+			return fmt.Errorf("getting PodRestart %s/%s: %w", pr.Namespace, pr.Name, err)
+		}
+
+		pods, err := controllers.Simulate(ctx, c, config, pr, features)
+		if err != nil {
+			return fmt.Errorf("simulating PodRestart %s/%s: %w", pr.Namespace, pr.Name, err)
+		}
+		reports = append(reports, report{PodRestart: pr.Namespace + "/" + pr.Name, Pods: pods})
+	}
+
+	if *output == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(reports)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "PODRESTART\tPOD\tPHASE\tRESULT\tTRIGGER\tREASON")
+	for _, rep := range reports {
+		for _, pod := range rep.Pods {
+			result, trigger, reason := "-", "-", "-"
+			if pod.Trigger != "" {
+				result = "restart"
+				trigger = pod.Trigger + "/" + pod.TriggerName
+				reason = pod.Reason
+				if pod.NotifyOnly {
+					result = "notify"
+				}
+				if pod.Skip != "" {
+					result = "skip (" + string(pod.Skip) + ")"
+					reason = pod.SkipMessage
+				}
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", rep.PodRestart, pod.Name, pod.Phase, result, trigger, oneLine(reason))
+		}
+	}
+	return w.Flush()
+}
+
+// oneLine keeps multi-line reasons from breaking the table
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
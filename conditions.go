@@ -0,0 +1,133 @@
+// conditions.go
+package controllers
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	operatorv1alpha1 "github.com/example/pod-restart-operator/api/v1alpha1"
+)
+
+// evaluation collects the outcome of a single reconcile pass
+type evaluation struct {
+	// restarted holds the names of pods restarted during this pass and
+	// reasonCodes the reason code of each restart
+	restarted   []string
+	reasonCodes []operatorv1alpha1.RestartReasonCode
+
+	// lastMessage is the rendered message of the most recent restart
+	lastMessage string
+
+	// degradedReason and degradedMessage are set when the pass hit an error
+	degradedReason  string
+	degradedMessage string
+
+	// stats is set once the pass starts evaluating pods
+	stats *passStats
+	// report collects the per-pod results when the PodRestart writes reports
+	report *reportBuilder
+	// sample is the subset of pods evaluated when the PodRestart samples
+	sample *fleetSample
+}
+
+// degrade marks the evaluation as degraded. The first error wins so the
+// condition reflects the root cause rather than its follow-on failures.
+func (e *evaluation) degrade(reason, message string) {
+	if e.degradedReason != "" {
+		return
+	}
+	e.degradedReason = reason
+	e.degradedMessage = message
+}
+
+// progressReason is the Progressing reason for a pass that restarted pods:
+// the restarts' reason code when they share one, PodsRestarted otherwise
+func (e *evaluation) progressReason() string {
+	if len(e.reasonCodes) == 0 || e.reasonCodes[0] == "" {
+		return "PodsRestarted"
+	}
+	for _, code := range e.reasonCodes[1:] {
+		if code != e.reasonCodes[0] {
+			return "PodsRestarted"
+		}
+	}
+	return string(e.reasonCodes[0])
+}
+
+// setConditions updates the standard conditions on the PodRestart from the
+// outcome of the current evaluation
+func setConditions(pr *operatorv1alpha1.PodRestart, eval *evaluation, now time.Time) {
+	gen := pr.Generation
+	set := func(condType string, status metav1.ConditionStatus, reason, message string) {
+		meta.SetStatusCondition(&pr.Status.Conditions, metav1.Condition{
+			Type:               condType,
+			Status:             status,
+			ObservedGeneration: gen,
+			Reason:             reason,
+			Message:            message,
+		})
+	}
+
+	if pr.Spec.Suspend {
+		set(operatorv1alpha1.ConditionSuspended, metav1.ConditionTrue, "Suspended", "Restarts are suspended by spec.suspend")
+	} else {
+		set(operatorv1alpha1.ConditionSuspended, metav1.ConditionFalse, "NotSuspended", "Restarts are enabled")
+	}
+
+	if budgetExhausted(pr, now) {
+		set(operatorv1alpha1.ConditionBudgetExhausted, metav1.ConditionTrue, "BudgetExhausted",
+			fmt.Sprintf("%d of %d restarts used in the last %s, restarts resume at %s",
+				restartsInWindow(pr, now), pr.Spec.RestartBudget.MaxRestarts, pr.Spec.RestartBudget.Window.Duration,
+				budgetFreesAt(pr, now).UTC().Format(time.RFC3339)))
+	} else {
+		set(operatorv1alpha1.ConditionBudgetExhausted, metav1.ConditionFalse, "WithinBudget", "Restart budget is available")
+	}
+
+	if eval.degradedReason != "" {
+		set(operatorv1alpha1.ConditionDegraded, metav1.ConditionTrue, eval.degradedReason, eval.degradedMessage)
+	} else {
+		set(operatorv1alpha1.ConditionDegraded, metav1.ConditionFalse, "Healthy", "Last evaluation completed without errors")
+	}
+
+	if len(eval.restarted) == 1 {
+		set(operatorv1alpha1.ConditionProgressing, metav1.ConditionTrue, eval.progressReason(), eval.lastMessage)
+	} else if len(eval.restarted) > 1 {
+		set(operatorv1alpha1.ConditionProgressing, metav1.ConditionTrue, eval.progressReason(),
+			fmt.Sprintf("Restarted pods: %s; last: %s", strings.Join(eval.restarted, ", "), eval.lastMessage))
+	} else {
+		set(operatorv1alpha1.ConditionProgressing, metav1.ConditionFalse, "Idle", "No pods needed a restart")
+	}
+
+	switch {
+	case pr.Spec.Suspend:
+		set(operatorv1alpha1.ConditionReady, metav1.ConditionFalse, "Suspended", "Restarts are suspended")
+	case eval.degradedReason != "":
+		set(operatorv1alpha1.ConditionReady, metav1.ConditionFalse, eval.degradedReason, eval.degradedMessage)
+	case pr.Status.TargetedPods == 0:
+		set(operatorv1alpha1.ConditionReady, metav1.ConditionTrue, "NoTargetedPods", "Pod selector does not match any pods")
+	default:
+		set(operatorv1alpha1.ConditionReady, metav1.ConditionTrue, "Reconciled",
+			fmt.Sprintf("Evaluating %d pods, %d matching restart triggers", pr.Status.TargetedPods, pr.Status.MatchingPods))
+	}
+
+	pr.Status.Phase = computePhase(pr)
+}
+
+// computePhase derives the phase from the conditions already set on the status
+func computePhase(pr *operatorv1alpha1.PodRestart) operatorv1alpha1.PodRestartPhase {
+	conds := pr.Status.Conditions
+	switch {
+	case meta.IsStatusConditionTrue(conds, operatorv1alpha1.ConditionSuspended):
+		return operatorv1alpha1.PhaseSuspended
+	case meta.IsStatusConditionTrue(conds, operatorv1alpha1.ConditionDegraded):
+		return operatorv1alpha1.PhaseDegraded
+	case meta.IsStatusConditionTrue(conds, operatorv1alpha1.ConditionBudgetExhausted):
+		return operatorv1alpha1.PhaseBudgetExhausted
+	default:
+		return operatorv1alpha1.PhaseActive
+	}
+}
@@ -0,0 +1,102 @@
+// connections.go
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	operatorv1alpha1 "github.com/example/pod-restart-operator/api/v1alpha1"
+)
+
+const defaultConnectionCheckTimeout = 10 * time.Second
+
+var (
+	// defaultConnectionCommand prints socket statistics, from which the
+	// established TCP connections are read
+	defaultConnectionCommand = []string{"ss", "-s"}
+
+	// ssEstablished matches the established count in the TCP line of `ss -s`
+	ssEstablished = regexp.MustCompile(`(?m)^TCP:.*\bestab (\d+)`)
+)
+
+// checkConnections counts the established connections of a pod and returns a
+// result when the count is outside the configured bounds
+func (r *PodRestartReconciler) checkConnections(ctx context.Context, cluster *clusterTarget, pod *corev1.Pod, pr *operatorv1alpha1.PodRestart, cond *operatorv1alpha1.ConnectionCountCondition) *triggerResult {
+	count, err := r.countConnections(ctx, cluster, pod, cond)
+	if err != nil {
+		r.probeFailed(pr, pod, "connections", err, "Failed to count connections of pod %s", pod.Name)
+		return nil
+	}
+	r.probeSucceeded(pr, pod, "connections")
+
+	var reason string
+	switch {
+	case cond.Max != nil && count > int64(*cond.Max):
+		reason = fmt.Sprintf("Pod has %d established connections, more than the maximum of %d", count, *cond.Max)
+	case cond.Min != nil && count < int64(*cond.Min):
+		reason = fmt.Sprintf("Pod has %d established connections, fewer than the minimum of %d", count, *cond.Min)
+	default:
+		return nil
+	}
+	conditionMatchesTotal.WithLabelValues(pr.Namespace, pr.Name, TriggerConnectionCount).Inc()
+	value := float64(count)
+	return &triggerResult{
+		Trigger:     TriggerConnectionCount,
+		Name:        "connections",
+		Reason:      reason,
+		MetricValue: &value,
+	}
+}
+
+// countConnections queries the MetricProvider when one is set and runs the
+// count command otherwise
+func (r *PodRestartReconciler) countConnections(ctx context.Context, cluster *clusterTarget, pod *corev1.Pod, cond *operatorv1alpha1.ConnectionCountCondition) (int64, error) {
+	if cond.Provider != "" {
+		if cond.Query == "" {
+			return 0, errors.New("connections.query is required with a provider")
+		}
+		value, err := r.queryMetric(ctx, cond.Provider, pod, func(*operatorv1alpha1.MetricProvider) (string, error) {
+			return renderQuery(cond.Query, queryData{Namespace: pod.Namespace, Pod: pod.Name})
+		})
+		return int64(value), err
+	}
+
+	container := cond.Container
+	if container == "" && len(pod.Spec.Containers) > 0 {
+		container = pod.Spec.Containers[0].Name
+	}
+	if !containerRunning(pod, container) {
+		return 0, fmt.Errorf("container %s is not running", container)
+	}
+	command := cond.Command
+	if len(command) == 0 {
+		command = defaultConnectionCommand
+	}
+	timeout := defaultConnectionCheckTimeout
+	if cond.Timeout != nil {
+		timeout = cond.Timeout.Duration
+	}
+
+	out, err := r.execProbe(ctx, cluster, pod, container, command, timeout)
+	if err != nil {
+		return 0, err
+	}
+	return parseConnectionCount(out)
+}
+
+// parseConnectionCount reads a plain number or the output of `ss -s`
+func parseConnectionCount(out string) (int64, error) {
+	if count, err := strconv.ParseInt(out, 10, 64); err == nil {
+		return count, nil
+	}
+	if m := ssEstablished.FindStringSubmatch(out); m != nil {
+		return strconv.ParseInt(m[1], 10, 64)
+	}
+	return 0, fmt.Errorf("no connection count in command output %q", out)
+}
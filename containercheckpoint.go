@@ -0,0 +1,93 @@
+// containercheckpoint.go
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	operatorv1alpha1 "github.com/example/pod-restart-operator/api/v1alpha1"
+)
+
+const defaultCheckpointTimeout = time.Minute
+
+// checkpointResponse is the body returned by the kubelet checkpoint API
+type checkpointResponse struct {
+	Items []string `json:"items"`
+}
+
+// checkpointContainers asks the kubelet of the pod's node to checkpoint the
+// configured containers and returns where the archives were written. Failures
+// are reported but never block the restart.
+func (r *PodRestartReconciler) checkpointContainers(ctx context.Context, pr *operatorv1alpha1.PodRestart, cluster *clusterTarget, pod *corev1.Pod) []operatorv1alpha1.ContainerCheckpointRecord {
+	if !r.featureEnabled(ContainerCheckpoints) {
+		r.Log.Info("Skipping container checkpoint, enable the ContainerCheckpoints feature gate to take them", "pod", pod.Name)
+		return nil
+	}
+	if pod.Spec.NodeName == "" {
+		return nil
+	}
+	spec := pr.Spec.ContainerCheckpoint
+	timeout := defaultCheckpointTimeout
+	if spec.Timeout != nil {
+		timeout = spec.Timeout.Duration
+	}
+
+	containers := spec.Containers
+	if len(containers) == 0 {
+		for _, c := range pod.Spec.Containers {
+			containers = append(containers, c.Name)
+		}
+	}
+	var records []operatorv1alpha1.ContainerCheckpointRecord
+	for _, container := range containers {
+		if !containerRunning(pod, container) {
+			continue
+		}
+		location, err := checkpointContainer(ctx, cluster, pod, container, timeout)
+		if err != nil {
+			r.Log.Error(err, "Failed to checkpoint container",
+				"pod", pod.Name,
+				"container", container,
+				"node", pod.Spec.NodeName)
+			r.recordPodEvent(pr, pod, corev1.EventTypeWarning, EventReasonCheckpointFailed,
+				"Failed to checkpoint container %s of pod %s: %v", container, pod.Name, err)
+			continue
+		}
+		records = append(records, operatorv1alpha1.ContainerCheckpointRecord{
+			Container: container,
+			Node:      pod.Spec.NodeName,
+			Location:  location,
+		})
+	}
+	return records
+}
+
+// checkpointContainer calls the kubelet checkpoint API through the API
+// server's node proxy
+func checkpointContainer(ctx context.Context, cluster *clusterTarget, pod *corev1.Pod, container string, timeout time.Duration) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	body, err := cluster.clientset.CoreV1().RESTClient().Post().
+		Resource("nodes").
+		Name(pod.Spec.NodeName).
+		SubResource("proxy").
+		Suffix("checkpoint", pod.Namespace, pod.Name, container).
+		Param("timeout", fmt.Sprint(int(timeout.Seconds()))).
+		DoRaw(ctx)
+	if err != nil {
+		return "", err
+	}
+	var resp checkpointResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return "", fmt.Errorf("decoding checkpoint response: %w", err)
+	}
+	if len(resp.Items) == 0 {
+		return "", fmt.Errorf("kubelet returned no checkpoint archive")
+	}
+	return resp.Items[0], nil
+}
@@ -3,39 +3,273 @@ package controllers
 
 import (
 	"context"
+	goerrors "errors"
 	"fmt"
-	"regexp"
+	"io"
+	"strings"
 	"time"
 
 	"github.com/go-logr/logr"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	operatorv1alpha1 "github.com/example/pod-restart-operator/api/v1alpha1"
 )
 
+const (
+	// TriggerErrorPattern is recorded when a log error pattern matched
+	TriggerErrorPattern = "ErrorPattern"
+
+	// TriggerMetricCondition is recorded when a metric condition was breached
+	TriggerMetricCondition = "MetricCondition"
+
+	// TriggerZombieProcesses is recorded when a container holds too many zombie processes
+	TriggerZombieProcesses = "ZombieProcesses"
+
+	// TriggerConnectionCount is recorded when the established connections left their bounds
+	TriggerConnectionCount = "ConnectionCount"
+
+	// TriggerHeartbeatFile is recorded when a heartbeat file went stale
+	TriggerHeartbeatFile = "HeartbeatFile"
+
+	// TriggerBurnRate is recorded when an SLO error budget burns too fast
+	TriggerBurnRate = "BurnRate"
+
+	// TriggerJVM is recorded when a JVM is about to exhaust its heap
+	TriggerJVM = "JVM"
+
+	// TriggerGroup is recorded when every trigger of a trigger group fired
+	TriggerGroup = "TriggerGroup"
+
+	// TriggerExitCode is recorded when a container terminated with a watched exit code
+	TriggerExitCode = "ExitCode"
+
+	// TriggerForbiddenImage is recorded when a container runs a forbidden image
+	TriggerForbiddenImage = "ForbiddenImage"
+
+	// defaultHistoryLimit is used when spec.historyLimit is not set
+	defaultHistoryLimit = 10
+
+	// idleRequeueInterval is used for PodRestarts that have nothing to scan
+	idleRequeueInterval = 10 * time.Minute
+
+	// podListPageSize is the number of pods processed at a time
+	podListPageSize = 500
+)
+
 // PodRestartReconciler reconciles a PodRestart object
 type PodRestartReconciler struct {
 	client.Client
-	Scheme *runtime.Scheme
-	Log    logr.Logger
+	Scheme   *runtime.Scheme
+	Log      logr.Logger
+	Recorder record.EventRecorder
+
+	// LogSources holds additional named log backends that PodRestarts may
+	// select through spec.logSource
+	LogSources map[string]LogSource
+
+	// Audit receives a record of every restart decision. Auditing is disabled when nil.
+	Audit AuditSink
+
+	// EvaluationWorkers is the number of pods of a PodRestart evaluated
+	// concurrently. Defaults to 8.
+	EvaluationWorkers int
+
+	// EvaluationSlots is the number of pods evaluated concurrently across all
+	// PodRestarts, shared by weight when they compete. Defaults to
+	// EvaluationWorkers times MaxConcurrentReconciles.
+	EvaluationSlots int
+
+	// PodEvaluationTimeout bounds the log scans and metric queries of a
+	// single pod. Defaults to 30s.
+	PodEvaluationTimeout time.Duration
+
+	// LogReadBytesPerReconcile and LogReadTimePerReconcile cap the logs read
+	// for a PodRestart in a single reconcile. Zero means unlimited.
+	LogReadBytesPerReconcile int64
+	LogReadTimePerReconcile  time.Duration
+
+	// LogReadBytesPerMinute caps the logs read by all PodRestarts together.
+	// Zero means unlimited.
+	LogReadBytesPerMinute int64
+
+	// MaxConcurrentReconciles is the number of PodRestarts reconciled
+	// concurrently. A single PodRestart is never reconciled twice at once.
+	MaxConcurrentReconciles int
+
+	// CacheSyncTimeout bounds the wait for the informer caches on startup
+	CacheSyncTimeout time.Duration
+
+	// Shard limits this replica to a subset of namespaces. All namespaces are
+	// handled when nil.
+	Shard Sharder
+
+	// BackoffBase and BackoffMax bound the exponential backoff of failing
+	// PodRestarts. Degraded PodRestarts back off from their check interval
+	// up to BackoffMax as well.
+	BackoffBase time.Duration
+	BackoffMax  time.Duration
+
+	// ReconcileQPS and ReconcileBurst limit the overall rate of retries
+	ReconcileQPS   float64
+	ReconcileBurst int
+
+	// LogsQPS and LogsBurst limit the clientset reading pod logs, exec'ing
+	// into containers and taking checkpoints, which would otherwise share the
+	// manager's client limits. The manager's limits apply when not set.
+	LogsQPS   float32
+	LogsBurst int
+
+	// MaxFollowStreams caps the log streams kept open for spec.logFollow
+	// across all PodRestarts. 0 means unlimited.
+	MaxFollowStreams int
+
+	// RequeueJitter is the fraction of a requeue interval added at random so
+	// PodRestarts with the same interval drift apart; defaults to 0.1. The
+	// first evaluations after startup are spread over StartupSpread,
+	// defaulting to 30s. Negative values disable either.
+	RequeueJitter float64
+	StartupSpread time.Duration
+
+	// ShutdownGracePeriod is how long a restart sequence or status write in
+	// progress may continue once the operator is shutting down. Defaults to 20s.
+	ShutdownGracePeriod time.Duration
+
+	// Features enables optional subsystems. The defaults apply when nil.
+	Features *FeatureGates
+
+	// ConfigName names the cluster-scoped OperatorConfig whose settings
+	// override the fields above while the operator runs. None is watched
+	// when empty.
+	ConfigName string
+
+	// Impersonation selects the identity pods are listed, read and deleted
+	// with on behalf of a PodRestart. With
+	// ImpersonateServiceAccount, ImpersonationServiceAccount names the
+	// ServiceAccount used in every namespace; defaults to pod-restart.
+	Impersonation               ImpersonationMode
+	ImpersonationServiceAccount string
+
+	// UnverifiedCreators is set when the mutating webhook does not record
+	// the creator annotations, so users may write anything there. PodRestarts
+	// may then only target their own namespace.
+	UnverifiedCreators bool
+
+	// DebugContainerImages are the images spec.diagnostics.debugContainer
+	// may run, as glob patterns such as "docker.io/nicolaka/netshoot:*".
+	// Debug containers are refused while it is empty.
+	DebugContainerImages []string
+
+	// ClusterServers are the API server URLs spec.cluster kubeconfigs may
+	// point at, as glob patterns such as "https://*.eks.amazonaws.com".
+	// Remote clusters are refused while it is empty, so tenants cannot make
+	// the operator probe arbitrary addresses.
+	ClusterServers []string
+
+	// ProtectedNamespaces are never touched, whatever the PodRestarts say.
+	// When AllowedNamespaces is set only matching namespaces are touched.
+	// Both accept glob patterns such as "team-*".
+	ProtectedNamespaces []string
+	AllowedNamespaces   []string
+
+	// restConfig and clientset are built once from the manager's config, which
+	// follows the standard kubeconfig loading rules outside of a cluster
+	restConfig *rest.Config
+	clientset  kubernetes.Interface
+
+	// apiReader reads directly from the API server: a pod right before it is
+	// deleted, and secrets when credentials were rejected. Pods are listed
+	// from the cache.
+	apiReader client.Reader
+
+	throttle      *notificationThrottle
+	alertAliases  *alertAliases
+	patterns      *patternCache
+	rounds        *evaluationRounds
+	logBudget     *sharedLogBudget
+	cursors       *logCursors
+	logShare      *logShare
+	backoff       *requeueBackoff
+	restores      *stateRestores
+	inflight      *restartsInFlight
+	retries       *restartRetries
+	spread        *requeueSpread
+	scheduler     *evaluationScheduler
+	follower      *logFollower
+	lastPasses    *lastPasses
+	probes        *probeFailures
+	debugRuns     *debugRuns
+	surges        *surges
+	uploads       chan struct{}
+	webhooks      *webhookQueue
+	config        *operatorConfig
+	remotes       *remoteClusters
+	impersonators *impersonatingClients
+	queriers      *querierCache
 }
 
 // +kubebuilder:rbac:groups=operator.example.com,resources=podrestarts,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=operator.example.com,resources=podrestarts/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=operator.example.com,resources=podrestarts/finalizers,verbs=update
-// +kubebuilder:rbac:groups=core,resources=pods,verbs=get;list;watch;delete
+// +kubebuilder:rbac:groups=core,resources=pods,verbs=get;list;watch;patch;delete
 // +kubebuilder:rbac:groups=core,resources=pods/log,verbs=get
+// +kubebuilder:rbac:groups=core,resources=pods/proxy,verbs=get
+// +kubebuilder:rbac:groups=core,resources=nodes,verbs=get
+// +kubebuilder:rbac:groups=core,resources=events,verbs=list;create;patch
+// +kubebuilder:rbac:groups=operator.example.com,resources=metricproviders,verbs=get;list;watch
+// +kubebuilder:rbac:groups=operator.example.com,resources=notificationchannels,verbs=get;list;watch
+// +kubebuilder:rbac:groups=operator.example.com,resources=operatorconfigs,verbs=get;list;watch
+// +kubebuilder:rbac:groups=operator.example.com,resources=podrestartpolicies,verbs=get;list;watch
+// +kubebuilder:rbac:groups=operator.example.com,resources=evaluationreports,verbs=list;create;delete
+// +kubebuilder:rbac:groups=operator.example.com,resources=operatorconfigs/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch;create;delete
+// +kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch;create;delete
+// +kubebuilder:rbac:groups=core,resources=namespaces,verbs=get;list;watch
+// +kubebuilder:rbac:groups=coordination.k8s.io,resources=leases,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=serviceaccounts;users;groups,verbs=impersonate
+// +kubebuilder:rbac:groups=authentication.k8s.io,resources=tokenreviews,verbs=create
+// +kubebuilder:rbac:groups=authorization.k8s.io,resources=subjectaccessreviews,verbs=create
+// +kubebuilder:rbac:groups=autoscaling,resources=horizontalpodautoscalers,verbs=get;list
+// +kubebuilder:rbac:groups=policy,resources=poddisruptionbudgets,verbs=list
+// +kubebuilder:rbac:groups=keda.sh,resources=scaledobjects,verbs=get;list
+// +kubebuilder:rbac:groups=apps,resources=deployments;statefulsets;daemonsets;replicasets,verbs=get
+// +kubebuilder:rbac:groups=apps,resources=deployments,verbs=patch
+// +kubebuilder:rbac:groups=kustomize.toolkit.fluxcd.io,resources=kustomizations,verbs=get
+// +kubebuilder:rbac:groups=helm.toolkit.fluxcd.io,resources=helmreleases,verbs=get
+// +kubebuilder:rbac:groups=autoscaling.k8s.io,resources=verticalpodautoscalers,verbs=get;create;update
 
 func (r *PodRestartReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	ctx, span := tracer.Start(ctx, "Reconcile", trace.WithAttributes(
+		attribute.String("podrestart.namespace", req.Namespace),
+		attribute.String("podrestart.name", req.Name),
+	))
+	defer span.End()
+
 	logger := log.FromContext(ctx)
+	if !r.ownsNamespace(ctx, req.Namespace) {
+		// The namespace moved to another shard since the request was queued
+		logger.V(1).Info("Namespace is owned by another shard", "namespace", req.Namespace)
+		return ctrl.Result{}, nil
+	}
 	logger.Info("Reconciling PodRestart", "name", req.NamespacedName)
 
 	// Fetch the PodRestart instance
@@ -43,163 +277,1083 @@ func (r *PodRestartReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 	if err := r.Get(ctx, req.NamespacedName, podRestart); err != nil {
 		if errors.IsNotFound(err) {
 			// Request object not found, could have been deleted after reconcile request
+			forgetMetrics(req.Namespace, req.Name)
+			r.throttle.forget(req.NamespacedName)
+			r.patterns.forget(req.NamespacedName)
+			r.rounds.forget(req.NamespacedName)
+			r.cursors.forget(req.NamespacedName)
+			r.backoff.reset(req.NamespacedName)
+			r.restores.forget(req.NamespacedName)
+			r.retries.forget(req.NamespacedName)
+			r.spread.forget(req.NamespacedName)
+			r.follower.forget(req.NamespacedName)
+			r.lastPasses.forget(req.NamespacedName)
+			r.probes.forget(req.NamespacedName)
+			r.debugRuns.forget(req.NamespacedName)
+			r.surges.forget(req.NamespacedName)
+			r.webhooks.forget(req.NamespacedName)
+			r.remotes.forget(req.NamespacedName)
+			r.impersonators.forget(req.NamespacedName)
 			return ctrl.Result{}, nil
 		}
 		// Error reading the object - requeue the request
 		return ctrl.Result{}, err
 	}
 
-	// List pods matching the label selector
-	podList := &corev1.PodList{}
+	// After startup every PodRestart is queued at once, each waits for its slot
+	if wait := r.spread.initialDelay(req.NamespacedName, time.Now()); wait > 0 {
+		logger.V(1).Info("Delaying first evaluation after startup", "delay", wait)
+		return ctrl.Result{RequeueAfter: wait}, nil
+	}
+
+	if v, ok := logVerbosityFor(podRestart); ok {
+		logger = withVerbosity(logger, v)
+		ctx = log.IntoContext(ctx, logger)
+		logger.V(1).Info("Log verbosity raised by annotation", "verbosity", v)
+	}
+
+	// Policy defaults are applied before the patch base is taken so the
+	// inherited spec fields never end up in the status patch. Every other
+	// targeted namespace may fall under another policy.
+	requested := podRestart.Spec.DeepCopy()
+	applied, policyErr := r.applyPolicy(ctx, podRestart)
+
+	// All status changes made during this pass are written with a single
+	// patch against this base
+	base := podRestart.DeepCopy()
+	eval := &evaluation{}
+	if policyErr != nil {
+		// Evaluating without the platform's safety defaults is not an
+		// option, so the pass ends degraded and the last policy stays in status
+		logger.Error(policyErr, "Failed to resolve PodRestartPolicy")
+		eval.degrade("PolicyUnavailable", policyErr.Error())
+		return r.finishReconcile(ctx, podRestart, base, eval)
+	}
+	podRestart.Status.Policy = applied
+	r.restoreState(podRestart)
+
+	if podRestart.Spec.Suspend {
+		logger.Info("PodRestart is suspended, skipping evaluation")
+		r.follower.forget(req.NamespacedName)
+		return r.finishReconcile(ctx, podRestart, base, eval)
+	}
+
 	labelSelector, err := metav1.LabelSelectorAsSelector(&podRestart.Spec.PodSelector)
 	if err != nil {
 		logger.Error(err, "Invalid label selector")
-		return ctrl.Result{}, err
+		eval.degrade("InvalidSelector", err.Error())
+		return r.finishReconcile(ctx, podRestart, base, eval)
 	}
-
-	listOpts := []client.ListOption{
-		client.InNamespace(req.Namespace),
-		client.MatchingLabelsSelector{Selector: labelSelector},
+	owners, err := newOwnerMatcher(podRestart)
+	if err != nil {
+		logger.Error(err, "Invalid owner filter")
+		eval.degrade("InvalidOwnerFilter", err.Error())
+		return r.finishReconcile(ctx, podRestart, base, eval)
 	}
 
-	if err := r.List(ctx, podList, listOpts...); err != nil {
-		logger.Error(err, "Failed to list pods")
-		return ctrl.Result{}, err
+	cluster, err := r.clusterFor(ctx, podRestart)
+	if err != nil {
+		logger.Error(err, "Target cluster unavailable")
+		eval.degrade("ClusterUnavailable", err.Error())
+		return r.finishReconcile(ctx, podRestart, base, eval)
+	}
+	// Protected namespaces, and other namespaces the creator has no access
+	// to, are refused while the others are still evaluated
+	var namespaces []string
+	for _, namespace := range targetNamespaces(podRestart, cluster) {
+		if err := r.checkNamespace(namespace); err != nil {
+			logger.Info("Refusing to evaluate pods", "namespace", namespace, "reason", err.Error())
+			eval.degrade("NamespaceProtected", err.Error())
+			continue
+		}
+		if err := r.mayTarget(ctx, podRestart, cluster, namespace); err != nil {
+			logger.Info("Refusing to evaluate pods", "namespace", namespace, "reason", err.Error())
+			eval.degrade("NamespaceNotPermitted", err.Error())
+			continue
+		}
+		namespaces = append(namespaces, namespace)
+	}
+	if len(namespaces) == 0 {
+		return r.finishReconcile(ctx, podRestart, base, eval)
+	}
+	if err := r.impersonate(podRestart, cluster); err != nil {
+		logger.Error(err, "Failed to impersonate the PodRestart's identity")
+		eval.degrade("ImpersonationFailed", err.Error())
+		return r.finishReconcile(ctx, podRestart, base, eval)
 	}
 
-	// Get the Kubernetes clientset for logs
-	config, err := rest.InClusterConfig()
+	logs, err := r.logSourceFor(podRestart, cluster)
 	if err != nil {
-		return ctrl.Result{}, err
+		logger.Error(err, "Invalid log source")
+		eval.degrade("InvalidLogSource", err.Error())
+		return r.finishReconcile(ctx, podRestart, base, eval)
 	}
-	clientset, err := kubernetes.NewForConfig(config)
+
+	// Invalid patterns degrade the PodRestart but the valid ones are still evaluated
+	patterns, err := r.patterns.get(podRestart)
 	if err != nil {
-		return ctrl.Result{}, err
+		logger.Error(err, "Failed to compile error patterns")
+		eval.degrade("InvalidErrorPattern", err.Error())
 	}
 
-	// Check each pod for error conditions
-	for _, pod := range podList.Items {
-		if pod.Status.Phase != corev1.PodRunning {
-			continue
+	r.checkReplacements(ctx, podRestart, cluster)
+
+	if err := r.cleanupDiagnostics(ctx, podRestart); err != nil {
+		logger.Error(err, "Failed to clean up expired diagnostics bundles")
+	}
+	if err := r.cleanupQuarantined(ctx, podRestart, cluster); err != nil {
+		logger.Error(err, "Failed to clean up expired quarantined pods")
+	}
+	if err := r.expireSurges(ctx, podRestart, cluster); err != nil {
+		logger.Error(err, "Failed to scale back expired surges")
+	}
+
+	// List pods matching the label selector a page at a time so memory stays
+	// bounded for selectors matching many pods
+	sample := r.fleetSampleFor(podRestart, podRestart.Status.TargetedPods, time.Now())
+	podRestart.Status.TargetedPods = 0
+	podRestart.Status.MatchingPods = 0
+	podRestart.Status.SkippedRestarts = nil
+	podRestart.Status.DeferredPods = 0
+	eval.stats = newPassStats()
+	ctx = withPassStats(ctx, eval.stats)
+	ctx = withBurnRatePass(ctx)
+	eval.report = newReportBuilder(podRestart)
+	maxLogBytes, maxLogDuration := r.logReadLimits()
+	budget := newLogReadBudget(podRestart, maxLogBytes, maxLogDuration, r.logBudget, time.Now())
+	eval.sample = sample
+	following := podRestart.Spec.LogFollow != nil && podRestart.Spec.LogFollow.Enabled && r.featureEnabled(LogFollow)
+	// A followed match requests a pass at most once per cooldown, or per
+	// check interval without one, so a noisy stream cannot evaluate the
+	// PodRestart more often than polling would
+	followInterval := podRestart.CheckIntervalDuration()
+	if cooldown := r.minTimeBetweenRestarts(podRestart); cooldown != nil && cooldown.Duration > followInterval {
+		followInterval = cooldown.Duration
+	}
+	followed := map[followKey]bool{}
+	ownSpec := podRestart.Spec
+	for n, namespace := range namespaces {
+		target := cluster.inNamespace(namespace)
+		// The pods of a namespace are evaluated under that namespace's policy
+		podRestart.Spec = ownSpec
+		if namespace != podRestart.Namespace {
+			spec, err := r.namespaceSpec(ctx, podRestart, requested, namespace)
+			if err != nil {
+				logger.Error(err, "Failed to resolve PodRestartPolicy", "namespace", namespace)
+				eval.degrade("PolicyUnavailable", err.Error())
+				continue
+			}
+			podRestart.Spec = *spec
 		}
+		pages, err := listPodPages(ctx, target, namespace, labelSelector, func(items []corev1.Pod) {
+			pods := owners.filter(items)
+			podRestart.Status.TargetedPods += int32(len(pods))
+			r.processPods(ctx, podRestart, target, sample.filter(pods), logs, patterns, budget, eval)
+			if following {
+				r.follower.follow(podRestart, target, pods, patterns, followInterval, followed)
+			}
+		})
+		if err != nil {
+			// A listed namespace the operator was not granted access to
+			// does not hold up the others
+			if errors.IsForbidden(err) {
+				logger.Info("Not allowed to list pods", "namespace", namespace, "reason", err.Error())
+				eval.degrade("NamespaceForbidden", err.Error())
+				continue
+			}
+			logger.Error(err, "Failed to list pods", "namespace", namespace)
+			if n == 0 && pages == 0 {
+				podRestart.Spec = ownSpec
+				return ctrl.Result{}, err
+			}
+			// Keep the restarts made for earlier pages in status
+			eval.degrade("ListFailed", err.Error())
+		}
+	}
+	podRestart.Spec = ownSpec
+	r.cursors.prune(req.NamespacedName, time.Now())
+	// Streams of pods that are gone, or of PodRestarts that stopped following, are closed
+	r.follower.prune(req.NamespacedName, followed)
+	if podRestart.Status.TargetedPods == 0 {
+		logger.Info("Pod selector matches no pods", "selector", labelSelector.String())
+	}
+	if deferred := podRestart.Status.DeferredPods; deferred > 0 {
+		logger.Info("Log read budget exhausted, deferring pods to the next pass", "deferred", deferred)
+		podsDeferredTotal.WithLabelValues(podRestart.Namespace, podRestart.Name).Add(float64(deferred))
+	} else {
+		// Every pod had its turn, start the next round with all pods
+		r.rounds.forget(req.NamespacedName)
+	}
 
-		shouldRestart, reason := r.shouldRestartPod(ctx, clientset, pod, podRestart)
-		if shouldRestart {
-			// Check if minimum time between restarts has elapsed
-			if podRestart.Spec.MinTimeBetweenRestarts != nil && podRestart.Status.LastRestartTime != nil {
-				sinceLastRestart := time.Since(podRestart.Status.LastRestartTime.Time)
-				minTime := podRestart.Spec.MinTimeBetweenRestarts.Duration
-				if sinceLastRestart < minTime {
-					logger.Info("Skipping restart due to minimum time between restarts not elapsed",
-						"pod", pod.Name,
-						"timeSinceLastRestart", sinceLastRestart,
-						"minimumTime", minTime)
-					continue
-				}
+	return r.finishReconcile(ctx, podRestart, base, eval)
+}
+
+// listPodPages hands the pods of a namespace matching selector to fn a page
+// at a time and returns the number of pages handed out. Pods of the local
+// cluster come from the manager's cache, which already holds them all, so
+// no reconcile lists them from the API server; other readers list them with
+// limit and continue.
+func listPodPages(ctx context.Context, target *clusterTarget, namespace string, selector labels.Selector, fn func([]corev1.Pod)) (int, error) {
+	opts := []client.ListOption{
+		client.InNamespace(namespace),
+		client.MatchingLabelsSelector{Selector: selector},
+	}
+	if target.cachedPods != nil {
+		podList := &corev1.PodList{}
+		if err := target.cachedPods.List(ctx, podList, opts...); err != nil {
+			return 0, err
+		}
+		pages := 0
+		for items := podList.Items; len(items) > 0; pages++ {
+			n := podListPageSize
+			if len(items) < n {
+				n = len(items)
 			}
+			fn(items[:n])
+			items = items[n:]
+		}
+		return pages, nil
+	}
 
-			// Restart the pod by deleting it (the controller will recreate it)
-			logger.Info("Restarting pod due to error condition",
-				"pod", pod.Name,
-				"reason", reason)
+	opts = append(opts, client.Limit(podListPageSize))
+	for page := 0; ; page++ {
+		podList := &corev1.PodList{}
+		if err := target.reader.List(ctx, podList, opts...); err != nil {
+			return page, err
+		}
+		fn(podList.Items)
+		if podList.Continue == "" {
+			return page + 1, nil
+		}
+		opts = append(opts[:3:3], client.Continue(podList.Continue))
+	}
+}
+
+// processPods evaluates a page of pods concurrently, then acts on the results
+// one pod at a time so cooldown and budget checks see every earlier restart
+func (r *PodRestartReconciler) processPods(ctx context.Context, podRestart *operatorv1alpha1.PodRestart, cluster *clusterTarget, pods []corev1.Pod, logs LogSource, patterns []errorPattern, budget *logReadBudget, eval *evaluation) {
+	logger := log.FromContext(ctx)
+
+	results, deferred := r.evaluatePods(ctx, cluster, logs, pods, podRestart, patterns, budget)
+	podRestart.Status.DeferredPods += deferred
+	if eval.stats != nil {
+		eval.stats.observe(results)
+	}
+	guard := newRestartGuard(podRestart, cluster)
+	key := types.NamespacedName{Namespace: podRestart.Namespace, Name: podRestart.Name}
+	for _, i := range r.restartOrder(ctx, podRestart, pods, results, guard) {
+		pod := pods[i]
+		if results[i] == nil {
+			// Logs read in full without a match need not be read again
+			r.cursors.commitPod(podRestart, &pod)
+			if eval.report != nil {
+				eval.report.add(&pod, nil, eval.stats.wasEvaluated(pod.UID), "")
+			}
+		}
+		if result := results[i]; result != nil {
+			podRestart.Status.MatchingPods++
 
-			if err := r.Delete(ctx, &pod); err != nil {
-				logger.Error(err, "Failed to delete pod for restart", "pod", pod.Name)
+			if result.NotifyOnly {
+				eval.report.add(&pod, result, true, "Notify")
+				r.auditTriggered(ctx, podRestart, &pod, result, AuditDecisionNotify, "Notified", "")
+				r.notifyTrigger(ctx, podRestart, &pod, result)
+				r.cursors.commitPod(podRestart, &pod)
 				continue
 			}
 
-			// Update the PodRestart status
-			patch := client.MergeFrom(podRestart.DeepCopy())
-			now := metav1.Now()
-			podRestart.Status.LastRestartTime = &now
-			podRestart.Status.RestartCount++
-
-			// Add a condition
-			condition := metav1.Condition{
-				Type:               "PodRestarted",
-				Status:             metav1.ConditionTrue,
-				LastTransitionTime: now,
-				Reason:             "ErrorDetected",
-				Message:            fmt.Sprintf("Pod %s restarted due to: %s", pod.Name, reason),
-			}
-
-			// Update or add the condition
-			found := false
-			for i, c := range podRestart.Status.Conditions {
-				if c.Type == condition.Type {
-					podRestart.Status.Conditions[i] = condition
-					found = true
-					break
+			// The cursors of a pod whose restart does not complete on this
+			// pass stay put, so its logs are matched again next pass
+
+			// A pod whose restart failed is retried with backoff, not on every pass
+			if failure := r.retries.waiting(key, pod.UID, time.Now()); failure != nil {
+				logger.V(1).Info("Waiting to retry failed restart",
+					"pod", pod.Name,
+					"attempts", failure.attempts,
+					"retryAt", failure.next)
+				if failure.persistent() {
+					eval.degrade("RestartFailing", fmt.Sprintf("Restart of pod %s failed %d times, last error: %s",
+						pod.Name, failure.attempts, failure.err))
 				}
+				eval.report.add(&pod, result, true, "RetryBackoff")
+				r.auditTriggered(ctx, podRestart, &pod, result, AuditDecisionRetryBackoff, "Waiting", failure.err)
+				r.cursors.dropPod(podRestart, &pod)
+				continue
 			}
-			if !found {
-				podRestart.Status.Conditions = append(podRestart.Status.Conditions, condition)
+
+			if reason, message := r.restartBlocked(ctx, podRestart, &pod, result, guard); reason != "" {
+				logger.Info("Skipping restart",
+					"pod", pod.Name,
+					"reason", reason,
+					"detail", message)
+				eval.report.add(&pod, result, true, string(reason))
+				r.skipRestart(ctx, podRestart, &pod, result, reason, message)
+				r.cursors.dropPod(podRestart, &pod)
+				continue
+			}
+
+			// Once shutdown began no new restart is started. The pod's cursors
+			// are dropped so the next operator instance evaluates it again.
+			if ctx.Err() != nil {
+				logger.Info("Operator is shutting down, leaving restart to the next instance", "pod", pod.Name)
+				r.cursors.forgetPod(podRestart, &pod)
+				eval.report.add(&pod, result, true, "ShuttingDown")
+				actx, cancel := gracefulContext(ctx, r.ShutdownGracePeriod)
+				r.auditTriggered(actx, podRestart, &pod, result, AuditDecisionShuttingDown, "Deferred", "")
+				cancel()
+				continue
+			}
+
+			// A restart that was started finishes even when shutdown begins
+			eval.report.add(&pod, result, true, "Restart")
+			rctx, cancel := gracefulContext(ctx, r.ShutdownGracePeriod)
+			if r.restartPod(rctx, podRestart, cluster, &pod, result, eval) {
+				r.cursors.commitPod(podRestart, &pod)
+				guard.disruptions.restarted(ctx, &pod)
+			} else {
+				r.cursors.dropPod(podRestart, &pod)
 			}
+			cancel()
+		}
+	}
+}
+
+// restartGuard holds the per-pass lookups behind the checks that can block a
+// triggered restart
+type restartGuard struct {
+	deferScaling bool
+	scaling      *scalingTracker
+	gitOps       *gitOpsTracker
+	nodes        *nodeTracker
+	disruptions  *disruptionBudgets
+	cluster      *clusterTarget
+}
+
+func newRestartGuard(pr *operatorv1alpha1.PodRestart, cluster *clusterTarget) *restartGuard {
+	deferScaling, settle := scalingSettings(pr)
+	return &restartGuard{
+		deferScaling: deferScaling,
+		scaling:      newScalingTracker(cluster, cluster.namespace, settle),
+		gitOps:       newGitOpsTracker(pr, cluster),
+		nodes:        newNodeTracker(cluster),
+		disruptions:  newDisruptionBudgets(cluster),
+		cluster:      cluster,
+	}
+}
+
+// restartBlocked returns why a pod whose trigger fired must not be restarted
+// now, or an empty reason when the restart may go ahead. Failed node,
+// disruption budget, autoscaler and GitOps lookups do not block the restart.
+// A dry run is reported last, so it shows the restarts that would happen.
+func (r *PodRestartReconciler) restartBlocked(ctx context.Context, pr *operatorv1alpha1.PodRestart, pod *corev1.Pod, result *triggerResult, guard *restartGuard) (operatorv1alpha1.SkipReason, string) {
+	logger := log.FromContext(ctx)
 
-			if err := r.Status().Patch(ctx, podRestart, patch); err != nil {
-				logger.Error(err, "Failed to update PodRestart status")
+	// Never delete a pod kubelet, a drain or another controller is already stopping
+	terminating, err := alreadyTerminating(ctx, guard.cluster, pod)
+	if err != nil {
+		logger.Error(err, "Failed to check whether pod is terminating", "pod", pod.Name)
+	} else if terminating != "" {
+		return operatorv1alpha1.SkipTerminating, terminating
+	}
+
+	// Leave pods on cordoned or draining nodes to the drain
+	if cordonedNodePolicy(pr) == operatorv1alpha1.CordonedNodesSkip {
+		cordoned, err := guard.nodes.cordoned(ctx, pod)
+		if err != nil {
+			logger.Error(err, "Failed to check node of pod", "pod", pod.Name)
+		} else if cordoned != "" {
+			return operatorv1alpha1.SkipNodeCordoned, cordoned
+		}
+	}
+
+	if window := openBlackoutWindow(pr, time.Now()); window != "" {
+		return operatorv1alpha1.SkipBlackoutWindow, window
+	}
+
+	// Check if minimum time between restarts has elapsed. A cooldown set on
+	// the trigger counts from the trigger's own last restart.
+	if cooldown := triggerCooldown(pr, result); cooldown != nil {
+		if last := lastTriggerRestart(pr, result); last != nil {
+			sinceLastRestart := time.Since(last.Time)
+			if sinceLastRestart < cooldown.Duration {
+				return operatorv1alpha1.SkipCooldown,
+					fmt.Sprintf("Last restart by %s %s was %s ago, minimum is %s",
+						result.Trigger, result.Name, sinceLastRestart.Round(time.Second), cooldown.Duration)
 			}
 		}
+	} else if cooldown := r.minTimeBetweenRestarts(pr); cooldown != nil && pr.Status.LastRestartTime != nil {
+		sinceLastRestart := time.Since(pr.Status.LastRestartTime.Time)
+		if sinceLastRestart < cooldown.Duration {
+			return operatorv1alpha1.SkipCooldown,
+				fmt.Sprintf("Last restart was %s ago, minimum is %s", sinceLastRestart.Round(time.Second), cooldown.Duration)
+		}
 	}
 
-	return ctrl.Result{RequeueAfter: 30 * time.Second}, nil
+	// Check the restart budget
+	if budgetExhausted(pr, time.Now()) {
+		return operatorv1alpha1.SkipBudgetExhausted,
+			fmt.Sprintf("%d restarts already performed in the last %s, restarts resume at %s",
+				restartsInWindow(pr, time.Now()), pr.Spec.RestartBudget.Window.Duration,
+				budgetFreesAt(pr, time.Now()).UTC().Format(time.RFC3339))
+	}
+	if until, open := circuitOpen(pr, time.Now()); open {
+		return operatorv1alpha1.SkipCircuitOpen,
+			fmt.Sprintf("%d restarts failed in a row, restarts resume at %s",
+				pr.Status.ConsecutiveRestartFailures, until.UTC().Format(time.RFC3339))
+	}
+
+	workload := workloadFor(pod)
+
+	// Deleting a pod nothing recreates is not a restart
+	if workload.Kind == "Pod" && !pr.Spec.AllowBarePods {
+		return operatorv1alpha1.SkipBarePod,
+			fmt.Sprintf("Pod %s has no owning controller and spec.allowBarePods is not set", pod.Name)
+	}
+
+	// A workload that did not replace an earlier restarted pod would lose
+	// this one for good
+	if isOrphaned(pr, pod.Namespace, workload.String()) {
+		return operatorv1alpha1.SkipOrphaned,
+			fmt.Sprintf("Workload %s did not replace a previously restarted pod", workload)
+	}
+
+	// Check whether an autoscaler is scaling the workload
+	if guard.deferScaling {
+		activity, err := guard.scaling.scaling(ctx, workload)
+		if err != nil {
+			logger.Error(err, "Failed to check autoscaler activity", "pod", pod.Name)
+		} else if activity != "" {
+			return operatorv1alpha1.SkipScaling, activity
+		}
+	}
+
+	// Check whether the workload's GitOps source is suspended or rolling out a change
+	if guard.gitOps != nil && workload.Kind != "Pod" {
+		blocked, err := guard.gitOps.blocked(ctx, workload)
+		if err != nil {
+			logger.Error(err, "Failed to check GitOps source", "pod", pod.Name)
+		} else if blocked != "" {
+			return operatorv1alpha1.SkipGitOps, blocked
+		}
+	}
+
+	// Deleting a pod does not go through the disruption budgets the way an
+	// eviction does, so they are checked here
+	if !pr.Spec.IgnoreDisruptionBudgets {
+		blocked, err := guard.disruptions.blocked(ctx, pod)
+		if err != nil {
+			logger.Error(err, "Failed to check PodDisruptionBudgets", "pod", pod.Name)
+		} else if blocked != "" {
+			return operatorv1alpha1.SkipDisruptionBudget, blocked
+		}
+	}
+
+	if pr.Spec.DryRun {
+		return operatorv1alpha1.SkipDryRun,
+			fmt.Sprintf("spec.dryRun is set, pod %s would have been restarted: %s", pod.Name, result.Reason)
+	}
+	return "", ""
 }
 
-// shouldRestartPod checks if a pod should be restarted based on log patterns or metrics
-func (r *PodRestartReconciler) shouldRestartPod(ctx context.Context, clientset *kubernetes.Clientset, pod corev1.Pod, pr *operatorv1alpha1.PodRestart) (bool, string) {
-	// Check log patterns if specified
-	if len(pr.Spec.ErrorPatterns) > 0 {
+// restartPod performs the restart sequence for a pod whose trigger fired:
+// diagnostics capture, deletion, status, audit, events and notifications. It
+// reports whether the pod was restarted; a deferred, skipped or failed
+// restart is decided again on a later pass.
+func (r *PodRestartReconciler) restartPod(ctx context.Context, podRestart *operatorv1alpha1.PodRestart, cluster *clusterTarget, pod *corev1.Pod, result *triggerResult, eval *evaluation) bool {
+	logger := log.FromContext(ctx)
+	trigger, reason := result.Trigger, result.Reason
+	code := result.reasonCode()
+	codeAnnotations := map[string]string{AnnotationReasonCode: string(code)}
+
+	// Restart the pod by deleting it (the controller will recreate it)
+	logger.Info("Restarting pod due to error condition",
+		"pod", pod.Name,
+		"reason", reason)
+
+	now := metav1.Now()
+	record := operatorv1alpha1.RestartRecord{
+		PodName:    pod.Name,
+		Trigger:    trigger,
+		ReasonCode: code,
+		Reason:     reason,
+		Time:       now,
+		Outcome:    operatorv1alpha1.RestartSucceeded,
+
+		Workload:            workloadFor(pod).String(),
+		OOMKilledContainers: oomKilledContainers(pod),
+	}
+	if pod.Namespace != podRestart.Namespace {
+		record.Namespace = pod.Namespace
+	}
+
+	auditRecord := newAuditRecord(podRestart, pod, AuditDecisionRestart)
+	auditRecord.Trigger = trigger
+	auditRecord.Reason = reason
+	auditRecord.EvidenceHash = evidenceHash(result)
+	auditRecord.Action = string(restartAction(podRestart))
+	if result.Quarantine {
+		auditRecord.Action = string(operatorv1alpha1.ExitCodeQuarantine)
+	}
+	auditRecord.Identity = cluster.identity
+
+	// A surge restart waits for its extra pod across passes
+	var surgeErr error
+	if restartAction(podRestart) == operatorv1alpha1.ActionSurge && !result.Quarantine {
+		var pending bool
+		if pending, surgeErr = r.surgePending(ctx, cluster, podRestart, pod); pending {
+			logger.Info("Waiting for surge pod before restarting pod", "pod", pod.Name)
+			return false
+		}
+	}
+
+	d := podRestart.Spec.Diagnostics
+	diagnostics := result.Diagnostics || d != nil && d.Enabled
+	if diagnostics && d != nil && d.DebugContainer != nil && r.debugContainerPending(ctx, podRestart, cluster, pod, d.DebugContainer) {
+		// Restarted on a later pass once the debug container finished
+		logger.Info("Waiting for debug container before restarting pod", "pod", pod.Name)
+		return false
+	}
+
+	done := r.inflight.start(podRestart, pod)
+	if diagnostics {
+		ref, url, err := r.captureDiagnostics(ctx, podRestart, cluster, pod)
+		if err != nil {
+			// A missing bundle must never block remediation
+			logger.Error(err, "Failed to capture diagnostics bundle", "pod", pod.Name)
+		}
+		record.DiagnosticsRef = ref
+		record.DiagnosticsURL = url
+	}
+	if c := podRestart.Spec.ContainerCheckpoint; c != nil && c.Enabled {
+		record.Checkpoints = r.checkpointContainers(ctx, podRestart, cluster, pod)
+	}
+
+	var err error
+	switch {
+	case result.Quarantine:
+		record.Quarantined = true
+		err = r.quarantinePod(ctx, cluster, podRestart, pod, result)
+	case surgeErr != nil:
+		err = surgeErr
+	case restartAction(podRestart) == operatorv1alpha1.ActionSurge:
+		err = r.surgeRestart(ctx, cluster, podRestart, pod, result)
+	default:
+		err = r.deletePod(ctx, cluster, podRestart, pod, result)
+	}
+	done()
+	if errors.IsNotFound(err) {
+		// Deleted by someone else since restartBlocked looked; not a failure
+		// and not a restart of ours, so no budget is used
+		r.skipRestart(ctx, podRestart, pod, result, operatorv1alpha1.SkipTerminating, "Pod was already deleted")
+		return false
+	}
+	if err != nil {
+		failure := r.retries.failed(types.NamespacedName{Namespace: podRestart.Namespace, Name: podRestart.Name}, pod.UID, err, now.Time)
+		logger.Error(err, "Failed to delete pod for restart",
+			"pod", pod.Name,
+			"attempts", failure.attempts,
+			"permanent", failure.permanent,
+			"retryAt", failure.next)
+		auditRecord.Result = string(operatorv1alpha1.RestartFailed)
+		auditRecord.Error = err.Error()
+		r.audit(ctx, auditRecord)
+		record.Outcome = operatorv1alpha1.RestartFailed
+		record.Message = err.Error()
+		recordRestart(podRestart, record)
+		degradedReason := "RestartFailed"
+		if failure.persistent() {
+			degradedReason = "RestartFailing"
+		}
+		eval.degrade(degradedReason, fmt.Sprintf("Failed to delete pod %s (attempt %d, next retry at %s): %v",
+			pod.Name, failure.attempts, failure.next.UTC().Format(time.RFC3339), err))
+		r.recordAnnotatedPodEvent(podRestart, pod, codeAnnotations, corev1.EventTypeWarning, EventReasonRestartFailed,
+			"Failed to restart pod %s: %v", pod.Name, err)
+		r.notify(ctx, podRestart, Notification{
+			Event:       operatorv1alpha1.EventRestartFailed,
+			Pod:         pod.Name,
+			Workload:    workloadFor(pod).String(),
+			Trigger:     trigger,
+			TriggerName: result.Name,
+			ReasonCode:  code,
+			Reason:      reason,
+			Message:     err.Error(),
+			MatchedLine: result.MatchedLine,
+			MetricValue: result.MetricValue,
+			Persistent:  failure.persistent(),
+			Time:        now.Time,
+		})
+		if tripCircuit(podRestart, now.Time) {
+			until := podRestart.Status.CircuitOpenUntil.Time
+			r.Recorder.Eventf(podRestart, corev1.EventTypeWarning, EventReasonCircuitOpen,
+				"%d restarts failed in a row, no restarts are attempted until %s",
+				podRestart.Status.ConsecutiveRestartFailures, until.UTC().Format(time.RFC3339))
+			r.notify(ctx, podRestart, Notification{
+				Event: operatorv1alpha1.EventCircuitOpen,
+				Reason: fmt.Sprintf("%d restarts failed in a row, last error: %v",
+					podRestart.Status.ConsecutiveRestartFailures, err),
+				Time: now.Time,
+			})
+		}
+		return false
+	}
+
+	// Update the PodRestart status
+	r.retries.succeeded(types.NamespacedName{Namespace: podRestart.Namespace, Name: podRestart.Name}, pod.UID)
+	resetCircuit(podRestart)
+	podRestart.Status.LastRestartTime = &now
+	recordTriggerRestart(podRestart, result, now)
+	podRestart.Status.RestartCount++
+	consumeBudget(podRestart, now)
+	recordRestart(podRestart, record)
+	r.awaitReplacement(ctx, podRestart, pod, now)
+	auditRecord.Result = string(operatorv1alpha1.RestartSucceeded)
+	r.audit(ctx, auditRecord)
+	restartsTotal.WithLabelValues(podRestart.Namespace, podRestart.Name, trigger, string(code)).Inc()
+	if len(record.OOMKilledContainers) > 0 {
+		r.recommendMemory(ctx, podRestart, cluster, pod, record.OOMKilledContainers)
+	}
+
+	message, err := restartMessage(podRestart, pod, result)
+	if err != nil {
+		logger.Error(err, "Failed to render message template")
+	}
+	eval.restarted = append(eval.restarted, pod.Name)
+	eval.reasonCodes = append(eval.reasonCodes, code)
+	eval.lastMessage = message
+	r.recordAnnotatedPodEvent(podRestart, pod, codeAnnotations, corev1.EventTypeNormal, EventReasonPodRestarted, "%s", message)
+	r.notify(ctx, podRestart, Notification{
+		Event:       operatorv1alpha1.EventRestarted,
+		Pod:         pod.Name,
+		Workload:    workloadFor(pod).String(),
+		Trigger:     trigger,
+		TriggerName: result.Name,
+		ReasonCode:  code,
+		Reason:      reason,
+		Message:     message,
+		MatchedLine: result.MatchedLine,
+		MetricValue: result.MetricValue,
+		Time:        now.Time,
+	})
+	if budgetExhausted(podRestart, now.Time) {
+		r.Recorder.Eventf(podRestart, corev1.EventTypeWarning, EventReasonBudgetExhausted,
+			"Restart budget of %d restarts per %s exhausted, further restarts are blocked",
+			podRestart.Spec.RestartBudget.MaxRestarts, podRestart.Spec.RestartBudget.Window.Duration)
+		r.notify(ctx, podRestart, Notification{
+			Event: operatorv1alpha1.EventBudgetExhausted,
+			Reason: fmt.Sprintf("%d of %d restarts used in the last window",
+				podRestart.Status.RestartsInWindow, podRestart.Spec.RestartBudget.MaxRestarts),
+			Time: now.Time,
+		})
+	}
+	return true
+}
+
+// finishReconcile computes the conditions for this pass and patches the status
+func (r *PodRestartReconciler) finishReconcile(ctx context.Context, pr, base *operatorv1alpha1.PodRestart, eval *evaluation) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	previousPhase := pr.Status.Phase
+	setConditions(pr, eval, time.Now())
+	r.lastPasses.record(types.NamespacedName{Namespace: pr.Namespace, Name: pr.Name}, eval, time.Now())
+	pr.Status.ObservedGeneration = pr.Generation
+	if r.webhooks != nil {
+		r.webhooks.record(pr)
+	}
+	if eval.stats != nil {
+		pr.Status.LastEvaluation = eval.stats.summary(metav1.Now())
+		if eval.sample != nil {
+			pr.Status.LastEvaluation.SampleRound = eval.sample.String()
+		}
+	}
+	if eval.report != nil {
+		if err := r.writeEvaluationReport(ctx, pr, eval.report, time.Now()); err != nil {
+			logger.Error(err, "Failed to write evaluation report")
+		}
+	}
+
+	refreshBudget(pr, time.Now())
+	if budget := pr.Spec.RestartBudget; budget != nil {
+		remaining := budget.MaxRestarts - pr.Status.RestartsInWindow
+		budgetRemaining.WithLabelValues(pr.Namespace, pr.Name).Set(float64(remaining))
+		pr.Status.BudgetRemaining = &remaining
+	} else {
+		budgetRemaining.DeleteLabelValues(pr.Namespace, pr.Name)
+		pr.Status.BudgetRemaining = nil
+	}
+
+	recovering := previousPhase == operatorv1alpha1.PhaseDegraded || previousPhase == operatorv1alpha1.PhaseBudgetExhausted
+	if recovering && pr.Status.Phase == operatorv1alpha1.PhaseActive {
+		r.notify(ctx, pr, Notification{
+			Event:  operatorv1alpha1.EventRecovered,
+			Reason: fmt.Sprintf("PodRestart is %s again after being %s", pr.Status.Phase, previousPhase),
+		})
+	}
+
+	// Without targeted pods there is nothing to scan until a pod watch event
+	// arrives, so only requeue for housekeeping such as diagnostics expiry.
+	// The backoff advances before the status is written so the checkpoint
+	// includes this pass.
+	key := types.NamespacedName{Namespace: pr.Namespace, Name: pr.Name}
+	requeueAfter := pr.CheckIntervalDuration()
+	switch {
+	case pr.Spec.Suspend || pr.Status.TargetedPods == 0:
+		requeueAfter = idleRequeueInterval
+	case eval.degradedReason != "":
+		requeueAfter = r.backoff.next(key, requeueAfter)
+	default:
+		r.backoff.reset(key)
+	}
+	requeueAfter = r.spread.jittered(requeueAfter)
+	// A pending restart retry may be due before the next regular pass
+	if next, ok := r.retries.nextRetry(key, time.Now()); ok {
+		if wait := time.Until(next); wait < requeueAfter {
+			requeueAfter = wait
+			if requeueAfter < time.Second {
+				requeueAfter = time.Second
+			}
+		}
+	}
+	// A restart waiting for its debug container or surge is retried shortly
+	if r.debugRuns.waiting(key) && requeueAfter > debugContainerRecheck {
+		requeueAfter = debugContainerRecheck
+	}
+	if len(r.surges.of(key)) > 0 && requeueAfter > surgeRecheck {
+		requeueAfter = surgeRecheck
+	}
+	r.checkpointState(pr)
+
+	// Persist the restarts of this pass even when shutdown began meanwhile
+	patchCtx, cancel := gracefulContext(ctx, r.ShutdownGracePeriod)
+	defer cancel()
+	if err := r.patchStatus(patchCtx, pr, base); err != nil {
+		logger.Error(err, "Failed to update PodRestart status")
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{RequeueAfter: requeueAfter}, nil
+}
+
+// triggerResult describes a restart trigger that fired for a pod
+type triggerResult struct {
+	// Trigger is the kind of condition that fired
+	Trigger string
+	// Name is the error pattern or metric condition that fired
+	Name string
+	// Reason is a human readable description of what fired
+	Reason string
+	// MatchedLine is the log line that matched an error pattern
+	MatchedLine string
+	// MetricValue is the observed value of a breached metric condition
+	MetricValue *float64
+	// NotifyOnly marks triggers that only send a notification, never restart the pod
+	NotifyOnly bool
+	// Quarantine takes the pod out of its workload instead of deleting it,
+	// for QuarantineTTL
+	Quarantine    bool
+	QuarantineTTL time.Duration
+	// Diagnostics captures a diagnostics bundle even when spec.diagnostics does not
+	Diagnostics bool
+	// Code overrides the reason code of the trigger kind, e.g. for an
+	// exit code condition matching an OOM kill
+	Code operatorv1alpha1.RestartReasonCode
+}
+
+// shouldRestartPod checks if a pod should be restarted based on log patterns or metrics.
+// It returns nil when no trigger fired.
+func (r *PodRestartReconciler) shouldRestartPod(ctx context.Context, cluster *clusterTarget, logs LogSource, pod corev1.Pod, pr *operatorv1alpha1.PodRestart, patterns []errorPattern) *triggerResult {
+	// Triggers that only warn do not end the evaluation, so a critical
+	// trigger checked later still restarts the pod. The first warning is
+	// returned when no critical trigger fires. With trigger groups every
+	// trigger is evaluated, since a group needs all of its members.
+	var warning *triggerResult
+	var groupMembers []*triggerResult
+	fired := func(result *triggerResult) bool {
+		if result == nil {
+			return false
+		}
+		if inTriggerGroup(pr, result) {
+			groupMembers = append(groupMembers, result)
+			return false
+		}
+		if warnOnly(pr, result) {
+			result.NotifyOnly = true
+		}
+		if !result.NotifyOnly {
+			return true
+		}
+		if warning == nil {
+			warning = result
+		}
+		return false
+	}
+	if result := r.checkExitCodes(&pod, pr); fired(result) {
+		return result
+	}
+	if result := r.checkForbiddenImages(&pod, pr); fired(result) {
+		return result
+	}
+
+	// Check log patterns if specified. Every container is scanned, even after
+	// a match, so pattern match metrics reflect all hot patterns.
+	if len(patterns) > 0 {
+		var matched []*triggerResult
 		for _, container := range pod.Spec.Containers {
-			podLogOpts := corev1.PodLogOptions{
-				Container: container.Name,
-				// Limit to recent logs (last 5 minutes)
-				SinceSeconds: ptr(int64(300)),
+			matched = append(matched, r.scanContainerLogs(ctx, logs, &pod, container.Name, pr, patterns)...)
+		}
+		for _, result := range matched {
+			if fired(result) {
+				return result
 			}
+		}
+	}
 
-			req := clientset.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, &podLogOpts)
-			podLogs, err := req.Stream(ctx)
-			if err != nil {
-				r.Log.Error(err, "Failed to get pod logs",
-					"pod", pod.Name,
-					"container", container.Name)
-				continue
+	// Check metric conditions against their MetricProviders
+	for _, cond := range pr.Spec.MetricConditions {
+		if cond.Provider == "" {
+			cond.Provider = r.defaultMetricProvider()
+		}
+		if cond.Provider == "" {
+			r.Log.Info("Skipping metric condition without a provider", "metric", cond.Name)
+			continue
+		}
+
+		breached, value, err := r.checkMetricCondition(ctx, &pod, cond)
+		if err != nil {
+			r.Log.Error(err, "Failed to evaluate metric condition",
+				"pod", pod.Name,
+				"metric", cond.Name,
+				"provider", cond.Provider)
+			r.Recorder.Eventf(pr, corev1.EventTypeWarning, EventReasonMetricQueryFailed,
+				"Failed to evaluate metric %s for pod %s using provider %s: %v", cond.Name, pod.Name, cond.Provider, err)
+			continue
+		}
+
+		if breached {
+			conditionMatchesTotal.WithLabelValues(pr.Namespace, pr.Name, cond.Name).Inc()
+			result := &triggerResult{
+				Trigger: TriggerMetricCondition,
+				Name:    cond.Name,
+				Reason: fmt.Sprintf("Metric %s is %g (threshold %s %s)",
+					cond.Name, value, cond.Operator, cond.Threshold),
+				MetricValue: &value,
+			}
+			if fired(result) {
+				return result
+			}
+		}
+	}
+
+	if result := r.checkBurnRates(ctx, &pod, pr); fired(result) {
+		return result
+	}
+
+	if cond := pr.Spec.JVM; cond != nil {
+		if result := r.checkJVM(ctx, cluster, &pod, pr, cond); fired(result) {
+			return result
+		}
+	}
+
+	// Exec based checks run last since they are the most expensive
+	if cond := pr.Spec.ZombieProcesses; cond != nil {
+		if result := r.checkZombieProcesses(ctx, cluster, &pod, pr, cond); fired(result) {
+			return result
+		}
+	}
+	if cond := pr.Spec.Connections; cond != nil {
+		if result := r.checkConnections(ctx, cluster, &pod, pr, cond); fired(result) {
+			return result
+		}
+	}
+	for _, cond := range pr.Spec.HeartbeatFiles {
+		if result := r.checkHeartbeatFile(ctx, cluster, &pod, pr, cond); fired(result) {
+			return result
+		}
+	}
+
+	if result := r.matchTriggerGroups(pr, groupMembers); result != nil {
+		return result
+	}
+	return warning
+}
+
+// scanContainerLogs fetches the recent logs of a container and matches them
+// against the PodRestart's error patterns. It counts every match and returns
+// the first match of each pattern, in pattern order. The cursor is staged
+// only when the logs were read to the end, not truncated by sampling or the
+// log read budget.
+func (r *PodRestartReconciler) scanContainerLogs(ctx context.Context, logs LogSource, pod *corev1.Pod, container string, pr *operatorv1alpha1.PodRestart, patterns []errorPattern) []*triggerResult {
+	source := pr.Spec.LogSource
+	if source == "" {
+		source = LogSourceKubeAPI
+	}
+	start := time.Now()
+	defer func() {
+		logFetchDuration.WithLabelValues(pr.Namespace, pr.Name, source).Observe(time.Since(start).Seconds())
+	}()
+
+	fetchCtx, fetchSpan := tracer.Start(ctx, "FetchLogs", trace.WithAttributes(
+		attribute.String("pod", pod.Name),
+		attribute.String("container", container),
+		attribute.String("log.source", source),
+	))
+	podLogs, stageCursor, err := r.openLogStream(fetchCtx, logs, pr, pod, container)
+	if err != nil {
+		fetchSpan.RecordError(err)
+		fetchSpan.SetStatus(codes.Error, err.Error())
+	}
+	fetchSpan.End()
+	if goerrors.Is(err, errLogReadBudgetExhausted) {
+		return nil
+	}
+	if err != nil {
+		r.Log.Error(err, "Failed to get pod logs",
+			"pod", pod.Name,
+			"container", container)
+		r.Recorder.Eventf(pr, corev1.EventTypeWarning, EventReasonLogFetchFailed,
+			"Failed to get logs of pod %s container %s: %v", pod.Name, container, err)
+		return nil
+	}
+	defer podLogs.Close()
+	sampled := newSampledLogReader(podLogs, pr)
+	if sampled != nil {
+		podLogs = sampled
+		defer func() {
+			if sampled.truncated {
+				logsTruncatedTotal.WithLabelValues(pr.Namespace, pr.Name).Inc()
+				recordTruncatedLog(ctx, pod.Name, container, sampled.read)
 			}
-			defer podLogs.Close()
+		}()
+	}
+
+	_, matchSpan := tracer.Start(ctx, "MatchPatterns", trace.WithAttributes(
+		attribute.String("pod", pod.Name),
+		attribute.String("container", container),
+		attribute.Int("patterns", len(patterns)),
+	))
+	defer matchSpan.End()
 
-			// Read logs and check for patterns
-			buf := make([]byte, 2048)
-			for {
-				n, err := podLogs.Read(buf)
-				if err != nil {
-					break
+	counts := make([]int, len(patterns))
+	defer func() {
+		for i, n := range counts {
+			if n > 0 {
+				patternMatchesTotal.WithLabelValues(pr.Namespace, pr.Name, patterns[i].name).Add(float64(n))
+			}
+		}
+	}()
+
+	// Read logs and check for patterns
+	matched := make([]*triggerResult, len(patterns))
+	var found bool
+	buf := make([]byte, 2048)
+	for {
+		n, err := podLogs.Read(buf)
+		if err != nil {
+			if err == io.EOF && (sampled == nil || !sampled.truncated) {
+				stageCursor()
+			}
+			var results []*triggerResult
+			for _, result := range matched {
+				if result != nil {
+					results = append(results, result)
 				}
+			}
+			return results
+		}
 
-				logChunk := string(buf[:n])
-				for _, pattern := range pr.Spec.ErrorPatterns {
-					matched, err := regexp.MatchString(pattern, logChunk)
-					if err != nil {
-						r.Log.Error(err, "Error matching pattern",
-							"pattern", pattern)
-						continue
-					}
-
-					if matched {
-						return true, fmt.Sprintf("Found error pattern '%s' in logs", pattern)
-					}
+		logChunk := string(buf[:n])
+		for i, pattern := range patterns {
+			locs := pattern.re.FindAllStringIndex(logChunk, -1)
+			if len(locs) == 0 {
+				continue
+			}
+			counts[i] += len(locs)
+			if !found {
+				found = true
+				matchSpan.SetAttributes(attribute.String("matched.pattern", pattern.name))
+			}
+			if matched[i] == nil {
+				matched[i] = &triggerResult{
+					Trigger:     TriggerErrorPattern,
+					Name:        pattern.name,
+					Reason:      fmt.Sprintf("Found error pattern '%s' in logs", pattern.name),
+					MatchedLine: lineAt(logChunk, locs[0][0]),
 				}
 			}
 		}
 	}
+}
 
-	// Metric conditions would be checked here
-	// This is a simplified example - in a real implementation, you would
-	// connect to a metrics provider (Prometheus, etc.) and check the conditions
-	if len(pr.Spec.MetricConditions) > 0 {
-		// This would be replaced with actual metric checking logic
-		r.Log.Info("Metric condition checking is not implemented in this example")
+// lineAt returns the line of s containing the byte offset i
+func lineAt(s string, i int) string {
+	start := strings.LastIndexByte(s[:i], '\n') + 1
+	end := strings.IndexByte(s[i:], '\n')
+	if end < 0 {
+		return s[start:]
 	}
+	return s[start : i+end]
+}
+
+// skipRestart records a suppressed restart in status and emits events about it
+func (r *PodRestartReconciler) skipRestart(ctx context.Context, pr *operatorv1alpha1.PodRestart, pod *corev1.Pod, result *triggerResult, reason operatorv1alpha1.SkipReason, message string) {
+	r.auditTriggered(ctx, pr, pod, result, AuditDecisionSkip, string(reason), message)
 
-	return false, ""
+	restartsSkippedTotal.WithLabelValues(pr.Namespace, pr.Name, string(reason)).Inc()
+	pr.Status.SkippedRestarts = append(pr.Status.SkippedRestarts, operatorv1alpha1.SkippedRestart{
+		PodName: pod.Name,
+		Reason:  reason,
+		Message: message,
+		Time:    metav1.Now(),
+	})
+	r.recordPodEvent(pr, pod, corev1.EventTypeNormal, EventReasonRestartSkipped,
+		"Restart of pod %s skipped (%s): %s", pod.Name, reason, message)
+	r.notify(ctx, pr, Notification{
+		Event:       operatorv1alpha1.EventRestartSkipped,
+		Pod:         pod.Name,
+		Workload:    workloadFor(pod).String(),
+		Trigger:     result.Trigger,
+		TriggerName: result.Name,
+		ReasonCode:  result.reasonCode(),
+		Reason:      string(reason),
+		Message:     message,
+	})
+}
+
+// recordRestart prepends a restart record to the status history, trimming it to
+// the configured history limit
+func recordRestart(pr *operatorv1alpha1.PodRestart, record operatorv1alpha1.RestartRecord) {
+	limit := historyLimit(pr)
+	history := append([]operatorv1alpha1.RestartRecord{record}, pr.Status.RecentRestarts...)
+	if len(history) > limit {
+		history = history[:limit]
+	}
+	pr.Status.RecentRestarts = history
+}
+
+// deletePod deletes a pod of the target cluster inside a trace span. The pod
+// is first annotated with the PodRestart and trigger responsible, so the
+// patch and delete requests in the API server audit log and the pod's last
+// state attribute the disruption. The delete is bound to the pod's UID so a
+// replacement with the same name is never deleted by mistake.
+func (r *PodRestartReconciler) deletePod(ctx context.Context, cluster *clusterTarget, pr *operatorv1alpha1.PodRestart, pod *corev1.Pod, result *triggerResult) error {
+	ctx, span := tracer.Start(ctx, "DeletePod", trace.WithAttributes(attribute.String("pod", pod.Name)))
+	defer span.End()
+
+	// Checked again right before acting in case the policy changed meanwhile
+	if err := r.checkNamespace(pod.Namespace); err != nil {
+		return err
+	}
+	if err := stampRestart(ctx, cluster.writer, pr, pod, result); err != nil {
+		if errors.IsNotFound(err) {
+			return err
+		}
+		// Attribution must never block remediation
+		log.FromContext(ctx).Error(err, "Failed to annotate pod before restart", "pod", pod.Name)
+	}
+
+	err := cluster.writer.Delete(ctx, pod, client.Preconditions{UID: &pod.UID})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return err
 }
 
 // Helper for creating pointers to int64
@@ -207,9 +1361,106 @@ func ptr(i int64) *int64 {
 	return &i
 }
 
+// logsConfig returns the config of the clientsets reading pod logs, with
+// LogsQPS and LogsBurst applied
+func (r *PodRestartReconciler) logsConfig() *rest.Config {
+	config := rest.CopyConfig(r.restConfig)
+	if r.LogsQPS > 0 {
+		config.QPS = r.LogsQPS
+	}
+	if r.LogsBurst > 0 {
+		config.Burst = r.LogsBurst
+	}
+	return config
+}
+
 // SetupWithManager sets up the controller with the Manager
 func (r *PodRestartReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
-		For(&operatorv1alpha1.PodRestart{}).
-		Complete(r)
+	r.restConfig = mgr.GetConfig()
+	clientset, err := kubernetes.NewForConfig(r.logsConfig())
+	if err != nil {
+		return err
+	}
+	r.clientset = clientset
+	r.apiReader = mgr.GetAPIReader()
+
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &operatorv1alpha1.PodRestart{},
+		podSelectorIndex, podSelectorTerms); err != nil {
+		return err
+	}
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &operatorv1alpha1.PodRestart{},
+		targetNamespaceIndex, targetNamespaceTerms); err != nil {
+		return err
+	}
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &corev1.Pod{},
+		podWorkloadIndex, podWorkloadTerms); err != nil {
+		return err
+	}
+	r.throttle = newNotificationThrottle()
+	r.alertAliases = newAlertAliases()
+	r.patterns = newPatternCache()
+	r.rounds = newEvaluationRounds()
+	r.logBudget = newSharedLogBudget(r.LogReadBytesPerMinute)
+	r.cursors = newLogCursors()
+	r.logShare = newLogShare()
+	r.backoff = newRequeueBackoff(r.BackoffMax)
+	r.restores = newStateRestores()
+	r.inflight = newRestartsInFlight()
+	r.retries = newRestartRetries()
+	r.spread = newRequeueSpread(r.RequeueJitter, r.StartupSpread)
+	r.scheduler = newEvaluationScheduler(r.evaluationSlots())
+	r.follower = newLogFollower(r.MaxFollowStreams)
+	r.lastPasses = newLastPasses()
+	r.probes = newProbeFailures()
+	r.debugRuns = newDebugRuns()
+	r.surges = newSurges()
+	r.uploads = make(chan struct{}, maxConcurrentUploads)
+	r.webhooks = newWebhookQueue()
+	if err := mgr.Add(r.follower); err != nil {
+		return err
+	}
+	if err := mgr.Add(r.webhooks); err != nil {
+		return err
+	}
+	r.remotes = newRemoteClusters()
+	r.impersonators = newImpersonatingClients()
+	r.queriers = newQuerierCache()
+	if err := r.setupOperatorConfig(mgr); err != nil {
+		return err
+	}
+	if err := metrics.Registry.Register(newNamespaceSummary(mgr.GetCache())); err != nil {
+		return err
+	}
+
+	owned := predicate.NewPredicateFuncs(func(obj client.Object) bool {
+		return r.ownsNamespace(context.Background(), obj.GetNamespace())
+	})
+
+	// Pod changes are picked up within seconds; the periodic requeue only
+	// remains for log and metric triggers, which produce no watch events
+	b := ctrl.NewControllerManagedBy(mgr).
+		// Status-only updates, including the ones this controller writes, do
+		// not need another evaluation pass; new restart requests do
+		For(&operatorv1alpha1.PodRestart{}, builder.WithPredicates(
+			predicate.Or(predicate.GenerationChangedPredicate{}, restartRequested), owned)).
+		WithOptions(controller.Options{
+			MaxConcurrentReconciles: r.MaxConcurrentReconciles,
+			CacheSyncTimeout:        r.CacheSyncTimeout,
+			RateLimiter:             newRateLimiter(r.BackoffBase, r.BackoffMax, r.ReconcileQPS, r.ReconcileBurst),
+		}).
+		Watches(&corev1.Pod{},
+			handler.EnqueueRequestsFromMapFunc(r.podRestartsForPod),
+			builder.WithPredicates(podStateChanged, owned)).
+		Watches(&operatorv1alpha1.PodRestartPolicy{},
+			handler.EnqueueRequestsFromMapFunc(r.podRestartsForPolicy),
+			builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		// Error pattern matches in followed log streams
+		WatchesRawSource(&source.Channel{Source: r.follower.events}, &handler.EnqueueRequestForObject{})
+	if _, ok := r.Shard.(*labelSharder); ok {
+		// Relabeling a namespace can move it into this shard
+		b = b.Watches(&corev1.Namespace{},
+			handler.EnqueueRequestsFromMapFunc(r.podRestartsInNamespace),
+			builder.WithPredicates(predicate.LabelChangedPredicate{}))
+	}
+	return b.Complete(r)
 }
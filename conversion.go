@@ -0,0 +1,6 @@
+// conversion.go
+package v1alpha1
+
+// Hub marks v1alpha1 as the version PodRestarts are stored in and converted
+// through. The webhook builder serves /convert for the other versions.
+func (*PodRestart) Hub() {}
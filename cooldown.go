@@ -0,0 +1,123 @@
+// cooldown.go
+package controllers
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	operatorv1alpha1 "github.com/example/pod-restart-operator/api/v1alpha1"
+)
+
+// optionsFor returns the options of the condition that produced a result.
+// Unnamed error patterns have none.
+func optionsFor(pr *operatorv1alpha1.PodRestart, result *triggerResult) operatorv1alpha1.TriggerOptions {
+	spec := &pr.Spec
+	switch result.Trigger {
+	case TriggerErrorPattern:
+		for _, p := range spec.NamedErrorPatterns {
+			if p.Name == result.Name {
+				return p.TriggerOptions
+			}
+		}
+	case TriggerMetricCondition:
+		for _, c := range spec.MetricConditions {
+			if c.Name == result.Name {
+				return c.TriggerOptions
+			}
+		}
+	case TriggerBurnRate:
+		for _, c := range spec.BurnRates {
+			if c.Name == result.Name {
+				return c.TriggerOptions
+			}
+		}
+	case TriggerHeartbeatFile:
+		for _, c := range spec.HeartbeatFiles {
+			if c.Path == result.Name {
+				return c.TriggerOptions
+			}
+		}
+	case TriggerExitCode:
+		for _, c := range spec.ExitCodes {
+			if c.Name == result.Name {
+				return c.TriggerOptions
+			}
+		}
+	case TriggerForbiddenImage:
+		for _, c := range spec.ForbiddenImages {
+			if c.Name == result.Name {
+				return c.TriggerOptions
+			}
+		}
+	case TriggerZombieProcesses:
+		if c := spec.ZombieProcesses; c != nil {
+			return c.TriggerOptions
+		}
+	case TriggerConnectionCount:
+		if c := spec.Connections; c != nil {
+			return c.TriggerOptions
+		}
+	case TriggerJVM:
+		if c := spec.JVM; c != nil {
+			return c.TriggerOptions
+		}
+	}
+	return operatorv1alpha1.TriggerOptions{}
+}
+
+// triggerCooldown returns the minimum time between restarts set on the
+// condition that produced a result, or nil when the condition has none and
+// the PodRestart's cooldown applies
+func triggerCooldown(pr *operatorv1alpha1.PodRestart, result *triggerResult) *metav1.Duration {
+	return optionsFor(pr, result).MinTimeBetweenRestarts
+}
+
+// warnOnly reports whether the condition that produced a result only warns
+func warnOnly(pr *operatorv1alpha1.PodRestart, result *triggerResult) bool {
+	return optionsFor(pr, result).Severity == operatorv1alpha1.TriggerSeverityWarn
+}
+
+// cooldownName is the name a trigger's restarts are recorded under. The
+// conditions that exist once per PodRestart share one cooldown whichever
+// container or check fired.
+func cooldownName(result *triggerResult) string {
+	switch result.Trigger {
+	case TriggerZombieProcesses, TriggerConnectionCount, TriggerJVM:
+		return ""
+	}
+	return result.Name
+}
+
+// lastTriggerRestart returns when the trigger of a result last restarted a pod
+func lastTriggerRestart(pr *operatorv1alpha1.PodRestart, result *triggerResult) *metav1.Time {
+	name := cooldownName(result)
+	for i := range pr.Status.TriggerRestarts {
+		if t := &pr.Status.TriggerRestarts[i]; t.Trigger == result.Trigger && t.Name == name {
+			return &t.Time
+		}
+	}
+	return nil
+}
+
+// recordTriggerRestart remembers the restart for triggers with their own
+// cooldown and drops the entries of conditions that no longer have one
+func recordTriggerRestart(pr *operatorv1alpha1.PodRestart, result *triggerResult, now metav1.Time) {
+	var kept []operatorv1alpha1.TriggerRestartTime
+	for _, t := range pr.Status.TriggerRestarts {
+		entry := &triggerResult{Trigger: t.Trigger, Name: t.Name}
+		if triggerCooldown(pr, entry) == nil {
+			continue
+		}
+		if t.Trigger == result.Trigger && t.Name == cooldownName(result) {
+			continue
+		}
+		kept = append(kept, t)
+	}
+	if triggerCooldown(pr, result) != nil {
+		kept = append(kept, operatorv1alpha1.TriggerRestartTime{
+			Trigger: result.Trigger,
+			Name:    cooldownName(result),
+			Time:    now,
+		})
+	}
+	pr.Status.TriggerRestarts = kept
+}
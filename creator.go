@@ -0,0 +1,72 @@
+// creator.go
+package v1alpha1
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+const (
+	// AnnotationCreatedBy records the user that created the PodRestart, or
+	// that last changed its spec
+	AnnotationCreatedBy = "operator.example.com/created-by"
+	// AnnotationCreatedByGroups records the groups of that user, comma separated
+	AnnotationCreatedByGroups = "operator.example.com/created-by-groups"
+)
+
+// +kubebuilder:webhook:path=/mutate-operator-example-com-v1alpha1-podrestart,mutating=true,failurePolicy=fail,sideEffects=None,groups=operator.example.com,resources=podrestarts,verbs=create;update,versions=v1alpha1,matchPolicy=Equivalent,name=mpodrestart.kb.io,admissionReviewVersions=v1
+
+// creatorRecorder records the creator of a PodRestart at admission so the
+// operator can act with the creator's permissions. The annotations cannot
+// be set or changed by users. Whoever changes the spec becomes the creator,
+// so editing a PodRestart never borrows the permissions of the user that
+// created it.
+type creatorRecorder struct{}
+
+var _ admission.CustomDefaulter = &creatorRecorder{}
+
+// Default implements admission.CustomDefaulter
+func (*creatorRecorder) Default(ctx context.Context, obj runtime.Object) error {
+	pr, ok := obj.(*PodRestart)
+	if !ok {
+		return fmt.Errorf("expected a PodRestart but got %T", obj)
+	}
+	req, err := admission.RequestFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	created, groups := req.UserInfo.Username, strings.Join(req.UserInfo.Groups, ",")
+	if req.Operation == admissionv1.Update {
+		old := &PodRestart{}
+		if err := json.Unmarshal(req.OldObject.Raw, old); err != nil {
+			return fmt.Errorf("decoding the previous PodRestart: %w", err)
+		}
+		// Keep what was recorded while only metadata changes
+		if equality.Semantic.DeepEqual(old.Spec, pr.Spec) {
+			created, groups = old.Annotations[AnnotationCreatedBy], old.Annotations[AnnotationCreatedByGroups]
+		}
+	}
+
+	if pr.Annotations == nil {
+		pr.Annotations = map[string]string{}
+	}
+	setOrDelete(pr.Annotations, AnnotationCreatedBy, created)
+	setOrDelete(pr.Annotations, AnnotationCreatedByGroups, groups)
+	return nil
+}
+
+func setOrDelete(annotations map[string]string, key, value string) {
+	if value == "" {
+		delete(annotations, key)
+		return
+	}
+	annotations[key] = value
+}
@@ -0,0 +1,90 @@
+// credentials.go
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// errUnauthorized is returned by providers and notifiers rejecting the
+// credentials, which is what a rotated API key looks like until the new one
+// is picked up
+var errUnauthorized = errors.New("credentials rejected")
+
+// unauthorized wraps errUnauthorized for HTTP 401 and 403 responses
+func unauthorized(status int, format string, args ...interface{}) error {
+	if status != http.StatusUnauthorized && status != http.StatusForbidden {
+		return fmt.Errorf(format, args...)
+	}
+	return fmt.Errorf("%w: %s", errUnauthorized, fmt.Sprintf(format, args...))
+}
+
+type freshSecretsKey struct{}
+
+// withFreshSecrets makes secrets read through ctx bypass the informer cache,
+// so a Secret rotated moments ago is seen even before its watch event arrives
+func withFreshSecrets(ctx context.Context) context.Context {
+	return context.WithValue(ctx, freshSecretsKey{}, true)
+}
+
+func freshSecrets(ctx context.Context) bool {
+	fresh, _ := ctx.Value(freshSecretsKey{}).(bool)
+	return fresh
+}
+
+// querierCache keeps one querier per MetricProvider so connections are
+// reused. A querier is rebuilt whenever the provider or the credentials it
+// was built from change, which the informer cache observes through its
+// watch on Secrets.
+type querierCache struct {
+	mu       sync.Mutex
+	queriers map[string]cachedQuerier
+}
+
+type cachedQuerier struct {
+	fingerprint string
+	querier     MetricQuerier
+}
+
+func newQuerierCache() *querierCache {
+	return &querierCache{queriers: map[string]cachedQuerier{}}
+}
+
+// fingerprint identifies a provider version and the credentials read for it
+func fingerprint(resourceVersion string, credentials ...[]byte) string {
+	h := sha256.New()
+	h.Write([]byte(resourceVersion))
+	for _, c := range credentials {
+		h.Write([]byte{0})
+		h.Write(c)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// get returns the cached querier when its fingerprint is unchanged, or
+// stores the one returned by build
+func (c *querierCache) get(name, fp string, build func() (MetricQuerier, error)) (MetricQuerier, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if cached, ok := c.queriers[name]; ok && cached.fingerprint == fp {
+		return cached.querier, nil
+	}
+	querier, err := build()
+	if err != nil {
+		return nil, err
+	}
+	c.queriers[name] = cachedQuerier{fingerprint: fp, querier: querier}
+	return querier, nil
+}
+
+// forget drops the querier of a provider so the next query rebuilds it
+func (c *querierCache) forget(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.queriers, name)
+}
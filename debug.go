@@ -0,0 +1,135 @@
+// debug.go
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	operatorv1alpha1 "github.com/example/pod-restart-operator/api/v1alpha1"
+)
+
+// controllerName is the name controller-runtime derives from the PodRestart kind
+const controllerName = "podrestart"
+
+// restartsInFlight tracks the restarts currently being performed, from
+// diagnostics capture to pod deletion
+type restartsInFlight struct {
+	mu      sync.Mutex
+	started map[string]inFlightRestart
+}
+
+// inFlightRestart is a restart that has not finished yet
+type inFlightRestart struct {
+	PodRestart string    `json:"podRestart"`
+	Pod        string    `json:"pod"`
+	Started    time.Time `json:"started"`
+}
+
+func newRestartsInFlight() *restartsInFlight {
+	return &restartsInFlight{started: map[string]inFlightRestart{}}
+}
+
+// start records a restart and returns the function marking it done
+func (f *restartsInFlight) start(pr *operatorv1alpha1.PodRestart, pod *corev1.Pod) func() {
+	restart := inFlightRestart{
+		PodRestart: types.NamespacedName{Namespace: pr.Namespace, Name: pr.Name}.String(),
+		Pod:        pod.Name,
+		Started:    time.Now(),
+	}
+	key := string(pod.UID)
+	f.mu.Lock()
+	f.started[key] = restart
+	f.mu.Unlock()
+	return func() {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		delete(f.started, key)
+	}
+}
+
+func (f *restartsInFlight) list() []inFlightRestart {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	restarts := make([]inFlightRestart, 0, len(f.started))
+	for _, restart := range f.started {
+		restarts = append(restarts, restart)
+	}
+	sort.Slice(restarts, func(i, j int) bool {
+		return restarts[i].Started.Before(restarts[j].Started)
+	})
+	return restarts
+}
+
+// debugState is the internal state served by DebugHandler
+type debugState struct {
+	QueueDepth      float64           `json:"queueDepth"`
+	PodRestarts     []debugPodRestart `json:"podRestarts"`
+	PendingRestarts []inFlightRestart `json:"pendingRestarts"`
+	LogWindows      int               `json:"sharedLogWindows"`
+}
+
+// debugPodRestart is the in-memory state kept for a single PodRestart
+type debugPodRestart struct {
+	Name           string `json:"name"`
+	DegradedPasses int    `json:"degradedPasses,omitempty"`
+	LogCursors     int    `json:"logCursors,omitempty"`
+	EvaluatedPods  int    `json:"evaluatedPodsThisRound,omitempty"`
+}
+
+// DebugHandler serves the controller's internal state as JSON: the
+// PodRestarts reconciled by this process, restarts in progress and the
+// workqueue depth. It is meant for an operator-only debug port.
+func (r *PodRestartReconciler) DebugHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		state := debugState{
+			QueueDepth:      queueDepth(),
+			PodRestarts:     []debugPodRestart{},
+			PendingRestarts: r.inflight.list(),
+		}
+		for _, key := range r.restores.keys() {
+			state.PodRestarts = append(state.PodRestarts, debugPodRestart{
+				Name:           key.String(),
+				DegradedPasses: r.backoff.failuresOf(key),
+				LogCursors:     r.cursors.count(key),
+				EvaluatedPods:  r.rounds.count(key),
+			})
+		}
+		r.logShare.mu.Lock()
+		state.LogWindows = len(r.logShare.entries)
+		r.logShare.mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(state)
+	})
+}
+
+// queueDepth reads the depth of the PodRestart workqueue from the
+// controller-runtime metrics registry
+func queueDepth() float64 {
+	families, err := metrics.Registry.Gather()
+	if err != nil {
+		return 0
+	}
+	for _, family := range families {
+		if family.GetName() != "workqueue_depth" {
+			continue
+		}
+		for _, m := range family.GetMetric() {
+			for _, label := range m.GetLabel() {
+				if label.GetName() == "name" && label.GetValue() == controllerName {
+					return m.GetGauge().GetValue()
+				}
+			}
+		}
+	}
+	return 0
+}
@@ -0,0 +1,209 @@
+// debugcontainer.go
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	operatorv1alpha1 "github.com/example/pod-restart-operator/api/v1alpha1"
+)
+
+const (
+	defaultDebugContainerTimeout = 2 * time.Minute
+
+	// debugContainerRecheck is how soon a PodRestart waiting for a debug
+	// container is evaluated again
+	debugContainerRecheck = 10 * time.Second
+
+	// debugRunExpiry is how long after its timeout a debug container whose
+	// pod was not restarted, e.g. because its trigger stopped firing, is
+	// forgotten
+	debugRunExpiry = 10 * time.Minute
+)
+
+// debugRuns tracks the debug containers started for pods about to be
+// restarted. The restart waits for the container on later passes rather
+// than blocking the reconcile that started it.
+type debugRuns struct {
+	mu   sync.Mutex
+	runs map[types.NamespacedName]map[types.UID]*debugRun
+}
+
+// debugRun is the debug container of a single pod
+type debugRun struct {
+	name     string
+	deadline time.Time
+	// done is set once the container finished, timed out or failed to
+	// start, err is set in the latter two cases
+	done bool
+	err  error
+}
+
+func newDebugRuns() *debugRuns {
+	return &debugRuns{runs: map[types.NamespacedName]map[types.UID]*debugRun{}}
+}
+
+func (d *debugRuns) get(key types.NamespacedName, uid types.UID) *debugRun {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.runs[key][uid]
+}
+
+// started records a debug container and drops those long past their deadline
+func (d *debugRuns) started(key types.NamespacedName, uid types.UID, run *debugRun, now time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	runs := d.runs[key]
+	if runs == nil {
+		runs = map[types.UID]*debugRun{}
+		d.runs[key] = runs
+	}
+	for u, r := range runs {
+		if now.Sub(r.deadline) > debugRunExpiry {
+			delete(runs, u)
+		}
+	}
+	runs[uid] = run
+}
+
+// take returns and forgets the debug container of a pod being restarted
+func (d *debugRuns) take(key types.NamespacedName, uid types.UID) *debugRun {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	run := d.runs[key][uid]
+	delete(d.runs[key], uid)
+	if len(d.runs[key]) == 0 {
+		delete(d.runs, key)
+	}
+	return run
+}
+
+// waiting reports whether a restart of the PodRestart waits for a debug container
+func (d *debugRuns) waiting(key types.NamespacedName) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, run := range d.runs[key] {
+		if !run.done {
+			return true
+		}
+	}
+	return false
+}
+
+// forget drops the debug containers of a deleted PodRestart
+func (d *debugRuns) forget(key types.NamespacedName) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.runs, key)
+}
+
+// debugImageAllowed reports whether image matches one of DebugContainerImages.
+// No image is allowed while the list is empty.
+func (r *PodRestartReconciler) debugImageAllowed(image string) bool {
+	return matchesAny(image, r.DebugContainerImages)
+}
+
+// debugContainerPending starts the debug container of a pod about to be
+// restarted and reports whether the restart has to wait for it. A container
+// that fails to start or does not finish in time no longer holds the
+// restart back; captureDiagnostics stores its error instead.
+func (r *PodRestartReconciler) debugContainerPending(ctx context.Context, pr *operatorv1alpha1.PodRestart, cluster *clusterTarget, pod *corev1.Pod, spec *operatorv1alpha1.DebugContainerSpec) bool {
+	if !r.featureEnabled(DebugContainers) || !r.debugImageAllowed(spec.Image) {
+		return false
+	}
+	key := types.NamespacedName{Namespace: pr.Namespace, Name: pr.Name}
+	now := time.Now()
+	run := r.debugRuns.get(key, pod.UID)
+	if run == nil {
+		timeout := defaultDebugContainerTimeout
+		if spec.Timeout != nil {
+			timeout = spec.Timeout.Duration
+		}
+		name, err := startDebugContainer(ctx, cluster, pod, spec, now)
+		run = &debugRun{name: name, deadline: now.Add(timeout), done: err != nil, err: err}
+		r.debugRuns.started(key, pod.UID, run, now)
+		return !run.done
+	}
+	if run.done {
+		return false
+	}
+	finished, err := debugContainerFinished(ctx, cluster, pod, run.name)
+	switch {
+	case err != nil:
+		run.done, run.err = true, err
+	case finished:
+		run.done = true
+	case now.After(run.deadline):
+		// Whatever it printed so far still helps
+		run.done, run.err = true, fmt.Errorf("debug container did not finish by %s", run.deadline.Format(time.RFC3339))
+	}
+	return !run.done
+}
+
+// startDebugContainer adds an ephemeral container sharing the process
+// namespace of the target container and returns its name. Ephemeral
+// containers cannot be removed again, which is fine since the pod is about
+// to be restarted.
+func startDebugContainer(ctx context.Context, cluster *clusterTarget, pod *corev1.Pod, spec *operatorv1alpha1.DebugContainerSpec, now time.Time) (string, error) {
+	target := spec.TargetContainer
+	if target == "" && len(pod.Spec.Containers) > 0 {
+		target = pod.Spec.Containers[0].Name
+	}
+	// Names must be unique among the pod's ephemeral containers
+	name := fmt.Sprintf("podrestart-debug-%d", now.Unix())
+
+	pods := cluster.clientset.CoreV1().Pods(pod.Namespace)
+	latest, err := pods.Get(ctx, pod.Name, metav1.GetOptions{})
+	if err != nil {
+		return name, err
+	}
+	if latest.UID != pod.UID {
+		return name, fmt.Errorf("pod %s was replaced", pod.Name)
+	}
+	latest.Spec.EphemeralContainers = append(latest.Spec.EphemeralContainers, corev1.EphemeralContainer{
+		EphemeralContainerCommon: corev1.EphemeralContainerCommon{
+			Name:                     name,
+			Image:                    spec.Image,
+			Command:                  spec.Command,
+			Args:                     spec.Args,
+			TerminationMessagePolicy: corev1.TerminationMessageFallbackToLogsOnError,
+		},
+		TargetContainerName: target,
+	})
+	_, err = pods.UpdateEphemeralContainers(ctx, pod.Name, latest, metav1.UpdateOptions{FieldManager: FieldManager})
+	return name, err
+}
+
+// debugContainerFinished reports whether the named debug container terminated
+func debugContainerFinished(ctx context.Context, cluster *clusterTarget, pod *corev1.Pod, name string) (bool, error) {
+	current, err := cluster.clientset.CoreV1().Pods(pod.Namespace).Get(ctx, pod.Name, metav1.GetOptions{})
+	if err != nil {
+		return false, err
+	}
+	if current.UID != pod.UID {
+		return false, fmt.Errorf("pod %s was replaced", pod.Name)
+	}
+	for _, status := range current.Status.EphemeralContainerStatuses {
+		if status.Name == name {
+			return status.State.Terminated != nil, nil
+		}
+	}
+	return false, nil
+}
+
+// debugContainerOutput returns what the named debug container printed
+func debugContainerOutput(ctx context.Context, cluster *clusterTarget, pod *corev1.Pod, name string) ([]byte, error) {
+	stream, err := cluster.clientset.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, &corev1.PodLogOptions{Container: name}).Stream(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer stream.Close()
+	return io.ReadAll(io.LimitReader(stream, maxBundleBytes))
+}
@@ -0,0 +1,70 @@
+// debugserver.go
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"time"
+)
+
+// debugServer serves net/http/pprof, the controller's debug state and, with
+// auth set, the in-memory state of single PodRestarts below
+// /debug/podrestarts/. It runs on every replica, not only the leader. With
+// tls set it serves HTTPS, and with auth set every request must be
+// authenticated and authorized.
+type debugServer struct {
+	addr        string
+	state       http.Handler
+	podRestarts http.Handler
+	tls         *inboundTLS
+	auth        *kubeAuth
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable
+func (s *debugServer) NeedLeaderElection() bool {
+	return false
+}
+
+// Start implements manager.Runnable
+func (s *debugServer) Start(ctx context.Context) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.Handle("/debug/state", s.state)
+
+	var handler http.Handler = mux
+	if s.auth != nil {
+		// PodRestart state is authorized per PodRestart, so it is never
+		// served unauthenticated
+		mux.Handle("/debug/podrestarts/", s.podRestarts)
+		handler = s.auth.wrap(mux)
+	}
+
+	ln, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return err
+	}
+	if s.tls != nil {
+		s.tls.start(ctx)
+		ln = tls.NewListener(ln, s.tls.config())
+	}
+	srv := &http.Server{Handler: handler, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shutdownCtx)
+	}()
+	setupLog.Info("Serving debug endpoints", "address", ln.Addr().String())
+	if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,271 @@
+// diagnostics.go
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	operatorv1alpha1 "github.com/example/pod-restart-operator/api/v1alpha1"
+)
+
+const (
+	// LabelPodRestart marks objects created on behalf of a PodRestart
+	LabelPodRestart = "operator.example.com/podrestart"
+	// LabelDiagnostics marks diagnostics bundles
+	LabelDiagnostics = "operator.example.com/diagnostics"
+	// AnnotationExpiresAt holds the RFC3339 time after which a bundle is deleted
+	AnnotationExpiresAt = "operator.example.com/expires-at"
+
+	defaultDiagnosticsLogLines = 200
+	defaultDiagnosticsTTL      = 24 * time.Hour
+
+	// maxBundleBytes keeps bundles comfortably below the 1MiB object size limit
+	maxBundleBytes = 900 * 1024
+)
+
+// diagnosticsBundle is the captured state of a pod before it is restarted
+type diagnosticsBundle struct {
+	data   map[string]string
+	binary map[string][]byte
+	size   int
+}
+
+// add stores an entry, truncating it if the bundle would exceed maxBundleBytes
+func (b *diagnosticsBundle) add(key, value string) {
+	if remaining := maxBundleBytes - b.size; len(value) > remaining {
+		if remaining <= 0 {
+			return
+		}
+		value = value[len(value)-remaining:]
+	}
+	b.data[key] = value
+	b.size += len(value)
+}
+
+// addBinary stores a binary entry if it fits as a whole, since a truncated
+// dump is useless. It reports whether the entry was stored.
+func (b *diagnosticsBundle) addBinary(key string, value []byte) bool {
+	if len(value) > maxBundleBytes-b.size {
+		return false
+	}
+	b.binary[key] = value
+	b.size += len(value)
+	return true
+}
+
+// diagnosticsFile is a single entry of a diagnostics bundle
+type diagnosticsFile struct {
+	name   string
+	data   []byte
+	binary bool
+}
+
+// captureDiagnostics collects the pod spec, container statuses, recent
+// events and the last log lines of each container and stores them in a
+// ConfigMap or Secret owned by the PodRestart. When an upload target is
+// configured the untruncated bundle is also written to object storage.
+// It returns the object name and the upload location, if any.
+func (r *PodRestartReconciler) captureDiagnostics(ctx context.Context, pr *operatorv1alpha1.PodRestart, cluster *clusterTarget, pod *corev1.Pod) (string, string, error) {
+	spec := pr.Spec.Diagnostics
+	if spec == nil {
+		// Requested by a trigger of a PodRestart without diagnostics settings
+		spec = &operatorv1alpha1.DiagnosticsSpec{}
+	}
+	clientset := cluster.clientset
+	now := time.Now().UTC()
+
+	// Structured data goes first so truncation only ever affects logs
+	var files []diagnosticsFile
+	if out, err := yaml.Marshal(pod.Spec); err == nil {
+		files = append(files, diagnosticsFile{name: "pod-spec.yaml", data: out})
+	}
+	if out, err := yaml.Marshal(pod.Status.ContainerStatuses); err == nil {
+		files = append(files, diagnosticsFile{name: "container-statuses.yaml", data: out})
+	}
+
+	events, err := clientset.CoreV1().Events(pod.Namespace).List(ctx, metav1.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("involvedObject.uid", string(pod.UID)).String(),
+	})
+	if err != nil {
+		r.Log.Error(err, "Failed to list pod events for diagnostics", "pod", pod.Name)
+	} else if out, err := yaml.Marshal(events.Items); err == nil {
+		files = append(files, diagnosticsFile{name: "events.yaml", data: out})
+	}
+
+	logOpts := corev1.PodLogOptions{}
+	limit := int64(maxBundleBytes)
+	if spec.Upload != nil && spec.Upload.FullLogs {
+		// Full captures only go to object storage, so they are not bounded by the object size limit
+		limit = maxUploadLogBytes
+	} else {
+		tail := int64(defaultDiagnosticsLogLines)
+		if spec.LogLines != nil {
+			tail = int64(*spec.LogLines)
+		}
+		logOpts.TailLines = &tail
+	}
+	for _, container := range pod.Spec.Containers {
+		opts := logOpts
+		opts.Container = container.Name
+		stream, err := clientset.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, &opts).Stream(ctx)
+		if err != nil {
+			r.Log.Error(err, "Failed to capture container logs for diagnostics", "pod", pod.Name, "container", container.Name)
+			continue
+		}
+		out, err := io.ReadAll(io.LimitReader(stream, limit))
+		stream.Close()
+		if err != nil {
+			r.Log.Error(err, "Failed to read container logs for diagnostics", "pod", pod.Name, "container", container.Name)
+		}
+		files = append(files, diagnosticsFile{name: "logs-" + container.Name + ".log", data: out})
+	}
+
+	for _, dump := range spec.Dumps {
+		if !r.featureEnabled(DiagnosticsDumps) {
+			files = append(files, diagnosticsFile{name: "dump-" + dump.Name + ".error",
+				data: []byte("dumps are disabled, enable the DiagnosticsDumps feature gate to run them")})
+			continue
+		}
+		out, err := execDump(ctx, cluster.config, clientset, pod, dump)
+		if err != nil {
+			r.Log.Error(err, "Failed to capture dump for diagnostics", "pod", pod.Name, "dump", dump.Name)
+			files = append(files, diagnosticsFile{name: "dump-" + dump.Name + ".error", data: []byte(err.Error())})
+			continue
+		}
+		files = append(files, diagnosticsFile{name: "dump-" + dump.Name, data: out, binary: true})
+	}
+
+	// The debug container was started by debugContainerPending on an
+	// earlier pass and has finished or timed out since
+	if debug := spec.DebugContainer; debug != nil {
+		run := r.debugRuns.take(types.NamespacedName{Namespace: pr.Namespace, Name: pr.Name}, pod.UID)
+		switch {
+		case !r.featureEnabled(DebugContainers):
+			files = append(files, diagnosticsFile{name: "debug.error",
+				data: []byte("debug containers are disabled, enable the DebugContainers feature gate to run them")})
+		case !r.debugImageAllowed(debug.Image):
+			files = append(files, diagnosticsFile{name: "debug.error",
+				data: []byte(fmt.Sprintf("image %s is not one of the operator's --debug-container-images", debug.Image))})
+		case run == nil:
+			files = append(files, diagnosticsFile{name: "debug.error", data: []byte("debug container was not started")})
+		default:
+			out, err := debugContainerOutput(ctx, cluster, pod, run.name)
+			if run.err != nil {
+				err = run.err
+			}
+			if err != nil {
+				r.Log.Error(err, "Failed to capture debug container output for diagnostics", "pod", pod.Name, "container", run.name)
+				files = append(files, diagnosticsFile{name: "debug.error", data: []byte(err.Error())})
+			}
+			if len(out) > 0 {
+				files = append(files, diagnosticsFile{name: "debug-" + run.name + ".log", data: out})
+			}
+		}
+	}
+
+	bundle := &diagnosticsBundle{data: map[string]string{}, binary: map[string][]byte{}}
+	var location string
+	if spec.Upload != nil {
+		location, err = r.uploadDiagnostics(ctx, pr, cluster, pod, files, now)
+		if err != nil {
+			r.Log.Error(err, "Failed to upload diagnostics bundle", "pod", pod.Name)
+		} else {
+			bundle.add("upload-location", location)
+		}
+	}
+	for _, f := range files {
+		if !f.binary {
+			bundle.add(f.name, string(f.data))
+		} else if !bundle.addBinary(f.name, f.data) {
+			bundle.add(f.name+".omitted", fmt.Sprintf("%d bytes do not fit into the bundle, configure an upload to keep them", len(f.data)))
+		}
+	}
+
+	ttl := defaultDiagnosticsTTL
+	if spec.TTL != nil {
+		ttl = spec.TTL.Duration
+	}
+	meta := metav1.ObjectMeta{
+		Name:      diagnosticsName(pr.Name, pod.Name, now),
+		Namespace: pr.Namespace,
+		Labels: map[string]string{
+			LabelPodRestart:  pr.Name,
+			LabelDiagnostics: "true",
+		},
+		Annotations: map[string]string{
+			AnnotationExpiresAt:            now.Add(ttl).Format(time.RFC3339),
+			"operator.example.com/pod":     pod.Name,
+			"operator.example.com/pod-uid": string(pod.UID),
+		},
+	}
+
+	var obj client.Object
+	if spec.StoreAs == operatorv1alpha1.DiagnosticsSecret {
+		obj = &corev1.Secret{ObjectMeta: meta, StringData: bundle.data, Data: bundle.binary}
+	} else {
+		obj = &corev1.ConfigMap{ObjectMeta: meta, Data: bundle.data, BinaryData: bundle.binary}
+	}
+	if err := ctrl.SetControllerReference(pr, obj, r.Scheme); err != nil {
+		return "", location, err
+	}
+	if err := r.Create(ctx, obj, client.FieldOwner(FieldManager)); err != nil {
+		return "", location, err
+	}
+	return meta.Name, location, nil
+}
+
+// cleanupDiagnostics deletes the diagnostics bundles of a PodRestart whose TTL has passed
+func (r *PodRestartReconciler) cleanupDiagnostics(ctx context.Context, pr *operatorv1alpha1.PodRestart) error {
+	opts := []client.ListOption{
+		client.InNamespace(pr.Namespace),
+		client.MatchingLabels{LabelPodRestart: pr.Name, LabelDiagnostics: "true"},
+	}
+
+	var objs []client.Object
+	configMaps := &corev1.ConfigMapList{}
+	if err := r.List(ctx, configMaps, opts...); err != nil {
+		return err
+	}
+	for i := range configMaps.Items {
+		objs = append(objs, &configMaps.Items[i])
+	}
+	secrets := &corev1.SecretList{}
+	if err := r.List(ctx, secrets, opts...); err != nil {
+		return err
+	}
+	for i := range secrets.Items {
+		objs = append(objs, &secrets.Items[i])
+	}
+
+	now := time.Now()
+	for _, obj := range objs {
+		expiresAt, err := time.Parse(time.RFC3339, obj.GetAnnotations()[AnnotationExpiresAt])
+		if err != nil || now.Before(expiresAt) {
+			continue
+		}
+		if err := r.Delete(ctx, obj); client.IgnoreNotFound(err) != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// diagnosticsName builds a unique, valid object name for a bundle
+func diagnosticsName(podRestart, pod string, t time.Time) string {
+	suffix := fmt.Sprintf("-%d", t.Unix())
+	name := strings.ToLower(podRestart + "-diag-" + pod)
+	if max := 253 - len(suffix); len(name) > max {
+		name = name[:max]
+	}
+	return strings.TrimRight(name, "-.") + suffix
+}
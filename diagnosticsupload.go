@@ -0,0 +1,243 @@
+// diagnosticsupload.go
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"google.golang.org/api/option"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	operatorv1alpha1 "github.com/example/pod-restart-operator/api/v1alpha1"
+)
+
+const (
+	// maxUploadLogBytes bounds a full log capture of a single container,
+	// which is held in memory until its upload finished
+	maxUploadLogBytes = 32 * 1024 * 1024
+
+	// maxConcurrentUploads bounds the bundles uploaded at a time. Bundles
+	// beyond it are not uploaded, so memory stays bounded while a provider
+	// is slow.
+	maxConcurrentUploads = 4
+
+	// diagnosticsUploadTimeout bounds the upload of a single bundle
+	diagnosticsUploadTimeout = 5 * time.Minute
+)
+
+// blobStore writes objects to a bucket or container
+type blobStore interface {
+	put(ctx context.Context, key string, data []byte) error
+	// url returns a provider specific URL for a key, e.g. s3://bucket/key
+	url(key string) string
+	close() error
+}
+
+// uploadDiagnostics starts writing every file of a bundle below a common
+// prefix and returns the URL of that prefix. The files are written in the
+// background, bounded by diagnosticsUploadTimeout, so a slow provider does
+// not hold back the restart; failures are logged and emitted as events.
+func (r *PodRestartReconciler) uploadDiagnostics(ctx context.Context, pr *operatorv1alpha1.PodRestart, cluster *clusterTarget, pod *corev1.Pod, files []diagnosticsFile, now time.Time) (string, error) {
+	select {
+	case r.uploads <- struct{}{}:
+	default:
+		return "", fmt.Errorf("%d diagnostics uploads are already in progress", maxConcurrentUploads)
+	}
+	upload := pr.Spec.Diagnostics.Upload
+	store, err := newBlobStore(ctx, cluster.secrets, pr.Namespace, upload)
+	if err != nil {
+		<-r.uploads
+		return "", err
+	}
+
+	prefix := path.Join(upload.Prefix, pr.Namespace, pr.Name, pod.Name, now.Format("20060102T150405Z"))
+	location := store.url(prefix + "/")
+	pr = pr.DeepCopy()
+	go func() {
+		defer func() { <-r.uploads }()
+		defer store.close()
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), diagnosticsUploadTimeout)
+		defer cancel()
+		for _, f := range files {
+			if err := store.put(ctx, path.Join(prefix, f.name), f.data); err != nil {
+				r.Log.Error(err, "Failed to upload diagnostics bundle", "pod", pod.Name, "file", f.name, "location", location)
+				r.Recorder.Eventf(pr, corev1.EventTypeWarning, EventReasonUploadFailed,
+					"Uploading diagnostics of pod %s to %s failed: %v", pod.Name, location, err)
+				return
+			}
+		}
+	}()
+	return location, nil
+}
+
+// newBlobStore creates a client for the configured provider. Without a
+// credentials Secret the provider's default chain is used, which picks up
+// workload identity (IRSA, GKE and Azure workload identity). The Secret is
+// read from namespace, the PodRestart's, whatever the reference says, with
+// secrets, which impersonates the PodRestart when configured.
+func newBlobStore(ctx context.Context, secrets client.Reader, namespace string, upload *operatorv1alpha1.DiagnosticsUpload) (blobStore, error) {
+	var creds map[string][]byte
+	if ref := upload.CredentialsSecretRef; ref != nil {
+		secret := &corev1.Secret{}
+		if err := secrets.Get(ctx, types.NamespacedName{Namespace: namespace, Name: ref.Name}, secret); err != nil {
+			return nil, fmt.Errorf("reading secret %s/%s: %w", namespace, ref.Name, err)
+		}
+		creds = secret.Data
+	}
+
+	switch upload.Provider {
+	case operatorv1alpha1.UploadS3:
+		return newS3BlobStore(ctx, upload, creds)
+	case operatorv1alpha1.UploadGCS:
+		return newGCSBlobStore(ctx, upload, creds)
+	case operatorv1alpha1.UploadAzureBlob:
+		return newAzureBlobStore(upload, creds)
+	default:
+		return nil, fmt.Errorf("unsupported upload provider %q", upload.Provider)
+	}
+}
+
+// s3BlobStore writes to an S3 compatible bucket
+type s3BlobStore struct {
+	client *s3.Client
+	bucket string
+}
+
+// newS3BlobStore uses AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY from the
+// credentials Secret when present
+func newS3BlobStore(ctx context.Context, upload *operatorv1alpha1.DiagnosticsUpload, creds map[string][]byte) (blobStore, error) {
+	var opts []func(*awsconfig.LoadOptions) error
+	if upload.Region != "" {
+		opts = append(opts, awsconfig.WithRegion(upload.Region))
+	}
+	if id, ok := creds["AWS_ACCESS_KEY_ID"]; ok {
+		opts = append(opts, awsconfig.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(
+			string(id), string(creds["AWS_SECRET_ACCESS_KEY"]), string(creds["AWS_SESSION_TOKEN"]))))
+	}
+	cfg, err := awsconfig.LoadDefaultConfig(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	client := s3.NewFromConfig(cfg, func(o *s3.Options) {
+		if upload.Endpoint != "" {
+			o.BaseEndpoint = aws.String(upload.Endpoint)
+			o.UsePathStyle = true
+		}
+	})
+	return &s3BlobStore{client: client, bucket: upload.Bucket}, nil
+}
+
+func (s *s3BlobStore) put(ctx context.Context, key string, data []byte) error {
+	_, err := s.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(s.bucket),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(data),
+		ContentType: aws.String(contentTypeFor(key)),
+	})
+	return err
+}
+
+func (s *s3BlobStore) url(key string) string { return "s3://" + s.bucket + "/" + key }
+func (s *s3BlobStore) close() error          { return nil }
+
+// gcsBlobStore writes to a Google Cloud Storage bucket
+type gcsBlobStore struct {
+	client *storage.Client
+	bucket string
+}
+
+// newGCSBlobStore uses a service account key from the credentials.json key
+// of the credentials Secret when present
+func newGCSBlobStore(ctx context.Context, upload *operatorv1alpha1.DiagnosticsUpload, creds map[string][]byte) (blobStore, error) {
+	var opts []option.ClientOption
+	if key, ok := creds["credentials.json"]; ok {
+		opts = append(opts, option.WithCredentialsJSON(key))
+	}
+	client, err := storage.NewClient(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &gcsBlobStore{client: client, bucket: upload.Bucket}, nil
+}
+
+func (s *gcsBlobStore) put(ctx context.Context, key string, data []byte) error {
+	w := s.client.Bucket(s.bucket).Object(key).NewWriter(ctx)
+	w.ContentType = contentTypeFor(key)
+	if _, err := w.Write(data); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+func (s *gcsBlobStore) url(key string) string { return "gs://" + s.bucket + "/" + key }
+func (s *gcsBlobStore) close() error          { return s.client.Close() }
+
+// azureBlobStore writes to an Azure Blob Storage container
+type azureBlobStore struct {
+	client    *azblob.Client
+	container string
+	account   string
+}
+
+// newAzureBlobStore uses the connectionString key of the credentials Secret
+// when present, otherwise the endpoint with the default Azure credential
+func newAzureBlobStore(upload *operatorv1alpha1.DiagnosticsUpload, creds map[string][]byte) (blobStore, error) {
+	var client *azblob.Client
+	var err error
+	if conn, ok := creds["connectionString"]; ok {
+		client, err = azblob.NewClientFromConnectionString(string(conn), nil)
+	} else {
+		if upload.Endpoint == "" {
+			return nil, fmt.Errorf("azure uploads require an endpoint or a connectionString credential")
+		}
+		cred, credErr := azidentity.NewDefaultAzureCredential(nil)
+		if credErr != nil {
+			return nil, credErr
+		}
+		client, err = azblob.NewClient(upload.Endpoint, cred, nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &azureBlobStore{client: client, container: upload.Bucket, account: client.URL()}, nil
+}
+
+func (s *azureBlobStore) put(ctx context.Context, key string, data []byte) error {
+	contentType := contentTypeFor(key)
+	_, err := s.client.UploadBuffer(ctx, s.container, key, data, &azblob.UploadBufferOptions{
+		HTTPHeaders: &blob.HTTPHeaders{BlobContentType: &contentType},
+	})
+	return err
+}
+
+func (s *azureBlobStore) url(key string) string {
+	return strings.TrimSuffix(s.account, "/") + "/" + s.container + "/" + key
+}
+func (s *azureBlobStore) close() error { return nil }
+
+// contentTypeFor guesses the content type of a bundle file
+func contentTypeFor(key string) string {
+	switch {
+	case strings.HasSuffix(key, ".yaml"):
+		return "application/yaml"
+	case strings.HasSuffix(key, ".log"):
+		return "text/plain; charset=utf-8"
+	default:
+		return "application/octet-stream"
+	}
+}
@@ -0,0 +1,85 @@
+// disruptionbudget.go
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// disruptionBudgets answers whether the PodDisruptionBudgets selecting a pod
+// allow it to be restarted. The budgets of a namespace are listed once per
+// pass, and each restart of the pass uses up one of their disruptions, so
+// two pods covered by a budget allowing one are not both restarted before
+// its status catches up.
+type disruptionBudgets struct {
+	reader  client.Reader
+	budgets map[string][]*policyv1.PodDisruptionBudget
+}
+
+func newDisruptionBudgets(cluster *clusterTarget) *disruptionBudgets {
+	return &disruptionBudgets{reader: cluster.lookups, budgets: map[string][]*policyv1.PodDisruptionBudget{}}
+}
+
+// blocked returns a description of the budget allowing no disruption of the
+// pod, or an empty string when every budget selecting it allows one
+func (d *disruptionBudgets) blocked(ctx context.Context, pod *corev1.Pod) (string, error) {
+	budgets, err := d.selecting(ctx, pod)
+	if err != nil {
+		return "", err
+	}
+	for _, pdb := range budgets {
+		if pdb.Status.DisruptionsAllowed < 1 {
+			return fmt.Sprintf("PodDisruptionBudget %s allows no disruption, %d of %d desired pods are healthy",
+				pdb.Name, pdb.Status.CurrentHealthy, pdb.Status.DesiredHealthy), nil
+		}
+	}
+	return "", nil
+}
+
+// restarted uses up a disruption of each budget selecting a restarted pod
+func (d *disruptionBudgets) restarted(ctx context.Context, pod *corev1.Pod) {
+	budgets, err := d.selecting(ctx, pod)
+	if err != nil {
+		return
+	}
+	for _, pdb := range budgets {
+		pdb.Status.DisruptionsAllowed--
+	}
+}
+
+// selecting returns the budgets of the pod's namespace that select it
+func (d *disruptionBudgets) selecting(ctx context.Context, pod *corev1.Pod) ([]*policyv1.PodDisruptionBudget, error) {
+	budgets, ok := d.budgets[pod.Namespace]
+	if !ok {
+		list := &policyv1.PodDisruptionBudgetList{}
+		if err := d.reader.List(ctx, list, client.InNamespace(pod.Namespace)); err != nil {
+			return nil, fmt.Errorf("listing PodDisruptionBudgets in %s: %w", pod.Namespace, err)
+		}
+		for i := range list.Items {
+			budgets = append(budgets, &list.Items[i])
+		}
+		d.budgets[pod.Namespace] = budgets
+	}
+
+	var selecting []*policyv1.PodDisruptionBudget
+	for _, pdb := range budgets {
+		// A budget without a selector selects nothing, an empty one every pod
+		if pdb.Spec.Selector == nil {
+			continue
+		}
+		selector, err := metav1.LabelSelectorAsSelector(pdb.Spec.Selector)
+		if err != nil {
+			continue
+		}
+		if selector.Matches(labels.Set(pod.Labels)) {
+			selecting = append(selecting, pdb)
+		}
+	}
+	return selecting, nil
+}
@@ -0,0 +1,5 @@
+// doc.go
+// Package v1alpha1 contains API Schema definitions for the operator v1alpha1 API group
+// +kubebuilder:object:generate=true
+// +groupName=operator.example.com
+package v1alpha1
@@ -0,0 +1,88 @@
+// dump.go
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/remotecommand"
+
+	operatorv1alpha1 "github.com/example/pod-restart-operator/api/v1alpha1"
+)
+
+const (
+	defaultDumpTimeout = 60 * time.Second
+
+	// maxDumpBytes bounds the output of a dump command held in memory while
+	// the restart waits for it
+	maxDumpBytes = 8 * 1024 * 1024
+)
+
+var errDumpTooLarge = errors.New("dump output exceeds size limit")
+
+// limitedBuffer fails writes once it holds more than max bytes so an
+// oversized dump aborts the stream instead of exhausting memory
+type limitedBuffer struct {
+	bytes.Buffer
+	max int
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if b.Len()+len(p) > b.max {
+		return 0, errDumpTooLarge
+	}
+	return b.Buffer.Write(p)
+}
+
+// execDump runs a dump command in a container of the pod and returns its stdout
+func execDump(ctx context.Context, config *rest.Config, clientset kubernetes.Interface, pod *corev1.Pod, dump operatorv1alpha1.DumpCommand) ([]byte, error) {
+	timeout := defaultDumpTimeout
+	if dump.Timeout != nil {
+		timeout = dump.Timeout.Duration
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	return execInContainer(ctx, config, clientset, pod, dump.Container, dump.Command, maxDumpBytes)
+}
+
+// execInContainer runs a command in a container of the pod, the first one
+// when container is empty, and returns at most maxStdout bytes of its stdout
+func execInContainer(ctx context.Context, config *rest.Config, clientset kubernetes.Interface, pod *corev1.Pod, container string, command []string, maxStdout int) ([]byte, error) {
+	if container == "" && len(pod.Spec.Containers) > 0 {
+		container = pod.Spec.Containers[0].Name
+	}
+
+	req := clientset.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Namespace(pod.Namespace).
+		Name(pod.Name).
+		SubResource("exec").
+		VersionedParams(&corev1.PodExecOptions{
+			Container: container,
+			Command:   command,
+			Stdout:    true,
+			Stderr:    true,
+		}, scheme.ParameterCodec)
+
+	exec, err := remotecommand.NewSPDYExecutor(config, "POST", req.URL())
+	if err != nil {
+		return nil, err
+	}
+	stdout := &limitedBuffer{max: maxStdout}
+	stderr := &limitedBuffer{max: 64 * 1024}
+	if err := exec.StreamWithContext(ctx, remotecommand.StreamOptions{Stdout: stdout, Stderr: stderr}); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%w: %s", err, msg)
+		}
+		return nil, err
+	}
+	return stdout.Bytes(), nil
+}
@@ -0,0 +1,205 @@
+// email.go
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+	"text/template"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	operatorv1alpha1 "github.com/example/pod-restart-operator/api/v1alpha1"
+)
+
+const (
+	defaultEmailSubject = `[pod-restart-operator] {{.Event}} {{.PodRestart}}{{if .Pod}} pod {{.Pod}}{{end}}`
+	defaultEmailBody    = `Event:      {{.Event}}
+PodRestart: {{.PodRestart}}
+{{- if .Pod}}
+Pod:        {{.Pod}}{{end}}
+{{- if .Workload}}
+Workload:   {{.Workload}}{{end}}
+{{- if .Trigger}}
+Trigger:    {{.Trigger}}{{end}}
+{{- if .ReasonCode}}
+Code:       {{.ReasonCode}}{{end}}
+{{- if .Reason}}
+Reason:     {{.Reason}}{{end}}
+Time:       {{.Time}}
+{{if .Message}}
+{{.Message}}
+{{end}}
+{{- if .MatchedLine}}
+Matched line:
+{{.MatchedLine}}
+{{end}}`
+
+	smtpDialTimeout = 10 * time.Second
+	// smtpIOTimeout bounds the whole SMTP conversation after connecting
+	smtpIOTimeout = 30 * time.Second
+)
+
+// headerValue rejects values that would end a header line and inject
+// further headers or a body
+func headerValue(name, value string) (string, error) {
+	if strings.ContainsAny(value, "\r\n") {
+		return "", fmt.Errorf("email %s must not contain line breaks", name)
+	}
+	return value, nil
+}
+
+// emailNotifier sends notifications through an SMTP server
+type emailNotifier struct {
+	host     string
+	port     string
+	username string
+	password string
+	from     string
+	to       []string
+	subject  *template.Template
+	body     *template.Template
+}
+
+// newEmailNotifier builds an email notifier. The SMTP Secret must contain
+// host and port, and may contain username and password.
+func (r *PodRestartReconciler) newEmailNotifier(ctx context.Context, channel *operatorv1alpha1.NotificationChannel) (Notifier, error) {
+	cfg := channel.Spec.Email
+	if cfg == nil {
+		return nil, fmt.Errorf("email channel %s has no email settings", channel.Name)
+	}
+
+	secret := &corev1.Secret{}
+	ref := cfg.SMTPSecretRef
+	if err := r.Get(ctx, types.NamespacedName{Namespace: ref.Namespace, Name: ref.Name}, secret); err != nil {
+		return nil, fmt.Errorf("reading secret %s/%s: %w", ref.Namespace, ref.Name, err)
+	}
+
+	n := &emailNotifier{
+		host:     string(secret.Data["host"]),
+		port:     string(secret.Data["port"]),
+		username: string(secret.Data["username"]),
+		password: string(secret.Data["password"]),
+		from:     cfg.From,
+		to:       cfg.To,
+	}
+	if n.host == "" {
+		return nil, fmt.Errorf("secret %s/%s has no host", ref.Namespace, ref.Name)
+	}
+	if _, err := headerValue("from", n.from); err != nil {
+		return nil, err
+	}
+	for _, to := range n.to {
+		if _, err := headerValue("recipient", to); err != nil {
+			return nil, err
+		}
+	}
+	if n.port == "" {
+		n.port = "587"
+	}
+
+	subject := cfg.SubjectTemplate
+	if subject == "" {
+		subject = defaultEmailSubject
+	}
+	body := cfg.BodyTemplate
+	if body == "" {
+		body = defaultEmailBody
+	}
+	var err error
+	if n.subject, err = template.New("subject").Parse(subject); err != nil {
+		return nil, fmt.Errorf("parsing subject template: %w", err)
+	}
+	if n.body, err = template.New("body").Parse(body); err != nil {
+		return nil, fmt.Errorf("parsing body template: %w", err)
+	}
+	return n, nil
+}
+
+// Notify implements Notifier
+func (n *emailNotifier) Notify(ctx context.Context, notification Notification) error {
+	var subject, body bytes.Buffer
+	if err := n.subject.Execute(&subject, notification); err != nil {
+		return fmt.Errorf("rendering subject: %w", err)
+	}
+	if err := n.body.Execute(&body, notification); err != nil {
+		return fmt.Errorf("rendering body: %w", err)
+	}
+
+	subjectLine, err := headerValue("subject", strings.TrimSpace(subject.String()))
+	if err != nil {
+		return err
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", n.from)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(n.to, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", subjectLine)
+	fmt.Fprintf(&msg, "Date: %s\r\n", notification.Time.Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	msg.WriteString(strings.ReplaceAll(body.String(), "\n", "\r\n"))
+
+	return n.send(ctx, msg.Bytes())
+}
+
+// send delivers a message, upgrading to TLS when the server supports STARTTLS
+func (n *emailNotifier) send(ctx context.Context, msg []byte) error {
+	dialer := &net.Dialer{Timeout: smtpDialTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(n.host, n.port))
+	if err != nil {
+		return err
+	}
+	// A server that stops responding must not hold the reconcile forever
+	deadline := time.Now().Add(smtpIOTimeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	if err := conn.SetDeadline(deadline); err != nil {
+		conn.Close()
+		return err
+	}
+
+	c, err := smtp.NewClient(conn, n.host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: n.host}); err != nil {
+			return err
+		}
+	}
+	if n.username != "" {
+		if err := c.Auth(smtp.PlainAuth("", n.username, n.password, n.host)); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(n.from); err != nil {
+		return err
+	}
+	for _, rcpt := range n.to {
+		if err := c.Rcpt(rcpt); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
@@ -0,0 +1,99 @@
+// evaluate.go
+package controllers
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	operatorv1alpha1 "github.com/example/pod-restart-operator/api/v1alpha1"
+)
+
+const (
+	defaultEvaluationWorkers    = 8
+	defaultPodEvaluationTimeout = 30 * time.Second
+)
+
+// evaluatePods runs shouldRestartPod for every running pod on a bounded pool
+// of workers, each pod limited to the per-pod timeout. Workers take their
+// slots from the scheduler shared by all PodRestarts. The result for a pod
+// is at the same index as the pod and nil when no trigger fired, so acting on
+// the results stays deterministic. Pods with a pending restart request are
+// not evaluated. Once the log read budget is used up the
+// remaining pods, and those whose logs it cut short, are deferred; their
+// number is returned.
+func (r *PodRestartReconciler) evaluatePods(ctx context.Context, cluster *clusterTarget, logs LogSource, pods []corev1.Pod, pr *operatorv1alpha1.PodRestart, patterns []errorPattern, budget *logReadBudget) ([]*triggerResult, int32) {
+	key := types.NamespacedName{Namespace: pr.Namespace, Name: pr.Name}
+
+	workers, timeout := r.evaluationSettings()
+	r.scheduler.join(key, evaluationWeight(pr), evaluationDeadline(pr, time.Now()))
+	defer r.scheduler.leave(key)
+
+	results := make([]*triggerResult, len(pods))
+	cut := make([]bool, len(pods))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers && w < len(pods); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				// The workers of all PodRestarts share the scheduler's slots
+				if err := r.scheduler.acquire(ctx, key); err != nil {
+					continue
+				}
+				podCtx, cancel := context.WithTimeout(ctx, timeout)
+				podLogs := budget.wrap(logs)
+				results[i] = r.shouldRestartPod(podCtx, cluster, podLogs, pods[i], pr, patterns)
+				recordEvaluated(ctx, pods[i].UID)
+				timedOut := errors.Is(podCtx.Err(), context.DeadlineExceeded)
+				if timedOut {
+					r.Log.Info("Pod evaluation timed out", "pod", pods[i].Name, "timeout", timeout)
+				}
+				cancel()
+				r.scheduler.release(key)
+				// A pod whose logs the budget cut short has not had its turn
+				// and counts as deferred
+				cut[i] = budgetCut(podLogs)
+				if budget != nil && !timedOut && !cut[i] {
+					r.rounds.mark(key, pods[i].UID)
+				}
+			}
+		}()
+	}
+
+	var deferred int32
+	for i := range pods {
+		// Pods someone else is already deleting are left alone
+		if pods[i].Status.Phase != corev1.PodRunning || pods[i].DeletionTimestamp != nil {
+			continue
+		}
+		// A requested restart needs no evaluation and no turn in the round
+		if requested := requestedRestart(pr, &pods[i], time.Now()); requested != nil {
+			results[i] = requested
+			continue
+		}
+		if budget != nil {
+			if r.rounds.done(key, pods[i].UID) {
+				continue
+			}
+			if budget.exhausted() {
+				deferred++
+				continue
+			}
+		}
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	for _, c := range cut {
+		if c {
+			deferred++
+		}
+	}
+	return results, deferred
+}
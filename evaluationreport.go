@@ -0,0 +1,157 @@
+// evaluationreport.go
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	operatorv1alpha1 "github.com/example/pod-restart-operator/api/v1alpha1"
+)
+
+const (
+	defaultReportHistory       = 3
+	defaultReportMaxLineLength = 256
+
+	// maxReportPods bounds the pods listed in a report so it stays well
+	// below the object size limit
+	maxReportPods = 1000
+
+	redacted = "[REDACTED]"
+)
+
+// reportBuilder collects the per-pod results of a pass for an
+// EvaluationReport. A nil reportBuilder ignores everything added to it.
+type reportBuilder struct {
+	maxLine int
+	redact  []*regexp.Regexp
+	// withhold drops matched lines entirely when a redact pattern is invalid
+	withhold  bool
+	pods      []operatorv1alpha1.PodEvaluation
+	truncated bool
+}
+
+// newReportBuilder returns a builder for the PodRestart, or nil when it does
+// not write reports
+func newReportBuilder(pr *operatorv1alpha1.PodRestart) *reportBuilder {
+	spec := pr.Spec.EvaluationReports
+	if spec == nil || !spec.Enabled {
+		return nil
+	}
+	b := &reportBuilder{maxLine: defaultReportMaxLineLength}
+	if spec.MaxLineLength != nil {
+		b.maxLine = int(*spec.MaxLineLength)
+	}
+	for _, pattern := range spec.RedactPatterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			b.withhold = true
+			continue
+		}
+		b.redact = append(b.redact, re)
+	}
+	return b
+}
+
+// add records the result of a pod. decision is empty for pods no trigger
+// fired for.
+func (b *reportBuilder) add(pod *corev1.Pod, result *triggerResult, evaluated bool, decision string) {
+	if b == nil {
+		return
+	}
+	if len(b.pods) >= maxReportPods {
+		b.truncated = true
+		return
+	}
+	entry := operatorv1alpha1.PodEvaluation{Name: pod.Name, Result: operatorv1alpha1.PodNotEvaluated}
+	switch {
+	case result != nil:
+		entry.Result = operatorv1alpha1.PodMatched
+		entry.Trigger = result.Trigger
+		entry.TriggerName = result.Name
+		entry.Reason = result.Reason
+		entry.MatchedLine = b.scrub(result.MatchedLine)
+		if result.MetricValue != nil {
+			entry.MetricValue = strconv.FormatFloat(*result.MetricValue, 'g', -1, 64)
+		}
+		entry.Decision = decision
+	case evaluated:
+		entry.Result = operatorv1alpha1.PodNotMatched
+	}
+	b.pods = append(b.pods, entry)
+}
+
+// scrub redacts and truncates a matched log line
+func (b *reportBuilder) scrub(line string) string {
+	if line == "" {
+		return ""
+	}
+	if b.withhold {
+		return redacted
+	}
+	for _, re := range b.redact {
+		line = re.ReplaceAllString(line, redacted)
+	}
+	if len(line) > b.maxLine {
+		cut := b.maxLine
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		line = line[:cut] + "..."
+	}
+	return line
+}
+
+// writeEvaluationReport creates the report of the pass and deletes the
+// reports beyond the configured history
+func (r *PodRestartReconciler) writeEvaluationReport(ctx context.Context, pr *operatorv1alpha1.PodRestart, b *reportBuilder, now time.Time) error {
+	report := &operatorv1alpha1.EvaluationReport{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s-%d", pr.Name, now.Unix()),
+			Namespace: pr.Namespace,
+			Labels:    map[string]string{LabelPodRestart: pr.Name},
+		},
+		Spec: operatorv1alpha1.EvaluationReportSpec{
+			PodRestart: pr.Name,
+			Time:       metav1.NewTime(now),
+			Pods:       b.pods,
+			Truncated:  b.truncated,
+		},
+	}
+	if err := ctrl.SetControllerReference(pr, report, r.Scheme); err != nil {
+		return err
+	}
+	// Two passes within the same second keep the first report
+	if err := r.Create(ctx, report, client.FieldOwner(FieldManager)); err != nil && !errors.IsAlreadyExists(err) {
+		return err
+	}
+
+	history := defaultReportHistory
+	if h := pr.Spec.EvaluationReports.History; h != nil {
+		history = int(*h)
+	}
+	reports := &operatorv1alpha1.EvaluationReportList{}
+	if err := r.apiReader.List(ctx, reports, client.InNamespace(pr.Namespace),
+		client.MatchingLabels{LabelPodRestart: pr.Name}); err != nil {
+		return err
+	}
+	sort.Slice(reports.Items, func(i, j int) bool {
+		return reports.Items[i].Spec.Time.After(reports.Items[j].Spec.Time.Time)
+	})
+	for i := history; i < len(reports.Items); i++ {
+		if err := r.Delete(ctx, &reports.Items[i]); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
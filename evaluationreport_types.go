@@ -0,0 +1,99 @@
+// evaluationreport_types.go
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// PodEvaluationResult is what the evaluation of a pod concluded
+type PodEvaluationResult string
+
+const (
+	// PodMatched means a trigger fired for the pod
+	PodMatched PodEvaluationResult = "Matched"
+	// PodNotMatched means the pod was evaluated and no trigger fired
+	PodNotMatched PodEvaluationResult = "NotMatched"
+	// PodNotEvaluated means the pod was not running or was deferred to a later pass
+	PodNotEvaluated PodEvaluationResult = "NotEvaluated"
+)
+
+// EvaluationReportSpec holds the results of one evaluation pass
+type EvaluationReportSpec struct {
+	// PodRestart is the name of the PodRestart that was evaluated
+	PodRestart string `json:"podRestart"`
+
+	// Time is when the pass finished
+	Time metav1.Time `json:"time"`
+
+	// Pods holds the result of every pod selected during the pass
+	// +optional
+	Pods []PodEvaluation `json:"pods,omitempty"`
+
+	// Truncated is set when the pass selected more pods than a report holds
+	// +optional
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// PodEvaluation is the result of evaluating a single pod
+type PodEvaluation struct {
+	// Name of the pod
+	Name string `json:"name"`
+
+	// Result is what the evaluation concluded
+	Result PodEvaluationResult `json:"result"`
+
+	// Trigger is the kind of condition that fired
+	// +optional
+	Trigger string `json:"trigger,omitempty"`
+
+	// TriggerName identifies the condition that fired within its kind
+	// +optional
+	TriggerName string `json:"triggerName,omitempty"`
+
+	// Reason describes why the condition fired
+	// +optional
+	Reason string `json:"reason,omitempty"`
+
+	// MatchedLine is the log line that matched, redacted and truncated
+	// +optional
+	MatchedLine string `json:"matchedLine,omitempty"`
+
+	// MetricValue is the value that breached the condition
+	// +optional
+	MetricValue string `json:"metricValue,omitempty"`
+
+	// Decision is what was done about the match: Restart, Notify or the
+	// reason the restart was skipped
+	// +optional
+	Decision string `json:"decision,omitempty"`
+}
+
+// +genclient
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:categories=remediation
+// +kubebuilder:printcolumn:name="PodRestart",type=string,JSONPath=`.spec.podRestart`
+// +kubebuilder:printcolumn:name="Time",type=date,JSONPath=`.spec.time`
+// +kubebuilder:printcolumn:name="Truncated",type=boolean,JSONPath=`.spec.truncated`,priority=1
+
+// EvaluationReport records the per-pod results of one evaluation pass of a
+// PodRestart. Reports are written by the operator when the PodRestart sets
+// spec.evaluationReports and are deleted along with it.
+type EvaluationReport struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec EvaluationReportSpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// EvaluationReportList contains a list of EvaluationReport
+type EvaluationReportList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []EvaluationReport `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&EvaluationReport{}, &EvaluationReportList{})
+}
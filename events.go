@@ -0,0 +1,48 @@
+// events.go
+package controllers
+
+import (
+	corev1 "k8s.io/api/core/v1"
+
+	operatorv1alpha1 "github.com/example/pod-restart-operator/api/v1alpha1"
+)
+
+// Event reasons emitted by the controller
+const (
+	EventReasonPodRestarted      = "PodRestarted"
+	EventReasonRestartFailed     = "RestartFailed"
+	EventReasonRestartSkipped    = "RestartSkipped"
+	EventReasonBudgetExhausted   = "BudgetExhausted"
+	EventReasonLogFetchFailed    = "LogFetchFailed"
+	EventReasonMetricQueryFailed = "MetricQueryFailed"
+	EventReasonProbeFailed       = "ProbeFailed"
+	EventReasonBurnRateExceeded  = "BurnRateExceeded"
+	EventReasonCheckpointFailed  = "CheckpointFailed"
+	EventReasonUploadFailed      = "DiagnosticsUploadFailed"
+	EventReasonTriggerWarning    = "TriggerWarning"
+	EventReasonWorkloadOrphaned  = "WorkloadOrphaned"
+	EventReasonCircuitOpen       = "CircuitOpen"
+)
+
+// recordPodEvent emits an event on the PodRestart, the affected pod and the
+// workload that owns it, so describing any of them shows what the operator did
+func (r *PodRestartReconciler) recordPodEvent(pr *operatorv1alpha1.PodRestart, pod *corev1.Pod, eventType, reason, messageFmt string, args ...interface{}) {
+	r.recordAnnotatedPodEvent(pr, pod, nil, eventType, reason, messageFmt, args...)
+}
+
+// recordAnnotatedPodEvent is recordPodEvent with annotations, such as the
+// restart reason code, set on each of the events
+func (r *PodRestartReconciler) recordAnnotatedPodEvent(pr *operatorv1alpha1.PodRestart, pod *corev1.Pod, annotations map[string]string, eventType, reason, messageFmt string, args ...interface{}) {
+	r.Recorder.AnnotatedEventf(pr, annotations, eventType, reason, messageFmt, args...)
+	if pr.Spec.Cluster != nil {
+		// Pods of remote clusters have no counterpart to attach events to
+		return
+	}
+	r.Recorder.AnnotatedEventf(pod, annotations, eventType, reason, "PodRestart %s: "+messageFmt, append([]interface{}{pr.Name}, args...)...)
+
+	workload := workloadFor(pod)
+	if workload.Kind != "Pod" {
+		r.Recorder.AnnotatedEventf(workload.objectReference(pod.Namespace), annotations, eventType, reason,
+			"PodRestart %s: "+messageFmt, append([]interface{}{pr.Name}, args...)...)
+	}
+}
@@ -0,0 +1,168 @@
+// exitcode.go
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	operatorv1alpha1 "github.com/example/pod-restart-operator/api/v1alpha1"
+)
+
+const (
+	// LabelQuarantined replaces the selector labels of a quarantined pod and
+	// names the PodRestart that quarantined it
+	LabelQuarantined = "operator.example.com/quarantined"
+
+	// defaultQuarantineTTL is how long a quarantined pod is kept by default
+	defaultQuarantineTTL = 24 * time.Hour
+)
+
+// checkExitCodes returns a result for the first exit code condition matching
+// the last termination of one of the pod's containers. Only the pod status is
+// read, so these are the cheapest triggers.
+func (r *PodRestartReconciler) checkExitCodes(pod *corev1.Pod, pr *operatorv1alpha1.PodRestart) *triggerResult {
+	for _, cond := range pr.Spec.ExitCodes {
+		for _, status := range pod.Status.ContainerStatuses {
+			terminated := status.LastTerminationState.Terminated
+			if terminated == nil || (len(cond.Containers) > 0 && !containsString(cond.Containers, status.Name)) {
+				continue
+			}
+			if cond.Within != nil && time.Since(terminated.FinishedAt.Time) > cond.Within.Duration {
+				continue
+			}
+			if !containsExitCode(cond.Codes, terminated.ExitCode) {
+				continue
+			}
+			conditionMatchesTotal.WithLabelValues(pr.Namespace, pr.Name, cond.Name).Inc()
+			reason := fmt.Sprintf("Container %s exited with code %d", status.Name, terminated.ExitCode)
+			if terminated.Reason != "" {
+				reason += " (" + terminated.Reason + ")"
+			}
+			result := &triggerResult{
+				Trigger:     TriggerExitCode,
+				Name:        cond.Name,
+				Reason:      reason,
+				Code:        terminationReasonCode(terminated),
+				NotifyOnly:  cond.Action == operatorv1alpha1.ExitCodeNotify,
+				Quarantine:  cond.Action == operatorv1alpha1.ExitCodeQuarantine,
+				Diagnostics: cond.Diagnostics,
+			}
+			if result.Quarantine {
+				result.QuarantineTTL = defaultQuarantineTTL
+				if cond.QuarantineTTL != nil {
+					result.QuarantineTTL = cond.QuarantineTTL.Duration
+				}
+			}
+			return result
+		}
+	}
+	return nil
+}
+
+func containsExitCode(codes []int32, code int32) bool {
+	for _, c := range codes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// quarantinePod replaces the labels of the pod's ReplicaSet selector with
+// LabelQuarantined. The ReplicaSet releases it and schedules a replacement,
+// while the pod itself keeps running for investigation until
+// cleanupQuarantined deletes it once its TTL passed. Only pods of
+// ReplicaSets are quarantined, since other controllers may not release them.
+func (r *PodRestartReconciler) quarantinePod(ctx context.Context, cluster *clusterTarget, pr *operatorv1alpha1.PodRestart, pod *corev1.Pod, result *triggerResult) error {
+	ctx, span := tracer.Start(ctx, "QuarantinePod", trace.WithAttributes(attribute.String("pod", pod.Name)))
+	defer span.End()
+
+	if err := r.checkNamespace(pod.Namespace); err != nil {
+		return err
+	}
+	owner := metav1.GetControllerOf(pod)
+	if owner == nil || owner.Kind != "ReplicaSet" {
+		return fmt.Errorf("pod %s is not owned by a ReplicaSet and cannot be quarantined", pod.Name)
+	}
+	rs := &appsv1.ReplicaSet{}
+	if err := cluster.lookups.Get(ctx, types.NamespacedName{Namespace: pod.Namespace, Name: owner.Name}, rs); err != nil {
+		return fmt.Errorf("reading ReplicaSet %s: %w", owner.Name, err)
+	}
+	if rs.UID != owner.UID || rs.Spec.Selector == nil {
+		return fmt.Errorf("ReplicaSet %s of pod %s was replaced", owner.Name, pod.Name)
+	}
+	if err := stampRestart(ctx, cluster.writer, pr, pod, result); err != nil {
+		if errors.IsNotFound(err) {
+			return err
+		}
+		// Attribution must never block remediation
+		log.FromContext(ctx).Error(err, "Failed to annotate pod before quarantine", "pod", pod.Name)
+	}
+
+	// Labels outside the selector, e.g. those of cost allocation, are kept
+	original := pod.DeepCopy()
+	for key := range rs.Spec.Selector.MatchLabels {
+		delete(pod.Labels, key)
+	}
+	for _, expr := range rs.Spec.Selector.MatchExpressions {
+		delete(pod.Labels, expr.Key)
+	}
+	if pod.Labels == nil {
+		pod.Labels = map[string]string{}
+	}
+	pod.Labels[LabelQuarantined] = pr.Name
+	if pod.Annotations == nil {
+		pod.Annotations = map[string]string{}
+	}
+	pod.Annotations[AnnotationExpiresAt] = time.Now().Add(result.QuarantineTTL).UTC().Format(time.RFC3339)
+	return cluster.writer.Patch(ctx, pod, client.MergeFrom(original))
+}
+
+// cleanupQuarantined deletes the pods a PodRestart quarantined in the
+// cluster once their TTL passed
+func (r *PodRestartReconciler) cleanupQuarantined(ctx context.Context, pr *operatorv1alpha1.PodRestart, cluster *clusterTarget) error {
+	pods := &corev1.PodList{}
+	opts := []client.ListOption{client.MatchingLabels{LabelQuarantined: pr.Name}}
+	if len(pr.Spec.Namespaces) == 0 {
+		opts = append(opts, client.InNamespace(pr.Namespace))
+	}
+	if err := cluster.reader.List(ctx, pods, opts...); err != nil {
+		return err
+	}
+	now := time.Now()
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		// The label only holds the name, the stamp tells PodRestarts of the same name apart
+		if pod.Annotations[AnnotationRestartedBy] != pr.Namespace+"/"+pr.Name {
+			continue
+		}
+		expiresAt, err := time.Parse(time.RFC3339, pod.Annotations[AnnotationExpiresAt])
+		if err != nil || now.Before(expiresAt) {
+			continue
+		}
+		if err := cluster.writer.Delete(ctx, pod, client.Preconditions{UID: &pod.UID}); client.IgnoreNotFound(err) != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,203 @@
+// fairshare.go
+package controllers
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+
+	operatorv1alpha1 "github.com/example/pod-restart-operator/api/v1alpha1"
+)
+
+// evaluationScheduler shares a fixed number of pod evaluation slots between
+// all PodRestarts reconciled concurrently. Without contention a PodRestart
+// gets as many slots as its workers ask for. Once PodRestarts wait, a freed
+// slot goes to a PodRestart past its evaluation deadline without any slot
+// first, earliest deadline first, and otherwise to the one holding the
+// fewest slots for its weight, so a PodRestart with a huge pod list cannot
+// hold every slot while others wait.
+type evaluationScheduler struct {
+	mu       sync.Mutex
+	capacity int
+	running  int
+	tenants  map[types.NamespacedName]*evaluationTenant
+}
+
+// evaluationTenant is a PodRestart with evaluations running or waiting
+type evaluationTenant struct {
+	weight   int
+	deadline time.Time
+	running  int
+	waiters  []chan struct{}
+	// joined counts the concurrent evaluatePods calls, a simulation may run
+	// alongside a reconcile
+	joined int
+}
+
+func newEvaluationScheduler(capacity int) *evaluationScheduler {
+	return &evaluationScheduler{capacity: capacity, tenants: map[types.NamespacedName]*evaluationTenant{}}
+}
+
+// evaluationSlots returns the capacity of the shared evaluation scheduler
+func (r *PodRestartReconciler) evaluationSlots() int {
+	if r.EvaluationSlots > 0 {
+		return r.EvaluationSlots
+	}
+	workers, reconciles := r.EvaluationWorkers, r.MaxConcurrentReconciles
+	if workers <= 0 {
+		workers = defaultEvaluationWorkers
+	}
+	if reconciles <= 0 {
+		reconciles = 1
+	}
+	return workers * reconciles
+}
+
+// evaluationWeight returns the PodRestart's share of contended evaluation
+// slots, set by the PodRestartPolicy of its namespace
+func evaluationWeight(pr *operatorv1alpha1.PodRestart) int {
+	if p := pr.Status.Policy; p != nil && p.EvaluationWeight != nil && *p.EvaluationWeight > 0 {
+		return int(*p.EvaluationWeight)
+	}
+	return 1
+}
+
+// evaluationDeadline returns when the PodRestart's current pass should have
+// run at the latest: its check interval plus spec.evaluationDeadline after
+// the previous pass. A PodRestart never evaluated is due right away.
+func evaluationDeadline(pr *operatorv1alpha1.PodRestart, now time.Time) time.Time {
+	last := pr.Status.LastEvaluation
+	if last == nil {
+		return now
+	}
+	interval := pr.CheckIntervalDuration()
+	grace := interval
+	if d := pr.Spec.EvaluationDeadline; d != nil {
+		grace = d.Duration
+	}
+	return last.Time.Add(interval + grace)
+}
+
+// join registers a PodRestart about to evaluate pods. Every join is paired
+// with a leave.
+func (s *evaluationScheduler) join(key types.NamespacedName, weight int, deadline time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t := s.tenants[key]
+	if t == nil {
+		t = &evaluationTenant{deadline: deadline}
+		s.tenants[key] = t
+	}
+	t.weight = weight
+	if deadline.Before(t.deadline) {
+		t.deadline = deadline
+	}
+	t.joined++
+}
+
+// leave unregisters a PodRestart once its evaluatePods call returned
+func (s *evaluationScheduler) leave(key types.NamespacedName) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if t := s.tenants[key]; t != nil {
+		t.joined--
+		if t.joined <= 0 && t.running == 0 && len(t.waiters) == 0 {
+			delete(s.tenants, key)
+		}
+	}
+}
+
+// acquire blocks until the PodRestart may evaluate one more pod
+func (s *evaluationScheduler) acquire(ctx context.Context, key types.NamespacedName) error {
+	s.mu.Lock()
+	t := s.tenants[key]
+	ready := make(chan struct{})
+	t.waiters = append(t.waiters, ready)
+	s.dispatchLocked()
+	s.mu.Unlock()
+
+	select {
+	case <-ready:
+		return nil
+	case <-ctx.Done():
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		for i, w := range t.waiters {
+			if w == ready {
+				t.waiters = append(t.waiters[:i], t.waiters[i+1:]...)
+				return ctx.Err()
+			}
+		}
+		// The slot was granted meanwhile, hand it on
+		s.releaseLocked(t)
+		return ctx.Err()
+	}
+}
+
+// runningOf returns the number of slots a PodRestart currently holds
+func (s *evaluationScheduler) runningOf(key types.NamespacedName) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if t := s.tenants[key]; t != nil {
+		return t.running
+	}
+	return 0
+}
+
+// release returns a slot taken with acquire
+func (s *evaluationScheduler) release(key types.NamespacedName) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.releaseLocked(s.tenants[key])
+}
+
+func (s *evaluationScheduler) releaseLocked(t *evaluationTenant) {
+	s.running--
+	t.running--
+	s.dispatchLocked()
+}
+
+// dispatchLocked hands free slots to waiting PodRestarts
+func (s *evaluationScheduler) dispatchLocked() {
+	for s.running < s.capacity {
+		next := s.nextTenant(time.Now())
+		if next == nil {
+			return
+		}
+		ready := next.waiters[0]
+		next.waiters = next.waiters[1:]
+		s.running++
+		next.running++
+		close(ready)
+	}
+}
+
+// nextTenant picks the waiting PodRestart the next free slot goes to
+func (s *evaluationScheduler) nextTenant(now time.Time) *evaluationTenant {
+	var best *evaluationTenant
+	for _, t := range s.tenants {
+		if len(t.waiters) == 0 {
+			continue
+		}
+		if best == nil || s.before(t, best, now) {
+			best = t
+		}
+	}
+	return best
+}
+
+// before reports whether a is served before b
+func (s *evaluationScheduler) before(a, b *evaluationTenant, now time.Time) bool {
+	aStarved := a.running == 0 && !now.Before(a.deadline)
+	bStarved := b.running == 0 && !now.Before(b.deadline)
+	if aStarved != bStarved {
+		return aStarved
+	}
+	// Compare running/weight without dividing
+	if ra, rb := a.running*b.weight, b.running*a.weight; ra != rb {
+		return ra < rb
+	}
+	return a.deadline.Before(b.deadline)
+}
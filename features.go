@@ -0,0 +1,184 @@
+// features.go
+package controllers
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Feature names a subsystem that can be switched on or off per installation
+type Feature string
+
+// Maturity is how far a feature has progressed towards general availability
+type Maturity string
+
+const (
+	// Alpha features are disabled by default and may change or disappear
+	Alpha Maturity = "Alpha"
+	// Beta features are enabled by default but can still be disabled
+	Beta Maturity = "Beta"
+	// GA features are always enabled
+	GA Maturity = "GA"
+)
+
+const (
+	// ContainerCheckpoints checkpoints containers through the kubelet API
+	// before a restart, which requires create on nodes/proxy. That lets the
+	// operator run commands through every kubelet, so the permission is only
+	// granted by the pod-restart-operator-checkpoint ClusterRole in
+	// optional-rbac.yaml.
+	ContainerCheckpoints Feature = "ContainerCheckpoints"
+
+	// DiagnosticsDumps runs spec.diagnostics.dumps commands in the target
+	// containers through pods/exec, granted by the pod-restart-operator-exec
+	// ClusterRole in optional-rbac.yaml
+	DiagnosticsDumps Feature = "DiagnosticsDumps"
+
+	// DebugContainers adds the ephemeral containers of
+	// spec.diagnostics.debugContainer to pods, limited to the images of
+	// --debug-container-images. Update on pods/ephemeralcontainers is
+	// granted by the pod-restart-operator-debug ClusterRole in
+	// optional-rbac.yaml.
+	DebugContainers Feature = "DebugContainers"
+
+	// ExecTriggers runs the commands of the zombieProcesses, connections and
+	// heartbeatFiles triggers in the target containers through pods/exec,
+	// granted by the same ClusterRole as DiagnosticsDumps
+	ExecTriggers Feature = "ExecTriggers"
+
+	// MultiCluster lets PodRestarts target the pods of remote clusters
+	// through spec.cluster
+	MultiCluster Feature = "MultiCluster"
+
+	// LogFollow keeps the log streams of spec.logFollow open
+	LogFollow Feature = "LogFollow"
+
+	// SharedLogWindows reuses a container's fetched log window across the
+	// PodRestarts targeting it
+	SharedLogWindows Feature = "SharedLogWindows"
+)
+
+// FeatureSpec describes a known feature
+type FeatureSpec struct {
+	Default  bool
+	Maturity Maturity
+}
+
+// knownFeatures lists every feature gate with its default
+var knownFeatures = map[Feature]FeatureSpec{
+	ContainerCheckpoints: {Default: false, Maturity: Alpha},
+	DiagnosticsDumps:     {Default: false, Maturity: Alpha},
+	DebugContainers:      {Default: false, Maturity: Alpha},
+	ExecTriggers:         {Default: false, Maturity: Alpha},
+	LogFollow:            {Default: false, Maturity: Alpha},
+	MultiCluster:         {Default: false, Maturity: Alpha},
+	SharedLogWindows:     {Default: true, Maturity: Beta},
+}
+
+// FeatureGates holds the features enabled for this installation. It
+// implements flag.Value and parses comma separated Name=bool pairs, as in
+// --feature-gates=DiagnosticsDumps=true,SharedLogWindows=false. A nil
+// FeatureGates reports the defaults.
+type FeatureGates struct {
+	mu      sync.RWMutex
+	enabled map[Feature]bool
+}
+
+// NewFeatureGates returns the default feature gates
+func NewFeatureGates() *FeatureGates {
+	return &FeatureGates{enabled: map[Feature]bool{}}
+}
+
+// Set implements flag.Value
+func (g *FeatureGates) Set(value string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		name, raw, ok := strings.Cut(pair, "=")
+		if !ok {
+			return fmt.Errorf("feature gate %q must be of the form Name=true|false", pair)
+		}
+		feature := Feature(strings.TrimSpace(name))
+		enabled, err := strconv.ParseBool(strings.TrimSpace(raw))
+		if err != nil {
+			return fmt.Errorf("invalid value for feature gate %q: %w", feature, err)
+		}
+		if err := checkFeature(feature, enabled); err != nil {
+			return err
+		}
+		g.enabled[feature] = enabled
+	}
+	return nil
+}
+
+// checkFeature validates a single feature gate setting
+func checkFeature(feature Feature, enabled bool) error {
+	spec, known := knownFeatures[feature]
+	if !known {
+		return fmt.Errorf("unknown feature gate %q, known gates are %s", feature, strings.Join(KnownFeatures(), ", "))
+	}
+	if spec.Maturity == GA && !enabled {
+		return fmt.Errorf("feature gate %q is GA and cannot be disabled", feature)
+	}
+	return nil
+}
+
+// validateFeatureOverrides validates the feature gates of an OperatorConfig
+func validateFeatureOverrides(gates map[string]bool) error {
+	names := make([]string, 0, len(gates))
+	for name := range gates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := checkFeature(Feature(name), gates[name]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// String implements flag.Value
+func (g *FeatureGates) String() string {
+	if g == nil {
+		return ""
+	}
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	pairs := make([]string, 0, len(g.enabled))
+	for feature, enabled := range g.enabled {
+		pairs = append(pairs, fmt.Sprintf("%s=%t", feature, enabled))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// Enabled reports whether a feature is enabled
+func (g *FeatureGates) Enabled(feature Feature) bool {
+	if g != nil {
+		g.mu.RLock()
+		enabled, ok := g.enabled[feature]
+		g.mu.RUnlock()
+		if ok {
+			return enabled
+		}
+	}
+	return knownFeatures[feature].Default
+}
+
+// KnownFeatures describes every feature gate for flag help and errors
+func KnownFeatures() []string {
+	features := make([]string, 0, len(knownFeatures))
+	for feature, spec := range knownFeatures {
+		features = append(features, fmt.Sprintf("%s=true|false (%s - default=%t)", feature, spec.Maturity, spec.Default))
+	}
+	sort.Strings(features)
+	return features
+}
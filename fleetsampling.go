@@ -0,0 +1,78 @@
+// fleetsampling.go
+package controllers
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	operatorv1alpha1 "github.com/example/pod-restart-operator/api/v1alpha1"
+)
+
+// defaultSamplingMinPods is used when spec.sampling.minPods is not set
+const defaultSamplingMinPods = 1000
+
+// fleetSample selects the pods evaluated by one sampled pass. Pods are
+// spread over rounds buckets by a hash of their UID, and the bucket of a
+// pass is the check interval of the wall clock it falls into, so every pod
+// is evaluated once per period however many passes pod events trigger, and
+// a failed pass does not shift the rotation. The hash is seeded with the
+// rotation, so the subsets change from one rotation to the next.
+type fleetSample struct {
+	round  int
+	rounds int
+	seed   uint64
+}
+
+// fleetSampleFor returns the sample of this pass, or nil when every pod is
+// evaluated: without spec.sampling, when the period does not exceed the
+// check interval, or while the previous pass targeted fewer than minPods.
+func (r *PodRestartReconciler) fleetSampleFor(pr *operatorv1alpha1.PodRestart, previouslyTargeted int32, now time.Time) *fleetSample {
+	spec := pr.Spec.Sampling
+	if spec == nil {
+		return nil
+	}
+	minPods := int32(defaultSamplingMinPods)
+	if spec.MinPods != nil {
+		minPods = *spec.MinPods
+	}
+	interval := pr.CheckIntervalDuration()
+	rounds := int((spec.Period.Duration + interval - 1) / interval)
+	if rounds <= 1 || previouslyTargeted < minPods {
+		return nil
+	}
+	n := now.UnixNano() / int64(interval)
+	return &fleetSample{round: int(n % int64(rounds)), rounds: rounds, seed: uint64(n / int64(rounds))}
+}
+
+// filter returns the pods of the sample's bucket
+func (s *fleetSample) filter(pods []corev1.Pod) []corev1.Pod {
+	if s == nil {
+		return pods
+	}
+	var sampled []corev1.Pod
+	for i := range pods {
+		if s.bucket(pods[i].UID) == s.round {
+			sampled = append(sampled, pods[i])
+		}
+	}
+	return sampled
+}
+
+func (s *fleetSample) bucket(uid types.UID) int {
+	h := fnv.New64a()
+	var seed [8]byte
+	binary.BigEndian.PutUint64(seed[:], s.seed)
+	h.Write(seed[:])
+	h.Write([]byte(uid))
+	return int(h.Sum64() % uint64(s.rounds))
+}
+
+// String returns the round as shown in status, e.g. 3/12
+func (s *fleetSample) String() string {
+	return fmt.Sprintf("%d/%d", s.round+1, s.rounds)
+}
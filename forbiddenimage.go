@@ -0,0 +1,65 @@
+// forbiddenimage.go
+package controllers
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	operatorv1alpha1 "github.com/example/pod-restart-operator/api/v1alpha1"
+)
+
+// checkForbiddenImages returns a result for the first forbidden image
+// condition matching an image one of the pod's containers runs. Image
+// references come from the pod spec, digests from the container statuses.
+func (r *PodRestartReconciler) checkForbiddenImages(pod *corev1.Pod, pr *operatorv1alpha1.PodRestart) *triggerResult {
+	for _, cond := range pr.Spec.ForbiddenImages {
+		for _, container := range pod.Spec.Containers {
+			if len(cond.Containers) > 0 && !containsString(cond.Containers, container.Name) {
+				continue
+			}
+			match := forbiddenImage(cond, container.Image, containerImageID(pod, container.Name))
+			if match == "" {
+				continue
+			}
+			conditionMatchesTotal.WithLabelValues(pr.Namespace, pr.Name, cond.Name).Inc()
+			return &triggerResult{
+				Trigger:    TriggerForbiddenImage,
+				Name:       cond.Name,
+				Reason:     fmt.Sprintf("Container %s runs forbidden image %s", container.Name, match),
+				NotifyOnly: cond.Action == operatorv1alpha1.ImageNotify,
+			}
+		}
+	}
+	return nil
+}
+
+// forbiddenImage returns the image reference or digest the condition
+// forbids, or "" when the container's image is allowed
+func forbiddenImage(cond operatorv1alpha1.ForbiddenImageCondition, image, imageID string) string {
+	for _, pattern := range cond.Images {
+		if ok, _ := path.Match(pattern, image); ok {
+			return image
+		}
+	}
+	for _, digest := range cond.Digests {
+		// imageID is e.g. docker-pullable://registry/app@sha256:..., the
+		// reference may pin the digest as well
+		if imageID != "" && strings.HasSuffix(imageID, digest) || strings.HasSuffix(image, "@"+digest) {
+			return digest
+		}
+	}
+	return ""
+}
+
+// containerImageID returns the image ID a container runs, "" before it started
+func containerImageID(pod *corev1.Pod, container string) string {
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.Name == container {
+			return status.ImageID
+		}
+	}
+	return ""
+}
@@ -0,0 +1,146 @@
+// gitops.go
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	operatorv1alpha1 "github.com/example/pod-restart-operator/api/v1alpha1"
+)
+
+// fluxOwner describes the labels Flux sets on the objects it applies and the
+// kind of object they point to
+type fluxOwner struct {
+	nameLabel      string
+	namespaceLabel string
+	gvk            schema.GroupVersionKind
+}
+
+var fluxOwners = []fluxOwner{
+	{
+		nameLabel:      "kustomize.toolkit.fluxcd.io/name",
+		namespaceLabel: "kustomize.toolkit.fluxcd.io/namespace",
+		gvk:            schema.GroupVersionKind{Group: "kustomize.toolkit.fluxcd.io", Version: "v1", Kind: "Kustomization"},
+	},
+	{
+		nameLabel:      "helm.toolkit.fluxcd.io/name",
+		namespaceLabel: "helm.toolkit.fluxcd.io/namespace",
+		gvk:            schema.GroupVersionKind{Group: "helm.toolkit.fluxcd.io", Version: "v2", Kind: "HelmRelease"},
+	},
+}
+
+// gitOpsTracker answers whether a workload's Flux source blocks restarts,
+// remembering the answer for the rest of the pass
+type gitOpsTracker struct {
+	reader    client.Reader
+	namespace string
+	spec      *operatorv1alpha1.GitOpsSpec
+	seen      map[workloadRef]string
+}
+
+// newGitOpsTracker returns nil when the PodRestart does not defer for GitOps
+func newGitOpsTracker(pr *operatorv1alpha1.PodRestart, cluster *clusterTarget) *gitOpsTracker {
+	spec := pr.Spec.GitOps
+	if spec == nil || (!spec.DeferWhenSuspended && !spec.DeferWhileReconciling) {
+		return nil
+	}
+	return &gitOpsTracker{
+		reader:    cluster.lookups,
+		namespace: cluster.namespace,
+		spec:      spec,
+		seen:      make(map[workloadRef]string),
+	}
+}
+
+// blocked returns why the workload's Kustomization or HelmRelease blocks
+// restarts, or an empty string when it does not
+func (t *gitOpsTracker) blocked(ctx context.Context, workload workloadRef) (string, error) {
+	if reason, ok := t.seen[workload]; ok {
+		return reason, nil
+	}
+
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(schema.FromAPIVersionAndKind(workload.APIVersion, workload.Kind))
+	if err := t.reader.Get(ctx, types.NamespacedName{Namespace: t.namespace, Name: workload.Name}, obj); err != nil {
+		if apierrors.IsNotFound(err) {
+			t.seen[workload] = ""
+			return "", nil
+		}
+		return "", fmt.Errorf("getting %s: %w", workload, err)
+	}
+
+	reason := ""
+	labels := obj.GetLabels()
+	for _, owner := range fluxOwners {
+		name := labels[owner.nameLabel]
+		if name == "" {
+			continue
+		}
+		namespace := labels[owner.namespaceLabel]
+		if namespace == "" {
+			namespace = t.namespace
+		}
+		r, err := t.sourceBlocked(ctx, owner.gvk, namespace, name)
+		if err != nil {
+			return "", err
+		}
+		if r != "" {
+			reason = r
+			break
+		}
+	}
+	t.seen[workload] = reason
+	return reason, nil
+}
+
+// sourceBlocked inspects a single Kustomization or HelmRelease
+func (t *gitOpsTracker) sourceBlocked(ctx context.Context, gvk schema.GroupVersionKind, namespace, name string) (string, error) {
+	source := &unstructured.Unstructured{}
+	source.SetGroupVersionKind(gvk)
+	if err := t.reader.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, source); err != nil {
+		if apierrors.IsNotFound(err) || meta.IsNoMatchError(err) {
+			return "", nil
+		}
+		return "", fmt.Errorf("getting %s %s/%s: %w", gvk.Kind, namespace, name, err)
+	}
+
+	if t.spec.DeferWhenSuspended {
+		if suspended, _, _ := unstructured.NestedBool(source.Object, "spec", "suspend"); suspended {
+			return fmt.Sprintf("%s %s/%s is suspended", gvk.Kind, namespace, name), nil
+		}
+	}
+	if t.spec.DeferWhileReconciling && fluxReconciling(source) {
+		return fmt.Sprintf("%s %s/%s is reconciling", gvk.Kind, namespace, name), nil
+	}
+	return "", nil
+}
+
+// fluxReconciling reports whether a Flux object is applying a change, either
+// through its Reconciling condition or a Ready condition that is still pending
+func fluxReconciling(obj *unstructured.Unstructured) bool {
+	conditions, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	for _, c := range conditions {
+		cond, ok := c.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		switch cond["type"] {
+		case "Reconciling":
+			if cond["status"] == "True" {
+				return true
+			}
+		case "Ready":
+			if cond["status"] == "Unknown" {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,83 @@
+// health.go
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
+
+	operatorv1alpha1 "github.com/example/pod-restart-operator/api/v1alpha1"
+)
+
+const (
+	// cacheSyncCheckTimeout bounds how long a readiness probe waits for the caches
+	cacheSyncCheckTimeout = time.Second
+
+	// providerCheckInterval is how long a provider connectivity result is
+	// reused, so frequent probes do not hammer the metrics backends
+	providerCheckInterval = 30 * time.Second
+
+	// providerCheckQuery is an instant query every Prometheus answers
+	providerCheckQuery = "vector(1)"
+)
+
+// CacheSyncCheck returns a readiness check that fails until the informer
+// caches have synced
+func CacheSyncCheck(c cache.Cache) healthz.Checker {
+	return func(req *http.Request) error {
+		ctx, cancel := context.WithTimeout(req.Context(), cacheSyncCheckTimeout)
+		defer cancel()
+		if !c.WaitForCacheSync(ctx) {
+			return errors.New("informer caches have not synced")
+		}
+		return nil
+	}
+}
+
+// ProviderCheck returns a readiness check that fails while a MetricProvider
+// cannot be queried. Results are cached for providerCheckInterval.
+func (r *PodRestartReconciler) ProviderCheck() healthz.Checker {
+	var mu sync.Mutex
+	var checked time.Time
+	var last error
+	return func(req *http.Request) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if !checked.IsZero() && time.Since(checked) < providerCheckInterval {
+			return last
+		}
+		last = r.checkProviders(req.Context())
+		checked = time.Now()
+		return last
+	}
+}
+
+// checkProviders queries every MetricProvider once
+func (r *PodRestartReconciler) checkProviders(ctx context.Context) error {
+	providers := &operatorv1alpha1.MetricProviderList{}
+	if err := r.List(ctx, providers); err != nil {
+		return fmt.Errorf("listing metric providers: %w", err)
+	}
+	var failed []string
+	for i := range providers.Items {
+		provider := &providers.Items[i]
+		querier, err := r.metricQuerierFor(ctx, provider)
+		if err == nil {
+			_, err = querier.Query(ctx, providerCheckQuery)
+		}
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %v", provider.Name, err))
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("metric providers unreachable: %s", strings.Join(failed, "; "))
+	}
+	return nil
+}
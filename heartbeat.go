@@ -0,0 +1,83 @@
+// heartbeat.go
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	operatorv1alpha1 "github.com/example/pod-restart-operator/api/v1alpha1"
+)
+
+const defaultHeartbeatCheckTimeout = 10 * time.Second
+
+// checkHeartbeatFile stats a heartbeat file in the container and returns a
+// result when it is older than its maximum age. The file's mtime is compared
+// with the operator's clock, so the node clocks are expected to be in sync.
+func (r *PodRestartReconciler) checkHeartbeatFile(ctx context.Context, cluster *clusterTarget, pod *corev1.Pod, pr *operatorv1alpha1.PodRestart, cond operatorv1alpha1.HeartbeatFileCondition) *triggerResult {
+	container := cond.Container
+	if container == "" && len(pod.Spec.Containers) > 0 {
+		container = pod.Spec.Containers[0].Name
+	}
+	started, ok := containerStartedAt(pod, container)
+	if !ok {
+		return nil
+	}
+	timeout := defaultHeartbeatCheckTimeout
+	if cond.Timeout != nil {
+		timeout = cond.Timeout.Duration
+	}
+	maxAge := cond.MaxAge.Duration
+
+	var reason string
+	probe := "heartbeat/" + container + ":" + cond.Path
+	out, err := r.execProbe(ctx, cluster, pod, container, []string{"stat", "-c", "%Y", cond.Path}, timeout)
+	if err == nil || strings.Contains(err.Error(), "No such file") {
+		r.probeSucceeded(pr, pod, probe)
+	}
+	switch {
+	case err != nil && strings.Contains(err.Error(), "No such file"):
+		// The worker may not have written its first heartbeat yet
+		if time.Since(started) < maxAge {
+			return nil
+		}
+		reason = fmt.Sprintf("Heartbeat file %s of container %s does not exist %s after the container started",
+			cond.Path, container, time.Since(started).Round(time.Second))
+	case err != nil:
+		r.probeFailed(pr, pod, probe, err, "Failed to stat heartbeat file %s of pod %s container %s", cond.Path, pod.Name, container)
+		return nil
+	default:
+		seconds, err := strconv.ParseInt(out, 10, 64)
+		if err != nil {
+			r.Log.Error(err, "Unexpected stat output", "pod", pod.Name, "output", out)
+			return nil
+		}
+		age := time.Since(time.Unix(seconds, 0))
+		if age < maxAge {
+			return nil
+		}
+		reason = fmt.Sprintf("Heartbeat file %s of container %s was last updated %s ago (maximum %s)",
+			cond.Path, container, age.Round(time.Second), maxAge)
+	}
+
+	conditionMatchesTotal.WithLabelValues(pr.Namespace, pr.Name, TriggerHeartbeatFile).Inc()
+	return &triggerResult{
+		Trigger: TriggerHeartbeatFile,
+		Name:    cond.Path,
+		Reason:  reason,
+	}
+}
+
+// containerStartedAt returns when the named container started, if it is running
+func containerStartedAt(pod *corev1.Pod, container string) (time.Time, bool) {
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.Name == container && status.State.Running != nil {
+			return status.State.Running.StartedAt.Time, true
+		}
+	}
+	return time.Time{}, false
+}
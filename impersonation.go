@@ -0,0 +1,180 @@
+// impersonation.go
+package controllers
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	operatorv1alpha1 "github.com/example/pod-restart-operator/api/v1alpha1"
+)
+
+// ImpersonationMode selects the identity pods are deleted with
+type ImpersonationMode string
+
+const (
+	// ImpersonateNone deletes pods with the operator's own identity
+	ImpersonateNone ImpersonationMode = ""
+	// ImpersonateServiceAccount deletes pods as a ServiceAccount in the
+	// PodRestart's namespace
+	ImpersonateServiceAccount ImpersonationMode = "ServiceAccount"
+	// ImpersonateCreator deletes pods as the user that created the
+	// PodRestart or last changed its spec, as recorded by the mutating webhook
+	ImpersonateCreator ImpersonationMode = "Creator"
+)
+
+// defaultImpersonatedServiceAccount is used when ImpersonationServiceAccount is not set
+const defaultImpersonatedServiceAccount = "pod-restart"
+
+// impersonatingClients caches the clients of each impersonated identity
+// while a PodRestart acts as it
+type impersonatingClients struct {
+	mu      sync.Mutex
+	clients map[string]*impersonatedClients
+	// users maps each PodRestart to the identity it last acted as
+	users map[types.NamespacedName]string
+}
+
+// impersonatedClients are the clients acting as a single identity
+type impersonatedClients struct {
+	client    client.Client
+	clientset kubernetes.Interface
+	// config is the clientset's config, used for exec streams
+	config *rest.Config
+}
+
+func newImpersonatingClients() *impersonatingClients {
+	return &impersonatingClients{
+		clients: map[string]*impersonatedClients{},
+		users:   map[types.NamespacedName]string{},
+	}
+}
+
+// get returns the clients impersonating user on behalf of the PodRestart
+// pr: a client built from config and a clientset built from logsConfig,
+// which carries the log read limits
+func (c *impersonatingClients) get(pr types.NamespacedName, config, logsConfig *rest.Config, opts client.Options, user rest.ImpersonationConfig) (*impersonatedClients, error) {
+	key := impersonationKey(user)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if previous, ok := c.users[pr]; ok && previous != key {
+		delete(c.users, pr)
+		c.dropUnusedLocked(previous)
+	}
+	c.users[pr] = key
+	if clients, ok := c.clients[key]; ok {
+		return clients, nil
+	}
+	impersonated := rest.CopyConfig(config)
+	impersonated.Impersonate = user
+	cl, err := client.New(impersonated, opts)
+	if err != nil {
+		return nil, err
+	}
+	impersonatedLogs := rest.CopyConfig(logsConfig)
+	impersonatedLogs.Impersonate = user
+	clientset, err := kubernetes.NewForConfig(impersonatedLogs)
+	if err != nil {
+		return nil, err
+	}
+	clients := &impersonatedClients{client: cl, clientset: clientset, config: impersonatedLogs}
+	c.clients[key] = clients
+	return clients, nil
+}
+
+// forget drops the identity a PodRestart acted as, and its clients when no
+// other PodRestart acts as it
+func (c *impersonatingClients) forget(pr types.NamespacedName) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if key, ok := c.users[pr]; ok {
+		delete(c.users, pr)
+		c.dropUnusedLocked(key)
+	}
+}
+
+func (c *impersonatingClients) dropUnusedLocked(key string) {
+	for _, used := range c.users {
+		if used == key {
+			return
+		}
+	}
+	delete(c.clients, key)
+}
+
+// impersonationKey identifies an impersonated user together with its groups
+func impersonationKey(user rest.ImpersonationConfig) string {
+	return user.UserName + "|" + strings.Join(user.Groups, ",")
+}
+
+// impersonationFor returns the identity pods of the PodRestart are deleted
+// with, or an empty config in ImpersonateNone mode
+func (r *PodRestartReconciler) impersonationFor(pr *operatorv1alpha1.PodRestart) (rest.ImpersonationConfig, error) {
+	switch r.Impersonation {
+	case ImpersonateNone:
+		return rest.ImpersonationConfig{}, nil
+	case ImpersonateServiceAccount:
+		name := r.ImpersonationServiceAccount
+		if name == "" {
+			name = defaultImpersonatedServiceAccount
+		}
+		return rest.ImpersonationConfig{
+			UserName: fmt.Sprintf("system:serviceaccount:%s:%s", pr.Namespace, name),
+			Groups:   []string{"system:serviceaccounts", "system:serviceaccounts:" + pr.Namespace, "system:authenticated"},
+		}, nil
+	case ImpersonateCreator:
+		user := pr.Annotations[operatorv1alpha1.AnnotationCreatedBy]
+		if user == "" {
+			// Refuse rather than fall back to the operator's own permissions
+			return rest.ImpersonationConfig{}, fmt.Errorf("PodRestart has no %s annotation, it was created before the webhook recorded creators",
+				operatorv1alpha1.AnnotationCreatedBy)
+		}
+		var groups []string
+		if raw := pr.Annotations[operatorv1alpha1.AnnotationCreatedByGroups]; raw != "" {
+			groups = strings.Split(raw, ",")
+			sort.Strings(groups)
+		}
+		return rest.ImpersonationConfig{UserName: user, Groups: groups}, nil
+	default:
+		return rest.ImpersonationConfig{}, fmt.Errorf("unknown impersonation mode %q", r.Impersonation)
+	}
+}
+
+// impersonate makes the local cluster target act as the PodRestart's
+// impersonated identity, so RBAC limits each PodRestart to what its owner
+// may do: listing and deleting pods, reading logs, exec, proxy and debug
+// containers, and reading the Secrets it refers to. The lookups behind the
+// operator's own safety checks keep the operator's identity. Remote
+// clusters keep the identity of their kubeconfig.
+func (r *PodRestartReconciler) impersonate(pr *operatorv1alpha1.PodRestart, cluster *clusterTarget) error {
+	key := types.NamespacedName{Namespace: pr.Namespace, Name: pr.Name}
+	if cluster.remote || r.Impersonation == ImpersonateNone {
+		r.impersonators.forget(key)
+		return nil
+	}
+	user, err := r.impersonationFor(pr)
+	if err != nil {
+		r.impersonators.forget(key)
+		return err
+	}
+	clients, err := r.impersonators.get(key, r.restConfig, r.logsConfig(), client.Options{Scheme: r.Scheme}, user)
+	if err != nil {
+		return fmt.Errorf("building clients impersonating %s: %w", user.UserName, err)
+	}
+	cluster.reader = clients.client
+	// Pods are listed as the impersonated user, not from the operator's cache
+	cluster.cachedPods = nil
+	cluster.writer = clients.client
+	cluster.secrets = clients.client
+	cluster.clientset = clients.clientset
+	cluster.config = clients.config
+	cluster.identity = user.UserName
+	cluster.identityKey = impersonationKey(user)
+	return nil
+}
@@ -0,0 +1,291 @@
+// inboundauth.go
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/controller-runtime/pkg/certwatcher"
+
+	operatorv1alpha1 "github.com/example/pod-restart-operator/api/v1alpha1"
+	"github.com/example/pod-restart-operator/controllers"
+)
+
+// authCacheTTL is how long authentication and authorization decisions are reused
+const authCacheTTL = time.Minute
+
+// inboundTLS serves a certificate that is reloaded when its files change,
+// as they do when cert-manager renews a mounted Secret, and optionally
+// verifies client certificates against a CA bundle that is reloaded as well
+type inboundTLS struct {
+	watcher      *certwatcher.CertWatcher
+	clientCAFile string
+
+	mu      sync.Mutex
+	caMod   time.Time
+	caPool  *x509.CertPool
+	baseCfg *tls.Config
+}
+
+func newInboundTLS(certFile, keyFile, clientCAFile string) (*inboundTLS, error) {
+	watcher, err := certwatcher.New(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("loading serving certificate: %w", err)
+	}
+	t := &inboundTLS{watcher: watcher, clientCAFile: clientCAFile}
+	t.baseCfg = &tls.Config{MinVersion: tls.VersionTLS12, GetCertificate: watcher.GetCertificate}
+	if clientCAFile != "" {
+		if _, err := t.clientCAs(); err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+// start watches the certificate files until ctx is done
+func (t *inboundTLS) start(ctx context.Context) {
+	go func() {
+		if err := t.watcher.Start(ctx); err != nil {
+			setupLog.Error(err, "Certificate watcher stopped")
+		}
+	}()
+}
+
+// config returns the server TLS config. Client certificates are verified
+// when presented, callers without one must send a bearer token instead.
+func (t *inboundTLS) config() *tls.Config {
+	cfg := t.baseCfg.Clone()
+	if t.clientCAFile != "" {
+		cfg.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			pool, err := t.clientCAs()
+			if err != nil {
+				return nil, err
+			}
+			c := t.baseCfg.Clone()
+			c.ClientAuth = tls.VerifyClientCertIfGiven
+			c.ClientCAs = pool
+			return c, nil
+		}
+	}
+	return cfg
+}
+
+// clientCAs returns the client CA pool, rereading the file when it changed
+func (t *inboundTLS) clientCAs() (*x509.CertPool, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	info, err := os.Stat(t.clientCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("reading client CA bundle: %w", err)
+	}
+	if t.caPool != nil && info.ModTime().Equal(t.caMod) {
+		return t.caPool, nil
+	}
+	data, err := os.ReadFile(t.clientCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("reading client CA bundle: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("client CA bundle %s contains no certificates", t.clientCAFile)
+	}
+	t.caPool, t.caMod = pool, info.ModTime()
+	return pool, nil
+}
+
+// cachedDecision is an authentication or authorization result
+type cachedDecision struct {
+	user    string
+	groups  []string
+	allowed bool
+	expires time.Time
+}
+
+// kubeAuth authenticates callers by client certificate or by bearer token
+// through a TokenReview, then authorizes each request with a
+// SubjectAccessReview for its path, so access is granted with regular RBAC:
+//
+//	rules:
+//	- nonResourceURLs: ["/debug/*"]
+//	  verbs: ["get"]
+//
+// Requests for /debug/podrestarts/{namespace}/{name} are authorized as get
+// on that PodRestart instead, so namespaced Roles grant them. Requests to the
+// trigger API are authorized as the trigger verb on the PodRestart:
+//
+//	rules:
+//	- apiGroups: ["operator.example.com"]
+//	  resources: ["podrestarts"]
+//	  resourceNames: ["my-app"]
+//	  verbs: ["trigger"]
+type kubeAuth struct {
+	clientset kubernetes.Interface
+
+	mu    sync.Mutex
+	cache map[string]cachedDecision
+}
+
+func newKubeAuth(clientset kubernetes.Interface) *kubeAuth {
+	return &kubeAuth{clientset: clientset, cache: map[string]cachedDecision{}}
+}
+
+// wrap rejects unauthenticated and unauthorized requests to next
+func (a *kubeAuth) wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		user, groups, err := a.authenticate(req)
+		if err != nil {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		allowed, err := a.authorize(req.Context(), user, groups, req.URL.Path)
+		if err != nil {
+			setupLog.Error(err, "Authorization check failed", "user", user, "path", req.URL.Path)
+			http.Error(w, "Authorization check failed", http.StatusInternalServerError)
+			return
+		}
+		if !allowed {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, req.WithContext(context.WithValue(req.Context(), callerKey{}, user)))
+	})
+}
+
+// callerKey is the context key of the authenticated caller
+type callerKey struct{}
+
+// callerFrom returns the caller a request was authenticated as
+func callerFrom(ctx context.Context) string {
+	user, _ := ctx.Value(callerKey{}).(string)
+	return user
+}
+
+func (a *kubeAuth) authenticate(req *http.Request) (string, []string, error) {
+	if req.TLS != nil && len(req.TLS.VerifiedChains) > 0 {
+		cert := req.TLS.VerifiedChains[0][0]
+		return cert.Subject.CommonName, cert.Subject.Organization, nil
+	}
+	token := strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer ")
+	if token == "" || token == req.Header.Get("Authorization") {
+		return "", nil, fmt.Errorf("no client certificate or bearer token")
+	}
+
+	sum := sha256.Sum256([]byte(token))
+	key := "token/" + hex.EncodeToString(sum[:])
+	if d, ok := a.cached(key); ok {
+		if !d.allowed {
+			return "", nil, fmt.Errorf("invalid token")
+		}
+		return d.user, d.groups, nil
+	}
+	review, err := a.clientset.AuthenticationV1().TokenReviews().Create(req.Context(), &authenticationv1.TokenReview{
+		Spec: authenticationv1.TokenReviewSpec{Token: token},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return "", nil, err
+	}
+	d := cachedDecision{
+		user:    review.Status.User.Username,
+		groups:  review.Status.User.Groups,
+		allowed: review.Status.Authenticated,
+	}
+	a.store(key, d)
+	if !d.allowed {
+		return "", nil, fmt.Errorf("invalid token")
+	}
+	return d.user, d.groups, nil
+}
+
+// sarCacheKey identifies a SubjectAccessReview decision. The identity is JSON
+// encoded before hashing, so user and group names holding separators cannot
+// collide with another identity.
+func sarCacheKey(user string, groups []string, path string) (string, error) {
+	b, err := json.Marshal(struct {
+		User   string   `json:"user"`
+		Groups []string `json:"groups"`
+		Path   string   `json:"path"`
+	}{user, groups, path})
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return "sar/" + hex.EncodeToString(sum[:]), nil
+}
+
+func (a *kubeAuth) authorize(ctx context.Context, user string, groups []string, path string) (bool, error) {
+	key, err := sarCacheKey(user, groups, path)
+	if err != nil {
+		return false, err
+	}
+	if d, ok := a.cached(key); ok {
+		return d.allowed, nil
+	}
+	spec := authorizationv1.SubjectAccessReviewSpec{
+		User:                  user,
+		Groups:                groups,
+		NonResourceAttributes: &authorizationv1.NonResourceAttributes{Path: path, Verb: "get"},
+	}
+	if key, ok := controllers.PodRestartDebugPath(path); ok {
+		spec.NonResourceAttributes = nil
+		spec.ResourceAttributes = &authorizationv1.ResourceAttributes{
+			Namespace: key.Namespace,
+			Verb:      "get",
+			Group:     operatorv1alpha1.GroupVersion.Group,
+			Resource:  "podrestarts",
+			Name:      key.Name,
+		}
+	}
+	if key, ok := triggerPath(path); ok {
+		spec.NonResourceAttributes = nil
+		spec.ResourceAttributes = &authorizationv1.ResourceAttributes{
+			Namespace: key.Namespace,
+			Verb:      "trigger",
+			Group:     operatorv1alpha1.GroupVersion.Group,
+			Resource:  "podrestarts",
+			Name:      key.Name,
+		}
+	}
+	review, err := a.clientset.AuthorizationV1().SubjectAccessReviews().Create(ctx, &authorizationv1.SubjectAccessReview{Spec: spec}, metav1.CreateOptions{})
+	if err != nil {
+		return false, err
+	}
+	a.store(key, cachedDecision{allowed: review.Status.Allowed})
+	return review.Status.Allowed, nil
+}
+
+func (a *kubeAuth) cached(key string) (cachedDecision, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	d, ok := a.cache[key]
+	if !ok || time.Now().After(d.expires) {
+		return cachedDecision{}, false
+	}
+	return d, true
+}
+
+func (a *kubeAuth) store(key string, d cachedDecision) {
+	now := time.Now()
+	d.expires = now.Add(authCacheTTL)
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for k, old := range a.cache {
+		if now.After(old.expires) {
+			delete(a.cache, k)
+		}
+	}
+	a.cache[key] = d
+}
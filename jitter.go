@@ -0,0 +1,87 @@
+// jitter.go
+package controllers
+
+import (
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+const (
+	// defaultRequeueJitter is the fraction of a requeue interval added at
+	// random when RequeueJitter is not set
+	defaultRequeueJitter = 0.1
+	// defaultStartupSpread is the window the first evaluations after startup
+	// are spread over when StartupSpread is not set
+	defaultStartupSpread = 30 * time.Second
+)
+
+// requeueSpread keeps PodRestarts created together from evaluating in the
+// same second: requeue intervals are jittered, and the first evaluation of
+// each PodRestart after startup is delayed to a slot derived from its name
+type requeueSpread struct {
+	jitter float64
+	spread time.Duration
+
+	mu      sync.Mutex
+	started time.Time
+	slots   map[types.NamespacedName]time.Time
+}
+
+func newRequeueSpread(jitter float64, spread time.Duration) *requeueSpread {
+	if jitter == 0 {
+		jitter = defaultRequeueJitter
+	}
+	if spread == 0 {
+		spread = defaultStartupSpread
+	}
+	return &requeueSpread{jitter: jitter, spread: spread, slots: map[types.NamespacedName]time.Time{}}
+}
+
+// jittered adds up to the jitter fraction of d at random
+func (s *requeueSpread) jittered(d time.Duration) time.Duration {
+	if s.jitter <= 0 || d <= 0 {
+		return d
+	}
+	return wait.Jitter(d, s.jitter)
+}
+
+// initialDelay returns how long the first evaluation of a PodRestart after
+// startup is held back. The slot is stable for the PodRestart, so watch
+// events arriving before it do not evaluate it early. PodRestarts created
+// after the spread window evaluate right away.
+func (s *requeueSpread) initialDelay(key types.NamespacedName, now time.Time) time.Duration {
+	if s.spread <= 0 {
+		return 0
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.started.IsZero() {
+		s.started = now
+	}
+	slot, ok := s.slots[key]
+	if !ok {
+		if now.Sub(s.started) >= s.spread {
+			return 0
+		}
+		h := fnv.New64a()
+		h.Write([]byte(key.String()))
+		slot = s.started.Add(time.Duration(h.Sum64() % uint64(s.spread)))
+		s.slots[key] = slot
+	}
+	if !now.Before(slot) {
+		// The entry stays so a later event does not assign a new slot
+		return 0
+	}
+	return slot.Sub(now)
+}
+
+// forget drops the slot of a deleted PodRestart
+func (s *requeueSpread) forget(key types.NamespacedName) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.slots, key)
+}
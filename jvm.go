@@ -0,0 +1,189 @@
+// jvm.go
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	operatorv1alpha1 "github.com/example/pod-restart-operator/api/v1alpha1"
+)
+
+const (
+	defaultJolokiaPort     = 8778
+	defaultJolokiaPath     = "/jolokia"
+	defaultJVMCheckTimeout = 10 * time.Second
+
+	// jolokiaMemoryPools reads the usage after the last collection of every memory pool
+	jolokiaMemoryPools = "read/java.lang:type=MemoryPool,name=*/CollectionUsage,Type"
+	// jolokiaLastGC reads the last collection of every garbage collector
+	jolokiaLastGC = "read/java.lang:type=GarbageCollector,name=*/LastGcInfo"
+)
+
+// jolokiaResponse is the envelope of a Jolokia read. Reads of MBean patterns
+// return the attributes keyed by the name of each matching MBean.
+type jolokiaResponse struct {
+	Status int                                   `json:"status"`
+	Error  string                                `json:"error"`
+	Value  map[string]map[string]json.RawMessage `json:"value"`
+}
+
+// jvmMemoryUsage is a java.lang.management.MemoryUsage
+type jvmMemoryUsage struct {
+	Used      int64 `json:"used"`
+	Committed int64 `json:"committed"`
+	Max       int64 `json:"max"`
+}
+
+// jvmGcInfo is the part of com.sun.management.GcInfo used here
+type jvmGcInfo struct {
+	Duration int64 `json:"duration"`
+}
+
+// checkJVM reads the JVM MBeans of the pod from its Jolokia agent and returns
+// a result when the old generation stays full after collection or the last
+// collection paused too long
+func (r *PodRestartReconciler) checkJVM(ctx context.Context, cluster *clusterTarget, pod *corev1.Pod, pr *operatorv1alpha1.PodRestart, cond *operatorv1alpha1.JVMCondition) *triggerResult {
+	result, err := r.evaluateJVM(ctx, cluster, pod, cond)
+	if err != nil {
+		r.probeFailed(pr, pod, "jvm", err, "Failed to read JVM metrics of pod %s from Jolokia", pod.Name)
+		return nil
+	}
+	r.probeSucceeded(pr, pod, "jvm")
+	if result != nil {
+		conditionMatchesTotal.WithLabelValues(pr.Namespace, pr.Name, TriggerJVM).Inc()
+	}
+	return result
+}
+
+func (r *PodRestartReconciler) evaluateJVM(ctx context.Context, cluster *clusterTarget, pod *corev1.Pod, cond *operatorv1alpha1.JVMCondition) (*triggerResult, error) {
+	if percent := cond.OldGenAfterGCPercent; percent != nil {
+		pools, err := jolokiaRead(ctx, cluster, pod, cond, jolokiaMemoryPools)
+		if err != nil {
+			return nil, err
+		}
+		name, usage, ok := oldGenUsage(pools, cond.OldGenPool)
+		if !ok {
+			return nil, fmt.Errorf("no old generation memory pool found")
+		}
+		limit := usage.Max
+		if limit <= 0 {
+			limit = usage.Committed
+		}
+		if limit > 0 {
+			occupancy := float64(usage.Used) * 100 / float64(limit)
+			if occupancy >= float64(*percent) {
+				return &triggerResult{
+					Trigger: TriggerJVM,
+					Name:    "oldGenAfterGC",
+					Reason: fmt.Sprintf("%s is %.0f%% full after the last collection (threshold %d%%)",
+						name, occupancy, *percent),
+					MetricValue: &occupancy,
+				}, nil
+			}
+		}
+	}
+
+	if maxPause := cond.MaxGCPause; maxPause != nil {
+		collectors, err := jolokiaRead(ctx, cluster, pod, cond, jolokiaLastGC)
+		if err != nil {
+			return nil, err
+		}
+		for mbean, attrs := range collectors {
+			var info *jvmGcInfo
+			if err := json.Unmarshal(attrs["LastGcInfo"], &info); err != nil || info == nil {
+				continue
+			}
+			pause := time.Duration(info.Duration) * time.Millisecond
+			if pause > maxPause.Duration {
+				seconds := pause.Seconds()
+				return &triggerResult{
+					Trigger: TriggerJVM,
+					Name:    "gcPause",
+					Reason: fmt.Sprintf("Last collection of %s paused for %s (maximum %s)",
+						mbeanName(mbean), pause, maxPause.Duration),
+					MetricValue: &seconds,
+				}, nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+// jolokiaRead performs a Jolokia request through the API server's pod proxy
+func jolokiaRead(ctx context.Context, cluster *clusterTarget, pod *corev1.Pod, cond *operatorv1alpha1.JVMCondition, request string) (map[string]map[string]json.RawMessage, error) {
+	port := int32(defaultJolokiaPort)
+	if cond.Port != 0 {
+		port = cond.Port
+	}
+	path := defaultJolokiaPath
+	if cond.Path != "" {
+		path = cond.Path
+	}
+	scheme := cond.Scheme
+	if scheme == "" {
+		scheme = "http"
+	}
+	timeout := defaultJVMCheckTimeout
+	if cond.Timeout != nil {
+		timeout = cond.Timeout.Duration
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	body, err := cluster.clientset.CoreV1().Pods(pod.Namespace).
+		ProxyGet(scheme, pod.Name, strconv.Itoa(int(port)), strings.TrimSuffix(path, "/")+"/"+request, nil).
+		DoRaw(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var resp jolokiaResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("decoding Jolokia response: %w", err)
+	}
+	if resp.Status != 200 {
+		return nil, fmt.Errorf("jolokia returned status %d: %s", resp.Status, resp.Error)
+	}
+	return resp.Value, nil
+}
+
+// oldGenUsage picks the old generation heap pool from a memory pool read
+func oldGenUsage(pools map[string]map[string]json.RawMessage, poolName string) (string, jvmMemoryUsage, bool) {
+	for mbean, attrs := range pools {
+		name := mbeanName(mbean)
+		if poolName != "" {
+			if name != poolName {
+				continue
+			}
+		} else {
+			var kind string
+			_ = json.Unmarshal(attrs["Type"], &kind)
+			if kind != "HEAP" || !(strings.Contains(name, "Old Gen") || strings.Contains(name, "Tenured")) {
+				continue
+			}
+		}
+		var usage *jvmMemoryUsage
+		if err := json.Unmarshal(attrs["CollectionUsage"], &usage); err != nil || usage == nil {
+			continue
+		}
+		return name, *usage, true
+	}
+	return "", jvmMemoryUsage{}, false
+}
+
+// mbeanName returns the name key of an MBean object name such as
+// java.lang:name=G1 Old Gen,type=MemoryPool
+func mbeanName(mbean string) string {
+	_, props, _ := strings.Cut(mbean, ":")
+	for _, prop := range strings.Split(props, ",") {
+		if value, ok := strings.CutPrefix(prop, "name="); ok {
+			return value
+		}
+	}
+	return mbean
+}
@@ -0,0 +1,175 @@
+// lastevaluation.go
+package controllers
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	operatorv1alpha1 "github.com/example/pod-restart-operator/api/v1alpha1"
+)
+
+// passStats collects what a single evaluation pass saw, for status.lastEvaluation
+type passStats struct {
+	mu             sync.Mutex
+	evaluated      map[types.UID]bool
+	matches        map[triggerKey]int32
+	providerErrors map[string]*operatorv1alpha1.ProviderErrorSummary
+	// lastErrors holds the untruncated last error of each provider for the
+	// debug endpoint
+	lastErrors map[string]string
+	truncated  []operatorv1alpha1.TruncatedLog
+}
+
+// maxTruncatedLogs bounds the truncated containers listed in status
+const maxTruncatedLogs = 20
+
+type triggerKey struct {
+	trigger string
+	name    string
+}
+
+func newPassStats() *passStats {
+	return &passStats{
+		evaluated:      map[types.UID]bool{},
+		matches:        map[triggerKey]int32{},
+		providerErrors: map[string]*operatorv1alpha1.ProviderErrorSummary{},
+		lastErrors:     map[string]string{},
+	}
+}
+
+type passStatsKey struct{}
+
+// withPassStats makes the metric queries made through ctx report their
+// failures to stats
+func withPassStats(ctx context.Context, stats *passStats) context.Context {
+	return context.WithValue(ctx, passStatsKey{}, stats)
+}
+
+// recordProviderError counts a failed query against the provider in the
+// pass stats of ctx, if any
+func recordProviderError(ctx context.Context, provider string, err error) {
+	stats, _ := ctx.Value(passStatsKey{}).(*passStats)
+	if stats == nil {
+		return
+	}
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+	summary, ok := stats.providerErrors[provider]
+	if !ok {
+		summary = &operatorv1alpha1.ProviderErrorSummary{Provider: provider}
+		stats.providerErrors[provider] = summary
+	}
+	summary.Count++
+	// Provider errors may quote whole response bodies
+	summary.LastError = truncateAnnotation(err.Error())
+	stats.lastErrors[provider] = err.Error()
+}
+
+// lastProviderErrors returns a copy of the last error of every provider
+// that failed during the pass
+func (s *passStats) lastProviderErrors() map[string]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.lastErrors) == 0 {
+		return nil
+	}
+	errs := make(map[string]string, len(s.lastErrors))
+	for provider, err := range s.lastErrors {
+		errs[provider] = err
+	}
+	return errs
+}
+
+// recordTruncatedLog notes a container whose logs were capped in the pass
+// stats of ctx, if any
+func recordTruncatedLog(ctx context.Context, pod, container string, read int64) {
+	stats, _ := ctx.Value(passStatsKey{}).(*passStats)
+	if stats == nil {
+		return
+	}
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+	stats.truncated = append(stats.truncated, operatorv1alpha1.TruncatedLog{
+		PodName:   pod,
+		Container: container,
+		BytesRead: read,
+	})
+}
+
+// recordEvaluated marks a pod as evaluated in the pass stats of ctx, if any
+func recordEvaluated(ctx context.Context, uid types.UID) {
+	stats, _ := ctx.Value(passStatsKey{}).(*passStats)
+	if stats == nil {
+		return
+	}
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+	stats.evaluated[uid] = true
+}
+
+// wasEvaluated reports whether the pod was evaluated during the pass rather
+// than skipped or deferred
+func (s *passStats) wasEvaluated(uid types.UID) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.evaluated[uid]
+}
+
+// observe records the triggers that fired on a page of pods
+func (s *passStats) observe(results []*triggerResult) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, result := range results {
+		if result != nil {
+			s.matches[triggerKey{trigger: result.Trigger, name: result.Name}]++
+		}
+	}
+}
+
+// summary returns the stats in their status form, sorted for stable patches
+func (s *passStats) summary(now metav1.Time) *operatorv1alpha1.EvaluationSummary {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	summary := &operatorv1alpha1.EvaluationSummary{
+		Time:        now,
+		PodsChecked: int32(len(s.evaluated)),
+	}
+	for key, count := range s.matches {
+		summary.Matches = append(summary.Matches, operatorv1alpha1.TriggerMatchCount{
+			Trigger: key.trigger,
+			Name:    key.name,
+			Count:   count,
+		})
+	}
+	sort.Slice(summary.Matches, func(i, j int) bool {
+		a, b := summary.Matches[i], summary.Matches[j]
+		if a.Trigger != b.Trigger {
+			return a.Trigger < b.Trigger
+		}
+		return a.Name < b.Name
+	})
+	for _, errs := range s.providerErrors {
+		summary.ProviderErrors = append(summary.ProviderErrors, *errs)
+	}
+	sort.Slice(summary.ProviderErrors, func(i, j int) bool {
+		return summary.ProviderErrors[i].Provider < summary.ProviderErrors[j].Provider
+	})
+	truncated := append([]operatorv1alpha1.TruncatedLog(nil), s.truncated...)
+	sort.Slice(truncated, func(i, j int) bool {
+		a, b := truncated[i], truncated[j]
+		if a.PodName != b.PodName {
+			return a.PodName < b.PodName
+		}
+		return a.Container < b.Container
+	})
+	summary.TruncatedContainers = int32(len(truncated))
+	if len(truncated) > maxTruncatedLogs {
+		truncated = truncated[:maxTruncatedLogs]
+	}
+	summary.TruncatedLogs = truncated
+	return summary
+}
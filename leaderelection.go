@@ -0,0 +1,30 @@
+// leaderelection.go
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+// inClusterNamespaceFile holds the namespace of the operator's ServiceAccount
+const inClusterNamespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+
+// newLeaderElectionLock builds a Lease lock held under an explicit identity.
+// controller-runtime only creates locks with a generated hostname_uuid
+// identity, which makes it hard to tell from the Lease which replica leads.
+func newLeaderElectionLock(config *rest.Config, namespace, name, identity string, renewDeadline time.Duration) (resourcelock.Interface, error) {
+	if namespace == "" {
+		data, err := os.ReadFile(inClusterNamespaceFile)
+		if err != nil {
+			return nil, fmt.Errorf("--leader-election-namespace is required when running outside a cluster: %w", err)
+		}
+		namespace = strings.TrimSpace(string(data))
+	}
+	return resourcelock.NewFromKubeconfig(resourcelock.LeasesResourceLock, namespace, name,
+		resourcelock.ResourceLockConfig{Identity: identity}, config, renewDeadline)
+}
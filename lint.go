@@ -0,0 +1,252 @@
+// lint.go
+package v1alpha1
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// metricOperators are the comparison operators of a MetricCondition
+var metricOperators = map[string]bool{">": true, "<": true, ">=": true, "<=": true, "==": true}
+
+// LintResult holds the problems found in a PodRestart. Errors make the
+// PodRestart invalid; warnings point at settings that are valid but unlikely
+// to do what was intended.
+type LintResult struct {
+	Errors   field.ErrorList
+	Warnings []string
+}
+
+// Lint checks a PodRestart without a cluster: everything the admission
+// webhook validates, the constraints enforced by the CRD schema and the
+// settings that only fail once the controller evaluates them, such as metric
+// thresholds and query templates
+func Lint(pr *PodRestart) LintResult {
+	allErrs := pr.validateSpec()
+	allErrs = append(allErrs, pr.lintSchema()...)
+	allErrs = append(allErrs, pr.lintEvaluation()...)
+	return LintResult{Errors: allErrs, Warnings: pr.warnings()}
+}
+
+// lintSchema repeats the checks of the CRD schema that the API server would
+// otherwise only apply when the PodRestart is submitted
+func (pr *PodRestart) lintSchema() field.ErrorList {
+	var allErrs field.ErrorList
+	specPath := field.NewPath("spec")
+
+	if _, err := metav1.LabelSelectorAsSelector(&pr.Spec.PodSelector); err != nil {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("podSelector"), pr.Spec.PodSelector, err.Error()))
+	}
+	if pr.Spec.HistoryLimit != nil && *pr.Spec.HistoryLimit < 0 {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("historyLimit"), *pr.Spec.HistoryLimit, "must not be negative"))
+	}
+	if budget := pr.Spec.RestartBudget; budget != nil {
+		budgetPath := specPath.Child("restartBudget")
+		if budget.MaxRestarts < 1 {
+			allErrs = append(allErrs, field.Invalid(budgetPath.Child("maxRestarts"), budget.MaxRestarts, "must be at least 1"))
+		}
+		if budget.Window.Duration <= 0 {
+			allErrs = append(allErrs, field.Invalid(budgetPath.Child("window"), budget.Window.Duration.String(), "must be positive"))
+		}
+	}
+	seen := map[string]bool{}
+	for i, pattern := range pr.Spec.NamedErrorPatterns {
+		if seen[pattern.Name] {
+			allErrs = append(allErrs, field.Duplicate(specPath.Child("namedErrorPatterns").Index(i).Child("name"), pattern.Name))
+		}
+		seen[pattern.Name] = true
+	}
+	return allErrs
+}
+
+// lintEvaluation checks the settings the controller only parses while evaluating pods
+func (pr *PodRestart) lintEvaluation() field.ErrorList {
+	var allErrs field.ErrorList
+	specPath := field.NewPath("spec")
+
+	for i, cond := range pr.Spec.MetricConditions {
+		condPath := specPath.Child("metricConditions").Index(i)
+		if _, err := strconv.ParseFloat(cond.Threshold, 64); err != nil {
+			allErrs = append(allErrs, field.Invalid(condPath.Child("threshold"), cond.Threshold, "must be a number"))
+		}
+		if !metricOperators[cond.Operator] {
+			allErrs = append(allErrs, field.NotSupported(condPath.Child("operator"), cond.Operator, []string{">", "<", ">=", "<=", "=="}))
+		}
+		if cond.Query != "" {
+			if _, err := template.New("query").Parse(cond.Query); err != nil {
+				allErrs = append(allErrs, field.Invalid(condPath.Child("query"), cond.Query, err.Error()))
+			}
+		}
+	}
+	if cond := pr.Spec.ZombieProcesses; cond != nil && cond.Threshold < 0 {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("zombieProcesses", "threshold"), cond.Threshold, "must not be negative"))
+	}
+	if cond := pr.Spec.Connections; cond != nil {
+		connPath := specPath.Child("connections")
+		if cond.Max == nil && cond.Min == nil {
+			allErrs = append(allErrs, field.Required(connPath, "max or min must be set"))
+		}
+		if cond.Max != nil && cond.Min != nil && *cond.Min > *cond.Max {
+			allErrs = append(allErrs, field.Invalid(connPath.Child("min"), *cond.Min, "must not be larger than max"))
+		}
+		if cond.Provider != "" && cond.Query == "" {
+			allErrs = append(allErrs, field.Required(connPath.Child("query"), "is required with provider"))
+		}
+		if cond.Query != "" {
+			if _, err := template.New("query").Parse(cond.Query); err != nil {
+				allErrs = append(allErrs, field.Invalid(connPath.Child("query"), cond.Query, err.Error()))
+			}
+		}
+	}
+	for i, cond := range pr.Spec.ForbiddenImages {
+		fiPath := specPath.Child("forbiddenImages").Index(i)
+		if len(cond.Images) == 0 && len(cond.Digests) == 0 {
+			allErrs = append(allErrs, field.Required(fiPath, "images or digests must be set"))
+		}
+		for j, pattern := range cond.Images {
+			if _, err := path.Match(pattern, ""); err != nil {
+				allErrs = append(allErrs, field.Invalid(fiPath.Child("images").Index(j), pattern, err.Error()))
+			}
+		}
+		for j, digest := range cond.Digests {
+			if !strings.Contains(digest, ":") {
+				allErrs = append(allErrs, field.Invalid(fiPath.Child("digests").Index(j), digest, "must be of the form algorithm:hex, e.g. sha256:..."))
+			}
+		}
+	}
+	if cond := pr.Spec.JVM; cond != nil && cond.OldGenAfterGCPercent == nil && cond.MaxGCPause == nil {
+		allErrs = append(allErrs, field.Required(specPath.Child("jvm"), "oldGenAfterGCPercent or maxGCPause must be set"))
+	}
+	if rec := pr.Spec.MemoryRecommendation; rec != nil && rec.Query != "" {
+		if _, err := template.New("query").Parse(rec.Query); err != nil {
+			allErrs = append(allErrs, field.Invalid(specPath.Child("memoryRecommendation", "query"), rec.Query, err.Error()))
+		}
+	}
+	return allErrs
+}
+
+// warnings reports valid settings that are unlikely to be intended. They are
+// also returned as admission warnings.
+func (pr *PodRestart) warnings() []string {
+	var warnings []string
+	warn := func(path *field.Path, format string, args ...interface{}) {
+		warnings = append(warnings, path.String()+": "+fmt.Sprintf(format, args...))
+	}
+	specPath := field.NewPath("spec")
+
+	if len(pr.Spec.PodSelector.MatchLabels) == 0 && len(pr.Spec.PodSelector.MatchExpressions) == 0 {
+		warn(specPath.Child("podSelector"), "selects every pod in the namespace")
+	}
+	if len(pr.Spec.ErrorPatterns) == 0 && len(pr.Spec.NamedErrorPatterns) == 0 && len(pr.Spec.MetricConditions) == 0 &&
+		pr.Spec.ZombieProcesses == nil && pr.Spec.Connections == nil && len(pr.Spec.HeartbeatFiles) == 0 &&
+		len(pr.Spec.BurnRates) == 0 && pr.Spec.JVM == nil && len(pr.Spec.ExitCodes) == 0 &&
+		len(pr.Spec.ForbiddenImages) == 0 {
+		warn(specPath, "no trigger is set, no pod will ever be restarted")
+	}
+	for i, pattern := range pr.Spec.ErrorPatterns {
+		if re, err := regexp.Compile(pattern); err == nil && re.MatchString("") {
+			warn(specPath.Child("errorPatterns").Index(i), "%q matches every log line", pattern)
+		}
+	}
+	for i, pattern := range pr.Spec.NamedErrorPatterns {
+		if re, err := regexp.Compile(pattern.Pattern); err == nil && re.MatchString("") {
+			warn(specPath.Child("namedErrorPatterns").Index(i).Child("pattern"), "%q matches every log line", pattern.Pattern)
+		}
+	}
+
+	for i, group := range pr.Spec.TriggerGroups {
+		for j, ref := range group.AllOf {
+			if ref.Name == "" && !pr.hasTrigger(ref) {
+				warn(specPath.Child("triggerGroups").Index(i).Child("allOf").Index(j),
+					"selects no %s condition, group %s can never fire", ref.Trigger, group.Name)
+			}
+		}
+	}
+
+	if budget := pr.Spec.RestartBudget; budget != nil && budget.MaxRestarts > 0 && pr.Spec.MinTimeBetweenRestarts != nil {
+		cooldown, window := pr.Spec.MinTimeBetweenRestarts.Duration, budget.Window.Duration
+		if cooldown > 0 && window > 0 {
+			if fit := int64((window + cooldown - 1) / cooldown); fit < int64(budget.MaxRestarts) {
+				warn(specPath.Child("restartBudget"), "minTimeBetweenRestarts of %s allows at most %d restarts per %s, the budget of %d can never be exhausted",
+					cooldown, fit, window, budget.MaxRestarts)
+			}
+		}
+	}
+	if s := pr.Spec.Sampling; s != nil && s.Period.Duration > 0 && s.Period.Duration <= pr.CheckIntervalDuration() {
+		warn(specPath.Child("sampling", "period"), "is not longer than the check interval, every pod is evaluated on every pass")
+	}
+	if rec := pr.Spec.MemoryRecommendation; rec != nil {
+		limit := int32(10) // the historyLimit default
+		if pr.Spec.HistoryLimit != nil {
+			limit = *pr.Spec.HistoryLimit
+		}
+		if rec.MinOOMRestarts > limit {
+			warn(specPath.Child("memoryRecommendation", "minOOMRestarts"),
+				"is larger than historyLimit %d, OOM restarts are counted in status.recentRestarts so no suggestion is ever made", limit)
+		}
+	}
+	return warnings
+}
+
+// hasTrigger reports whether the PodRestart sets a condition the reference selects
+func (pr *PodRestart) hasTrigger(ref TriggerReference) bool {
+	named := func(name string) bool { return ref.Name == "" || ref.Name == name }
+	spec := &pr.Spec
+	switch ref.Trigger {
+	case "ErrorPattern":
+		for _, p := range spec.ErrorPatterns {
+			if named(p) {
+				return true
+			}
+		}
+		for _, p := range spec.NamedErrorPatterns {
+			if named(p.Name) {
+				return true
+			}
+		}
+	case "MetricCondition":
+		for _, c := range spec.MetricConditions {
+			if named(c.Name) {
+				return true
+			}
+		}
+	case "BurnRate":
+		for _, c := range spec.BurnRates {
+			if named(c.Name) {
+				return true
+			}
+		}
+	case "HeartbeatFile":
+		for _, c := range spec.HeartbeatFiles {
+			if named(c.Path) {
+				return true
+			}
+		}
+	case "ExitCode":
+		for _, c := range spec.ExitCodes {
+			if named(c.Name) {
+				return true
+			}
+		}
+	case "ForbiddenImage":
+		for _, c := range spec.ForbiddenImages {
+			if named(c.Name) {
+				return true
+			}
+		}
+	case "JVM":
+		return spec.JVM != nil
+	case "ZombieProcesses":
+		return spec.ZombieProcesses != nil
+	case "ConnectionCount":
+		return spec.Connections != nil
+	}
+	return false
+}
@@ -0,0 +1,250 @@
+// logbudget.go
+package controllers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	operatorv1alpha1 "github.com/example/pod-restart-operator/api/v1alpha1"
+)
+
+// errLogReadBudgetExhausted is returned by budgeted log sources once the
+// budget of the current reconcile is used up
+var errLogReadBudgetExhausted = errors.New("log read budget exhausted")
+
+// logReadWindow is the window the operator-wide log read rate is counted in
+const logReadWindow = time.Minute
+
+// logReadBudget limits the bytes and time spent reading logs in one
+// reconcile, and charges every byte to the operator-wide rate. A nil budget
+// is unlimited.
+type logReadBudget struct {
+	maxBytes int64
+	deadline time.Time
+	read     atomic.Int64
+	shared   *sharedLogBudget
+}
+
+// sharedLogBudget caps the log bytes read by all PodRestarts together per
+// logReadWindow, so many PodRestarts within their own budgets cannot
+// overload the API server. A nil budget is unlimited.
+type sharedLogBudget struct {
+	maxBytes int64
+
+	mu     sync.Mutex
+	window time.Time
+	read   int64
+}
+
+// newSharedLogBudget returns nil when maxBytes is not positive
+func newSharedLogBudget(maxBytes int64) *sharedLogBudget {
+	if maxBytes <= 0 {
+		return nil
+	}
+	return &sharedLogBudget{maxBytes: maxBytes}
+}
+
+// exhausted reports whether the current window's bytes are used up
+func (s *sharedLogBudget) exhausted(now time.Time) bool {
+	if s == nil {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.roll(now)
+	return s.read >= s.maxBytes
+}
+
+// charge counts bytes read against the current window
+func (s *sharedLogBudget) charge(n int64, now time.Time) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.roll(now)
+	s.read += n
+}
+
+func (s *sharedLogBudget) roll(now time.Time) {
+	if window := now.Truncate(logReadWindow); !window.Equal(s.window) {
+		s.window = window
+		s.read = 0
+	}
+}
+
+// newLogReadBudget combines the operator-wide limits with the PodRestart's
+// own, the lower limit wins. It returns nil when neither sets a limit and
+// there is no operator-wide rate.
+func newLogReadBudget(pr *operatorv1alpha1.PodRestart, maxBytes int64, maxDuration time.Duration, shared *sharedLogBudget, now time.Time) *logReadBudget {
+	if spec := pr.Spec.LogReadBudget; spec != nil {
+		if spec.MaxBytes != nil {
+			if v := spec.MaxBytes.Value(); v > 0 && (maxBytes <= 0 || v < maxBytes) {
+				maxBytes = v
+			}
+		}
+		if spec.MaxDuration != nil {
+			if d := spec.MaxDuration.Duration; d > 0 && (maxDuration <= 0 || d < maxDuration) {
+				maxDuration = d
+			}
+		}
+	}
+	if maxBytes <= 0 && maxDuration <= 0 && shared == nil {
+		return nil
+	}
+	b := &logReadBudget{maxBytes: maxBytes, shared: shared}
+	if maxDuration > 0 {
+		b.deadline = now.Add(maxDuration)
+	}
+	return b
+}
+
+// exhausted reports whether no more logs may be read
+func (b *logReadBudget) exhausted() bool {
+	if b == nil {
+		return false
+	}
+	if b.maxBytes > 0 && b.read.Load() >= b.maxBytes {
+		return true
+	}
+	now := time.Now()
+	if !b.deadline.IsZero() && now.After(b.deadline) {
+		return true
+	}
+	return b.shared.exhausted(now)
+}
+
+// wrap returns a LogSource that stops serving logs once the budget is used
+// up. It is wrapped per pod, so budgetCut tells whether the budget cut
+// short that pod's reads.
+func (b *logReadBudget) wrap(logs LogSource) LogSource {
+	if b == nil {
+		return logs
+	}
+	source := &budgetedLogSource{LogSource: logs, budget: b}
+	if cl, ok := logs.(cursorLogSource); ok {
+		return &budgetedCursorLogSource{budgetedLogSource: source, cursors: cl}
+	}
+	return source
+}
+
+// budgetCut reports whether the budget refused or ended a read of logs,
+// as returned by wrap
+func budgetCut(logs LogSource) bool {
+	switch s := logs.(type) {
+	case *budgetedLogSource:
+		return s.cut.Load()
+	case *budgetedCursorLogSource:
+		return s.cut.Load()
+	}
+	return false
+}
+
+// budgetedLogSource charges every byte read to a logReadBudget
+type budgetedLogSource struct {
+	LogSource
+	budget *logReadBudget
+	cut    atomic.Bool
+}
+
+// Stream implements LogSource
+func (s *budgetedLogSource) Stream(ctx context.Context, pod *corev1.Pod, container string, since time.Duration) (io.ReadCloser, error) {
+	if s.budget.exhausted() {
+		s.cut.Store(true)
+		return nil, errLogReadBudgetExhausted
+	}
+	stream, err := s.LogSource.Stream(ctx, pod, container, since)
+	if err != nil {
+		return nil, err
+	}
+	return &budgetedReader{ReadCloser: stream, source: s}, nil
+}
+
+// budgetedCursorLogSource keeps timestamp support of the wrapped source
+type budgetedCursorLogSource struct {
+	*budgetedLogSource
+	cursors cursorLogSource
+}
+
+// StreamTimestamped implements cursorLogSource
+func (s *budgetedCursorLogSource) StreamTimestamped(ctx context.Context, pod *corev1.Pod, container string, since time.Time) (io.ReadCloser, error) {
+	if s.budget.exhausted() {
+		s.cut.Store(true)
+		return nil, errLogReadBudgetExhausted
+	}
+	stream, err := s.cursors.StreamTimestamped(ctx, pod, container, since)
+	if err != nil {
+		return nil, err
+	}
+	return &budgetedReader{ReadCloser: stream, source: s.budgetedLogSource}, nil
+}
+
+// budgetedReader ends the stream early with errLogReadBudgetExhausted once
+// the budget is used up, so the partial read does not advance the cursor
+type budgetedReader struct {
+	io.ReadCloser
+	source *budgetedLogSource
+}
+
+func (r *budgetedReader) Read(p []byte) (int, error) {
+	budget := r.source.budget
+	if budget.exhausted() {
+		r.source.cut.Store(true)
+		return 0, errLogReadBudgetExhausted
+	}
+	n, err := r.ReadCloser.Read(p)
+	budget.read.Add(int64(n))
+	budget.shared.charge(int64(n), time.Now())
+	return n, err
+}
+
+// evaluationRounds tracks which pods of a PodRestart were evaluated in the
+// current round. When a log read budget defers pods, the next passes skip the
+// pods already evaluated until every pod had its turn, so deferred pods are
+// never starved by the ones listed before them.
+type evaluationRounds struct {
+	mu     sync.Mutex
+	rounds map[types.NamespacedName]map[types.UID]bool
+}
+
+func newEvaluationRounds() *evaluationRounds {
+	return &evaluationRounds{rounds: map[types.NamespacedName]map[types.UID]bool{}}
+}
+
+// done reports whether the pod was already evaluated in the current round
+func (e *evaluationRounds) done(key types.NamespacedName, uid types.UID) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.rounds[key][uid]
+}
+
+// count returns the number of pods evaluated in the current round
+func (e *evaluationRounds) count(key types.NamespacedName) int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return len(e.rounds[key])
+}
+
+// mark records that the pod was evaluated in the current round
+func (e *evaluationRounds) mark(key types.NamespacedName, uid types.UID) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.rounds[key] == nil {
+		e.rounds[key] = map[types.UID]bool{}
+	}
+	e.rounds[key][uid] = true
+}
+
+// forget ends the current round, so every pod is evaluated again
+func (e *evaluationRounds) forget(key types.NamespacedName) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	delete(e.rounds, key)
+}
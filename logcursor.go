@@ -0,0 +1,288 @@
+// logcursor.go
+package controllers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	operatorv1alpha1 "github.com/example/pod-restart-operator/api/v1alpha1"
+)
+
+const (
+	// logWindow is how far back logs are read for containers without a cursor
+	logWindow = 5 * time.Minute
+
+	// cursorTTL drops cursors of containers that logged nothing for a while,
+	// such as those of deleted pods
+	cursorTTL = time.Hour
+)
+
+// cursorLogSource is implemented by log sources that can prefix every line
+// with its RFC3339Nano timestamp, which lets a scan resume where the
+// previous one stopped
+type cursorLogSource interface {
+	LogSource
+	// StreamTimestamped returns the logs written since t, each line prefixed
+	// with its timestamp and a space
+	StreamTimestamped(ctx context.Context, pod *corev1.Pod, container string, since time.Time) (io.ReadCloser, error)
+}
+
+// StreamTimestamped implements cursorLogSource
+func (s *kubeLogSource) StreamTimestamped(ctx context.Context, pod *corev1.Pod, container string, since time.Time) (io.ReadCloser, error) {
+	sinceTime := metav1.NewTime(since)
+	podLogOpts := corev1.PodLogOptions{
+		Container:  container,
+		SinceTime:  &sinceTime,
+		Timestamps: true,
+	}
+	return s.clientset.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, &podLogOpts).Stream(ctx)
+}
+
+// logCursors remembers the timestamp of the last log line evaluated per
+// PodRestart, pod and container. A scan only stages its cursor; it is
+// committed once the pod's restart decision completed, so an error whose
+// restart was skipped, blocked, deferred or failed is matched again on the
+// next pass.
+type logCursors struct {
+	mu      sync.Mutex
+	cursors map[types.NamespacedName]map[string]time.Time
+	staged  map[types.NamespacedName]map[string]time.Time
+}
+
+func newLogCursors() *logCursors {
+	return &logCursors{
+		cursors: map[types.NamespacedName]map[string]time.Time{},
+		staged:  map[types.NamespacedName]map[string]time.Time{},
+	}
+}
+
+func cursorKey(pod *corev1.Pod, container string) string {
+	return string(pod.UID) + "/" + container
+}
+
+func (c *logCursors) get(pr *operatorv1alpha1.PodRestart, pod *corev1.Pod, container string) time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.cursors[types.NamespacedName{Namespace: pr.Namespace, Name: pr.Name}][cursorKey(pod, container)]
+}
+
+// stage remembers the cursor a scan reached until commitPod or dropPod
+func (c *logCursors) stage(pr *operatorv1alpha1.PodRestart, pod *corev1.Pod, container string, t time.Time) {
+	key := types.NamespacedName{Namespace: pr.Namespace, Name: pr.Name}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.staged[key] == nil {
+		c.staged[key] = map[string]time.Time{}
+	}
+	c.staged[key][cursorKey(pod, container)] = t
+}
+
+// commitPod advances the cursors of a pod to those staged by its scans
+func (c *logCursors) commitPod(pr *operatorv1alpha1.PodRestart, pod *corev1.Pod) {
+	key := types.NamespacedName{Namespace: pr.Namespace, Name: pr.Name}
+	prefix := string(pod.UID) + "/"
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for k, t := range c.staged[key] {
+		if !strings.HasPrefix(k, prefix) {
+			continue
+		}
+		if c.cursors[key] == nil {
+			c.cursors[key] = map[string]time.Time{}
+		}
+		c.cursors[key][k] = t
+		delete(c.staged[key], k)
+	}
+	if len(c.staged[key]) == 0 {
+		delete(c.staged, key)
+	}
+}
+
+// dropPod discards the staged cursors of a pod, its logs are read again
+func (c *logCursors) dropPod(pr *operatorv1alpha1.PodRestart, pod *corev1.Pod) {
+	key := types.NamespacedName{Namespace: pr.Namespace, Name: pr.Name}
+	prefix := string(pod.UID) + "/"
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for k := range c.staged[key] {
+		if strings.HasPrefix(k, prefix) {
+			delete(c.staged[key], k)
+		}
+	}
+	if len(c.staged[key]) == 0 {
+		delete(c.staged, key)
+	}
+}
+
+// prune drops the cursors of a PodRestart that are older than cursorTTL
+func (c *logCursors) prune(key types.NamespacedName, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for k, t := range c.cursors[key] {
+		if now.Sub(t) > cursorTTL {
+			delete(c.cursors[key], k)
+		}
+	}
+}
+
+// count returns the number of cursors kept for a PodRestart
+func (c *logCursors) count(key types.NamespacedName) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.cursors[key])
+}
+
+// snapshot returns up to limit cursors of a PodRestart, newest first
+func (c *logCursors) snapshot(key types.NamespacedName, limit int) []operatorv1alpha1.LogCursor {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cursors := make([]operatorv1alpha1.LogCursor, 0, len(c.cursors[key]))
+	for k, t := range c.cursors[key] {
+		i := strings.IndexByte(k, '/')
+		cursors = append(cursors, operatorv1alpha1.LogCursor{
+			PodUID:    k[:i],
+			Container: k[i+1:],
+			Time:      metav1.NewMicroTime(t),
+		})
+	}
+	sort.Slice(cursors, func(i, j int) bool {
+		return cursors[i].Time.After(cursors[j].Time.Time)
+	})
+	if len(cursors) > limit {
+		cursors = cursors[:limit]
+	}
+	return cursors
+}
+
+// restore loads the cursors of a PodRestart from a checkpoint, keeping any
+// cursor that is already newer
+func (c *logCursors) restore(key types.NamespacedName, cursors []operatorv1alpha1.LogCursor) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, cursor := range cursors {
+		if c.cursors[key] == nil {
+			c.cursors[key] = map[string]time.Time{}
+		}
+		k := cursor.PodUID + "/" + cursor.Container
+		if cursor.Time.After(c.cursors[key][k]) {
+			c.cursors[key][k] = cursor.Time.Time
+		}
+	}
+}
+
+// forgetPod drops the cursors of all containers of a pod
+func (c *logCursors) forgetPod(pr *operatorv1alpha1.PodRestart, pod *corev1.Pod) {
+	key := types.NamespacedName{Namespace: pr.Namespace, Name: pr.Name}
+	prefix := string(pod.UID) + "/"
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for k := range c.cursors[key] {
+		if strings.HasPrefix(k, prefix) {
+			delete(c.cursors[key], k)
+		}
+	}
+	for k := range c.staged[key] {
+		if strings.HasPrefix(k, prefix) {
+			delete(c.staged[key], k)
+		}
+	}
+}
+
+// forget drops all cursors of a deleted PodRestart
+func (c *logCursors) forget(key types.NamespacedName) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.cursors, key)
+	delete(c.staged, key)
+}
+
+// openLogStream opens the logs of a container. Sources supporting timestamps
+// resume after the last committed line; the returned stage function stages
+// the last line read as the next cursor and must only be called once the
+// stream was read to the end. Other sources always return the last logWindow.
+func (r *PodRestartReconciler) openLogStream(ctx context.Context, logs LogSource, pr *operatorv1alpha1.PodRestart, pod *corev1.Pod, container string) (io.ReadCloser, func(), error) {
+	cl, ok := logs.(cursorLogSource)
+	if !ok {
+		stream, err := logs.Stream(ctx, pod, container, logWindow)
+		return stream, func() {}, err
+	}
+
+	after := r.cursors.get(pr, pod, container)
+	since := time.Now().Add(-logWindow)
+	if after.After(since) {
+		since = after
+	}
+	stream, err := cl.StreamTimestamped(ctx, pod, container, since)
+	if err != nil {
+		return nil, func() {}, err
+	}
+	tr := &timestampReader{src: stream, lines: bufio.NewReader(stream), after: after}
+	stage := func() {
+		if !tr.last.IsZero() {
+			r.cursors.stage(pr, pod, container, tr.last)
+		}
+	}
+	return tr, stage, nil
+}
+
+// timestampReader strips the timestamp prefix of every line, drops lines not
+// newer than after and remembers the timestamp of the last line returned.
+// SinceTime has second precision, so the stream overlaps the previous one.
+type timestampReader struct {
+	src     io.Closer
+	lines   *bufio.Reader
+	after   time.Time
+	last    time.Time
+	pending []byte
+}
+
+func (r *timestampReader) Read(p []byte) (int, error) {
+	for len(r.pending) == 0 {
+		line, err := r.lines.ReadBytes('\n')
+		if len(line) > 0 {
+			r.accept(line)
+		}
+		if err != nil {
+			if len(r.pending) > 0 {
+				break
+			}
+			return 0, err
+		}
+	}
+	n := copy(p, r.pending)
+	r.pending = r.pending[n:]
+	return n, nil
+}
+
+// accept queues a line unless it was already read on a previous pass
+func (r *timestampReader) accept(line []byte) {
+	i := bytes.IndexByte(line, ' ')
+	if i < 0 {
+		r.pending = append(r.pending, line...)
+		return
+	}
+	ts, err := time.Parse(time.RFC3339Nano, string(line[:i]))
+	if err != nil {
+		r.pending = append(r.pending, line...)
+		return
+	}
+	if !ts.After(r.after) {
+		return
+	}
+	r.last = ts
+	r.pending = append(r.pending, line[i+1:]...)
+}
+
+func (r *timestampReader) Close() error {
+	return r.src.Close()
+}
@@ -0,0 +1,301 @@
+// logfollow.go
+package controllers
+
+import (
+	"bufio"
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+
+	operatorv1alpha1 "github.com/example/pod-restart-operator/api/v1alpha1"
+)
+
+const (
+	// defaultFollowStreams is the number of log streams a PodRestart keeps
+	// open when spec.logFollow.maxStreams is not set
+	defaultFollowStreams = 10
+	// followReconnectMax caps the wait before a broken stream is reopened
+	followReconnectMax = time.Minute
+	// followTriggerInterval is the shortest time between two evaluations
+	// of a PodRestart triggered by its followed streams
+	followTriggerInterval = time.Second
+)
+
+// logFollower keeps follow=true log streams open for the pods of PodRestarts
+// with spec.logFollow, while the LogFollow feature gate is on, and requests
+// an evaluation pass as soon as a streamed line matches an error pattern. The pass then finds the line like any other
+// and applies every safety check, only sooner than the next polling cycle.
+// It runs as a manager Runnable, so streams only exist on the leader.
+type logFollower struct {
+	maxStreams int
+	events     chan event.GenericEvent
+
+	mu       sync.Mutex
+	ctx      context.Context
+	open     int
+	followed map[types.NamespacedName]*followedPodRestart
+}
+
+// followedPodRestart holds the streams of one PodRestart
+type followedPodRestart struct {
+	patterns []errorPattern
+	streams  map[followKey]*followStream
+	// interval is the shortest time between two triggered passes
+	interval  time.Duration
+	triggered time.Time
+}
+
+// followStream is an open stream, closed through cancel
+type followStream struct {
+	cancel context.CancelFunc
+}
+
+type followKey struct {
+	uid       types.UID
+	container string
+}
+
+func newLogFollower(maxStreams int) *logFollower {
+	return &logFollower{
+		maxStreams: maxStreams,
+		events:     make(chan event.GenericEvent, 1024),
+		followed:   map[types.NamespacedName]*followedPodRestart{},
+	}
+}
+
+// Start implements manager.Runnable
+func (f *logFollower) Start(ctx context.Context) error {
+	f.mu.Lock()
+	f.ctx = ctx
+	f.mu.Unlock()
+	<-ctx.Done()
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for key := range f.followed {
+		f.forgetLocked(key)
+	}
+	return nil
+}
+
+// followStreams returns the number of streams a PodRestart may keep open:
+// its maxStreams, up to an equal share of the operator's streams between
+// every following PodRestart
+func (f *logFollower) followStreams(pr *operatorv1alpha1.PodRestart) int {
+	limit := defaultFollowStreams
+	if n := pr.Spec.LogFollow.MaxStreams; n != nil {
+		limit = int(*n)
+	}
+	if limit > operatorv1alpha1.MaxLogFollowStreams {
+		limit = operatorv1alpha1.MaxLogFollowStreams
+	}
+	if f.maxStreams > 0 && len(f.followed) > 0 {
+		share := f.maxStreams / len(f.followed)
+		if share < 1 {
+			share = 1
+		}
+		if share < limit {
+			limit = share
+		}
+	}
+	return limit
+}
+
+// follow opens streams for the running containers of a page of pods, within
+// the PodRestart's and the operator's limits, and marks them in seen so
+// prune can close the streams of pods that are gone. Pods beyond the limits
+// are only covered by the regular passes. A match requests a pass at most
+// once per interval.
+func (f *logFollower) follow(pr *operatorv1alpha1.PodRestart, cluster *clusterTarget, pods []corev1.Pod, patterns []errorPattern, interval time.Duration, seen map[followKey]bool) {
+	key := types.NamespacedName{Namespace: pr.Namespace, Name: pr.Name}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.ctx == nil || len(patterns) == 0 {
+		return
+	}
+	fp := f.followed[key]
+	if fp == nil {
+		fp = &followedPodRestart{streams: map[followKey]*followStream{}}
+		f.followed[key] = fp
+	}
+	fp.patterns = patterns
+	fp.interval = interval
+
+	limit := f.followStreams(pr)
+	for i := range pods {
+		pod := &pods[i]
+		if pod.Status.Phase != corev1.PodRunning || pod.DeletionTimestamp != nil {
+			continue
+		}
+		for _, container := range pod.Spec.Containers {
+			fk := followKey{uid: pod.UID, container: container.Name}
+			if _, ok := fp.streams[fk]; ok {
+				seen[fk] = true
+				continue
+			}
+			if len(fp.streams) >= limit || (f.maxStreams > 0 && f.open >= f.maxStreams) {
+				continue
+			}
+			ctx, cancel := context.WithCancel(f.ctx)
+			s := &followStream{cancel: cancel}
+			fp.streams[fk] = s
+			f.open++
+			seen[fk] = true
+			go f.stream(ctx, s, key, cluster.clientset, pod.Namespace, pod.Name, fk)
+		}
+	}
+}
+
+// prune closes the streams of a PodRestart not marked in seen during the pass
+func (f *logFollower) prune(key types.NamespacedName, seen map[followKey]bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	fp := f.followed[key]
+	if fp == nil {
+		return
+	}
+	for fk := range fp.streams {
+		if !seen[fk] {
+			f.closeLocked(fp, fk)
+		}
+	}
+}
+
+// forget closes every stream of a PodRestart
+func (f *logFollower) forget(key types.NamespacedName) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.forgetLocked(key)
+}
+
+// streamsOf returns the number of streams a PodRestart keeps open
+func (f *logFollower) streamsOf(key types.NamespacedName) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if fp := f.followed[key]; fp != nil {
+		return len(fp.streams)
+	}
+	return 0
+}
+
+func (f *logFollower) forgetLocked(key types.NamespacedName) {
+	if fp := f.followed[key]; fp != nil {
+		for fk := range fp.streams {
+			f.closeLocked(fp, fk)
+		}
+		delete(f.followed, key)
+	}
+}
+
+func (f *logFollower) closeLocked(fp *followedPodRestart, fk followKey) {
+	if s, ok := fp.streams[fk]; ok {
+		s.cancel()
+		delete(fp.streams, fk)
+		f.open--
+	}
+}
+
+// stream reads a container's logs as they are written and reopens the
+// stream with backoff when it breaks, until the pod is gone or the stream
+// is closed. A reopened stream resumes at the timestamp of the last line
+// read, so lines written while it was broken are not missed.
+func (f *logFollower) stream(ctx context.Context, s *followStream, key types.NamespacedName, clientset kubernetes.Interface, namespace, name string, fk followKey) {
+	defer func() {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		// A pruned stream may have been replaced by a new one meanwhile
+		if fp := f.followed[key]; fp != nil && fp.streams[fk] == s {
+			f.closeLocked(fp, fk)
+		}
+	}()
+
+	since := metav1.Now()
+	wait := time.Second
+	for ctx.Err() == nil {
+		opts := &corev1.PodLogOptions{Container: fk.container, Follow: true, Timestamps: true, SinceTime: &since}
+		logs, err := clientset.CoreV1().Pods(namespace).GetLogs(name, opts).Stream(ctx)
+		if errors.IsNotFound(err) {
+			return
+		}
+		if err == nil {
+			opened := time.Now()
+			if last := f.scan(key, bufio.NewScanner(logs)); !last.IsZero() {
+				since = metav1.NewTime(last)
+			}
+			logs.Close()
+			if time.Since(opened) > followReconnectMax {
+				wait = time.Second
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+		if wait *= 2; wait > followReconnectMax {
+			wait = followReconnectMax
+		}
+	}
+}
+
+// scan matches streamed lines against the PodRestart's current patterns and
+// returns the timestamp of the last line read, if any
+func (f *logFollower) scan(key types.NamespacedName, lines *bufio.Scanner) time.Time {
+	lines.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	var last time.Time
+	for lines.Scan() {
+		line := lines.Text()
+		// Every line starts with its RFC3339 timestamp, as requested
+		if stamp, rest, ok := strings.Cut(line, " "); ok {
+			if t, err := time.Parse(time.RFC3339Nano, stamp); err == nil {
+				last, line = t, rest
+			}
+		}
+		f.mu.Lock()
+		fp := f.followed[key]
+		if fp == nil {
+			f.mu.Unlock()
+			return last
+		}
+		patterns := fp.patterns
+		f.mu.Unlock()
+		for _, pattern := range patterns {
+			if pattern.re.MatchString(line) {
+				f.trigger(key)
+				break
+			}
+		}
+	}
+	return last
+}
+
+// trigger requests an evaluation pass of the PodRestart
+func (f *logFollower) trigger(key types.NamespacedName) {
+	f.mu.Lock()
+	fp := f.followed[key]
+	interval := followTriggerInterval
+	if fp != nil && fp.interval > interval {
+		interval = fp.interval
+	}
+	if fp == nil || time.Since(fp.triggered) < interval {
+		f.mu.Unlock()
+		return
+	}
+	fp.triggered = time.Now()
+	f.mu.Unlock()
+
+	pr := &operatorv1alpha1.PodRestart{ObjectMeta: metav1.ObjectMeta{Namespace: key.Namespace, Name: key.Name}}
+	select {
+	case f.events <- event.GenericEvent{Object: pr}:
+	default:
+		// The queue already holds plenty of passes to run
+	}
+}
@@ -0,0 +1,86 @@
+// logging.go
+package controllers
+
+import (
+	"strconv"
+
+	"github.com/go-logr/logr"
+
+	operatorv1alpha1 "github.com/example/pod-restart-operator/api/v1alpha1"
+)
+
+const (
+	// AnnotationLogVerbosity raises the log verbosity while reconciling a
+	// single PodRestart, e.g. "5" to log at -v=5 for that PodRestart only
+	AnnotationLogVerbosity = "operator.example.com/log-verbosity"
+
+	// MaxLogVerbosity is the highest verbosity the annotation can request.
+	// The logger below the verbosity filter must be built to pass it.
+	MaxLogVerbosity = 10
+)
+
+// verbositySink drops Info logs above its verbosity in front of a sink that
+// passes every verbosity up to MaxLogVerbosity. Filtering here rather than
+// in the zap core lets a single PodRestart log at a higher verbosity.
+type verbositySink struct {
+	sink logr.LogSink
+	max  int
+}
+
+// NewVerbositySink filters the Info logs of sink to verbosity max
+func NewVerbositySink(sink logr.LogSink, max int) logr.LogSink {
+	return &verbositySink{sink: sink, max: max}
+}
+
+func (s *verbositySink) Init(info logr.RuntimeInfo) {
+	// Account for the frame added by this sink
+	info.CallDepth++
+	s.sink.Init(info)
+}
+
+func (s *verbositySink) Enabled(level int) bool {
+	return level <= s.max && s.sink.Enabled(level)
+}
+
+func (s *verbositySink) Info(level int, msg string, keysAndValues ...interface{}) {
+	s.sink.Info(level, msg, keysAndValues...)
+}
+
+func (s *verbositySink) Error(err error, msg string, keysAndValues ...interface{}) {
+	s.sink.Error(err, msg, keysAndValues...)
+}
+
+func (s *verbositySink) WithValues(keysAndValues ...interface{}) logr.LogSink {
+	return &verbositySink{sink: s.sink.WithValues(keysAndValues...), max: s.max}
+}
+
+func (s *verbositySink) WithName(name string) logr.LogSink {
+	return &verbositySink{sink: s.sink.WithName(name), max: s.max}
+}
+
+// withVerbosity raises the verbosity of a logger built on a verbositySink.
+// Other loggers are returned unchanged.
+func withVerbosity(logger logr.Logger, v int) logr.Logger {
+	s, ok := logger.GetSink().(*verbositySink)
+	if !ok || v <= s.max {
+		return logger
+	}
+	if v > MaxLogVerbosity {
+		v = MaxLogVerbosity
+	}
+	return logger.WithSink(&verbositySink{sink: s.sink, max: v})
+}
+
+// logVerbosityFor returns the verbosity requested by the PodRestart's
+// annotation, if any
+func logVerbosityFor(pr *operatorv1alpha1.PodRestart) (int, bool) {
+	raw, ok := pr.Annotations[AnnotationLogVerbosity]
+	if !ok {
+		return 0, false
+	}
+	v, err := strconv.Atoi(raw)
+	if err != nil || v < 0 {
+		return 0, false
+	}
+	return v, true
+}
@@ -0,0 +1,78 @@
+// logsampling.go
+package controllers
+
+import (
+	"bufio"
+	"io"
+
+	operatorv1alpha1 "github.com/example/pod-restart-operator/api/v1alpha1"
+)
+
+// sampledLogReader caps the log bytes read from a single container and
+// optionally passes on only every Nth line, so one verbose container cannot
+// use up the pass's log read budget or the matching time of its workers.
+// Only whole lines are passed on.
+type sampledLogReader struct {
+	src      io.ReadCloser
+	lines    *bufio.Reader
+	every    int
+	maxBytes int64
+
+	read      int64
+	line      int
+	truncated bool
+	pending   []byte
+}
+
+// newSampledLogReader wraps stream according to spec.logSampling. It returns
+// nil when the PodRestart does not sample, the stream is used as is then.
+func newSampledLogReader(stream io.ReadCloser, pr *operatorv1alpha1.PodRestart) *sampledLogReader {
+	spec := pr.Spec.LogSampling
+	if spec == nil {
+		return nil
+	}
+	r := &sampledLogReader{src: stream, lines: bufio.NewReader(stream), every: 1}
+	if spec.MaxBytesPerContainer != nil {
+		r.maxBytes = spec.MaxBytesPerContainer.Value()
+	}
+	if spec.SampleEvery != nil && *spec.SampleEvery > 1 {
+		r.every = int(*spec.SampleEvery)
+	}
+	if r.maxBytes <= 0 && r.every == 1 {
+		return nil
+	}
+	return r
+}
+
+func (r *sampledLogReader) Read(p []byte) (int, error) {
+	for len(r.pending) == 0 {
+		if r.maxBytes > 0 && r.read >= r.maxBytes {
+			// Only note the cap when there was more to read
+			if _, err := r.lines.Peek(1); err == nil {
+				r.truncated = true
+			}
+			return 0, io.EOF
+		}
+		line, err := r.lines.ReadBytes('\n')
+		if len(line) > 0 {
+			r.read += int64(len(line))
+			if r.line%r.every == 0 {
+				r.pending = line
+			}
+			r.line++
+		}
+		if err != nil {
+			if len(r.pending) > 0 {
+				break
+			}
+			return 0, err
+		}
+	}
+	n := copy(p, r.pending)
+	r.pending = r.pending[n:]
+	return n, nil
+}
+
+func (r *sampledLogReader) Close() error {
+	return r.src.Close()
+}
@@ -0,0 +1,40 @@
+// logsetup.go
+package main
+
+import (
+	"github.com/go-logr/logr"
+	"go.uber.org/zap/zapcore"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+
+	"github.com/example/pod-restart-operator/controllers"
+)
+
+// newLogger builds the operator logger from the --zap-* flags. The zap core
+// passes every verbosity up to controllers.MaxLogVerbosity while a verbosity
+// filter applies the selected level, so the log-verbosity annotation can
+// raise it for a single PodRestart.
+func newLogger(opts *zap.Options) logr.Logger {
+	v := verbosityOf(opts)
+	opts.Level = zapcore.Level(-controllers.MaxLogVerbosity)
+	logger := zap.New(zap.UseFlagOptions(opts))
+	return logger.WithSink(controllers.NewVerbositySink(logger.GetSink(), v))
+}
+
+// verbosityOf returns the highest logr verbosity enabled by the zap level,
+// or -1 when even V(0) Info logs are disabled
+func verbosityOf(opts *zap.Options) int {
+	level := opts.Level
+	if level == nil {
+		// Mirrors the defaults applied by zap.New
+		if opts.Development {
+			return 1
+		}
+		return 0
+	}
+	for v := controllers.MaxLogVerbosity; v >= 0; v-- {
+		if level.Enabled(zapcore.Level(-v)) {
+			return v
+		}
+	}
+	return -1
+}
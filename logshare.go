@@ -0,0 +1,143 @@
+// logshare.go
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// logShareTTL is how long a fetched log window is reused by other
+	// PodRestarts targeting the same container, about one evaluation cycle
+	logShareTTL = 10 * time.Second
+
+	// maxSharedLogBytes bounds the log window kept per container
+	maxSharedLogBytes = 4 * 1024 * 1024
+
+	// maxSharedLogTotal bounds the log windows kept for all containers
+	// together; the oldest windows are evicted first
+	maxSharedLogTotal = 64 * 1024 * 1024
+)
+
+// sharedLog is a log window fetched for one container
+type sharedLog struct {
+	data    []byte
+	since   time.Time
+	fetched time.Time
+}
+
+// logShare keeps recently fetched log windows so PodRestarts targeting the
+// same pod read each container's logs from the API server once per cycle
+type logShare struct {
+	mu      sync.Mutex
+	entries map[string]sharedLog
+	// size is the number of bytes held by entries
+	size int
+}
+
+func newLogShare() *logShare {
+	return &logShare{entries: map[string]sharedLog{}}
+}
+
+// lookup returns a fresh window covering since
+func (s *logShare) lookup(key string, since time.Time) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.entries[key]
+	if !ok || time.Since(e.fetched) >= logShareTTL || since.Before(e.since) {
+		return nil, false
+	}
+	return e.data, true
+}
+
+// store keeps a completely read window and drops expired ones, then the
+// oldest ones until the windows fit in maxSharedLogTotal
+func (s *logShare) store(key string, entry sharedLog) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.remove(key)
+	for k, e := range s.entries {
+		if time.Since(e.fetched) >= logShareTTL {
+			s.remove(k)
+		}
+	}
+	for s.size+len(entry.data) > maxSharedLogTotal && len(s.entries) > 0 {
+		var oldest string
+		for k, e := range s.entries {
+			if oldest == "" || e.fetched.Before(s.entries[oldest].fetched) {
+				oldest = k
+			}
+		}
+		s.remove(oldest)
+	}
+	s.entries[key] = entry
+	s.size += len(entry.data)
+}
+
+func (s *logShare) remove(key string) {
+	if e, ok := s.entries[key]; ok {
+		s.size -= len(e.data)
+		delete(s.entries, key)
+	}
+}
+
+// sharedLogSource serves timestamped log windows from a logShare, falling
+// back to the wrapped source on a miss. Windows are only shared between
+// sources reading as the same identity, so a PodRestart never sees logs
+// its identity may not read.
+type sharedLogSource struct {
+	cursorLogSource
+	share *logShare
+	// identity keys the impersonated user, empty for the operator's own
+	identity string
+}
+
+// StreamTimestamped implements cursorLogSource. Callers filter the lines
+// they already read by timestamp, so a window starting earlier than since
+// can be served as is.
+func (s *sharedLogSource) StreamTimestamped(ctx context.Context, pod *corev1.Pod, container string, since time.Time) (io.ReadCloser, error) {
+	key := s.identity + "/" + string(pod.UID) + "/" + container
+	if data, ok := s.share.lookup(key, since); ok {
+		return io.NopCloser(bytes.NewReader(data)), nil
+	}
+
+	fetched := time.Now()
+	stream, err := s.cursorLogSource.StreamTimestamped(ctx, pod, container, since)
+	if err != nil {
+		return nil, err
+	}
+	return &recordingReader{ReadCloser: stream, onEOF: func(data []byte) {
+		s.share.store(key, sharedLog{data: data, since: since, fetched: fetched})
+	}}, nil
+}
+
+// recordingReader copies everything read and hands it to onEOF once the
+// stream was read completely. Streams above maxSharedLogBytes are not kept.
+type recordingReader struct {
+	io.ReadCloser
+	buf      bytes.Buffer
+	overflow bool
+	onEOF    func([]byte)
+}
+
+func (r *recordingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	if !r.overflow {
+		if r.buf.Len()+n > maxSharedLogBytes {
+			r.overflow = true
+			r.buf = bytes.Buffer{}
+		} else {
+			r.buf.Write(p[:n])
+		}
+	}
+	if err == io.EOF && !r.overflow && r.onEOF != nil {
+		r.onEOF(r.buf.Bytes())
+		r.onEOF = nil
+	}
+	return n, err
+}
@@ -0,0 +1,57 @@
+// logsource.go
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+
+	operatorv1alpha1 "github.com/example/pod-restart-operator/api/v1alpha1"
+)
+
+// LogSourceKubeAPI is the name of the built-in backend that reads logs through the Kubernetes API
+const LogSourceKubeAPI = "kubeAPI"
+
+// LogSource is a backend that can provide the recent log output of a container
+type LogSource interface {
+	// Stream returns the logs written by the container within the given window.
+	// The caller is responsible for closing the returned reader.
+	Stream(ctx context.Context, pod *corev1.Pod, container string, since time.Duration) (io.ReadCloser, error)
+}
+
+// kubeLogSource reads container logs from the kubelet via the API server
+type kubeLogSource struct {
+	clientset kubernetes.Interface
+}
+
+// Stream implements LogSource
+func (s *kubeLogSource) Stream(ctx context.Context, pod *corev1.Pod, container string, since time.Duration) (io.ReadCloser, error) {
+	podLogOpts := corev1.PodLogOptions{
+		Container:    container,
+		SinceSeconds: ptr(int64(since.Seconds())),
+	}
+	return s.clientset.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, &podLogOpts).Stream(ctx)
+}
+
+// logSourceFor resolves the log backend selected by the PodRestart. The
+// Kubernetes API backend is used when spec.logSource is empty; with the
+// SharedLogWindows feature its log windows are shared between PodRestarts
+// targeting the same pods as the same identity.
+func (r *PodRestartReconciler) logSourceFor(pr *operatorv1alpha1.PodRestart, cluster *clusterTarget) (LogSource, error) {
+	name := pr.Spec.LogSource
+	if name == "" || name == LogSourceKubeAPI {
+		source := &kubeLogSource{clientset: cluster.clientset}
+		if !r.featureEnabled(SharedLogWindows) {
+			return source, nil
+		}
+		return &sharedLogSource{cursorLogSource: source, share: r.logShare, identity: cluster.identityKey}, nil
+	}
+	if source, ok := r.LogSources[name]; ok {
+		return source, nil
+	}
+	return nil, fmt.Errorf("unknown log source %q", name)
+}
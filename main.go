@@ -2,18 +2,28 @@
 package main
 
 import (
+	"context"
 	"flag"
+	"fmt"
+	"hash/fnv"
 	"os"
+	"strings"
+	"time"
 
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/kubernetes"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 
 	operatorv1alpha1 "github.com/example/pod-restart-operator/api/v1alpha1"
+	operatorv1beta1 "github.com/example/pod-restart-operator/api/v1beta1"
 	"github.com/example/pod-restart-operator/controllers"
 )
 
@@ -25,47 +35,401 @@ var (
 func init() {
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
 	utilruntime.Must(operatorv1alpha1.AddToScheme(scheme))
+	utilruntime.Must(operatorv1beta1.AddToScheme(scheme))
 }
 
 func main() {
 	var metricsAddr string
 	var enableLeaderElection bool
+	var leaderElectionNamespace, leaderElectionID, leaderElectionIdentity string
+	var leaseDuration, renewDeadline, retryPeriod time.Duration
+	var shardCount, shardID int
+	var shardNamespaceSelector string
+	var watchNamespaces string
 	var probeAddr string
+	var readyzCheckProviders bool
+	var debugAddr string
+	var debugCertFile, debugKeyFile, debugClientCAFile string
+	var debugAuth bool
+	var triggerAddr string
+	var triggerCertFile, triggerKeyFile, triggerClientCAFile string
+	var otlpEndpoint string
+	var otlpInsecure bool
+	var traceSampleRatio float64
+	var auditLog string
+	var auditS3Bucket, auditS3Prefix, auditS3Endpoint string
+	var auditSQLDriver, auditSQLDSN, auditSQLTable string
+	var auditBatchSize int
+	var auditFlushInterval time.Duration
+	var evaluationWorkers int
+	var evaluationSlots int
+	var podEvaluationTimeout time.Duration
+	var logReadBudgetBytes int64
+	var logReadBudgetDuration time.Duration
+	var logReadBytesPerMinute int64
+	var maxConcurrentReconciles int
+	var cacheSyncTimeout time.Duration
+	var kubeAPIQPS float64
+	var kubeAPIBurst int
+	var logsAPIQPS float64
+	var logsAPIBurst int
+	var namespaceAPIQPS float64
+	var namespaceAPIBurst int
+	var backoffBase, backoffMax time.Duration
+	var reconcileQPS float64
+	var reconcileBurst int
+	var gracefulShutdownTimeout, shutdownGracePeriod time.Duration
+	var requeueJitter float64
+	var maxFollowStreams int
+	var startupSpread time.Duration
+	features := controllers.NewFeatureGates()
+	var operatorConfig string
+	var impersonation, impersonationServiceAccount string
+	var protectedNamespaces, allowedNamespaces string
+	var debugContainerImages string
+	var clusterServers string
 
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
+	flag.BoolVar(&readyzCheckProviders, "readyz-check-providers", false,
+		"Report not ready while a MetricProvider cannot be queried. Off by default because an unready "+
+			"operator also stops serving its webhooks.")
+	flag.StringVar(&debugAddr, "debug-bind-address", "",
+		"Serve pprof, /debug/state and /debug/podrestarts/{namespace}/{name} on this address, e.g. localhost:6060. Disabled when empty; "+
+			"the endpoints are unauthenticated unless --debug-auth is set, so keep them off public interfaces.")
+	flag.StringVar(&debugCertFile, "debug-tls-cert-file", "",
+		"Serve the debug endpoints over HTTPS with this certificate. It is reloaded when the file changes.")
+	flag.StringVar(&debugKeyFile, "debug-tls-key-file", "", "Private key of --debug-tls-cert-file.")
+	flag.StringVar(&debugClientCAFile, "debug-client-ca-file", "",
+		"Verify client certificates presented to the debug endpoints against this CA bundle.")
+	flag.BoolVar(&debugAuth, "debug-auth", false,
+		"Require a client certificate or a Kubernetes bearer token on the debug endpoints and authorize "+
+			"each path with a SubjectAccessReview. /debug/podrestarts/ is only served with it, authorized as get on the PodRestart.")
+	flag.StringVar(&triggerAddr, "trigger-bind-address", "",
+		"Serve the inbound trigger API and the Alertmanager receiver on this address, e.g. :9443. Disabled when empty. "+
+			"Requires --trigger-tls-cert-file; callers are authorized as the trigger verb on each PodRestart.")
+	flag.StringVar(&triggerCertFile, "trigger-tls-cert-file", "",
+		"Certificate the trigger API is served with. It is reloaded when the file changes.")
+	flag.StringVar(&triggerKeyFile, "trigger-tls-key-file", "", "Private key of --trigger-tls-cert-file.")
+	flag.StringVar(&triggerClientCAFile, "trigger-client-ca-file", "",
+		"Verify client certificates presented to the trigger API against this CA bundle. Callers without "+
+			"a certificate must send a Kubernetes bearer token.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
 		"Enable leader election for controller manager. Enabling this will ensure there is only one active controller manager.")
+	flag.StringVar(&leaderElectionNamespace, "leader-election-namespace", "",
+		"Namespace of the leader election Lease. Defaults to the operator's namespace when running in a cluster.")
+	flag.StringVar(&leaderElectionID, "leader-election-id", "pod-restart-operator-leader-election",
+		"Name of the leader election Lease.")
+	flag.StringVar(&leaderElectionIdentity, "leader-election-identity", os.Getenv("POD_NAME"),
+		"Identity recorded in the Lease by this replica. Defaults to $POD_NAME, or a generated identity when unset.")
+	flag.DurationVar(&leaseDuration, "leader-election-lease-duration", 15*time.Second,
+		"Duration non-leader replicas wait before trying to acquire leadership.")
+	flag.DurationVar(&renewDeadline, "leader-election-renew-deadline", 10*time.Second,
+		"Duration the leader retries renewing leadership before giving it up.")
+	flag.DurationVar(&retryPeriod, "leader-election-retry-period", 2*time.Second,
+		"Interval between leader election attempts.")
+	flag.IntVar(&shardCount, "shard-count", 0,
+		"Split namespaces by hash into this many shards. 0 disables hash sharding.")
+	flag.IntVar(&shardID, "shard-id", 0, "Shard handled by this replica, from 0 to --shard-count - 1.")
+	flag.StringVar(&shardNamespaceSelector, "shard-namespace-selector", "",
+		"Only handle namespaces matching this label selector. Mutually exclusive with --shard-count.")
+	flag.StringVar(&watchNamespaces, "namespaces", os.Getenv("WATCH_NAMESPACE"),
+		"Comma separated namespaces to watch. Defaults to $WATCH_NAMESPACE; all namespaces when empty. "+
+			"Secrets referenced by channels and providers must live in a watched namespace.")
 
+	flag.StringVar(&otlpEndpoint, "otlp-endpoint", "",
+		"OTLP/gRPC endpoint (host:port) to export reconcile traces to. Tracing is disabled when empty.")
+	flag.BoolVar(&otlpInsecure, "otlp-insecure", false, "Disable TLS when exporting traces.")
+	flag.Float64Var(&traceSampleRatio, "trace-sample-ratio", 1.0, "Fraction of reconciles to trace.")
+	flag.StringVar(&auditLog, "audit-log", "",
+		"Write a JSON audit record of every restart decision to this file, or to stdout if set to \"-\". Disabled when empty.")
+	flag.StringVar(&auditS3Bucket, "audit-s3-bucket", "", "Ship audit records in batches to this S3 compatible bucket.")
+	flag.StringVar(&auditS3Prefix, "audit-s3-prefix", "podrestart-audit", "Object key prefix for audit batches.")
+	flag.StringVar(&auditS3Endpoint, "audit-s3-endpoint", "",
+		"S3 compatible endpoint for audit batches, e.g. https://storage.googleapis.com for GCS. Defaults to AWS S3.")
+	flag.StringVar(&auditSQLDriver, "audit-sql-driver", "pgx", "database/sql driver used for the SQL audit sink.")
+	flag.StringVar(&auditSQLDSN, "audit-sql-dsn", "", "Ship audit records in batches to this SQL database.")
+	flag.StringVar(&auditSQLTable, "audit-sql-table", "podrestart_audit", "Table used by the SQL audit sink.")
+	flag.IntVar(&auditBatchSize, "audit-batch-size", 100, "Maximum number of audit records per upload.")
+	flag.DurationVar(&auditFlushInterval, "audit-flush-interval", 30*time.Second, "How often buffered audit records are uploaded.")
+	flag.IntVar(&evaluationWorkers, "evaluation-workers", 8, "Number of pods of a PodRestart evaluated concurrently.")
+	flag.IntVar(&evaluationSlots, "evaluation-slots", 0,
+		"Number of pods evaluated concurrently across all PodRestarts, shared by the evaluationWeight of PodRestartPolicies. "+
+			"0 means --evaluation-workers times --max-concurrent-reconciles.")
+	flag.DurationVar(&podEvaluationTimeout, "pod-evaluation-timeout", 30*time.Second,
+		"Maximum time spent scanning logs and querying metrics for a single pod.")
+	flag.Int64Var(&logReadBudgetBytes, "log-read-budget-bytes", 0,
+		"Maximum log bytes read per PodRestart in a single reconcile. 0 means unlimited.")
+	flag.DurationVar(&logReadBudgetDuration, "log-read-budget-duration", 0,
+		"Maximum time spent reading logs per PodRestart in a single reconcile. 0 means unlimited.")
+	flag.Int64Var(&logReadBytesPerMinute, "log-read-bytes-per-minute", 0,
+		"Maximum log bytes read per minute by all PodRestarts together. Pods left over are evaluated on later passes. 0 means unlimited.")
+	flag.IntVar(&maxConcurrentReconciles, "max-concurrent-reconciles", 1,
+		"Number of PodRestarts reconciled concurrently.")
+	flag.DurationVar(&cacheSyncTimeout, "cache-sync-timeout", 2*time.Minute,
+		"Time to wait for the informer caches to sync before the controller fails to start.")
+	flag.Float64Var(&kubeAPIQPS, "kube-api-qps", 20, "Sustained queries per second allowed against the Kubernetes API.")
+	flag.IntVar(&kubeAPIBurst, "kube-api-burst", 30, "Burst of queries allowed against the Kubernetes API.")
+	flag.Float64Var(&logsAPIQPS, "logs-api-qps", 0,
+		"Sustained queries per second allowed for reading pod logs, exec and checkpoints. 0 means --kube-api-qps.")
+	flag.IntVar(&logsAPIBurst, "logs-api-burst", 0,
+		"Burst of queries allowed for reading pod logs, exec and checkpoints. 0 means --kube-api-burst.")
+	flag.Float64Var(&namespaceAPIQPS, "namespace-api-qps", 0,
+		"Sustained queries per second allowed against any single namespace, so one large namespace cannot "+
+			"use up the whole client budget. 0 disables the per-namespace limit.")
+	flag.IntVar(&namespaceAPIBurst, "namespace-api-burst", 10, "Burst of queries allowed against any single namespace.")
+	flag.DurationVar(&backoffBase, "reconcile-backoff-base", 5*time.Millisecond,
+		"Initial retry delay of a PodRestart whose reconcile failed.")
+	flag.DurationVar(&backoffMax, "reconcile-backoff-max", 5*time.Minute,
+		"Maximum retry delay of failing or degraded PodRestarts.")
+	flag.Float64Var(&reconcileQPS, "reconcile-qps", 10, "Overall rate of reconcile retries per second.")
+	flag.IntVar(&reconcileBurst, "reconcile-burst", 100, "Burst of reconcile retries.")
+	flag.IntVar(&maxFollowStreams, "max-log-follow-streams", 200,
+		"Maximum number of container log streams kept open for spec.logFollow across all PodRestarts. 0 means unlimited.")
+	flag.Float64Var(&requeueJitter, "requeue-jitter", 0.1,
+		"Fraction of a PodRestart's requeue interval added at random. Negative disables jitter.")
+	flag.DurationVar(&startupSpread, "startup-spread", 30*time.Second,
+		"Window the first evaluations after startup are spread over. Negative disables the spread.")
+	flag.DurationVar(&gracefulShutdownTimeout, "graceful-shutdown-timeout", 30*time.Second,
+		"Time the manager waits for controllers and sinks to stop on shutdown.")
+	flag.DurationVar(&shutdownGracePeriod, "restart-shutdown-grace", 20*time.Second,
+		"Time a restart in progress may take to finish once shutdown began. Keep it below --graceful-shutdown-timeout.")
+	if env := os.Getenv("FEATURE_GATES"); env != "" {
+		if err := features.Set(env); err != nil {
+			fmt.Fprintf(os.Stderr, "invalid FEATURE_GATES: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	flag.StringVar(&operatorConfig, "operator-config", "",
+		"Name of the cluster-scoped OperatorConfig whose settings are applied while running, overriding the flags. "+
+			"Disabled when empty.")
+	flag.StringVar(&impersonation, "impersonation-mode", "",
+		"Identity pods are listed, read, exec'd into and deleted with on behalf of a PodRestart: empty for the operator's own, ServiceAccount for a ServiceAccount in the "+
+			"PodRestart's namespace, or Creator for the user that created the PodRestart.")
+	flag.StringVar(&impersonationServiceAccount, "impersonation-service-account", "pod-restart",
+		"ServiceAccount impersonated in each namespace with --impersonation-mode=ServiceAccount.")
+	flag.StringVar(&protectedNamespaces, "protected-namespaces", strings.Join(controllers.DefaultProtectedNamespaces, ","),
+		"Comma separated namespaces whose pods are never restarted, whatever the PodRestarts say. Accepts glob patterns.")
+	flag.StringVar(&allowedNamespaces, "allowed-namespaces", "",
+		"Comma separated namespaces whose pods may be restarted. All namespaces but the protected ones when empty. "+
+			"Accepts glob patterns.")
+	flag.StringVar(&debugContainerImages, "debug-container-images", "",
+		"Comma separated images spec.diagnostics.debugContainer may run with the DebugContainers feature gate. "+
+			"Accepts glob patterns. No debug containers run when empty.")
+	flag.StringVar(&clusterServers, "cluster-servers", "",
+		"Comma separated API server URLs the kubeconfigs of spec.cluster may point at with the MultiCluster feature gate. "+
+			"Accepts glob patterns. No remote cluster is targeted when empty.")
+	flag.Var(features, "feature-gates",
+		"Comma separated Name=true|false pairs enabling optional features, overriding $FEATURE_GATES. Options are:\n"+
+			strings.Join(controllers.KnownFeatures(), "\n"))
 	opts := zap.Options{
 		Development: true,
 	}
 	opts.BindFlags(flag.CommandLine)
 	flag.Parse()
 
-	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
+	ctrl.SetLogger(newLogger(&opts))
+	if gates := features.String(); gates != "" {
+		setupLog.Info("Feature gates set", "gates", gates)
+	}
+
+	if otlpEndpoint != "" {
+		shutdown, err := setupTracing(context.Background(), otlpEndpoint, otlpInsecure, traceSampleRatio)
+		if err != nil {
+			setupLog.Error(err, "unable to set up tracing")
+			os.Exit(1)
+		}
+		defer shutdown(context.Background())
+	}
+
+	restConfig := ctrl.GetConfigOrDie()
+	restConfig.QPS = float32(kubeAPIQPS)
+	restConfig.Burst = kubeAPIBurst
+	if namespaceAPIQPS > 0 {
+		restConfig.Wrap(controllers.NamespaceRateLimiter(namespaceAPIQPS, namespaceAPIBurst))
+	}
+	// Attributes the operator's requests in the API server audit log
+	restConfig.UserAgent = "pod-restart-operator"
+
+	// Each shard elects its own leader, so replicas of one shard fail over
+	// to each other without ever handling another shard's namespaces
+	var shardLabels labels.Selector
+	switch {
+	case shardCount > 0 && shardNamespaceSelector != "":
+		setupLog.Error(nil, "--shard-count and --shard-namespace-selector are mutually exclusive")
+		os.Exit(1)
+	case shardCount > 0:
+		if shardID < 0 || shardID >= shardCount {
+			setupLog.Error(nil, "--shard-id must be between 0 and --shard-count - 1", "shardID", shardID, "shardCount", shardCount)
+			os.Exit(1)
+		}
+		leaderElectionID = fmt.Sprintf("%s-shard-%d", leaderElectionID, shardID)
+	case shardNamespaceSelector != "":
+		selector, err := labels.Parse(shardNamespaceSelector)
+		if err != nil {
+			setupLog.Error(err, "invalid --shard-namespace-selector")
+			os.Exit(1)
+		}
+		shardLabels = selector
+		h := fnv.New32a()
+		h.Write([]byte(shardLabels.String()))
+		leaderElectionID = fmt.Sprintf("%s-shard-%08x", leaderElectionID, h.Sum32())
+	}
+
+	switch controllers.ImpersonationMode(impersonation) {
+	case controllers.ImpersonateNone, controllers.ImpersonateServiceAccount, controllers.ImpersonateCreator:
+	default:
+		setupLog.Error(fmt.Errorf("unknown mode %q", impersonation), "invalid --impersonation-mode")
+		os.Exit(1)
+	}
+	// Without the mutating webhook the creator annotations are whatever the
+	// user wrote, so they cannot be impersonated
+	enableWebhooks := os.Getenv("ENABLE_WEBHOOKS") != "false"
+	if !enableWebhooks && controllers.ImpersonationMode(impersonation) == controllers.ImpersonateCreator {
+		setupLog.Error(nil, "--impersonation-mode=Creator requires the webhooks, which ENABLE_WEBHOOKS=false disables")
+		os.Exit(1)
+	}
+
+	mgrOpts := ctrl.Options{
+		Scheme:                  scheme,
+		MetricsBindAddress:      metricsAddr,
+		Port:                    9443,
+		HealthProbeBindAddress:  probeAddr,
+		LeaderElection:          enableLeaderElection,
+		LeaderElectionID:        leaderElectionID,
+		LeaderElectionNamespace: leaderElectionNamespace,
+		LeaseDuration:           &leaseDuration,
+		RenewDeadline:           &renewDeadline,
+		RetryPeriod:             &retryPeriod,
+		GracefulShutdownTimeout: &gracefulShutdownTimeout,
+		// Hand over leadership immediately on shutdown. Safe because the
+		// process exits right after the manager stops.
+		LeaderElectionReleaseOnCancel: true,
+	}
+	if namespaces := splitNamespaces(watchNamespaces); len(namespaces) > 0 {
+		// Cluster-scoped MetricProviders and NotificationChannels are still
+		// read cluster wide; see namespaced-rbac.yaml for the RBAC this needs
+		mgrOpts.Cache = cache.Options{Namespaces: namespaces}
+		setupLog.Info("Watching a subset of namespaces", "namespaces", namespaces)
+	}
+	if enableLeaderElection && leaderElectionIdentity != "" {
+		lock, err := newLeaderElectionLock(restConfig, leaderElectionNamespace, leaderElectionID, leaderElectionIdentity, renewDeadline)
+		if err != nil {
+			setupLog.Error(err, "unable to set up leader election")
+			os.Exit(1)
+		}
+		mgrOpts.LeaderElectionResourceLockInterface = lock
+	}
 
-	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
-		Scheme:                 scheme,
-		MetricsBindAddress:     metricsAddr,
-		Port:                   9443,
-		HealthProbeBindAddress: probeAddr,
-		LeaderElection:         enableLeaderElection,
-		LeaderElectionID:       "pod-restart-operator-leader-election",
-	})
+	mgr, err := ctrl.NewManager(restConfig, mgrOpts)
 	if err != nil {
 		setupLog.Error(err, "unable to start manager")
 		os.Exit(1)
 	}
 
-	if err = (&controllers.PodRestartReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
-		Log:    ctrl.Log.WithName("controllers").WithName("PodRestart"),
-	}).SetupWithManager(mgr); err != nil {
+	var auditSink controllers.AuditSink
+	switch auditLog {
+	case "":
+	case "-":
+		auditSink = controllers.NewJSONAuditSink(os.Stdout)
+	default:
+		f, err := os.OpenFile(auditLog, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+		if err != nil {
+			setupLog.Error(err, "unable to open audit log", "path", auditLog)
+			os.Exit(1)
+		}
+		defer f.Close()
+		auditSink = controllers.NewJSONAuditSink(f)
+	}
+
+	var uploaders []controllers.AuditUploader
+	if auditS3Bucket != "" {
+		u, err := controllers.NewS3AuditUploader(context.Background(), auditS3Bucket, auditS3Prefix, auditS3Endpoint)
+		if err != nil {
+			setupLog.Error(err, "unable to set up S3 audit sink")
+			os.Exit(1)
+		}
+		uploaders = append(uploaders, u)
+	}
+	if auditSQLDSN != "" {
+		u, err := controllers.NewSQLAuditUploader(context.Background(), auditSQLDriver, auditSQLDSN, auditSQLTable)
+		if err != nil {
+			setupLog.Error(err, "unable to set up SQL audit sink")
+			os.Exit(1)
+		}
+		uploaders = append(uploaders, u)
+	}
+	for _, u := range uploaders {
+		batching := controllers.NewBatchingAuditSink(u, auditBatchSize, auditFlushInterval, ctrl.Log.WithName("audit"))
+		if err := mgr.Add(batching); err != nil {
+			setupLog.Error(err, "unable to register audit sink")
+			os.Exit(1)
+		}
+		auditSink = controllers.NewMultiAuditSink(auditSink, batching)
+	}
+
+	var shard controllers.Sharder
+	switch {
+	case shardCount > 0:
+		shard = controllers.NewHashSharder(shardCount, shardID)
+	case shardLabels != nil:
+		shard = controllers.NewLabelSharder(mgr.GetClient(), shardLabels)
+	}
+
+	reconciler := &controllers.PodRestartReconciler{
+		Client:   mgr.GetClient(),
+		Scheme:   mgr.GetScheme(),
+		Log:      ctrl.Log.WithName("controllers").WithName("PodRestart"),
+		Recorder: mgr.GetEventRecorderFor("podrestart-controller"),
+		Audit:    auditSink,
+
+		EvaluationWorkers:    evaluationWorkers,
+		EvaluationSlots:      evaluationSlots,
+		PodEvaluationTimeout: podEvaluationTimeout,
+
+		LogReadBytesPerReconcile: logReadBudgetBytes,
+		LogReadTimePerReconcile:  logReadBudgetDuration,
+		LogReadBytesPerMinute:    logReadBytesPerMinute,
+
+		MaxConcurrentReconciles: maxConcurrentReconciles,
+		CacheSyncTimeout:        cacheSyncTimeout,
+		Shard:                   shard,
+
+		BackoffBase:    backoffBase,
+		BackoffMax:     backoffMax,
+		ReconcileQPS:   reconcileQPS,
+		ReconcileBurst: reconcileBurst,
+		LogsQPS:        float32(logsAPIQPS),
+		LogsBurst:      logsAPIBurst,
+		RequeueJitter:  requeueJitter,
+		StartupSpread:  startupSpread,
+
+		MaxFollowStreams: maxFollowStreams,
+
+		ShutdownGracePeriod: shutdownGracePeriod,
+		Features:            features,
+		ConfigName:          operatorConfig,
+
+		Impersonation:               controllers.ImpersonationMode(impersonation),
+		ImpersonationServiceAccount: impersonationServiceAccount,
+		UnverifiedCreators:          !enableWebhooks,
+
+		ProtectedNamespaces: splitNamespaces(protectedNamespaces),
+		AllowedNamespaces:   splitNamespaces(allowedNamespaces),
+
+		DebugContainerImages: splitNamespaces(debugContainerImages),
+		ClusterServers:       splitNamespaces(clusterServers),
+	}
+	if err = reconciler.SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "PodRestart")
 		os.Exit(1)
 	}
+	if enableWebhooks {
+		if err = (&operatorv1alpha1.PodRestart{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "PodRestart")
+			os.Exit(1)
+		}
+	}
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
 		setupLog.Error(err, "unable to set up health check")
@@ -75,6 +439,69 @@ func main() {
 		setupLog.Error(err, "unable to set up ready check")
 		os.Exit(1)
 	}
+	if err := mgr.AddReadyzCheck("cache-sync", controllers.CacheSyncCheck(mgr.GetCache())); err != nil {
+		setupLog.Error(err, "unable to set up cache sync check")
+		os.Exit(1)
+	}
+	if debugClientCAFile != "" && debugCertFile == "" {
+		setupLog.Error(nil, "--debug-client-ca-file requires --debug-tls-cert-file, client certificates are only verified over HTTPS")
+		os.Exit(1)
+	}
+	if debugAddr != "" {
+		srv := &debugServer{
+			addr:        debugAddr,
+			state:       reconciler.DebugHandler(),
+			podRestarts: reconciler.PodRestartDebugHandler(mgr.Elected()),
+		}
+		if debugCertFile != "" {
+			if srv.tls, err = newInboundTLS(debugCertFile, debugKeyFile, debugClientCAFile); err != nil {
+				setupLog.Error(err, "unable to set up debug endpoint TLS")
+				os.Exit(1)
+			}
+		}
+		if debugAuth {
+			clientset, err := kubernetes.NewForConfig(restConfig)
+			if err != nil {
+				setupLog.Error(err, "unable to set up debug endpoint authentication")
+				os.Exit(1)
+			}
+			srv.auth = newKubeAuth(clientset)
+			if srv.tls == nil {
+				setupLog.Info("Debug endpoints accept bearer tokens over plain HTTP, set --debug-tls-cert-file")
+			}
+		}
+		if err := mgr.Add(srv); err != nil {
+			setupLog.Error(err, "unable to set up debug endpoints")
+			os.Exit(1)
+		}
+	}
+	if triggerAddr != "" {
+		if triggerCertFile == "" {
+			setupLog.Error(nil, "--trigger-bind-address requires --trigger-tls-cert-file")
+			os.Exit(1)
+		}
+		srv := &triggerServer{addr: triggerAddr, requests: reconciler.RequestRestarts}
+		if srv.tls, err = newInboundTLS(triggerCertFile, triggerKeyFile, triggerClientCAFile); err != nil {
+			setupLog.Error(err, "unable to set up trigger API TLS")
+			os.Exit(1)
+		}
+		clientset, err := kubernetes.NewForConfig(restConfig)
+		if err != nil {
+			setupLog.Error(err, "unable to set up trigger API authentication")
+			os.Exit(1)
+		}
+		srv.auth = newKubeAuth(clientset)
+		if err := mgr.Add(srv); err != nil {
+			setupLog.Error(err, "unable to set up trigger API")
+			os.Exit(1)
+		}
+	}
+	if readyzCheckProviders {
+		if err := mgr.AddReadyzCheck("metric-providers", reconciler.ProviderCheck()); err != nil {
+			setupLog.Error(err, "unable to set up metric provider check")
+			os.Exit(1)
+		}
+	}
 
 	setupLog.Info("starting manager")
 	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
@@ -82,3 +509,14 @@ func main() {
 		os.Exit(1)
 	}
 }
+
+// splitNamespaces parses a comma separated namespace list, ignoring blanks
+func splitNamespaces(list string) []string {
+	var namespaces []string
+	for _, ns := range strings.Split(list, ",") {
+		if ns = strings.TrimSpace(ns); ns != "" {
+			namespaces = append(namespaces, ns)
+		}
+	}
+	return namespaces
+}
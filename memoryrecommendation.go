@@ -0,0 +1,271 @@
+// memoryrecommendation.go
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	operatorv1alpha1 "github.com/example/pod-restart-operator/api/v1alpha1"
+)
+
+const (
+	// EventReasonMemoryRecommendation is emitted when a larger memory limit is suggested
+	EventReasonMemoryRecommendation = "MemoryRecommendation"
+
+	// AnnotationMemorySuggestions holds the suggested memory limits on the
+	// operator's VerticalPodAutoscalers, as container=quantity pairs
+	AnnotationMemorySuggestions = "operator.example.com/memory-suggestions"
+
+	// oomKilledReason is the termination reason of a container killed for exceeding its memory limit
+	oomKilledReason = "OOMKilled"
+
+	defaultMinOOMRestarts  = 3
+	defaultHeadroomPercent = 25
+
+	// defaultWorkingSetQuery reports the peak working set of a container over the last hour
+	defaultWorkingSetQuery = `max(max_over_time(container_memory_working_set_bytes{namespace="{{.Namespace}}",pod="{{.Pod}}",container="{{.Container}}"}[1h]))`
+)
+
+// verticalPodAutoscalerGVK is created through unstructured objects so the VPA
+// CRD and its client are not required unless a PodRestart asks for one
+var verticalPodAutoscalerGVK = schema.GroupVersionKind{Group: "autoscaling.k8s.io", Version: "v1", Kind: "VerticalPodAutoscaler"}
+
+// oomKilledContainers returns the containers of a pod whose current or last
+// termination was an OOM kill
+func oomKilledContainers(pod *corev1.Pod) []string {
+	var names []string
+	for _, status := range pod.Status.ContainerStatuses {
+		if t := status.State.Terminated; t != nil && t.Reason == oomKilledReason {
+			names = append(names, status.Name)
+			continue
+		}
+		if t := status.LastTerminationState.Terminated; t != nil && t.Reason == oomKilledReason {
+			names = append(names, status.Name)
+		}
+	}
+	return names
+}
+
+// oomRestartsOf counts the successful restarts in the status history where the
+// container of the workload had been OOM killed
+func oomRestartsOf(pr *operatorv1alpha1.PodRestart, workload, container string) int32 {
+	var count int32
+	for _, record := range pr.Status.RecentRestarts {
+		if record.Workload != workload || record.Outcome != operatorv1alpha1.RestartSucceeded {
+			continue
+		}
+		for _, name := range record.OOMKilledContainers {
+			if name == container {
+				count++
+				break
+			}
+		}
+	}
+	return count
+}
+
+// recommendMemory suggests a larger memory limit for the OOM-killed containers
+// of a restarted pod once its workload has been restarted for the same reason
+// often enough, since restarting it again without more memory is futile
+func (r *PodRestartReconciler) recommendMemory(ctx context.Context, pr *operatorv1alpha1.PodRestart, cluster *clusterTarget, pod *corev1.Pod, containers []string) {
+	spec := pr.Spec.MemoryRecommendation
+	if spec == nil {
+		return
+	}
+	logger := log.FromContext(ctx)
+
+	minRestarts := int32(defaultMinOOMRestarts)
+	if spec.MinOOMRestarts > 0 {
+		minRestarts = spec.MinOOMRestarts
+	}
+	headroom := int64(defaultHeadroomPercent)
+	if spec.HeadroomPercent != nil {
+		headroom = int64(*spec.HeadroomPercent)
+	}
+
+	workload := workloadFor(pod)
+	for _, container := range containers {
+		restarts := oomRestartsOf(pr, workload.String(), container)
+		if restarts < minRestarts {
+			continue
+		}
+
+		recommendation := operatorv1alpha1.MemoryRecommendation{
+			Workload:    workload.String(),
+			Container:   container,
+			OOMRestarts: restarts,
+			Time:        metav1.Now(),
+		}
+		base := int64(0)
+		if limit, ok := containerMemoryLimit(pod, container); ok {
+			recommendation.CurrentLimit = &limit
+			base = limit.Value()
+		}
+		if spec.Provider != "" {
+			peak, err := r.observedWorkingSet(ctx, spec, pod, container)
+			if err != nil {
+				logger.Error(err, "Failed to query working set, suggesting from the current limit", "pod", pod.Name, "container", container)
+			} else {
+				q := resource.NewQuantity(peak, resource.BinarySI)
+				recommendation.ObservedPeak = q
+				if peak > base {
+					base = peak
+				}
+			}
+		}
+		if base == 0 {
+			logger.Info("No memory limit or working set to base a suggestion on", "pod", pod.Name, "container", container)
+			continue
+		}
+		recommendation.SuggestedLimit = suggestedLimit(base, headroom)
+
+		setMemoryRecommendation(pr, recommendation)
+		memoryRecommendationBytes.WithLabelValues(pr.Namespace, pr.Name, workload.String(), container).
+			Set(float64(recommendation.SuggestedLimit.Value()))
+		r.recordPodEvent(pr, pod, corev1.EventTypeWarning, EventReasonMemoryRecommendation,
+			"Container %s of %s was OOM killed on %d restarts, consider raising its memory limit to %s",
+			container, workload, restarts, recommendation.SuggestedLimit.String())
+
+		if spec.CreateVerticalPodAutoscaler {
+			if err := r.applyVerticalPodAutoscaler(ctx, pr, cluster, pod.Namespace, workload, recommendation); err != nil {
+				logger.Error(err, "Failed to apply VerticalPodAutoscaler", "workload", workload.String())
+			}
+		}
+	}
+}
+
+// containerMemoryLimit returns the memory limit of a container of the pod
+func containerMemoryLimit(pod *corev1.Pod, container string) (resource.Quantity, bool) {
+	for _, c := range pod.Spec.Containers {
+		if c.Name != container {
+			continue
+		}
+		limit, ok := c.Resources.Limits[corev1.ResourceMemory]
+		return limit, ok && !limit.IsZero()
+	}
+	return resource.Quantity{}, false
+}
+
+// suggestedLimit adds the headroom to base and rounds up to a whole MiB
+func suggestedLimit(base, headroomPercent int64) resource.Quantity {
+	const mi = 1 << 20
+	bytes := float64(base) * float64(100+headroomPercent) / 100
+	return *resource.NewQuantity(int64(math.Ceil(bytes/mi))*mi, resource.BinarySI)
+}
+
+// setMemoryRecommendation records a suggestion in the status, replacing any
+// earlier one for the same container of the workload
+func setMemoryRecommendation(pr *operatorv1alpha1.PodRestart, recommendation operatorv1alpha1.MemoryRecommendation) {
+	for i, existing := range pr.Status.MemoryRecommendations {
+		if existing.Workload == recommendation.Workload && existing.Container == recommendation.Container {
+			pr.Status.MemoryRecommendations[i] = recommendation
+			return
+		}
+	}
+	pr.Status.MemoryRecommendations = append(pr.Status.MemoryRecommendations, recommendation)
+}
+
+// observedWorkingSet queries the peak working set of a container in bytes
+func (r *PodRestartReconciler) observedWorkingSet(ctx context.Context, spec *operatorv1alpha1.MemoryRecommendationSpec, pod *corev1.Pod, container string) (int64, error) {
+	value, err := r.queryMetric(ctx, spec.Provider, pod, func(*operatorv1alpha1.MetricProvider) (string, error) {
+		tmpl := spec.Query
+		if tmpl == "" {
+			tmpl = defaultWorkingSetQuery
+		}
+		return renderQuery(tmpl, queryData{Namespace: pod.Namespace, Pod: pod.Name, Container: container})
+	})
+	if err != nil {
+		return 0, err
+	}
+	return int64(value), nil
+}
+
+// applyVerticalPodAutoscaler creates or updates the operator's own
+// VerticalPodAutoscaler for the workload, named <workload>-podrestart, in
+// recommendation-only mode. Its resource policy is left to the VPA; the
+// suggestion is recorded in the AnnotationMemorySuggestions annotation. A
+// VerticalPodAutoscaler of that name not labeled as the PodRestart's is
+// never touched. Bare pods and remote clusters are skipped.
+func (r *PodRestartReconciler) applyVerticalPodAutoscaler(ctx context.Context, pr *operatorv1alpha1.PodRestart, cluster *clusterTarget, namespace string, workload workloadRef, recommendation operatorv1alpha1.MemoryRecommendation) error {
+	if workload.Kind == "Pod" || cluster.remote {
+		return nil
+	}
+
+	name := workload.Name + "-podrestart"
+	vpa := &unstructured.Unstructured{}
+	vpa.SetGroupVersionKind(verticalPodAutoscalerGVK)
+	err := cluster.reader.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, vpa)
+	exists := err == nil
+	switch {
+	case errors.IsNotFound(err):
+		vpa.SetNamespace(namespace)
+		vpa.SetName(name)
+		vpa.SetLabels(map[string]string{LabelPodRestart: pr.Name})
+		// Owner references cannot cross namespaces, the label is kept either way
+		if namespace == pr.Namespace {
+			if err := ctrl.SetControllerReference(pr, vpa, r.Scheme); err != nil {
+				return err
+			}
+		}
+	case err != nil:
+		return fmt.Errorf("reading VerticalPodAutoscaler %s/%s: %w", namespace, name, err)
+	case vpa.GetLabels()[LabelPodRestart] != pr.Name:
+		return fmt.Errorf("VerticalPodAutoscaler %s/%s is not managed by PodRestart %s", namespace, name, pr.Name)
+	}
+
+	if err := unstructured.SetNestedMap(vpa.Object, map[string]interface{}{
+		"apiVersion": workload.APIVersion,
+		"kind":       workload.Kind,
+		"name":       workload.Name,
+	}, "spec", "targetRef"); err != nil {
+		return err
+	}
+	if err := unstructured.SetNestedField(vpa.Object, "Off", "spec", "updatePolicy", "updateMode"); err != nil {
+		return err
+	}
+	annotations := vpa.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[AnnotationMemorySuggestions] = withMemorySuggestion(annotations[AnnotationMemorySuggestions],
+		recommendation.Container, recommendation.SuggestedLimit.String())
+	vpa.SetAnnotations(annotations)
+
+	if exists {
+		err = cluster.writer.Update(ctx, vpa, client.FieldOwner(FieldManager))
+	} else {
+		err = cluster.writer.Create(ctx, vpa, client.FieldOwner(FieldManager))
+	}
+	if err != nil {
+		return fmt.Errorf("applying VerticalPodAutoscaler %s/%s: %w", namespace, name, err)
+	}
+	return nil
+}
+
+// withMemorySuggestion sets the suggestion of a container in a comma
+// separated list of container=quantity pairs, keeping the other containers'
+func withMemorySuggestion(list, container, limit string) string {
+	var pairs []string
+	for _, pair := range strings.Split(list, ",") {
+		if pair != "" && !strings.HasPrefix(pair, container+"=") {
+			pairs = append(pairs, pair)
+		}
+	}
+	pairs = append(pairs, container+"="+limit)
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
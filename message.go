@@ -0,0 +1,67 @@
+// message.go
+package controllers
+
+import (
+	"bytes"
+	"fmt"
+	"text/template"
+
+	corev1 "k8s.io/api/core/v1"
+
+	operatorv1alpha1 "github.com/example/pod-restart-operator/api/v1alpha1"
+)
+
+// defaultMessageTemplate matches the message used before templates were configurable
+const defaultMessageTemplate = `Pod {{.Pod}} restarted due to: {{.Reason}}`
+
+// messageData is passed to spec.messageTemplate
+type messageData struct {
+	Pod         string
+	Namespace   string
+	PodRestart  string
+	Trigger     string
+	ReasonCode  string
+	Reason      string
+	MatchedLine string
+	MetricValue string
+}
+
+// restartMessage renders the restart message for a pod. If the user template
+// fails to render, the default message is returned alongside the error.
+func restartMessage(pr *operatorv1alpha1.PodRestart, pod *corev1.Pod, result *triggerResult) (string, error) {
+	data := messageData{
+		Pod:         pod.Name,
+		Namespace:   pod.Namespace,
+		PodRestart:  pr.Name,
+		Trigger:     result.Trigger,
+		ReasonCode:  string(result.reasonCode()),
+		Reason:      result.Reason,
+		MatchedLine: result.MatchedLine,
+	}
+	if result.MetricValue != nil {
+		data.MetricValue = fmt.Sprintf("%g", *result.MetricValue)
+	}
+
+	if pr.Spec.MessageTemplate != "" {
+		msg, err := renderMessage(pr.Spec.MessageTemplate, data)
+		if err == nil {
+			return msg, nil
+		}
+		fallback, _ := renderMessage(defaultMessageTemplate, data)
+		return fallback, err
+	}
+	return renderMessage(defaultMessageTemplate, data)
+}
+
+// renderMessage executes a message template
+func renderMessage(tmpl string, data messageData) (string, error) {
+	t, err := template.New("message").Parse(tmpl)
+	if err != nil {
+		return "", fmt.Errorf("parsing message template: %w", err)
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("rendering message template: %w", err)
+	}
+	return buf.String(), nil
+}
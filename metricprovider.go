@@ -0,0 +1,322 @@
+// metricprovider.go
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	operatorv1alpha1 "github.com/example/pod-restart-operator/api/v1alpha1"
+)
+
+const (
+	// defaultQueryTemplate is used when neither the condition nor the provider set a query
+	defaultQueryTemplate = `{{.Metric}}{namespace="{{.Namespace}}",pod="{{.Pod}}"}`
+
+	// defaultQueryTimeout is used when the provider does not set a timeout
+	defaultQueryTimeout = 10 * time.Second
+)
+
+// MetricQuerier runs a query against a metrics backend and returns a single value
+type MetricQuerier interface {
+	Query(ctx context.Context, query string) (float64, error)
+}
+
+// queryData is passed to query templates
+type queryData struct {
+	Metric    string
+	Namespace string
+	Pod       string
+	Container string
+	Workload  string
+	Window    string
+}
+
+// prometheusQuerier performs instant queries against the Prometheus HTTP API
+type prometheusQuerier struct {
+	address    string
+	token      string
+	httpClient *http.Client
+}
+
+// prometheusResponse is the subset of the Prometheus query response used here
+type prometheusResponse struct {
+	Status string `json:"status"`
+	Error  string `json:"error"`
+	Data   struct {
+		ResultType string `json:"resultType"`
+		Result     []struct {
+			Value []interface{} `json:"value"`
+		} `json:"result"`
+	} `json:"data"`
+}
+
+// Query implements MetricQuerier. The value of the first returned series is used.
+func (q *prometheusQuerier) Query(ctx context.Context, query string) (float64, error) {
+	u := strings.TrimSuffix(q.address, "/") + "/api/v1/query?query=" + url.QueryEscape(query)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return 0, err
+	}
+	if q.token != "" {
+		req.Header.Set("Authorization", "Bearer "+q.token)
+	}
+
+	resp, err := q.httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return 0, unauthorized(resp.StatusCode, "query failed (HTTP %d)", resp.StatusCode)
+	}
+
+	var body prometheusResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return 0, fmt.Errorf("decoding response (HTTP %d): %w", resp.StatusCode, err)
+	}
+	if body.Status != "success" {
+		return 0, fmt.Errorf("query failed (HTTP %d): %s", resp.StatusCode, body.Error)
+	}
+	if body.Data.ResultType != "vector" || len(body.Data.Result) == 0 {
+		return 0, fmt.Errorf("query %q returned no samples", query)
+	}
+
+	sample := body.Data.Result[0].Value
+	if len(sample) != 2 {
+		return 0, fmt.Errorf("unexpected sample format %v", sample)
+	}
+	raw, ok := sample[1].(string)
+	if !ok {
+		return 0, fmt.Errorf("unexpected sample value %v", sample[1])
+	}
+	return strconv.ParseFloat(raw, 64)
+}
+
+// metricQuerierFor returns a querier for the MetricProvider, reusing the
+// cached one while neither the provider nor its credentials changed
+func (r *PodRestartReconciler) metricQuerierFor(ctx context.Context, provider *operatorv1alpha1.MetricProvider) (MetricQuerier, error) {
+	timeout := defaultQueryTimeout
+	if provider.Spec.Timeout != nil {
+		timeout = provider.Spec.Timeout.Duration
+	}
+
+	var ca []byte
+	if cfg := provider.Spec.TLS; cfg != nil && cfg.CASecretRef != nil {
+		data, err := r.secretValue(ctx, cfg.CASecretRef)
+		if err != nil {
+			return nil, err
+		}
+		ca = data
+	}
+	var token string
+	if ref := provider.Spec.BearerTokenSecretRef; ref != nil {
+		data, err := r.secretValue(ctx, ref)
+		if err != nil {
+			return nil, err
+		}
+		token = strings.TrimSpace(string(data))
+	}
+
+	fp := fingerprint(provider.ResourceVersion, ca, []byte(token))
+	return r.queriers.get(provider.Name, fp, func() (MetricQuerier, error) {
+		return r.buildQuerier(ctx, provider, timeout, token)
+	})
+}
+
+// buildQuerier creates a querier for the MetricProvider
+func (r *PodRestartReconciler) buildQuerier(ctx context.Context, provider *operatorv1alpha1.MetricProvider, timeout time.Duration, token string) (MetricQuerier, error) {
+	tlsConfig, err := r.tlsConfigFor(ctx, provider.Spec.TLS)
+	if err != nil {
+		return nil, err
+	}
+
+	switch provider.Spec.Type {
+	case "", operatorv1alpha1.MetricProviderPrometheus:
+		return &prometheusQuerier{
+			address: provider.Spec.Address,
+			token:   token,
+			httpClient: &http.Client{
+				Timeout:   timeout,
+				Transport: &http.Transport{TLSClientConfig: tlsConfig},
+			},
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported metric provider type %q", provider.Spec.Type)
+	}
+}
+
+// tlsConfigFor converts the API TLS settings into a crypto/tls configuration
+func (r *PodRestartReconciler) tlsConfigFor(ctx context.Context, cfg *operatorv1alpha1.TLSConfig) (*tls.Config, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{
+		ServerName:         cfg.ServerName,
+		InsecureSkipVerify: cfg.InsecureSkipVerify,
+	}
+	if cfg.CASecretRef != nil {
+		ca, err := r.secretValue(ctx, cfg.CASecretRef)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("no certificates found in secret %s/%s", cfg.CASecretRef.Namespace, cfg.CASecretRef.Name)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	return tlsConfig, nil
+}
+
+// secretValue reads a single key from a Secret, from the API server rather
+// than the cache when ctx asks for fresh secrets
+func (r *PodRestartReconciler) secretValue(ctx context.Context, ref *operatorv1alpha1.SecretKeyReference) ([]byte, error) {
+	var reader client.Reader = r.Client
+	if freshSecrets(ctx) {
+		reader = r.apiReader
+	}
+	secret := &corev1.Secret{}
+	if err := reader.Get(ctx, types.NamespacedName{Namespace: ref.Namespace, Name: ref.Name}, secret); err != nil {
+		return nil, fmt.Errorf("reading secret %s/%s: %w", ref.Namespace, ref.Name, err)
+	}
+	data, ok := secret.Data[ref.Key]
+	if !ok {
+		return nil, fmt.Errorf("secret %s/%s has no key %q", ref.Namespace, ref.Name, ref.Key)
+	}
+	return data, nil
+}
+
+// checkMetricCondition evaluates a single metric condition for a pod
+func (r *PodRestartReconciler) checkMetricCondition(ctx context.Context, pod *corev1.Pod, cond operatorv1alpha1.MetricCondition) (bool, float64, error) {
+	value, err := r.queryMetric(ctx, cond.Provider, pod, func(provider *operatorv1alpha1.MetricProvider) (string, error) {
+		tmpl := cond.Query
+		if tmpl == "" {
+			tmpl = provider.Spec.QueryTemplate
+		}
+		if tmpl == "" {
+			tmpl = defaultQueryTemplate
+		}
+		return renderQuery(tmpl, queryData{Metric: cond.Name, Namespace: pod.Namespace, Pod: pod.Name})
+	})
+	if err != nil {
+		return false, 0, err
+	}
+
+	breached, err := compareThreshold(value, cond.Operator, cond.Threshold)
+	return breached, value, err
+}
+
+// queryMetric runs a query rendered for the named MetricProvider. Rejected
+// credentials are retried once with the current secrets. Failures are
+// counted in the pass stats of ctx.
+func (r *PodRestartReconciler) queryMetric(ctx context.Context, providerName string, pod *corev1.Pod, render func(*operatorv1alpha1.MetricProvider) (string, error)) (float64, error) {
+	value, err := r.runQuery(ctx, providerName, pod, render)
+	if err != nil {
+		recordProviderError(ctx, providerName, err)
+	}
+	return value, err
+}
+
+func (r *PodRestartReconciler) runQuery(ctx context.Context, providerName string, pod *corev1.Pod, render func(*operatorv1alpha1.MetricProvider) (string, error)) (float64, error) {
+	provider := &operatorv1alpha1.MetricProvider{}
+	if err := r.Get(ctx, types.NamespacedName{Name: providerName}, provider); err != nil {
+		if apierrors.IsNotFound(err) {
+			// Credentials of a deleted provider are not kept around
+			r.queriers.forget(providerName)
+		}
+		return 0, fmt.Errorf("getting metric provider %q: %w", providerName, err)
+	}
+
+	querier, err := r.metricQuerierFor(ctx, provider)
+	if err != nil {
+		return 0, err
+	}
+	query, err := render(provider)
+	if err != nil {
+		return 0, err
+	}
+	if query, err = scopeQuery(provider, query, pod.Namespace); err != nil {
+		return 0, err
+	}
+
+	queryCtx, span := tracer.Start(ctx, "QueryMetric", trace.WithAttributes(
+		attribute.String("pod", pod.Name),
+		attribute.String("metric.provider", providerName),
+		attribute.String("metric.query", query),
+	))
+	start := time.Now()
+	value, err := querier.Query(queryCtx, query)
+	if errors.Is(err, errUnauthorized) {
+		// The credentials may have been rotated, retry once with the current ones
+		r.queriers.forget(provider.Name)
+		if querier, err = r.metricQuerierFor(withFreshSecrets(ctx), provider); err == nil {
+			value, err = querier.Query(queryCtx, query)
+		}
+	}
+	result := "success"
+	if err != nil {
+		result = "error"
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+	metricQueryDuration.WithLabelValues(providerName, result).Observe(time.Since(start).Seconds())
+	return value, err
+}
+
+// renderQuery executes a query template
+func renderQuery(tmpl string, data queryData) (string, error) {
+	t, err := template.New("query").Parse(tmpl)
+	if err != nil {
+		return "", fmt.Errorf("parsing query template: %w", err)
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("rendering query template: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// compareThreshold applies a MetricCondition operator to a value
+func compareThreshold(value float64, operator, threshold string) (bool, error) {
+	limit, err := strconv.ParseFloat(threshold, 64)
+	if err != nil {
+		return false, fmt.Errorf("invalid threshold %q: %w", threshold, err)
+	}
+
+	switch operator {
+	case ">":
+		return value > limit, nil
+	case "<":
+		return value < limit, nil
+	case ">=":
+		return value >= limit, nil
+	case "<=":
+		return value <= limit, nil
+	case "==":
+		return value == limit, nil
+	default:
+		return false, fmt.Errorf("unsupported operator %q", operator)
+	}
+}
@@ -0,0 +1,117 @@
+// metricprovider_types.go
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// MetricProviderType is the kind of metrics backend
+type MetricProviderType string
+
+const (
+	// MetricProviderPrometheus queries a Prometheus compatible HTTP API
+	MetricProviderPrometheus MetricProviderType = "Prometheus"
+)
+
+// SecretKeyReference selects a key of a Secret in a given namespace
+type SecretKeyReference struct {
+	// Namespace of the Secret
+	Namespace string `json:"namespace"`
+
+	// Name of the Secret
+	Name string `json:"name"`
+
+	// Key within the Secret data
+	Key string `json:"key"`
+}
+
+// TLSConfig configures how the operator connects to an external endpoint
+type TLSConfig struct {
+	// CASecretRef references a PEM encoded CA bundle used to verify the server
+	// +optional
+	CASecretRef *SecretKeyReference `json:"caSecretRef,omitempty"`
+
+	// ServerName overrides the name used to verify the server certificate
+	// +optional
+	ServerName string `json:"serverName,omitempty"`
+
+	// InsecureSkipVerify disables server certificate verification
+	// +optional
+	InsecureSkipVerify bool `json:"insecureSkipVerify,omitempty"`
+}
+
+// MetricProviderSpec defines the connection to a metrics backend
+type MetricProviderSpec struct {
+	// Type of the metrics backend
+	// +kubebuilder:validation:Enum=Prometheus
+	// +kubebuilder:default=Prometheus
+	// +optional
+	Type MetricProviderType `json:"type,omitempty"`
+
+	// Address is the base URL of the metrics API, e.g. http://prometheus.monitoring:9090
+	Address string `json:"address"`
+
+	// BearerTokenSecretRef references a Secret key holding a bearer token
+	// +optional
+	BearerTokenSecretRef *SecretKeyReference `json:"bearerTokenSecretRef,omitempty"`
+
+	// TLS configures the connection to the metrics backend
+	// +optional
+	TLS *TLSConfig `json:"tls,omitempty"`
+
+	// QueryTemplate is the default Go template used to build a query from a
+	// MetricCondition. It receives .Metric, .Namespace and .Pod.
+	// +optional
+	QueryTemplate string `json:"queryTemplate,omitempty"`
+
+	// NamespaceLabel is the series label holding the namespace of a pod.
+	// Every selector of a query run for a PodRestart is restricted to the
+	// namespace of the evaluated pod through this label, whatever the query
+	// itself selects. Defaults to namespace.
+	// +optional
+	NamespaceLabel string `json:"namespaceLabel,omitempty"`
+
+	// Timeout bounds each query against the backend
+	// +kubebuilder:validation:Format=duration
+	// +optional
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+}
+
+// MetricProviderStatus defines the observed state of MetricProvider
+type MetricProviderStatus struct {
+	// Conditions represent the latest available observations of the MetricProvider state
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// +genclient
+// +genclient:nonNamespaced
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:printcolumn:name="Type",type=string,JSONPath=`.spec.type`
+// +kubebuilder:printcolumn:name="Address",type=string,JSONPath=`.spec.address`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// MetricProvider is the Schema for the metricproviders API
+type MetricProvider struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   MetricProviderSpec   `json:"spec,omitempty"`
+	Status MetricProviderStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// MetricProviderList contains a list of MetricProvider
+type MetricProviderList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []MetricProvider `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&MetricProvider{}, &MetricProviderList{})
+}
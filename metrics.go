@@ -0,0 +1,126 @@
+// metrics.go
+package controllers
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const metricsNamespace = "podrestart"
+
+var (
+	restartsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "restarts_total",
+		Help:      "Number of pod restarts performed, by PodRestart, trigger and restart reason code.",
+	}, []string{"namespace", "podrestart", "reason", "reason_code"})
+
+	restartsSkippedTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "restarts_skipped_total",
+		Help:      "Number of triggered restarts that were suppressed, by skip reason.",
+	}, []string{"namespace", "podrestart", "reason"})
+
+	patternMatchesTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "pattern_matches_total",
+		Help:      "Number of log matches per error pattern, counted whether or not a restart followed.",
+	}, []string{"namespace", "podrestart", "pattern"})
+
+	conditionMatchesTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "condition_matches_total",
+		Help:      "Number of breached metric condition evaluations, counted whether or not a restart followed.",
+	}, []string{"namespace", "podrestart", "condition"})
+
+	triggerWarningsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "trigger_warnings_total",
+		Help:      "Number of pods a trigger fired for without restarting them, because it only warns or notifies.",
+	}, []string{"namespace", "podrestart", "trigger", "name"})
+
+	notificationsSuppressedTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "notifications_suppressed_total",
+		Help:      "Number of notifications dropped by channel throttling, rate limiting or deduplication.",
+	}, []string{"namespace", "podrestart", "channel", "reason"})
+
+	escalationsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "escalations_total",
+		Help:      "Number of critical escalations, such as an exhausted restart budget, that need human attention.",
+	}, []string{"namespace", "podrestart", "event"})
+
+	podsDeferredTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "pods_deferred_total",
+		Help:      "Number of pod evaluations deferred to a later pass because the log read budget ran out.",
+	}, []string{"namespace", "podrestart"})
+
+	logsTruncatedTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "logs_truncated_total",
+		Help:      "Number of container log reads cut off at spec.logSampling.maxBytesPerContainer.",
+	}, []string{"namespace", "podrestart"})
+
+	logFetchDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "log_fetch_duration_seconds",
+		Help:      "Time spent fetching and scanning the logs of a single container.",
+		Buckets:   prometheus.ExponentialBuckets(0.01, 2, 12),
+	}, []string{"namespace", "podrestart", "source"})
+
+	metricQueryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "metric_query_duration_seconds",
+		Help:      "Time spent querying a MetricProvider.",
+		Buckets:   prometheus.ExponentialBuckets(0.005, 2, 12),
+	}, []string{"provider", "result"})
+
+	budgetRemaining = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "budget_remaining",
+		Help:      "Restarts remaining in the current budget window. Only reported for PodRestarts with a budget.",
+	}, []string{"namespace", "podrestart"})
+
+	memoryRecommendationBytes = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "memory_recommendation_bytes",
+		Help:      "Suggested memory limit for containers of workloads restarted repeatedly after OOM kills.",
+	}, []string{"namespace", "podrestart", "workload", "container"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(
+		restartsTotal,
+		restartsSkippedTotal,
+		patternMatchesTotal,
+		conditionMatchesTotal,
+		triggerWarningsTotal,
+		notificationsSuppressedTotal,
+		escalationsTotal,
+		podsDeferredTotal,
+		logsTruncatedTotal,
+		logFetchDuration,
+		metricQueryDuration,
+		budgetRemaining,
+		memoryRecommendationBytes,
+	)
+}
+
+// forgetMetrics drops all series of a deleted PodRestart
+func forgetMetrics(namespace, name string) {
+	labels := prometheus.Labels{"namespace": namespace, "podrestart": name}
+	restartsTotal.DeletePartialMatch(labels)
+	restartsSkippedTotal.DeletePartialMatch(labels)
+	patternMatchesTotal.DeletePartialMatch(labels)
+	conditionMatchesTotal.DeletePartialMatch(labels)
+	triggerWarningsTotal.DeletePartialMatch(labels)
+	notificationsSuppressedTotal.DeletePartialMatch(labels)
+	escalationsTotal.DeletePartialMatch(labels)
+	podsDeferredTotal.DeletePartialMatch(labels)
+	logsTruncatedTotal.DeletePartialMatch(labels)
+	logFetchDuration.DeletePartialMatch(labels)
+	budgetRemaining.DeletePartialMatch(labels)
+	memoryRecommendationBytes.DeletePartialMatch(labels)
+}
@@ -0,0 +1,45 @@
+// namespacepolicy.go
+package controllers
+
+import (
+	"fmt"
+	"path"
+)
+
+// DefaultProtectedNamespaces are never touched unless the operator is
+// configured otherwise
+var DefaultProtectedNamespaces = []string{"kube-system", "kube-public", "kube-node-lease"}
+
+// matchesAny reports whether namespace matches one of the patterns, which
+// use path.Match syntax such as "team-*"
+func matchesAny(namespace string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, namespace); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// checkNamespace enforces the operator-level namespace policy: protected
+// namespaces are never touched and, when an allow-list is set, only listed
+// namespaces are. The flags and the OperatorConfig both apply, so the config
+// can tighten the boundary but never loosen it.
+func (r *PodRestartReconciler) checkNamespace(namespace string) error {
+	protected, allowed := [][]string{r.ProtectedNamespaces}, [][]string{r.AllowedNamespaces}
+	if spec := r.config.get(); spec != nil {
+		protected = append(protected, spec.ProtectedNamespaces)
+		allowed = append(allowed, spec.AllowedNamespaces)
+	}
+	for _, patterns := range protected {
+		if matchesAny(namespace, patterns) {
+			return fmt.Errorf("namespace %s is protected by the operator configuration", namespace)
+		}
+	}
+	for _, patterns := range allowed {
+		if len(patterns) > 0 && !matchesAny(namespace, patterns) {
+			return fmt.Errorf("namespace %s is not in the operator's allowed namespaces", namespace)
+		}
+	}
+	return nil
+}
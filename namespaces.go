@@ -0,0 +1,180 @@
+// namespaces.go
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	operatorv1alpha1 "github.com/example/pod-restart-operator/api/v1alpha1"
+)
+
+// targetNamespaceIndex indexes PodRestarts by the namespaces listed in
+// spec.namespaces, so pods outside a PodRestart's own namespace still map
+// back to it
+const targetNamespaceIndex = "spec.namespaces"
+
+// targetNamespaceTerms is the IndexerFunc for targetNamespaceIndex
+func targetNamespaceTerms(obj client.Object) []string {
+	pr, ok := obj.(*operatorv1alpha1.PodRestart)
+	if !ok {
+		return nil
+	}
+	return pr.Spec.Namespaces
+}
+
+// targetNamespaces returns the namespaces whose pods the PodRestart
+// evaluates: spec.namespaces when set, the cluster target's otherwise
+func targetNamespaces(pr *operatorv1alpha1.PodRestart, cluster *clusterTarget) []string {
+	if len(pr.Spec.Namespaces) == 0 {
+		return []string{cluster.namespace}
+	}
+	seen := make(map[string]bool, len(pr.Spec.Namespaces))
+	namespaces := make([]string, 0, len(pr.Spec.Namespaces))
+	for _, ns := range pr.Spec.Namespaces {
+		if !seen[ns] {
+			seen[ns] = true
+			namespaces = append(namespaces, ns)
+		}
+	}
+	return namespaces
+}
+
+// targetAccess returns what the creator of a PodRestart must be allowed to
+// do in a namespace other than the PodRestart's own before its pods are
+// targeted: delete pods and read their logs, plus whatever else the spec
+// makes the operator do to them
+func targetAccess(pr *operatorv1alpha1.PodRestart) []authorizationv1.ResourceAttributes {
+	access := []authorizationv1.ResourceAttributes{
+		{Verb: "delete", Resource: "pods"},
+		{Verb: "get", Resource: "pods", Subresource: "log"},
+	}
+	spec := &pr.Spec
+	exec := spec.ZombieProcesses != nil || len(spec.HeartbeatFiles) > 0 ||
+		(spec.Connections != nil && spec.Connections.Provider == "") ||
+		(spec.Diagnostics != nil && len(spec.Diagnostics.Dumps) > 0)
+	if exec {
+		access = append(access, authorizationv1.ResourceAttributes{Verb: "create", Resource: "pods", Subresource: "exec"})
+	}
+	if spec.JVM != nil {
+		access = append(access, authorizationv1.ResourceAttributes{Verb: "get", Resource: "pods", Subresource: "proxy"})
+	}
+	if spec.Diagnostics != nil && spec.Diagnostics.DebugContainer != nil {
+		access = append(access, authorizationv1.ResourceAttributes{Verb: "patch", Resource: "pods", Subresource: "ephemeralcontainers"})
+	}
+	for _, cond := range spec.ExitCodes {
+		if cond.Action == operatorv1alpha1.ExitCodeQuarantine {
+			access = append(access, authorizationv1.ResourceAttributes{Verb: "patch", Resource: "pods"})
+			break
+		}
+	}
+	if spec.Action == operatorv1alpha1.ActionSurge {
+		access = append(access, authorizationv1.ResourceAttributes{Verb: "patch", Group: "apps", Resource: "deployments"})
+	}
+	return access
+}
+
+// commandAccess returns what the creator of a PodRestart must be allowed to
+// do in the PodRestart's own namespace: run the commands and debug
+// containers its spec supplies. Without impersonation they run with the
+// operator's permissions. With it RBAC already checks them against the
+// impersonated identity.
+func (r *PodRestartReconciler) commandAccess(pr *operatorv1alpha1.PodRestart) []authorizationv1.ResourceAttributes {
+	if r.Impersonation != ImpersonateNone {
+		return nil
+	}
+	var access []authorizationv1.ResourceAttributes
+	spec := &pr.Spec
+	custom := (spec.ZombieProcesses != nil && len(spec.ZombieProcesses.Command) > 0) ||
+		(spec.Connections != nil && spec.Connections.Provider == "" && len(spec.Connections.Command) > 0) ||
+		(spec.Diagnostics != nil && len(spec.Diagnostics.Dumps) > 0)
+	if custom {
+		access = append(access, authorizationv1.ResourceAttributes{Verb: "create", Resource: "pods", Subresource: "exec"})
+	}
+	if spec.Diagnostics != nil && spec.Diagnostics.DebugContainer != nil {
+		access = append(access, authorizationv1.ResourceAttributes{Verb: "patch", Resource: "pods", Subresource: "ephemeralcontainers"})
+	}
+	return access
+}
+
+// mayTarget checks with SubjectAccessReviews that the creator of a
+// PodRestart has targetAccess in a namespace other than the PodRestart's
+// own, and commandAccess in its own. Pods are deleted with the operator's
+// permissions unless impersonation is configured, so without this check
+// spec.namespaces would let anyone able to create a PodRestart act on other
+// teams' pods. Remote clusters are bounded by the identity of their
+// kubeconfig instead.
+func (r *PodRestartReconciler) mayTarget(ctx context.Context, pr *operatorv1alpha1.PodRestart, cluster *clusterTarget, namespace string) error {
+	access := targetAccess(pr)
+	if namespace == pr.Namespace {
+		access = r.commandAccess(pr)
+	}
+	if len(access) == 0 || cluster.remote {
+		return nil
+	}
+	if r.UnverifiedCreators {
+		return fmt.Errorf("creators are not recorded while the webhooks are disabled, access to namespace %s cannot be checked", namespace)
+	}
+	user := pr.Annotations[operatorv1alpha1.AnnotationCreatedBy]
+	if user == "" {
+		return fmt.Errorf("PodRestart has no %s annotation, access to namespace %s is only granted to a known creator",
+			operatorv1alpha1.AnnotationCreatedBy, namespace)
+	}
+	var groups []string
+	if raw := pr.Annotations[operatorv1alpha1.AnnotationCreatedByGroups]; raw != "" {
+		groups = strings.Split(raw, ",")
+	}
+	for _, attrs := range access {
+		attrs.Namespace = namespace
+		review, err := r.clientset.AuthorizationV1().SubjectAccessReviews().Create(ctx, &authorizationv1.SubjectAccessReview{
+			Spec: authorizationv1.SubjectAccessReviewSpec{User: user, Groups: groups, ResourceAttributes: &attrs},
+		}, metav1.CreateOptions{})
+		if err != nil {
+			return fmt.Errorf("checking the access of %s to namespace %s: %w", user, namespace, err)
+		}
+		if !review.Status.Allowed {
+			resource := attrs.Resource
+			if attrs.Subresource != "" {
+				resource += "/" + attrs.Subresource
+			}
+			return fmt.Errorf("creator %s may not %s %s in namespace %s", user, attrs.Verb, resource, namespace)
+		}
+	}
+	return nil
+}
+
+// inNamespace returns a copy of the cluster target for another namespace,
+// sharing its clients and identity
+func (c *clusterTarget) inNamespace(namespace string) *clusterTarget {
+	target := *c
+	target.namespace = namespace
+	return &target
+}
+
+// podRestartsTargeting maps a pod to the PodRestarts of other namespaces
+// that list the pod's namespace in spec.namespaces and select the pod
+func (r *PodRestartReconciler) podRestartsTargeting(ctx context.Context, obj client.Object, podLabels labels.Set) []reconcile.Request {
+	list := &operatorv1alpha1.PodRestartList{}
+	if err := r.List(ctx, list, client.MatchingFields{targetNamespaceIndex: obj.GetNamespace()}); err != nil {
+		r.Log.Error(err, "Failed to list PodRestarts targeting namespace", "namespace", obj.GetNamespace())
+		return nil
+	}
+	var requests []reconcile.Request
+	for i := range list.Items {
+		pr := &list.Items[i]
+		if pr.Namespace == obj.GetNamespace() || !podRestartSelects(pr, obj, podLabels) {
+			continue
+		}
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{Namespace: pr.Namespace, Name: pr.Name},
+		})
+	}
+	return requests
+}
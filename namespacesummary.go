@@ -0,0 +1,117 @@
+// namespacesummary.go
+package controllers
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	operatorv1alpha1 "github.com/example/pod-restart-operator/api/v1alpha1"
+)
+
+// summaryListTimeout bounds the cache read behind a metrics scrape
+const summaryListTimeout = 5 * time.Second
+
+var (
+	namespacePodRestartsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metricsNamespace, "namespace", "podrestarts"),
+		"Number of PodRestarts per namespace and phase.",
+		[]string{"namespace", "phase"}, nil)
+	namespaceRestartsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metricsNamespace, "namespace", "restarts"),
+		"Restarts performed by all PodRestarts of a namespace over their lifetime, from their status.",
+		[]string{"namespace"}, nil)
+	namespaceRestartsInWindowDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metricsNamespace, "namespace", "restarts_in_window"),
+		"Restarts counted against the current budget windows of a namespace's PodRestarts.",
+		[]string{"namespace"}, nil)
+	namespaceBudgetRemainingDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metricsNamespace, "namespace", "budget_remaining"),
+		"Restarts remaining in the current budget windows of a namespace's PodRestarts with a budget.",
+		[]string{"namespace"}, nil)
+)
+
+// namespaceSummary is a collector aggregating the status of every
+// PodRestart per namespace at scrape time, so fleet-wide dashboards need
+// neither one series per PodRestart nor a read of every CR
+type namespaceSummary struct {
+	reader client.Reader
+}
+
+func newNamespaceSummary(reader client.Reader) *namespaceSummary {
+	return &namespaceSummary{reader: reader}
+}
+
+// Describe implements prometheus.Collector
+func (s *namespaceSummary) Describe(ch chan<- *prometheus.Desc) {
+	ch <- namespacePodRestartsDesc
+	ch <- namespaceRestartsDesc
+	ch <- namespaceRestartsInWindowDesc
+	ch <- namespaceBudgetRemainingDesc
+}
+
+// namespaceTotals are the aggregated values of one namespace
+type namespaceTotals struct {
+	phases          map[operatorv1alpha1.PodRestartPhase]int
+	restarts        int64
+	restartsInWin   int64
+	budgetRemaining int64
+	budgeted        bool
+}
+
+// Collect implements prometheus.Collector. The PodRestarts are read from
+// the manager's cache, so a scrape costs no API requests.
+func (s *namespaceSummary) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), summaryListTimeout)
+	defer cancel()
+	list := &operatorv1alpha1.PodRestartList{}
+	if err := s.reader.List(ctx, list); err != nil {
+		ch <- prometheus.NewInvalidMetric(namespacePodRestartsDesc, err)
+		return
+	}
+
+	now := time.Now()
+	totals := map[string]*namespaceTotals{}
+	for i := range list.Items {
+		pr := &list.Items[i]
+		t, ok := totals[pr.Namespace]
+		if !ok {
+			t = &namespaceTotals{phases: map[operatorv1alpha1.PodRestartPhase]int{}}
+			totals[pr.Namespace] = t
+		}
+		phase := pr.Status.Phase
+		if phase == "" {
+			phase = operatorv1alpha1.PhaseActive
+		}
+		t.phases[phase]++
+		t.restarts += int64(pr.Status.RestartCount)
+		if budget := pr.Spec.RestartBudget; budget != nil {
+			t.budgeted = true
+			used := restartsInWindow(pr, now)
+			t.restartsInWin += int64(used)
+			if remaining := budget.MaxRestarts - used; remaining > 0 {
+				t.budgetRemaining += int64(remaining)
+			}
+		}
+	}
+
+	phases := []operatorv1alpha1.PodRestartPhase{
+		operatorv1alpha1.PhaseActive,
+		operatorv1alpha1.PhaseSuspended,
+		operatorv1alpha1.PhaseDegraded,
+		operatorv1alpha1.PhaseBudgetExhausted,
+	}
+	for namespace, t := range totals {
+		for _, phase := range phases {
+			ch <- prometheus.MustNewConstMetric(namespacePodRestartsDesc, prometheus.GaugeValue,
+				float64(t.phases[phase]), namespace, string(phase))
+		}
+		ch <- prometheus.MustNewConstMetric(namespaceRestartsDesc, prometheus.GaugeValue, float64(t.restarts), namespace)
+		if t.budgeted {
+			ch <- prometheus.MustNewConstMetric(namespaceRestartsInWindowDesc, prometheus.GaugeValue, float64(t.restartsInWin), namespace)
+			ch <- prometheus.MustNewConstMetric(namespaceBudgetRemainingDesc, prometheus.GaugeValue, float64(t.budgetRemaining), namespace)
+		}
+	}
+}
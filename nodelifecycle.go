@@ -0,0 +1,97 @@
+// nodelifecycle.go
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	operatorv1alpha1 "github.com/example/pod-restart-operator/api/v1alpha1"
+)
+
+// taintToBeDeletedByClusterAutoscaler marks nodes the cluster autoscaler is draining
+const taintToBeDeletedByClusterAutoscaler = "ToBeDeletedByClusterAutoscaler"
+
+// nodeTracker answers whether a node is cordoned or draining, remembering
+// the answer for the rest of the pass so pods on one node share the lookup
+type nodeTracker struct {
+	reader client.Reader
+	seen   map[string]string
+}
+
+func newNodeTracker(cluster *clusterTarget) *nodeTracker {
+	return &nodeTracker{reader: cluster.lookups, seen: make(map[string]string)}
+}
+
+// cordoned returns a description of why the pod's node takes no new pods, or
+// an empty string when it is schedulable
+func (t *nodeTracker) cordoned(ctx context.Context, pod *corev1.Pod) (string, error) {
+	name := pod.Spec.NodeName
+	if name == "" {
+		return "", nil
+	}
+	if reason, ok := t.seen[name]; ok {
+		return reason, nil
+	}
+
+	node := &corev1.Node{}
+	if err := t.reader.Get(ctx, client.ObjectKey{Name: name}, node); err != nil {
+		if errors.IsNotFound(err) {
+			return "", nil
+		}
+		return "", fmt.Errorf("getting node %s: %w", name, err)
+	}
+	var reason string
+	for _, taint := range node.Spec.Taints {
+		if taint.Key == taintToBeDeletedByClusterAutoscaler {
+			reason = fmt.Sprintf("Node %s is being drained by the cluster autoscaler", name)
+		}
+	}
+	if reason == "" && node.Spec.Unschedulable {
+		reason = fmt.Sprintf("Node %s is cordoned", name)
+	}
+	t.seen[name] = reason
+	return reason, nil
+}
+
+// cordonedNodePolicy returns the PodRestart's policy for pods on cordoned nodes
+func cordonedNodePolicy(pr *operatorv1alpha1.PodRestart) operatorv1alpha1.CordonedNodePolicy {
+	if pr.Spec.CordonedNodes == "" {
+		return operatorv1alpha1.CordonedNodesNormal
+	}
+	return pr.Spec.CordonedNodes
+}
+
+// restartOrder returns the order in which the triggered pods of a page are
+// acted on. With the Prefer policy pods on cordoned nodes go first, so they
+// get the cooldown and budget before pods on healthy nodes do.
+func (r *PodRestartReconciler) restartOrder(ctx context.Context, pr *operatorv1alpha1.PodRestart, pods []corev1.Pod, results []*triggerResult, guard *restartGuard) []int {
+	order := make([]int, len(pods))
+	for i := range order {
+		order[i] = i
+	}
+	if cordonedNodePolicy(pr) != operatorv1alpha1.CordonedNodesPrefer {
+		return order
+	}
+	logger := log.FromContext(ctx)
+	cordoned := make([]bool, len(pods))
+	for i := range pods {
+		if results[i] == nil {
+			continue
+		}
+		reason, err := guard.nodes.cordoned(ctx, &pods[i])
+		if err != nil {
+			logger.Error(err, "Failed to check node of pod", "pod", pods[i].Name)
+		}
+		cordoned[i] = reason != ""
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return cordoned[order[a]] && !cordoned[order[b]]
+	})
+	return order
+}
@@ -0,0 +1,292 @@
+// notificationchannel_types.go
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// NotificationChannelType is the kind of notification backend
+type NotificationChannelType string
+
+const (
+	// ChannelSlack posts to a Slack incoming webhook
+	ChannelSlack NotificationChannelType = "Slack"
+	// ChannelTeams posts a connector card to a Microsoft Teams incoming webhook
+	ChannelTeams NotificationChannelType = "Teams"
+	// ChannelWebhook posts a JSON payload to an arbitrary URL
+	ChannelWebhook NotificationChannelType = "Webhook"
+	// ChannelPagerDuty sends events to the PagerDuty Events API
+	ChannelPagerDuty NotificationChannelType = "PagerDuty"
+	// ChannelOpsgenie creates alerts through the Opsgenie Alert API
+	ChannelOpsgenie NotificationChannelType = "Opsgenie"
+	// ChannelEmail sends email through an SMTP server
+	ChannelEmail NotificationChannelType = "Email"
+)
+
+// NotificationEventType identifies what happened to trigger a notification
+type NotificationEventType string
+
+const (
+	// EventRestarted is sent when a pod was restarted
+	EventRestarted NotificationEventType = "Restarted"
+	// EventRestartFailed is sent when restarting a pod failed
+	EventRestartFailed NotificationEventType = "RestartFailed"
+	// EventRestartSkipped is sent when a triggered restart was suppressed
+	EventRestartSkipped NotificationEventType = "RestartSkipped"
+	// EventBudgetExhausted is sent when a PodRestart used up its restart budget
+	EventBudgetExhausted NotificationEventType = "BudgetExhausted"
+	// EventRecovered is sent when a degraded or budget exhausted PodRestart is Ready again
+	EventRecovered NotificationEventType = "Recovered"
+	// EventBurnRateExceeded is sent when a burn rate condition with the Notify action fires
+	EventBurnRateExceeded NotificationEventType = "BurnRateExceeded"
+	// EventTriggerWarning is sent when a trigger with the Warn severity fires
+	EventTriggerWarning NotificationEventType = "TriggerWarning"
+	// EventWorkloadOrphaned is sent when the workload of a restarted pod did
+	// not schedule a replacement in time
+	EventWorkloadOrphaned NotificationEventType = "WorkloadOrphaned"
+	// EventCircuitOpen is sent when restarts failed so often in a row that
+	// the circuit breaker stopped attempting them
+	EventCircuitOpen NotificationEventType = "CircuitOpen"
+)
+
+// NotificationSeverity ranks events by how urgently a human needs to look
+type NotificationSeverity string
+
+const (
+	// SeverityInfo covers routine restarts, skips and recoveries
+	SeverityInfo NotificationSeverity = "Info"
+	// SeverityWarning covers failed restarts
+	SeverityWarning NotificationSeverity = "Warning"
+	// SeverityCritical covers escalations such as an exhausted restart budget,
+	// after which the operator stops remediating on its own
+	SeverityCritical NotificationSeverity = "Critical"
+)
+
+// Severity returns the severity notifications about the event are sent with
+func (e NotificationEventType) Severity() NotificationSeverity {
+	switch e {
+	case EventBudgetExhausted, EventWorkloadOrphaned, EventCircuitOpen:
+		return SeverityCritical
+	case EventRestartFailed, EventBurnRateExceeded, EventTriggerWarning:
+		return SeverityWarning
+	default:
+		return SeverityInfo
+	}
+}
+
+// rank orders severities, unknown severities rank lowest
+func (s NotificationSeverity) rank() int {
+	switch s {
+	case SeverityCritical:
+		return 2
+	case SeverityWarning:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// NotificationRateLimit allows at most MaxNotifications within a sliding window
+type NotificationRateLimit struct {
+	// MaxNotifications is the number of notifications allowed per window
+	// +kubebuilder:validation:Minimum=1
+	MaxNotifications int32 `json:"maxNotifications"`
+
+	// Window is the length of the sliding window
+	// +kubebuilder:validation:Format=duration
+	Window metav1.Duration `json:"window"`
+}
+
+// NotificationChannelSpec defines where and how notifications are delivered
+type NotificationChannelSpec struct {
+	// Type of the notification backend
+	// +kubebuilder:validation:Enum=Slack;Teams;Webhook;PagerDuty;Opsgenie;Email
+	Type NotificationChannelType `json:"type"`
+
+	// SecretRef references the Secret key holding the backend credentials,
+	// e.g. the Slack or Teams webhook URL or the PagerDuty routing key
+	// +optional
+	SecretRef *SecretKeyReference `json:"secretRef,omitempty"`
+
+	// Events limits the channel to the given event types. All events are
+	// delivered when empty.
+	// +optional
+	Events []NotificationEventType `json:"events,omitempty"`
+
+	// MinSeverity limits the channel to events of at least this severity
+	// +kubebuilder:validation:Enum=Info;Warning;Critical
+	// +optional
+	MinSeverity NotificationSeverity `json:"minSeverity,omitempty"`
+
+	// MinInterval throttles the channel to at most one notification per
+	// PodRestart and event type within the interval. Critical escalations
+	// are never throttled.
+	// +kubebuilder:validation:Format=duration
+	// +optional
+	MinInterval *metav1.Duration `json:"minInterval,omitempty"`
+
+	// RateLimit caps the number of notifications per PodRestart across all
+	// event types. Critical escalations are never rate limited.
+	// +optional
+	RateLimit *NotificationRateLimit `json:"rateLimit,omitempty"`
+
+	// DedupWindow suppresses notifications identical in event, pod and
+	// trigger to one already sent within the window
+	// +kubebuilder:validation:Format=duration
+	// +optional
+	DedupWindow *metav1.Duration `json:"dedupWindow,omitempty"`
+
+	// PayloadTemplate is a Go template with Sprig functions that replaces the
+	// default request body of Slack, Teams and Webhook channels. It is passed
+	// the notification along with the spec and status of the PodRestart and
+	// must render to JSON, e.g. Slack blocks.
+	// +optional
+	PayloadTemplate string `json:"payloadTemplate,omitempty"`
+
+	// Slack holds Slack specific settings
+	// +optional
+	Slack *SlackConfig `json:"slack,omitempty"`
+
+	// Webhook holds settings for generic webhook channels
+	// +optional
+	Webhook *WebhookConfig `json:"webhook,omitempty"`
+
+	// Opsgenie holds Opsgenie specific settings
+	// +optional
+	Opsgenie *OpsgenieConfig `json:"opsgenie,omitempty"`
+
+	// Email holds settings for email channels
+	// +optional
+	Email *EmailConfig `json:"email,omitempty"`
+}
+
+// SecretReference names a Secret in a given namespace
+type SecretReference struct {
+	// Namespace of the Secret
+	Namespace string `json:"namespace"`
+
+	// Name of the Secret
+	Name string `json:"name"`
+}
+
+// EmailConfig holds settings for email channels
+type EmailConfig struct {
+	// SMTPSecretRef references a Secret with the keys host, port and optionally
+	// username and password
+	SMTPSecretRef SecretReference `json:"smtpSecretRef"`
+
+	// From is the sender address
+	From string `json:"from"`
+
+	// To lists the recipient addresses
+	// +kubebuilder:validation:MinItems=1
+	To []string `json:"to"`
+
+	// SubjectTemplate is a Go template for the subject line
+	// +optional
+	SubjectTemplate string `json:"subjectTemplate,omitempty"`
+
+	// BodyTemplate is a Go template for the plain text body
+	// +optional
+	BodyTemplate string `json:"bodyTemplate,omitempty"`
+}
+
+// WebhookConfig holds settings for generic webhook channels. When secretRef is
+// set its value is used as the HMAC-SHA256 key for the X-PodRestart-Signature header.
+type WebhookConfig struct {
+	// URL receives a JSON POST for every notification
+	URL string `json:"url"`
+
+	// MaxRetries is the number of retries for transient delivery failures
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=10
+	// +kubebuilder:default=3
+	// +optional
+	MaxRetries *int32 `json:"maxRetries,omitempty"`
+}
+
+// OpsgenieConfig holds settings for Opsgenie channels. The API key is read from secretRef.
+type OpsgenieConfig struct {
+	// APIURL overrides the Opsgenie API endpoint, e.g. https://api.eu.opsgenie.com
+	// +optional
+	APIURL string `json:"apiURL,omitempty"`
+
+	// Priorities maps event types to alert priorities (P1-P5). Events without
+	// a priority do not create alerts. Defaults to BudgetExhausted=P1 and
+	// RestartFailed=P2.
+	// +optional
+	Priorities map[NotificationEventType]string `json:"priorities,omitempty"`
+
+	// Teams lists the Opsgenie teams notified about alerts
+	// +optional
+	Teams []string `json:"teams,omitempty"`
+}
+
+// SlackConfig holds settings for Slack channels. The webhook URL is read from secretRef.
+type SlackConfig struct {
+	// Channel overrides the default channel of the incoming webhook
+	// +optional
+	Channel string `json:"channel,omitempty"`
+
+	// Username overrides the name the message is posted as
+	// +optional
+	Username string `json:"username,omitempty"`
+
+	// IconEmoji overrides the icon of the message, e.g. :robot_face:
+	// +optional
+	IconEmoji string `json:"iconEmoji,omitempty"`
+}
+
+// NotificationChannelStatus defines the observed state of NotificationChannel
+type NotificationChannelStatus struct {
+	// Conditions represent the latest available observations of the NotificationChannel state
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// +genclient
+// +genclient:nonNamespaced
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:printcolumn:name="Type",type=string,JSONPath=`.spec.type`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// NotificationChannel is the Schema for the notificationchannels API
+type NotificationChannel struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   NotificationChannelSpec   `json:"spec,omitempty"`
+	Status NotificationChannelStatus `json:"status,omitempty"`
+}
+
+// WantsEvent reports whether the channel should receive the given event type
+func (c *NotificationChannel) WantsEvent(event NotificationEventType) bool {
+	if event.Severity().rank() < c.Spec.MinSeverity.rank() {
+		return false
+	}
+	if len(c.Spec.Events) == 0 {
+		return true
+	}
+	for _, e := range c.Spec.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// +kubebuilder:object:root=true
+
+// NotificationChannelList contains a list of NotificationChannel
+type NotificationChannelList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []NotificationChannel `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&NotificationChannel{}, &NotificationChannelList{})
+}
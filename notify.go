@@ -0,0 +1,470 @@
+// notify.go
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	operatorv1alpha1 "github.com/example/pod-restart-operator/api/v1alpha1"
+)
+
+// Notification describes a single event delivered to NotificationChannels
+type Notification struct {
+	Event       operatorv1alpha1.NotificationEventType
+	PodRestart  types.NamespacedName
+	Pod         string
+	Workload    string
+	Severity    operatorv1alpha1.NotificationSeverity
+	Trigger     string
+	TriggerName string
+	ReasonCode  operatorv1alpha1.RestartReasonCode
+	Reason      string
+	Message     string
+	MatchedLine string
+	MetricValue *float64
+	// Persistent marks a failed restart that failed repeatedly or will not
+	// succeed until someone intervenes
+	Persistent bool
+	Time       time.Time
+}
+
+// Reasons a notification was suppressed by the throttle
+const (
+	suppressedThrottled   = "Throttled"
+	suppressedRateLimited = "RateLimited"
+	suppressedDuplicate   = "Duplicate"
+)
+
+// notificationThrottle limits how often a channel is notified about a PodRestart
+type notificationThrottle struct {
+	mu   sync.Mutex
+	last map[string]time.Time
+	// sent holds the send times within the rate limit window, oldest first
+	sent map[string][]time.Time
+	// seen holds the last send time of each distinct notification
+	seen map[string]time.Time
+}
+
+func newNotificationThrottle() *notificationThrottle {
+	return &notificationThrottle{
+		last: map[string]time.Time{},
+		sent: map[string][]time.Time{},
+		seen: map[string]time.Time{},
+	}
+}
+
+// allow reports whether the notification may be sent now and, if so, records
+// it. Otherwise it returns the reason the notification was suppressed.
+// Channels without minInterval, rateLimit or dedupWindow are never throttled.
+func (t *notificationThrottle) allow(channel *operatorv1alpha1.NotificationChannel, n Notification) (bool, string) {
+	spec := channel.Spec
+	if spec.MinInterval == nil && spec.RateLimit == nil && spec.DedupWindow == nil {
+		return true, ""
+	}
+
+	base := channel.Name + "/" + n.PodRestart.String()
+	eventKey := base + "/" + string(n.Event)
+	dedupKey := eventKey + "/" + n.Pod + "/" + n.Trigger + "/" + n.TriggerName
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if spec.DedupWindow != nil {
+		// Entries past the window no longer suppress anything
+		for key, last := range t.seen {
+			if strings.HasPrefix(key, base+"/") && n.Time.Sub(last) >= spec.DedupWindow.Duration {
+				delete(t.seen, key)
+			}
+		}
+		if _, ok := t.seen[dedupKey]; ok {
+			return false, suppressedDuplicate
+		}
+	}
+	if spec.MinInterval != nil && n.Severity != operatorv1alpha1.SeverityCritical {
+		if last, ok := t.last[eventKey]; ok && n.Time.Sub(last) < spec.MinInterval.Duration {
+			return false, suppressedThrottled
+		}
+	}
+	var sent []time.Time
+	if limit := spec.RateLimit; limit != nil && n.Severity != operatorv1alpha1.SeverityCritical {
+		sent = t.sent[base]
+		for len(sent) > 0 && n.Time.Sub(sent[0]) >= limit.Window.Duration {
+			sent = sent[1:]
+		}
+		if len(sent) >= int(limit.MaxNotifications) {
+			t.sent[base] = sent
+			return false, suppressedRateLimited
+		}
+		t.sent[base] = append(sent, n.Time)
+	}
+
+	t.last[eventKey] = n.Time
+	if spec.DedupWindow != nil {
+		t.seen[dedupKey] = n.Time
+	}
+	return true, ""
+}
+
+// forget drops the throttle state of a deleted PodRestart
+func (t *notificationThrottle) forget(pr types.NamespacedName) {
+	// Keys start with the channel name, which cannot contain a slash
+	owned := func(key string) bool {
+		rest := key[strings.IndexByte(key, '/')+1:]
+		return rest == pr.String() || strings.HasPrefix(rest, pr.String()+"/")
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, m := range []map[string]time.Time{t.last, t.seen} {
+		for key := range m {
+			if owned(key) {
+				delete(m, key)
+			}
+		}
+	}
+	for key := range t.sent {
+		if owned(key) {
+			delete(t.sent, key)
+		}
+	}
+}
+
+// snapshot returns the last send time per channel and event of a PodRestart
+func (t *notificationThrottle) snapshot(pr types.NamespacedName) []operatorv1alpha1.NotificationCheckpoint {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var sent []operatorv1alpha1.NotificationCheckpoint
+	for key, last := range t.last {
+		i := strings.IndexByte(key, '/')
+		rest, prefix := key[i+1:], pr.String()+"/"
+		if !strings.HasPrefix(rest, prefix) {
+			continue
+		}
+		event := rest[len(prefix):]
+		sent = append(sent, operatorv1alpha1.NotificationCheckpoint{
+			Channel: key[:i],
+			Event:   operatorv1alpha1.NotificationEventType(event),
+			Time:    metav1.NewTime(last),
+		})
+	}
+	sort.Slice(sent, func(i, j int) bool {
+		if sent[i].Channel != sent[j].Channel {
+			return sent[i].Channel < sent[j].Channel
+		}
+		return sent[i].Event < sent[j].Event
+	})
+	return sent
+}
+
+// snapshotRateLimits returns the send times within the rate limit window of
+// each channel of a PodRestart
+func (t *notificationThrottle) snapshotRateLimits(pr types.NamespacedName) []operatorv1alpha1.RateLimitCheckpoint {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var limits []operatorv1alpha1.RateLimitCheckpoint
+	for key, sent := range t.sent {
+		i := strings.IndexByte(key, '/')
+		if key[i+1:] != pr.String() || len(sent) == 0 {
+			continue
+		}
+		times := make([]metav1.Time, 0, len(sent))
+		for _, s := range sent {
+			times = append(times, metav1.NewTime(s))
+		}
+		limits = append(limits, operatorv1alpha1.RateLimitCheckpoint{Channel: key[:i], Sent: times})
+	}
+	sort.Slice(limits, func(i, j int) bool {
+		return limits[i].Channel < limits[j].Channel
+	})
+	return limits
+}
+
+// snapshotDedup returns up to limit distinct notifications of a PodRestart,
+// newest first
+func (t *notificationThrottle) snapshotDedup(pr types.NamespacedName, limit int) []operatorv1alpha1.DedupCheckpoint {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var seen []operatorv1alpha1.DedupCheckpoint
+	for key, last := range t.seen {
+		i := strings.IndexByte(key, '/')
+		rest, prefix := key[i+1:], pr.String()+"/"
+		if !strings.HasPrefix(rest, prefix) {
+			continue
+		}
+		// The trigger name comes last since it may contain slashes
+		parts := strings.SplitN(rest[len(prefix):], "/", 4)
+		if len(parts) != 4 {
+			continue
+		}
+		seen = append(seen, operatorv1alpha1.DedupCheckpoint{
+			Channel:     key[:i],
+			Event:       operatorv1alpha1.NotificationEventType(parts[0]),
+			Pod:         parts[1],
+			Trigger:     parts[2],
+			TriggerName: parts[3],
+			Time:        metav1.NewTime(last),
+		})
+	}
+	sort.Slice(seen, func(i, j int) bool {
+		return seen[i].Time.After(seen[j].Time.Time)
+	})
+	if len(seen) > limit {
+		seen = seen[:limit]
+	}
+	return seen
+}
+
+// restore loads the send times of a PodRestart from a checkpoint so
+// minInterval, rateLimit and dedupWindow keep applying across operator
+// restarts
+func (t *notificationThrottle) restore(pr types.NamespacedName, cp *operatorv1alpha1.StateCheckpoint) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, s := range cp.Notifications {
+		key := s.Channel + "/" + pr.String() + "/" + string(s.Event)
+		if s.Time.After(t.last[key]) {
+			t.last[key] = s.Time.Time
+		}
+	}
+	for _, l := range cp.RateLimits {
+		key := l.Channel + "/" + pr.String()
+		if len(t.sent[key]) > 0 {
+			continue
+		}
+		for _, s := range l.Sent {
+			t.sent[key] = append(t.sent[key], s.Time)
+		}
+	}
+	for _, d := range cp.Deduplicated {
+		key := d.Channel + "/" + pr.String() + "/" + string(d.Event) + "/" + d.Pod + "/" + d.Trigger + "/" + d.TriggerName
+		if d.Time.After(t.seen[key]) {
+			t.seen[key] = d.Time.Time
+		}
+	}
+}
+
+// Notifier delivers notifications to a single backend
+type Notifier interface {
+	Notify(ctx context.Context, n Notification) error
+}
+
+// newNotifier builds the notifier for a NotificationChannel, applying any
+// per-PodRestart overrides
+func (r *PodRestartReconciler) newNotifier(ctx context.Context, channel *operatorv1alpha1.NotificationChannel, pr *operatorv1alpha1.PodRestart) (Notifier, error) {
+	switch channel.Spec.Type {
+	case operatorv1alpha1.ChannelSlack:
+		return r.newSlackNotifier(ctx, channel, pr)
+	case operatorv1alpha1.ChannelTeams:
+		return r.newTeamsNotifier(ctx, channel, pr)
+	case operatorv1alpha1.ChannelPagerDuty:
+		return r.newPagerDutyNotifier(ctx, channel)
+	case operatorv1alpha1.ChannelOpsgenie:
+		return r.newOpsgenieNotifier(ctx, channel)
+	case operatorv1alpha1.ChannelWebhook:
+		return r.newWebhookNotifier(ctx, channel, pr)
+	case operatorv1alpha1.ChannelEmail:
+		return r.newEmailNotifier(ctx, channel)
+	default:
+		return nil, fmt.Errorf("unsupported notification channel type %q", channel.Spec.Type)
+	}
+}
+
+// postJSON sends payload as a JSON POST request and treats any non-2xx
+// response as an error
+func postJSON(ctx context.Context, httpClient *http.Client, url string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return unauthorized(resp.StatusCode, "%s returned HTTP %d", req.URL.Host, resp.StatusCode)
+	}
+	return nil
+}
+
+// channelsFor resolves the NotificationChannels referenced by a PodRestart,
+// including the escalation channels if escalate is set
+func (r *PodRestartReconciler) channelsFor(ctx context.Context, pr *operatorv1alpha1.PodRestart, escalate bool) ([]operatorv1alpha1.NotificationChannel, error) {
+	spec := pr.Spec.Notifications
+	if spec == nil {
+		return nil, nil
+	}
+
+	var channels []operatorv1alpha1.NotificationChannel
+	seen := map[string]bool{}
+
+	names := spec.Channels
+	if escalate {
+		names = append(append([]string{}, names...), spec.EscalationChannels...)
+	}
+	for _, name := range names {
+		if seen[name] {
+			continue
+		}
+		channel := operatorv1alpha1.NotificationChannel{}
+		if err := r.Get(ctx, types.NamespacedName{Name: name}, &channel); err != nil {
+			return nil, fmt.Errorf("getting notification channel %q: %w", name, err)
+		}
+		seen[name] = true
+		channels = append(channels, channel)
+	}
+
+	if spec.ChannelSelector != nil {
+		selector, err := metav1.LabelSelectorAsSelector(spec.ChannelSelector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid channel selector: %w", err)
+		}
+		list := &operatorv1alpha1.NotificationChannelList{}
+		if err := r.List(ctx, list, client.MatchingLabelsSelector{Selector: selector}); err != nil {
+			return nil, fmt.Errorf("listing notification channels: %w", err)
+		}
+		for _, channel := range list.Items {
+			if !seen[channel.Name] {
+				seen[channel.Name] = true
+				channels = append(channels, channel)
+			}
+		}
+	}
+
+	return channels, nil
+}
+
+// notify delivers a notification to every channel selected by the PodRestart.
+// Delivery failures are logged and never block the reconcile.
+func (r *PodRestartReconciler) notify(ctx context.Context, pr *operatorv1alpha1.PodRestart, n Notification) {
+	logger := log.FromContext(ctx)
+
+	if n.Severity == "" {
+		n.Severity = n.Event.Severity()
+	}
+	if n.Severity == operatorv1alpha1.SeverityCritical {
+		escalationsTotal.WithLabelValues(pr.Namespace, pr.Name, string(n.Event)).Inc()
+	}
+	channels, err := r.channelsFor(ctx, pr, n.Severity == operatorv1alpha1.SeverityCritical)
+	if err != nil {
+		logger.Error(err, "Failed to resolve notification channels")
+		return
+	}
+
+	n.PodRestart = types.NamespacedName{Namespace: pr.Namespace, Name: pr.Name}
+	if n.Time.IsZero() {
+		n.Time = time.Now()
+	}
+
+	for i := range channels {
+		channel := &channels[i]
+		if !channel.WantsEvent(n.Event) {
+			continue
+		}
+
+		if ok, reason := r.throttle.allow(channel, n); !ok {
+			notificationsSuppressedTotal.WithLabelValues(pr.Namespace, pr.Name, channel.Name, reason).Inc()
+			logger.V(1).Info("Notification suppressed", "channel", channel.Name, "event", n.Event, "reason", reason)
+			continue
+		}
+
+		notifier, err := r.newNotifier(ctx, channel, pr)
+		if err != nil {
+			logger.Error(err, "Failed to set up notifier", "channel", channel.Name)
+			recordDelivery(pr, channel.Name, n, err)
+			continue
+		}
+		if _, ok := notifier.(*webhookNotifier); ok && r.webhooks != nil {
+			r.queueWebhook(ctx, pr, channel, notifier, n)
+			continue
+		}
+		err = notifier.Notify(ctx, n)
+		if errors.Is(err, errUnauthorized) {
+			// The credentials may have been rotated, retry once with the current ones
+			logger.Info("Notification credentials rejected, retrying with a fresh secret", "channel", channel.Name)
+			if notifier, err = r.newNotifier(withFreshSecrets(ctx), channel, pr); err == nil {
+				err = notifier.Notify(ctx, n)
+			}
+		}
+		if err != nil {
+			logger.Error(err, "Failed to send notification", "channel", channel.Name, "event", n.Event)
+		}
+		recordDelivery(pr, channel.Name, n, err)
+	}
+}
+
+// queueWebhook hands a webhook notification to the delivery queue. A full
+// queue fails the delivery right away rather than blocking the reconcile.
+func (r *PodRestartReconciler) queueWebhook(ctx context.Context, pr *operatorv1alpha1.PodRestart, channel *operatorv1alpha1.NotificationChannel, notifier Notifier, n Notification) {
+	pr, channel = pr.DeepCopy(), channel.DeepCopy()
+	job := webhookJob{
+		podRestart:   n.PodRestart,
+		channel:      channel.Name,
+		notification: n,
+		deliver: func(dctx context.Context) error {
+			err := notifier.Notify(dctx, n)
+			if errors.Is(err, errUnauthorized) {
+				// The credentials may have been rotated, retry once with the current ones
+				if notifier, err = r.newNotifier(withFreshSecrets(dctx), channel, pr); err == nil {
+					err = notifier.Notify(dctx, n)
+				}
+			}
+			if err != nil {
+				r.Log.Error(err, "Failed to send notification", "channel", channel.Name, "event", n.Event, "podrestart", n.PodRestart)
+			}
+			return err
+		},
+	}
+	if !r.webhooks.enqueue(job) {
+		err := fmt.Errorf("webhook delivery queue is full, %d notifications are waiting", webhookQueueSize)
+		log.FromContext(ctx).Error(err, "Failed to send notification", "channel", channel.Name, "event", n.Event)
+		recordDelivery(pr, channel.Name, n, err)
+	}
+}
+
+// recordDelivery updates the per-channel delivery status on the PodRestart
+func recordDelivery(pr *operatorv1alpha1.PodRestart, channel string, n Notification, err error) {
+	var status *operatorv1alpha1.NotificationDeliveryStatus
+	for i := range pr.Status.NotificationDeliveries {
+		if pr.Status.NotificationDeliveries[i].Channel == channel {
+			status = &pr.Status.NotificationDeliveries[i]
+			break
+		}
+	}
+	if status == nil {
+		pr.Status.NotificationDeliveries = append(pr.Status.NotificationDeliveries,
+			operatorv1alpha1.NotificationDeliveryStatus{Channel: channel})
+		status = &pr.Status.NotificationDeliveries[len(pr.Status.NotificationDeliveries)-1]
+	}
+
+	now := metav1.NewTime(n.Time)
+	status.LastAttemptTime = &now
+	status.LastEvent = n.Event
+	if err != nil {
+		status.ConsecutiveFailures++
+		status.LastError = err.Error()
+		return
+	}
+	status.LastSuccessTime = &now
+	status.ConsecutiveFailures = 0
+	status.LastError = ""
+}
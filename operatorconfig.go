@@ -0,0 +1,166 @@
+// operatorconfig.go
+package controllers
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	operatorv1alpha1 "github.com/example/pod-restart-operator/api/v1alpha1"
+)
+
+// ConditionApplied reports whether the OperatorConfig is in effect
+const ConditionApplied = "Applied"
+
+// operatorConfig holds the spec of the OperatorConfig currently applied
+type operatorConfig struct {
+	mu   sync.RWMutex
+	spec *operatorv1alpha1.OperatorConfigSpec
+}
+
+// get returns the applied spec, or nil when there is none
+func (c *operatorConfig) get() *operatorv1alpha1.OperatorConfigSpec {
+	if c == nil {
+		return nil
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.spec
+}
+
+func (c *operatorConfig) set(spec *operatorv1alpha1.OperatorConfigSpec) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.spec = spec
+}
+
+// setupOperatorConfig watches the OperatorConfig named by ConfigName
+func (r *PodRestartReconciler) setupOperatorConfig(mgr ctrl.Manager) error {
+	r.config = &operatorConfig{}
+	if r.ConfigName == "" {
+		return nil
+	}
+	named := predicate.NewPredicateFuncs(func(obj client.Object) bool {
+		return obj.GetName() == r.ConfigName
+	})
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("operatorconfig").
+		For(&operatorv1alpha1.OperatorConfig{}, builder.WithPredicates(named, predicate.GenerationChangedPredicate{})).
+		Complete(reconcile.Func(r.reloadConfig))
+}
+
+// reloadConfig applies the OperatorConfig. An invalid config is reported in
+// its status and the previous one stays in effect.
+func (r *PodRestartReconciler) reloadConfig(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	cfg := &operatorv1alpha1.OperatorConfig{}
+	if err := r.Get(ctx, req.NamespacedName, cfg); err != nil {
+		if errors.IsNotFound(err) {
+			logger.Info("OperatorConfig removed, using command-line settings")
+			r.config.set(nil)
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, err
+	}
+
+	condition := metav1.Condition{
+		Type:               ConditionApplied,
+		Status:             metav1.ConditionTrue,
+		Reason:             "Applied",
+		Message:            "Settings are in effect",
+		ObservedGeneration: cfg.Generation,
+	}
+	if err := validateFeatureOverrides(cfg.Spec.FeatureGates); err != nil {
+		logger.Error(err, "Invalid OperatorConfig, keeping the previous settings")
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "InvalidFeatureGates"
+		condition.Message = err.Error()
+	} else {
+		spec := cfg.Spec.DeepCopy()
+		r.config.set(spec)
+		logger.Info("Applied OperatorConfig", "generation", cfg.Generation)
+	}
+
+	meta.SetStatusCondition(&cfg.Status.Conditions, condition)
+	cfg.Status.ObservedGeneration = cfg.Generation
+	if err := r.applyStatus(ctx, cfg, &cfg.Status); err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{}, nil
+}
+
+// featureEnabled reports whether a feature is enabled, the OperatorConfig
+// taking precedence over --feature-gates
+func (r *PodRestartReconciler) featureEnabled(feature Feature) bool {
+	if spec := r.config.get(); spec != nil {
+		if enabled, ok := spec.FeatureGates[string(feature)]; ok {
+			return enabled
+		}
+	}
+	return r.Features.Enabled(feature)
+}
+
+// evaluationSettings returns the number of evaluation workers and the per-pod timeout
+func (r *PodRestartReconciler) evaluationSettings() (int, time.Duration) {
+	workers, timeout := r.EvaluationWorkers, r.PodEvaluationTimeout
+	if spec := r.config.get(); spec != nil {
+		if spec.EvaluationWorkers != nil {
+			workers = int(*spec.EvaluationWorkers)
+		}
+		if spec.PodEvaluationTimeout != nil {
+			timeout = spec.PodEvaluationTimeout.Duration
+		}
+	}
+	if workers <= 0 {
+		workers = defaultEvaluationWorkers
+	}
+	if timeout <= 0 {
+		timeout = defaultPodEvaluationTimeout
+	}
+	return workers, timeout
+}
+
+// logReadLimits returns the operator-wide log read budget
+func (r *PodRestartReconciler) logReadLimits() (int64, time.Duration) {
+	maxBytes, maxDuration := r.LogReadBytesPerReconcile, r.LogReadTimePerReconcile
+	if spec := r.config.get(); spec != nil && spec.LogReadBudget != nil {
+		if spec.LogReadBudget.MaxBytes != nil {
+			maxBytes = spec.LogReadBudget.MaxBytes.Value()
+		}
+		if spec.LogReadBudget.MaxDuration != nil {
+			maxDuration = spec.LogReadBudget.MaxDuration.Duration
+		}
+	}
+	return maxBytes, maxDuration
+}
+
+// minTimeBetweenRestarts returns the cooldown of a PodRestart, falling back
+// to the OperatorConfig default
+func (r *PodRestartReconciler) minTimeBetweenRestarts(pr *operatorv1alpha1.PodRestart) *metav1.Duration {
+	if pr.Spec.MinTimeBetweenRestarts != nil {
+		return pr.Spec.MinTimeBetweenRestarts
+	}
+	if spec := r.config.get(); spec != nil {
+		return spec.DefaultMinTimeBetweenRestarts
+	}
+	return nil
+}
+
+// defaultMetricProvider returns the MetricProvider used by conditions that do not name one
+func (r *PodRestartReconciler) defaultMetricProvider() string {
+	if spec := r.config.get(); spec != nil {
+		return spec.DefaultMetricProvider
+	}
+	return ""
+}
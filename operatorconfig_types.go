@@ -0,0 +1,92 @@
+// operatorconfig_types.go
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// OperatorConfigSpec holds operator-wide settings. They are applied without
+// restarting the operator and take precedence over the command-line flags;
+// unset fields keep the flag values.
+type OperatorConfigSpec struct {
+	// DefaultMinTimeBetweenRestarts applies to PodRestarts that do not set
+	// spec.minTimeBetweenRestarts
+	// +kubebuilder:validation:Format=duration
+	// +optional
+	DefaultMinTimeBetweenRestarts *metav1.Duration `json:"defaultMinTimeBetweenRestarts,omitempty"`
+
+	// DefaultMetricProvider is the MetricProvider used by metric conditions
+	// that do not name one
+	// +optional
+	DefaultMetricProvider string `json:"defaultMetricProvider,omitempty"`
+
+	// LogReadBudget caps the logs read per PodRestart in a single reconcile
+	// +optional
+	LogReadBudget *LogReadBudget `json:"logReadBudget,omitempty"`
+
+	// EvaluationWorkers is the number of pods of a PodRestart evaluated concurrently
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	EvaluationWorkers *int32 `json:"evaluationWorkers,omitempty"`
+
+	// PodEvaluationTimeout bounds the log scans and metric queries of a single pod
+	// +kubebuilder:validation:Format=duration
+	// +optional
+	PodEvaluationTimeout *metav1.Duration `json:"podEvaluationTimeout,omitempty"`
+
+	// ProtectedNamespaces are never touched, in addition to those protected
+	// by the flags. Entries may use glob patterns such as "team-*".
+	// +optional
+	ProtectedNamespaces []string `json:"protectedNamespaces,omitempty"`
+
+	// AllowedNamespaces restricts the operator to matching namespaces, on
+	// top of any allow-list set by the flags. Entries may use glob patterns.
+	// +optional
+	AllowedNamespaces []string `json:"allowedNamespaces,omitempty"`
+
+	// FeatureGates enables or disables optional features by name
+	// +optional
+	FeatureGates map[string]bool `json:"featureGates,omitempty"`
+}
+
+// OperatorConfigStatus defines the observed state of OperatorConfig
+type OperatorConfigStatus struct {
+	// ObservedGeneration is the generation last applied by the operator
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Conditions represent the latest available observations of the OperatorConfig state
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// +genclient
+// +genclient:nonNamespaced
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:printcolumn:name="Applied",type=string,JSONPath=`.status.conditions[?(@.type=="Applied")].status`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// OperatorConfig is the Schema for the operatorconfigs API
+type OperatorConfig struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   OperatorConfigSpec   `json:"spec,omitempty"`
+	Status OperatorConfigStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// OperatorConfigList contains a list of OperatorConfig
+type OperatorConfigList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []OperatorConfig `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&OperatorConfig{}, &OperatorConfigList{})
+}
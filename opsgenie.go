@@ -0,0 +1,196 @@
+// opsgenie.go
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	operatorv1alpha1 "github.com/example/pod-restart-operator/api/v1alpha1"
+)
+
+const defaultOpsgenieURL = "https://api.opsgenie.com"
+
+// defaultOpsgeniePriorities mirrors the PagerDuty integration: only sustained
+// failures open alerts unless the channel maps additional events
+var defaultOpsgeniePriorities = map[operatorv1alpha1.NotificationEventType]string{
+	operatorv1alpha1.EventBudgetExhausted: "P1",
+	operatorv1alpha1.EventCircuitOpen:     "P1",
+	operatorv1alpha1.EventRestartFailed:   "P2",
+}
+
+// opsgenieNotifier creates and closes alerts through the Opsgenie Alert API
+type opsgenieNotifier struct {
+	apiURL     string
+	apiKey     string
+	priorities map[operatorv1alpha1.NotificationEventType]string
+	responders []string
+	aliases    *alertAliases
+	httpClient *http.Client
+}
+
+type opsgenieAlert struct {
+	Message     string              `json:"message"`
+	Alias       string              `json:"alias"`
+	Description string              `json:"description,omitempty"`
+	Responders  []opsgenieResponder `json:"responders,omitempty"`
+	Tags        []string            `json:"tags,omitempty"`
+	Details     map[string]string   `json:"details,omitempty"`
+	Entity      string              `json:"entity,omitempty"`
+	Source      string              `json:"source,omitempty"`
+	Priority    string              `json:"priority"`
+}
+
+type opsgenieResponder struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// alertAliases remembers which alert aliases were opened per PodRestart so
+// they can all be closed on recovery. The state is in memory only; alerts
+// opened before an operator restart must be closed by hand or will be
+// deduplicated into the next alert for the same workload.
+type alertAliases struct {
+	mu   sync.Mutex
+	open map[string]map[string]bool
+}
+
+func newAlertAliases() *alertAliases {
+	return &alertAliases{open: map[string]map[string]bool{}}
+}
+
+func (a *alertAliases) add(owner, alias string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.open[owner] == nil {
+		a.open[owner] = map[string]bool{}
+	}
+	a.open[owner][alias] = true
+}
+
+func (a *alertAliases) drain(owner string) []string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	var aliases []string
+	for alias := range a.open[owner] {
+		aliases = append(aliases, alias)
+	}
+	delete(a.open, owner)
+	return aliases
+}
+
+// newOpsgenieNotifier builds an Opsgenie notifier from a channel
+func (r *PodRestartReconciler) newOpsgenieNotifier(ctx context.Context, channel *operatorv1alpha1.NotificationChannel) (Notifier, error) {
+	if channel.Spec.SecretRef == nil {
+		return nil, fmt.Errorf("opsgenie channel %s has no secretRef", channel.Name)
+	}
+	key, err := r.secretValue(ctx, channel.Spec.SecretRef)
+	if err != nil {
+		return nil, err
+	}
+
+	n := &opsgenieNotifier{
+		apiURL:     defaultOpsgenieURL,
+		apiKey:     strings.TrimSpace(string(key)),
+		priorities: defaultOpsgeniePriorities,
+		aliases:    r.alertAliases,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+	if cfg := channel.Spec.Opsgenie; cfg != nil {
+		if cfg.APIURL != "" {
+			n.apiURL = cfg.APIURL
+		}
+		if len(cfg.Priorities) > 0 {
+			n.priorities = cfg.Priorities
+		}
+		n.responders = cfg.Teams
+	}
+	return n, nil
+}
+
+// Notify implements Notifier
+func (n *opsgenieNotifier) Notify(ctx context.Context, notification Notification) error {
+	owner := notification.PodRestart.String()
+
+	if notification.Event == operatorv1alpha1.EventRecovered {
+		aliases := append(n.aliases.drain(owner), opsgenieAlias(notification))
+		for _, alias := range aliases {
+			if err := n.close(ctx, alias); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	priority, ok := n.priorities[notification.Event]
+	if !ok {
+		return nil
+	}
+
+	alias := opsgenieAlias(notification)
+	alert := opsgenieAlert{
+		Message:     pagerDutySummary(notification),
+		Alias:       alias,
+		Description: notification.Message,
+		Tags:        []string{"pod-restart-operator", "namespace:" + notification.PodRestart.Namespace},
+		Details:     notificationDetails(notification),
+		Entity:      notification.Workload,
+		Source:      "pod-restart-operator",
+		Priority:    priority,
+	}
+	for _, team := range n.responders {
+		alert.Responders = append(alert.Responders, opsgenieResponder{Name: team, Type: "team"})
+	}
+
+	if err := n.send(ctx, http.MethodPost, "/v2/alerts", alert); err != nil {
+		return err
+	}
+	n.aliases.add(owner, alias)
+	return nil
+}
+
+// close closes the alert with the given alias
+func (n *opsgenieNotifier) close(ctx context.Context, alias string) error {
+	path := "/v2/alerts/" + url.PathEscape(alias) + "/close?identifierType=alias"
+	return n.send(ctx, http.MethodPost, path, map[string]string{"source": "pod-restart-operator"})
+}
+
+// send performs an authenticated request against the Opsgenie API
+func (n *opsgenieNotifier) send(ctx context.Context, method, path string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(n.apiURL, "/")+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "GenieKey "+n.apiKey)
+
+	resp, err := n.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return unauthorized(resp.StatusCode, "opsgenie returned HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// opsgenieAlias derives the dedup alias from the PodRestart and, for pod level
+// events, the owning workload
+func opsgenieAlias(n Notification) string {
+	alias := "podrestart/" + n.PodRestart.String()
+	if n.Workload != "" {
+		alias += "/" + n.Workload
+	}
+	return alias
+}
@@ -0,0 +1,162 @@
+// orphans.go
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	operatorv1alpha1 "github.com/example/pod-restart-operator/api/v1alpha1"
+)
+
+const (
+	// defaultReplacementDeadline is used when spec.replacementDeadline is not set
+	defaultReplacementDeadline = 5 * time.Minute
+
+	// podWorkloadIndex indexes the cached pods by their workload, as Kind/name
+	podWorkloadIndex = "metadata.workload"
+)
+
+// podWorkloadTerms is the IndexerFunc for podWorkloadIndex
+func podWorkloadTerms(obj client.Object) []string {
+	pod, ok := obj.(*corev1.Pod)
+	if !ok {
+		return nil
+	}
+	return []string{workloadFor(pod).String()}
+}
+
+func replacementDeadline(pr *operatorv1alpha1.PodRestart) time.Duration {
+	if d := pr.Spec.ReplacementDeadline; d != nil {
+		return d.Duration
+	}
+	return defaultReplacementDeadline
+}
+
+// awaitReplacement remembers a restarted pod so the next passes can verify
+// its workload replaces it. Pods without a controller are never replaced,
+// which is reported right away.
+func (r *PodRestartReconciler) awaitReplacement(ctx context.Context, pr *operatorv1alpha1.PodRestart, pod *corev1.Pod, now metav1.Time) {
+	pending := operatorv1alpha1.PendingReplacement{
+		Workload:    workloadFor(pod).String(),
+		Namespace:   pod.Namespace,
+		PodName:     pod.Name,
+		PodUID:      pod.UID,
+		RestartTime: now,
+	}
+	if workloadFor(pod).Kind == "Pod" {
+		r.reportOrphaned(ctx, pr, pending, fmt.Sprintf("Pod %s has no owning controller and will not be recreated", pod.Name))
+		return
+	}
+	pr.Status.PendingReplacements = append(pr.Status.PendingReplacements, pending)
+}
+
+// checkReplacements resolves the pending replacements of earlier restarts:
+// a workload that scheduled a new pod is fine, one that missed the deadline
+// is orphaned. Orphaned workloads are released once a pod of theirs is
+// scheduled again.
+func (r *PodRestartReconciler) checkReplacements(ctx context.Context, pr *operatorv1alpha1.PodRestart, cluster *clusterTarget) {
+	if len(pr.Status.PendingReplacements) == 0 && len(pr.Status.OrphanedWorkloads) == 0 {
+		return
+	}
+	logger := log.FromContext(ctx)
+	deadline := replacementDeadline(pr)
+	now := time.Now()
+
+	// The pods of a workload are listed from the cache by the workload
+	// index. Targets without a cache list the pods the PodRestart selects,
+	// since replacements share the labels of the pod they replace, once
+	// per namespace however many checks are pending.
+	listed := map[string][]corev1.Pod{}
+	replaced := func(p operatorv1alpha1.PendingReplacement) (bool, error) {
+		target := cluster.inNamespace(p.Namespace)
+		reader, key := target.cachedPods, p.Namespace+"/"+p.Workload
+		opts := []client.ListOption{client.InNamespace(p.Namespace), client.MatchingFields{podWorkloadIndex: p.Workload}}
+		if reader == nil {
+			reader, key = target.reader, p.Namespace
+			opts = []client.ListOption{client.InNamespace(p.Namespace)}
+			if selector, err := metav1.LabelSelectorAsSelector(&pr.Spec.PodSelector); err == nil {
+				opts = append(opts, client.MatchingLabelsSelector{Selector: selector})
+			}
+		}
+		pods, ok := listed[key]
+		if !ok {
+			list := &corev1.PodList{}
+			if err := reader.List(ctx, list, opts...); err != nil {
+				return false, err
+			}
+			pods = list.Items
+			listed[key] = pods
+		}
+		for i := range pods {
+			pod := &pods[i]
+			if pod.UID == p.PodUID || pod.Spec.NodeName == "" || workloadFor(pod).String() != p.Workload {
+				continue
+			}
+			// Creation timestamps have second precision
+			if !pod.CreationTimestamp.Time.Before(p.RestartTime.Time.Truncate(time.Second)) {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+
+	var pending []operatorv1alpha1.PendingReplacement
+	for _, p := range pr.Status.PendingReplacements {
+		ok, err := replaced(p)
+		switch {
+		case err != nil:
+			logger.Error(err, "Failed to check for replacement pod", "workload", p.Workload)
+			pending = append(pending, p)
+		case ok:
+		case now.Sub(p.RestartTime.Time) < deadline:
+			pending = append(pending, p)
+		default:
+			r.reportOrphaned(ctx, pr, p, fmt.Sprintf("Workload %s did not schedule a replacement for pod %s within %s",
+				p.Workload, p.PodName, deadline))
+		}
+	}
+	pr.Status.PendingReplacements = pending
+
+	var orphaned []operatorv1alpha1.PendingReplacement
+	for _, p := range pr.Status.OrphanedWorkloads {
+		if ok, err := replaced(p); err == nil && ok {
+			logger.Info("Orphaned workload scheduled a pod again, restarts resume", "workload", p.Workload)
+			continue
+		}
+		orphaned = append(orphaned, p)
+	}
+	pr.Status.OrphanedWorkloads = orphaned
+}
+
+// reportOrphaned records a workload that did not replace a restarted pod and
+// alerts about it. Bare pods are only alerted about, they have no other pods
+// to protect.
+func (r *PodRestartReconciler) reportOrphaned(ctx context.Context, pr *operatorv1alpha1.PodRestart, p operatorv1alpha1.PendingReplacement, message string) {
+	log.FromContext(ctx).Info("Restarted pod was not replaced", "workload", p.Workload, "pod", p.PodName)
+	if p.Workload != "Pod/"+p.PodName && !isOrphaned(pr, p.Namespace, p.Workload) {
+		pr.Status.OrphanedWorkloads = append(pr.Status.OrphanedWorkloads, p)
+	}
+	r.Recorder.Eventf(pr, corev1.EventTypeWarning, EventReasonWorkloadOrphaned, "%s", message)
+	r.notify(ctx, pr, Notification{
+		Event:    operatorv1alpha1.EventWorkloadOrphaned,
+		Pod:      p.PodName,
+		Workload: p.Workload,
+		Reason:   message,
+	})
+}
+
+// isOrphaned reports whether a workload is recorded as orphaned
+func isOrphaned(pr *operatorv1alpha1.PodRestart, namespace, workload string) bool {
+	for _, o := range pr.Status.OrphanedWorkloads {
+		if o.Workload == workload && o.Namespace == namespace {
+			return true
+		}
+	}
+	return false
+}
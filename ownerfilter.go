@@ -0,0 +1,63 @@
+// ownerfilter.go
+package controllers
+
+import (
+	"fmt"
+	"regexp"
+
+	corev1 "k8s.io/api/core/v1"
+
+	operatorv1alpha1 "github.com/example/pod-restart-operator/api/v1alpha1"
+)
+
+// ownerMatcher narrows the pods of the label selector to those owned by
+// matching workloads. A nil matcher keeps every pod.
+type ownerMatcher struct {
+	kind string
+	name *regexp.Regexp
+}
+
+// newOwnerMatcher compiles the PodRestart's owner filter, returning nil when
+// it has none
+func newOwnerMatcher(pr *operatorv1alpha1.PodRestart) (*ownerMatcher, error) {
+	filter := pr.Spec.OwnerFilter
+	if filter == nil {
+		return nil, nil
+	}
+	m := &ownerMatcher{kind: filter.Kind}
+	if filter.NameRegex != "" {
+		re, err := regexp.Compile("^(?:" + filter.NameRegex + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("invalid owner name regex %q: %w", filter.NameRegex, err)
+		}
+		m.name = re
+	}
+	return m, nil
+}
+
+// matches reports whether the pod's workload passes the filter. Pods of
+// Deployments are matched by their Deployment, not their ReplicaSet.
+func (m *ownerMatcher) matches(pod *corev1.Pod) bool {
+	if m == nil {
+		return true
+	}
+	workload := workloadFor(pod)
+	if m.kind != "" && workload.Kind != m.kind {
+		return false
+	}
+	return m.name == nil || m.name.MatchString(workload.Name)
+}
+
+// filter returns the pods passing the filter, reusing the backing array
+func (m *ownerMatcher) filter(pods []corev1.Pod) []corev1.Pod {
+	if m == nil {
+		return pods
+	}
+	kept := pods[:0]
+	for i := range pods {
+		if m.matches(&pods[i]) {
+			kept = append(kept, pods[i])
+		}
+	}
+	return kept
+}
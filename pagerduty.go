@@ -0,0 +1,170 @@
+// pagerduty.go
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	operatorv1alpha1 "github.com/example/pod-restart-operator/api/v1alpha1"
+)
+
+const (
+	pagerDutyEnqueueURL = "https://events.pagerduty.com/v2/enqueue"
+	pagerDutyChangeURL  = "https://events.pagerduty.com/v2/change/enqueue"
+)
+
+// pagerDutyNotifier sends events to the PagerDuty Events API v2. Routine
+// restarts are sent as change events, which never page; a single failed
+// restart opens a warning incident, sustained failures raise it to error,
+// an exhausted budget or open circuit breaker to critical. The incident is resolved once the
+// PodRestart recovers.
+type pagerDutyNotifier struct {
+	routingKey string
+	httpClient *http.Client
+}
+
+type pagerDutyEvent struct {
+	RoutingKey  string            `json:"routing_key"`
+	EventAction string            `json:"event_action"`
+	DedupKey    string            `json:"dedup_key"`
+	Payload     *pagerDutyPayload `json:"payload,omitempty"`
+}
+
+type pagerDutyPayload struct {
+	Summary       string            `json:"summary"`
+	Source        string            `json:"source"`
+	Severity      string            `json:"severity"`
+	Timestamp     string            `json:"timestamp,omitempty"`
+	Component     string            `json:"component,omitempty"`
+	Group         string            `json:"group,omitempty"`
+	Class         string            `json:"class,omitempty"`
+	CustomDetails map[string]string `json:"custom_details,omitempty"`
+}
+
+type pagerDutyChangeEvent struct {
+	RoutingKey string               `json:"routing_key"`
+	Payload    pagerDutyChangeEntry `json:"payload"`
+}
+
+type pagerDutyChangeEntry struct {
+	Summary       string            `json:"summary"`
+	Source        string            `json:"source"`
+	Timestamp     string            `json:"timestamp,omitempty"`
+	CustomDetails map[string]string `json:"custom_details,omitempty"`
+}
+
+// newPagerDutyNotifier builds a PagerDuty notifier from a channel
+func (r *PodRestartReconciler) newPagerDutyNotifier(ctx context.Context, channel *operatorv1alpha1.NotificationChannel) (Notifier, error) {
+	if channel.Spec.SecretRef == nil {
+		return nil, fmt.Errorf("pagerduty channel %s has no secretRef", channel.Name)
+	}
+	key, err := r.secretValue(ctx, channel.Spec.SecretRef)
+	if err != nil {
+		return nil, err
+	}
+	return &pagerDutyNotifier{
+		routingKey: strings.TrimSpace(string(key)),
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+// Notify implements Notifier
+func (n *pagerDutyNotifier) Notify(ctx context.Context, notification Notification) error {
+	source := "podrestart/" + notification.PodRestart.String()
+	timestamp := notification.Time.UTC().Format(time.RFC3339)
+	details := notificationDetails(notification)
+
+	switch notification.Event {
+	case operatorv1alpha1.EventBudgetExhausted, operatorv1alpha1.EventCircuitOpen, operatorv1alpha1.EventRestartFailed:
+		severity := "warning"
+		switch {
+		case notification.Event != operatorv1alpha1.EventRestartFailed:
+			severity = "critical"
+		case notification.Persistent:
+			severity = "error"
+		}
+		return postJSON(ctx, n.httpClient, pagerDutyEnqueueURL, pagerDutyEvent{
+			RoutingKey:  n.routingKey,
+			EventAction: "trigger",
+			DedupKey:    source,
+			Payload: &pagerDutyPayload{
+				Summary:       pagerDutySummary(notification),
+				Source:        source,
+				Severity:      severity,
+				Timestamp:     timestamp,
+				Component:     notification.Pod,
+				Group:         notification.PodRestart.Namespace,
+				Class:         string(notification.Event),
+				CustomDetails: details,
+			},
+		})
+	case operatorv1alpha1.EventRecovered:
+		return postJSON(ctx, n.httpClient, pagerDutyEnqueueURL, pagerDutyEvent{
+			RoutingKey:  n.routingKey,
+			EventAction: "resolve",
+			DedupKey:    source,
+		})
+	default:
+		return postJSON(ctx, n.httpClient, pagerDutyChangeURL, pagerDutyChangeEvent{
+			RoutingKey: n.routingKey,
+			Payload: pagerDutyChangeEntry{
+				Summary:       pagerDutySummary(notification),
+				Source:        source,
+				Timestamp:     timestamp,
+				CustomDetails: details,
+			},
+		})
+	}
+}
+
+// pagerDutySummary builds the one line summary shown on the incident
+func pagerDutySummary(n Notification) string {
+	switch n.Event {
+	case operatorv1alpha1.EventBudgetExhausted:
+		return fmt.Sprintf("PodRestart %s exhausted its restart budget", n.PodRestart)
+	case operatorv1alpha1.EventCircuitOpen:
+		return fmt.Sprintf("PodRestart %s stopped restarting pods after repeated failures", n.PodRestart)
+	case operatorv1alpha1.EventRestartFailed:
+		return fmt.Sprintf("PodRestart %s failed to restart pod %s", n.PodRestart, n.Pod)
+	case operatorv1alpha1.EventRestartSkipped:
+		return fmt.Sprintf("PodRestart %s skipped restart of pod %s: %s", n.PodRestart, n.Pod, n.Reason)
+	default:
+		return fmt.Sprintf("PodRestart %s restarted pod %s: %s", n.PodRestart, n.Pod, n.Reason)
+	}
+}
+
+// notificationDetails flattens a notification into key/value details
+func notificationDetails(n Notification) map[string]string {
+	details := map[string]string{
+		"event":      string(n.Event),
+		"podRestart": n.PodRestart.String(),
+	}
+	if n.Pod != "" {
+		details["pod"] = n.Pod
+	}
+	if n.Workload != "" {
+		details["workload"] = n.Workload
+	}
+	if n.Trigger != "" {
+		details["trigger"] = n.Trigger
+	}
+	if n.ReasonCode != "" {
+		details["reasonCode"] = string(n.ReasonCode)
+	}
+	if n.Reason != "" {
+		details["reason"] = n.Reason
+	}
+	if n.Message != "" {
+		details["message"] = n.Message
+	}
+	if n.MatchedLine != "" {
+		details["matchedLine"] = n.MatchedLine
+	}
+	if n.MetricValue != nil {
+		details["metricValue"] = fmt.Sprintf("%g", *n.MetricValue)
+	}
+	return details
+}
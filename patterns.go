@@ -0,0 +1,96 @@
+// patterns.go
+package controllers
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/types"
+
+	operatorv1alpha1 "github.com/example/pod-restart-operator/api/v1alpha1"
+)
+
+// errorPattern is a log regex and the name it is reported under
+type errorPattern struct {
+	name    string
+	pattern string
+	re      *regexp.Regexp
+}
+
+// errorPatternsFor returns the unnamed and named error patterns of a
+// PodRestart. Unnamed patterns are reported under the regex itself.
+func errorPatternsFor(pr *operatorv1alpha1.PodRestart) []errorPattern {
+	patterns := make([]errorPattern, 0, len(pr.Spec.ErrorPatterns)+len(pr.Spec.NamedErrorPatterns))
+	for _, p := range pr.Spec.ErrorPatterns {
+		patterns = append(patterns, errorPattern{name: p, pattern: p})
+	}
+	for _, p := range pr.Spec.NamedErrorPatterns {
+		patterns = append(patterns, errorPattern{name: p.Name, pattern: p.Pattern})
+	}
+	return patterns
+}
+
+// errorPatternRegexes returns the regexes of all error patterns of a PodRestart
+func errorPatternRegexes(pr *operatorv1alpha1.PodRestart) []string {
+	var regexes []string
+	for _, p := range errorPatternsFor(pr) {
+		regexes = append(regexes, p.pattern)
+	}
+	return regexes
+}
+
+// compiledPatterns are the error patterns of one PodRestart generation
+type compiledPatterns struct {
+	generation int64
+	patterns   []errorPattern
+	err        error
+}
+
+// patternCache holds compiled error patterns per PodRestart so they are only
+// compiled again when the spec changes
+type patternCache struct {
+	mu      sync.Mutex
+	entries map[types.NamespacedName]compiledPatterns
+}
+
+func newPatternCache() *patternCache {
+	return &patternCache{entries: map[types.NamespacedName]compiledPatterns{}}
+}
+
+// get returns the compiled patterns of a PodRestart. Patterns that fail to
+// compile are left out and reported in the returned error.
+func (c *patternCache) get(pr *operatorv1alpha1.PodRestart) ([]errorPattern, error) {
+	key := types.NamespacedName{Namespace: pr.Namespace, Name: pr.Name}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if entry, ok := c.entries[key]; ok && entry.generation == pr.Generation {
+		return entry.patterns, entry.err
+	}
+
+	var compiled []errorPattern
+	var invalid []string
+	for _, p := range errorPatternsFor(pr) {
+		re, err := regexp.Compile(p.pattern)
+		if err != nil {
+			invalid = append(invalid, fmt.Sprintf("%s: %v", p.name, err))
+			continue
+		}
+		p.re = re
+		compiled = append(compiled, p)
+	}
+	entry := compiledPatterns{generation: pr.Generation, patterns: compiled}
+	if len(invalid) > 0 {
+		entry.err = fmt.Errorf("invalid error patterns: %s", strings.Join(invalid, "; "))
+	}
+	c.entries[key] = entry
+	return entry.patterns, entry.err
+}
+
+// forget drops the patterns of a deleted PodRestart
+func (c *patternCache) forget(key types.NamespacedName) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, key)
+}
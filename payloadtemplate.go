@@ -0,0 +1,61 @@
+// payloadtemplate.go
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"text/template"
+
+	"github.com/Masterminds/sprig/v3"
+
+	operatorv1alpha1 "github.com/example/pod-restart-operator/api/v1alpha1"
+)
+
+// payloadData is passed to a channel's payloadTemplate
+type payloadData struct {
+	Notification
+	// Channel is the name of the NotificationChannel being rendered
+	Channel string
+	// Spec and Status are those of the PodRestart that made the decision
+	Spec   operatorv1alpha1.PodRestartSpec
+	Status operatorv1alpha1.PodRestartStatus
+}
+
+// payloadTemplate renders a custom request body for a channel
+type payloadTemplate struct {
+	tmpl    *template.Template
+	channel string
+	pr      *operatorv1alpha1.PodRestart
+}
+
+// newPayloadTemplate parses the payloadTemplate of a channel. It returns nil
+// when the channel uses the default payload.
+func newPayloadTemplate(channel *operatorv1alpha1.NotificationChannel, pr *operatorv1alpha1.PodRestart) (*payloadTemplate, error) {
+	if channel.Spec.PayloadTemplate == "" {
+		return nil, nil
+	}
+	tmpl, err := template.New(channel.Name).Funcs(sprig.TxtFuncMap()).Parse(channel.Spec.PayloadTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("parsing payload template of channel %s: %w", channel.Name, err)
+	}
+	return &payloadTemplate{tmpl: tmpl, channel: channel.Name, pr: pr}, nil
+}
+
+// render executes the template and checks that the result is JSON
+func (p *payloadTemplate) render(n Notification) (json.RawMessage, error) {
+	var buf bytes.Buffer
+	err := p.tmpl.Execute(&buf, payloadData{
+		Notification: n,
+		Channel:      p.channel,
+		Spec:         p.pr.Spec,
+		Status:       p.pr.Status,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("rendering payload template of channel %s: %w", p.channel, err)
+	}
+	if !json.Valid(buf.Bytes()) {
+		return nil, fmt.Errorf("payload template of channel %s did not render valid JSON", p.channel)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,201 @@
+// podrestartdebug.go
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+
+	operatorv1alpha1 "github.com/example/pod-restart-operator/api/v1alpha1"
+)
+
+// debugPrefix is the path PodRestartDebugHandler is served below
+const debugPrefix = "/debug/podrestarts/"
+
+// lastPasses keeps what the most recent pass of each PodRestart ran into,
+// with provider errors in full rather than truncated as in status
+type lastPasses struct {
+	mu     sync.Mutex
+	passes map[types.NamespacedName]debugPass
+}
+
+// debugPass is the outcome of a PodRestart's most recent pass
+type debugPass struct {
+	Time            time.Time         `json:"time"`
+	DegradedReason  string            `json:"degradedReason,omitempty"`
+	DegradedMessage string            `json:"degradedMessage,omitempty"`
+	ProviderErrors  map[string]string `json:"providerErrors,omitempty"`
+	Restarted       []string          `json:"restarted,omitempty"`
+}
+
+func newLastPasses() *lastPasses {
+	return &lastPasses{passes: map[types.NamespacedName]debugPass{}}
+}
+
+// record remembers the outcome of a pass
+func (l *lastPasses) record(key types.NamespacedName, eval *evaluation, now time.Time) {
+	pass := debugPass{
+		Time:            now,
+		DegradedReason:  eval.degradedReason,
+		DegradedMessage: eval.degradedMessage,
+		Restarted:       eval.restarted,
+	}
+	if eval.stats != nil {
+		pass.ProviderErrors = eval.stats.lastProviderErrors()
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.passes[key] = pass
+}
+
+func (l *lastPasses) get(key types.NamespacedName) (debugPass, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	pass, ok := l.passes[key]
+	return pass, ok
+}
+
+// forget drops the pass of a deleted PodRestart
+func (l *lastPasses) forget(key types.NamespacedName) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.passes, key)
+}
+
+// debugPodRestartState is the in-memory view of a single PodRestart
+type debugPodRestartState struct {
+	Name string `json:"name"`
+	// Leader is false on replicas not holding the leader lease, which keep
+	// no evaluation state
+	Leader          bool                `json:"leader"`
+	PendingRestarts []inFlightRestart   `json:"pendingRestarts"`
+	RestartRetries  []debugRestartRetry `json:"restartRetries"`
+	Cooldowns       []debugCooldown     `json:"cooldowns"`
+	DegradedPasses  int                 `json:"degradedPasses"`
+	LogCursors      int                 `json:"logCursors"`
+	EvaluatedPods   int                 `json:"evaluatedPodsThisRound"`
+	FollowedStreams int                 `json:"followedStreams"`
+	EvaluationSlots int                 `json:"evaluationSlotsInUse"`
+	LastPass        *debugPass          `json:"lastPass,omitempty"`
+}
+
+// debugRestartRetry is a failed restart waiting for its retry
+type debugRestartRetry struct {
+	PodUID      types.UID `json:"podUID"`
+	Attempts    int       `json:"attempts"`
+	Permanent   bool      `json:"permanent,omitempty"`
+	LastAttempt time.Time `json:"lastAttempt"`
+	NextAttempt time.Time `json:"nextAttempt"`
+	Error       string    `json:"error"`
+}
+
+// debugCooldown is a cooldown that currently blocks restarts
+type debugCooldown struct {
+	Trigger string    `json:"trigger,omitempty"`
+	Name    string    `json:"name,omitempty"`
+	Until   time.Time `json:"until"`
+}
+
+// PodRestartDebugHandler serves the in-memory state of a single PodRestart
+// as JSON at /debug/podrestarts/{namespace}/{name}: restarts in progress and
+// waiting for a retry, active cooldowns, counters and the errors of the last
+// pass, and whether this replica is the leader, i.e. elected is closed. It
+// must only be served behind authorization of get on the PodRestart, see
+// PodRestartDebugPath.
+func (r *PodRestartReconciler) PodRestartDebugHandler(elected <-chan struct{}) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		key, ok := PodRestartDebugPath(req.URL.Path)
+		if !ok {
+			http.Error(w, "expected "+debugPrefix+"{namespace}/{name}", http.StatusBadRequest)
+			return
+		}
+
+		pr := &operatorv1alpha1.PodRestart{}
+		if err := r.Get(req.Context(), key, pr); err != nil {
+			status := http.StatusInternalServerError
+			if errors.IsNotFound(err) {
+				status = http.StatusNotFound
+			}
+			http.Error(w, err.Error(), status)
+			return
+		}
+		// Cooldowns inherited from a PodRestartPolicy count as well
+		if _, err := r.applyPolicy(req.Context(), pr); err != nil {
+			r.Log.Error(err, "Failed to resolve PodRestartPolicy for debug state", "podrestart", key)
+		}
+
+		state := debugPodRestartState{
+			Name:            key.String(),
+			Leader:          isClosed(elected),
+			PendingRestarts: []inFlightRestart{},
+			RestartRetries:  r.retries.snapshot(key),
+			Cooldowns:       r.activeCooldowns(pr, time.Now()),
+			DegradedPasses:  r.backoff.failuresOf(key),
+			LogCursors:      r.cursors.count(key),
+			EvaluatedPods:   r.rounds.count(key),
+			FollowedStreams: r.follower.streamsOf(key),
+			EvaluationSlots: r.scheduler.runningOf(key),
+		}
+		for _, restart := range r.inflight.list() {
+			if restart.PodRestart == key.String() {
+				state.PendingRestarts = append(state.PendingRestarts, restart)
+			}
+		}
+		if pass, ok := r.lastPasses.get(key); ok {
+			state.LastPass = &pass
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(state)
+	})
+}
+
+// PodRestartDebugPath returns the PodRestart a path below
+// /debug/podrestarts/ refers to
+func PodRestartDebugPath(path string) (types.NamespacedName, bool) {
+	if !strings.HasPrefix(path, debugPrefix) {
+		return types.NamespacedName{}, false
+	}
+	parts := strings.Split(strings.TrimPrefix(path, debugPrefix), "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return types.NamespacedName{}, false
+	}
+	return types.NamespacedName{Namespace: parts[0], Name: parts[1]}, true
+}
+
+func isClosed(ch <-chan struct{}) bool {
+	select {
+	case <-ch:
+		return true
+	default:
+		return false
+	}
+}
+
+// activeCooldowns returns the cooldowns of a PodRestart that have not
+// passed yet: the PodRestart's own and those of its triggers
+func (r *PodRestartReconciler) activeCooldowns(pr *operatorv1alpha1.PodRestart, now time.Time) []debugCooldown {
+	cooldowns := []debugCooldown{}
+	if cooldown := r.minTimeBetweenRestarts(pr); cooldown != nil && pr.Status.LastRestartTime != nil {
+		if until := pr.Status.LastRestartTime.Add(cooldown.Duration); until.After(now) {
+			cooldowns = append(cooldowns, debugCooldown{Until: until})
+		}
+	}
+	for _, t := range pr.Status.TriggerRestarts {
+		cooldown := triggerCooldown(pr, &triggerResult{Trigger: t.Trigger, Name: t.Name})
+		if cooldown == nil {
+			continue
+		}
+		if until := t.Time.Add(cooldown.Duration); until.After(now) {
+			cooldowns = append(cooldowns, debugCooldown{Trigger: t.Trigger, Name: t.Name, Until: until})
+		}
+	}
+	return cooldowns
+}
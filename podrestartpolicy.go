@@ -0,0 +1,145 @@
+// podrestartpolicy.go
+package controllers
+
+import (
+	"context"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	operatorv1alpha1 "github.com/example/pod-restart-operator/api/v1alpha1"
+)
+
+// policyFor returns the PodRestartPolicy applying to a namespace, or nil
+func (r *PodRestartReconciler) policyFor(ctx context.Context, namespace string) (*operatorv1alpha1.PodRestartPolicy, error) {
+	policies := &operatorv1alpha1.PodRestartPolicyList{}
+	if err := r.List(ctx, policies); err != nil {
+		return nil, err
+	}
+	if len(policies.Items) == 0 {
+		return nil, nil
+	}
+	ns := &corev1.Namespace{}
+	if err := r.Get(ctx, types.NamespacedName{Name: namespace}, ns); err != nil {
+		return nil, err
+	}
+
+	var matching []*operatorv1alpha1.PodRestartPolicy
+	for i := range policies.Items {
+		policy := &policies.Items[i]
+		selector, err := metav1.LabelSelectorAsSelector(&policy.Spec.NamespaceSelector)
+		if err != nil {
+			r.Log.Error(err, "Ignoring PodRestartPolicy with an invalid namespace selector", "policy", policy.Name)
+			continue
+		}
+		if selector.Matches(labels.Set(ns.Labels)) {
+			matching = append(matching, policy)
+		}
+	}
+	if len(matching) == 0 {
+		return nil, nil
+	}
+	sort.Slice(matching, func(i, j int) bool {
+		if matching[i].Spec.Priority != matching[j].Spec.Priority {
+			return matching[i].Spec.Priority > matching[j].Spec.Priority
+		}
+		return matching[i].Name < matching[j].Name
+	})
+	return matching[0], nil
+}
+
+// applyPolicy fills the fields the PodRestart leaves unset from the policy
+// whose namespace selector matches the labels of the PodRestart's namespace
+// and returns what was inherited, for status. The spec is only changed in
+// memory, never written back.
+func (r *PodRestartReconciler) applyPolicy(ctx context.Context, pr *operatorv1alpha1.PodRestart) (*operatorv1alpha1.AppliedPolicy, error) {
+	policy, err := r.policyFor(ctx, pr.Namespace)
+	if err != nil || policy == nil {
+		return nil, err
+	}
+	return applyPolicyDefaults(&pr.Spec, policy), nil
+}
+
+// namespaceSpec returns the spec the pods of a targeted namespace are
+// evaluated with. requested is the spec before any policy was applied. A
+// namespace under another policy than the PodRestart's own gets its own
+// policy's defaults, so targeting it from elsewhere does not escape them.
+func (r *PodRestartReconciler) namespaceSpec(ctx context.Context, pr *operatorv1alpha1.PodRestart, requested *operatorv1alpha1.PodRestartSpec, namespace string) (*operatorv1alpha1.PodRestartSpec, error) {
+	policy, err := r.policyFor(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+	own := pr.Status.Policy
+	if policy == nil && own == nil || policy != nil && own != nil && policy.Name == own.Name {
+		return &pr.Spec, nil
+	}
+	spec := requested.DeepCopy()
+	if policy != nil {
+		applyPolicyDefaults(spec, policy)
+	}
+	return spec, nil
+}
+
+// applyPolicyDefaults fills the fields of spec left unset from the policy
+func applyPolicyDefaults(spec *operatorv1alpha1.PodRestartSpec, policy *operatorv1alpha1.PodRestartPolicy) *operatorv1alpha1.AppliedPolicy {
+	applied := &operatorv1alpha1.AppliedPolicy{Name: policy.Name, EvaluationWeight: policy.Spec.EvaluationWeight}
+	defaults := policy.Spec.Defaults.DeepCopy()
+	inherit := func(field string, unset bool, apply func()) {
+		if unset {
+			apply()
+			applied.InheritedFields = append(applied.InheritedFields, field)
+		}
+	}
+	inherit("minTimeBetweenRestarts", spec.MinTimeBetweenRestarts == nil && defaults.MinTimeBetweenRestarts != nil, func() {
+		spec.MinTimeBetweenRestarts = defaults.MinTimeBetweenRestarts
+	})
+	inherit("checkInterval", spec.CheckInterval == nil && defaults.CheckInterval != nil, func() {
+		spec.CheckInterval = defaults.CheckInterval
+	})
+	inherit("restartBudget", spec.RestartBudget == nil && defaults.RestartBudget != nil, func() {
+		spec.RestartBudget = defaults.RestartBudget
+	})
+	inherit("notifications", spec.Notifications == nil && defaults.Notifications != nil, func() {
+		spec.Notifications = defaults.Notifications
+	})
+	inherit("autoscaling", spec.Autoscaling == nil && defaults.Autoscaling != nil, func() {
+		spec.Autoscaling = defaults.Autoscaling
+	})
+	inherit("gitOps", spec.GitOps == nil && defaults.GitOps != nil, func() {
+		spec.GitOps = defaults.GitOps
+	})
+
+	// Effective shows what the PodRestart runs with, set by itself or inherited
+	effective := operatorv1alpha1.PodRestartDefaults{
+		MinTimeBetweenRestarts: spec.MinTimeBetweenRestarts,
+		CheckInterval:          spec.CheckInterval,
+		RestartBudget:          spec.RestartBudget,
+		Notifications:          spec.Notifications,
+		Autoscaling:            spec.Autoscaling,
+		GitOps:                 spec.GitOps,
+	}
+	applied.Effective = *effective.DeepCopy()
+	return applied
+}
+
+// podRestartsForPolicy maps a PodRestartPolicy change to every PodRestart,
+// since a policy's selector may have stopped or started matching any namespace
+func (r *PodRestartReconciler) podRestartsForPolicy(ctx context.Context, obj client.Object) []reconcile.Request {
+	list := &operatorv1alpha1.PodRestartList{}
+	if err := r.List(ctx, list); err != nil {
+		r.Log.Error(err, "Failed to list PodRestarts for policy", "policy", obj.GetName())
+		return nil
+	}
+	requests := make([]reconcile.Request, 0, len(list.Items))
+	for _, pr := range list.Items {
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{Namespace: pr.Namespace, Name: pr.Name},
+		})
+	}
+	return requests
+}
@@ -0,0 +1,84 @@
+// podrestartpolicy_types.go
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// PodRestartPolicySpec holds defaults for the PodRestarts of the selected namespaces
+type PodRestartPolicySpec struct {
+	// NamespaceSelector selects the namespaces whose PodRestarts inherit the
+	// defaults. An empty selector selects every namespace.
+	// +optional
+	NamespaceSelector metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+
+	// Priority orders policies selecting the same namespace; the highest
+	// applies and ties go to the policy whose name sorts first
+	// +optional
+	Priority int32 `json:"priority,omitempty"`
+
+	// Defaults are applied to the fields a PodRestart leaves unset
+	Defaults PodRestartDefaults `json:"defaults"`
+
+	// EvaluationWeight is the share of the operator's evaluation workers of
+	// each selected PodRestart while several PodRestarts compete for them. A
+	// PodRestart of weight 2 gets twice the workers of one of weight 1.
+	// PodRestarts cannot set it themselves.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=100
+	// +optional
+	EvaluationWeight *int32 `json:"evaluationWeight,omitempty"`
+}
+
+// PodRestartDefaults are the PodRestart fields a policy can default
+type PodRestartDefaults struct {
+	// +kubebuilder:validation:Format=duration
+	// +optional
+	MinTimeBetweenRestarts *metav1.Duration `json:"minTimeBetweenRestarts,omitempty"`
+
+	// +kubebuilder:validation:Format=duration
+	// +optional
+	CheckInterval *metav1.Duration `json:"checkInterval,omitempty"`
+
+	// +optional
+	RestartBudget *RestartBudget `json:"restartBudget,omitempty"`
+
+	// +optional
+	Notifications *NotificationSpec `json:"notifications,omitempty"`
+
+	// +optional
+	Autoscaling *AutoscalingSpec `json:"autoscaling,omitempty"`
+
+	// +optional
+	GitOps *GitOpsSpec `json:"gitOps,omitempty"`
+}
+
+// +genclient
+// +genclient:nonNamespaced
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster,categories=remediation
+// +kubebuilder:printcolumn:name="Priority",type=integer,JSONPath=`.spec.priority`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// PodRestartPolicy is the Schema for the podrestartpolicies API. It lets
+// platform teams set defaults, such as restart budgets and cooldowns, for
+// every PodRestart in the namespaces it selects.
+type PodRestartPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec PodRestartPolicySpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// PodRestartPolicyList contains a list of PodRestartPolicy
+type PodRestartPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []PodRestartPolicy `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&PodRestartPolicy{}, &PodRestartPolicyList{})
+}
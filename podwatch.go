@@ -0,0 +1,128 @@
+// podwatch.go
+package controllers
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	operatorv1alpha1 "github.com/example/pod-restart-operator/api/v1alpha1"
+)
+
+const (
+	// podSelectorIndex indexes PodRestarts by the key=value terms of their
+	// pod selector's matchLabels
+	podSelectorIndex = "spec.podSelector.matchLabels"
+
+	// anySelectorTerm indexes PodRestarts without matchLabels, which have to
+	// be considered for every pod in their namespace
+	anySelectorTerm = "*"
+)
+
+// podSelectorTerms is the IndexerFunc for podSelectorIndex
+func podSelectorTerms(obj client.Object) []string {
+	pr, ok := obj.(*operatorv1alpha1.PodRestart)
+	if !ok {
+		return nil
+	}
+	if len(pr.Spec.PodSelector.MatchLabels) == 0 {
+		return []string{anySelectorTerm}
+	}
+	terms := make([]string, 0, len(pr.Spec.PodSelector.MatchLabels))
+	for k, v := range pr.Spec.PodSelector.MatchLabels {
+		terms = append(terms, k+"="+v)
+	}
+	return terms
+}
+
+// podRestartsForPod maps a pod to the PodRestarts whose selector and owner
+// filter match it, in its namespace or listing it in spec.namespaces. Candidates are looked up in podSelectorIndex by the
+// pod's labels, so only PodRestarts sharing a label with the pod are matched.
+func (r *PodRestartReconciler) podRestartsForPod(ctx context.Context, obj client.Object) []reconcile.Request {
+	terms := []string{anySelectorTerm}
+	for k, v := range obj.GetLabels() {
+		terms = append(terms, k+"="+v)
+	}
+
+	podLabels := labels.Set(obj.GetLabels())
+	seen := map[string]bool{}
+	var requests []reconcile.Request
+	for _, term := range terms {
+		list := &operatorv1alpha1.PodRestartList{}
+		if err := r.List(ctx, list, client.InNamespace(obj.GetNamespace()), client.MatchingFields{podSelectorIndex: term}); err != nil {
+			r.Log.Error(err, "Failed to list PodRestarts for pod", "pod", obj.GetName())
+			return nil
+		}
+		for _, pr := range list.Items {
+			if seen[pr.Name] {
+				continue
+			}
+			seen[pr.Name] = true
+			if podRestartSelects(&pr, obj, podLabels) {
+				requests = append(requests, reconcile.Request{
+					NamespacedName: types.NamespacedName{Namespace: pr.Namespace, Name: pr.Name},
+				})
+			}
+		}
+	}
+	return append(requests, r.podRestartsTargeting(ctx, obj, podLabels)...)
+}
+
+// podRestartSelects reports whether the PodRestart's selector and owner
+// filter match a pod
+func podRestartSelects(pr *operatorv1alpha1.PodRestart, obj client.Object, podLabels labels.Set) bool {
+	selector, err := metav1.LabelSelectorAsSelector(&pr.Spec.PodSelector)
+	if err != nil || !selector.Matches(podLabels) {
+		return false
+	}
+	if pod, ok := obj.(*corev1.Pod); ok {
+		if owners, err := newOwnerMatcher(pr); err == nil && !owners.matches(pod) {
+			return false
+		}
+	}
+	return true
+}
+
+// podStateChanged ignores pod updates that cannot change an evaluation, such
+// as condition heartbeats and annotation changes. Label changes move pods in
+// and out of selectors, the status fields are what the reconciler acts on.
+var podStateChanged = predicate.Or(predicate.LabelChangedPredicate{}, podStatusChanged)
+
+// podStatusChanged passes pod updates that change the phase, deletion or
+// container state of a pod
+var podStatusChanged = predicate.Funcs{
+	UpdateFunc: func(e event.UpdateEvent) bool {
+		oldPod, ok := e.ObjectOld.(*corev1.Pod)
+		if !ok {
+			return true
+		}
+		newPod, ok := e.ObjectNew.(*corev1.Pod)
+		if !ok {
+			return true
+		}
+		return podStateDiffers(oldPod, newPod)
+	},
+}
+
+// podStateDiffers compares the parts of a pod the reconciler acts on
+func podStateDiffers(oldPod, newPod *corev1.Pod) bool {
+	if oldPod.Status.Phase != newPod.Status.Phase ||
+		(oldPod.DeletionTimestamp == nil) != (newPod.DeletionTimestamp == nil) ||
+		len(oldPod.Status.ContainerStatuses) != len(newPod.Status.ContainerStatuses) {
+		return true
+	}
+	for i, status := range newPod.Status.ContainerStatuses {
+		old := oldPod.Status.ContainerStatuses[i]
+		if old.RestartCount != status.RestartCount || old.Ready != status.Ready {
+			return true
+		}
+	}
+	return false
+}
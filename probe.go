@@ -0,0 +1,141 @@
+// probe.go
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	operatorv1alpha1 "github.com/example/pod-restart-operator/api/v1alpha1"
+)
+
+const (
+	// maxProbeOutput bounds the output of the commands run by exec based triggers
+	maxProbeOutput = 64 * 1024
+
+	// probeFailureRepeat is how long a probe failing the same way stays
+	// quiet before its event is emitted again. Failures of pods that were
+	// not probed for as long are dropped.
+	probeFailureRepeat = time.Hour
+)
+
+// errExecTriggersDisabled is returned by exec based triggers while the
+// ExecTriggers feature gate is off
+var errExecTriggersDisabled = fmt.Errorf("exec based triggers are disabled, enable the %s feature gate to run them", ExecTriggers)
+
+// execProbe runs the command of an exec based trigger in a container of the
+// target cluster and returns its trimmed stdout
+func (r *PodRestartReconciler) execProbe(ctx context.Context, cluster *clusterTarget, pod *corev1.Pod, container string, command []string, timeout time.Duration) (string, error) {
+	if !r.featureEnabled(ExecTriggers) {
+		return "", errExecTriggersDisabled
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	out, err := execInContainer(ctx, cluster.config, cluster.clientset, pod, container, command, maxProbeOutput)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// containerRunning reports whether the named container of the pod is running,
+// since commands cannot be executed in any other state
+func containerRunning(pod *corev1.Pod, container string) bool {
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.Name == container {
+			return status.State.Running != nil
+		}
+	}
+	return false
+}
+
+// probeFailures remembers the failing probes of each PodRestart, so a probe
+// failing the same way on every pass emits a single ProbeFailed event
+type probeFailures struct {
+	mu       sync.Mutex
+	failures map[types.NamespacedName]map[probeKey]*probeFailure
+}
+
+// probeKey identifies a probe of a pod, e.g. the zombie count of a container
+type probeKey struct {
+	uid   types.UID
+	probe string
+}
+
+type probeFailure struct {
+	message string
+	emitted time.Time
+	seen    time.Time
+}
+
+func newProbeFailures() *probeFailures {
+	return &probeFailures{failures: map[types.NamespacedName]map[probeKey]*probeFailure{}}
+}
+
+// failed records a failed probe and reports whether its event is due: the
+// probe was passing, fails differently, or last reported probeFailureRepeat ago
+func (p *probeFailures) failed(key types.NamespacedName, pk probeKey, message string, now time.Time) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	probes := p.failures[key]
+	if probes == nil {
+		probes = map[probeKey]*probeFailure{}
+		p.failures[key] = probes
+	}
+	for k, f := range probes {
+		if now.Sub(f.seen) > probeFailureRepeat {
+			delete(probes, k)
+		}
+	}
+	f := probes[pk]
+	if f == nil {
+		f = &probeFailure{}
+		probes[pk] = f
+	}
+	f.seen = now
+	if f.message == message && now.Sub(f.emitted) < probeFailureRepeat {
+		return false
+	}
+	f.message = message
+	f.emitted = now
+	return true
+}
+
+// succeeded clears the failure of a probe that passed again
+func (p *probeFailures) succeeded(key types.NamespacedName, pk probeKey) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if probes := p.failures[key]; probes != nil {
+		delete(probes, pk)
+		if len(probes) == 0 {
+			delete(p.failures, key)
+		}
+	}
+}
+
+// forget drops the failures of a deleted PodRestart
+func (p *probeFailures) forget(key types.NamespacedName) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.failures, key)
+}
+
+// probeFailed logs a failed probe and emits a ProbeFailed event on the
+// PodRestart unless the probe already reported the same error
+func (r *PodRestartReconciler) probeFailed(pr *operatorv1alpha1.PodRestart, pod *corev1.Pod, probe string, err error, messageFmt string, args ...interface{}) {
+	r.Log.Error(err, "Probe failed", "pod", pod.Name, "probe", probe)
+	key := types.NamespacedName{Namespace: pr.Namespace, Name: pr.Name}
+	if r.probes.failed(key, probeKey{uid: pod.UID, probe: probe}, err.Error(), time.Now()) {
+		r.Recorder.Eventf(pr, corev1.EventTypeWarning, EventReasonProbeFailed, messageFmt+": %v", append(args, err)...)
+	}
+}
+
+// probeSucceeded clears the failure of a probe that passed
+func (r *PodRestartReconciler) probeSucceeded(pr *operatorv1alpha1.PodRestart, pod *corev1.Pod, probe string) {
+	r.probes.succeeded(types.NamespacedName{Namespace: pr.Namespace, Name: pr.Name}, probeKey{uid: pod.UID, probe: probe})
+}
@@ -0,0 +1,49 @@
+// promqlscope.go
+package controllers
+
+import (
+	"fmt"
+
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql/parser"
+
+	operatorv1alpha1 "github.com/example/pod-restart-operator/api/v1alpha1"
+)
+
+// defaultNamespaceLabel is the series label holding the namespace when the
+// provider does not name another
+const defaultNamespaceLabel = "namespace"
+
+// scopeQuery restricts every series selector of a PromQL query to the
+// namespace of the evaluated pod, so a PodRestart cannot read the metrics of
+// other namespaces through a shared MetricProvider. A matcher on the
+// namespace label the query sets itself is replaced.
+func scopeQuery(provider *operatorv1alpha1.MetricProvider, query, namespace string) (string, error) {
+	label := provider.Spec.NamespaceLabel
+	if label == "" {
+		label = defaultNamespaceLabel
+	}
+	expr, err := parser.ParseExpr(query)
+	if err != nil {
+		return "", fmt.Errorf("parsing query: %w", err)
+	}
+	matcher, err := labels.NewMatcher(labels.MatchEqual, label, namespace)
+	if err != nil {
+		return "", err
+	}
+	parser.Inspect(expr, func(node parser.Node, _ []parser.Node) error {
+		selector, ok := node.(*parser.VectorSelector)
+		if !ok {
+			return nil
+		}
+		matchers := []*labels.Matcher{matcher}
+		for _, m := range selector.LabelMatchers {
+			if m.Name != label {
+				matchers = append(matchers, m)
+			}
+		}
+		selector.LabelMatchers = matchers
+		return nil
+	})
+	return expr.String(), nil
+}
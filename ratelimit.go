@@ -0,0 +1,70 @@
+// ratelimit.go
+package controllers
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+
+	"golang.org/x/time/rate"
+)
+
+// namespaceRateLimiter holds one token bucket per namespace, so the API
+// requests of one namespace's large evaluation pass queue behind each other
+// instead of taking the operator's whole client budget
+type namespaceRateLimiter struct {
+	mu       sync.Mutex
+	qps      rate.Limit
+	burst    int
+	limiters map[string]*rate.Limiter
+}
+
+func (l *namespaceRateLimiter) limiter(namespace string) *rate.Limiter {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	limiter, ok := l.limiters[namespace]
+	if !ok {
+		limiter = rate.NewLimiter(l.qps, l.burst)
+		l.limiters[namespace] = limiter
+	}
+	return limiter
+}
+
+// NamespaceRateLimiter returns a rest.Config transport wrapper limiting the
+// requests against each namespace to qps, with bursts of burst. Requests
+// for cluster-scoped objects and leader election leases are not limited.
+func NamespaceRateLimiter(qps float64, burst int) func(http.RoundTripper) http.RoundTripper {
+	if burst <= 0 {
+		burst = 1
+	}
+	limits := &namespaceRateLimiter{qps: rate.Limit(qps), burst: burst, limiters: map[string]*rate.Limiter{}}
+	return func(rt http.RoundTripper) http.RoundTripper {
+		return &namespaceRoundTripper{next: rt, limits: limits}
+	}
+}
+
+type namespaceRoundTripper struct {
+	next   http.RoundTripper
+	limits *namespaceRateLimiter
+}
+
+func (t *namespaceRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if namespace := requestNamespace(req.URL.Path); namespace != "" && !strings.Contains(req.URL.Path, "/leases") {
+		if err := t.limits.limiter(namespace).Wait(req.Context()); err != nil {
+			return nil, err
+		}
+	}
+	return t.next.RoundTrip(req)
+}
+
+// requestNamespace extracts the namespace from an API path such as
+// /api/v1/namespaces/team-a/pods or /apis/apps/v1/namespaces/team-a/deployments
+func requestNamespace(path string) string {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	for i := 0; i+2 < len(parts); i++ {
+		if parts[i] == "namespaces" {
+			return parts[i+1]
+		}
+	}
+	return ""
+}
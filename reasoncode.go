@@ -0,0 +1,46 @@
+// reasoncode.go
+package controllers
+
+import (
+	corev1 "k8s.io/api/core/v1"
+
+	operatorv1alpha1 "github.com/example/pod-restart-operator/api/v1alpha1"
+)
+
+// AnnotationReasonCode carries the restart reason code on the events of a restart
+const AnnotationReasonCode = "operator.example.com/reason-code"
+
+// triggerReasonCodes maps each trigger kind to its reason code
+var triggerReasonCodes = map[string]operatorv1alpha1.RestartReasonCode{
+	TriggerErrorPattern:    operatorv1alpha1.ReasonLogPatternMatched,
+	TriggerMetricCondition: operatorv1alpha1.ReasonMetricThresholdBreached,
+	TriggerZombieProcesses: operatorv1alpha1.ReasonZombieProcesses,
+	TriggerConnectionCount: operatorv1alpha1.ReasonConnectionCountOutOfBounds,
+	TriggerHeartbeatFile:   operatorv1alpha1.ReasonHeartbeatStale,
+	TriggerBurnRate:        operatorv1alpha1.ReasonErrorBudgetBurn,
+	TriggerJVM:             operatorv1alpha1.ReasonJVMHeapExhausted,
+	TriggerGroup:           operatorv1alpha1.ReasonTriggerGroupMatched,
+	TriggerExitCode:        operatorv1alpha1.ReasonContainerExitCode,
+	TriggerForbiddenImage:  operatorv1alpha1.ReasonForbiddenImage,
+	TriggerManual:          operatorv1alpha1.ReasonManual,
+	TriggerAlert:           operatorv1alpha1.ReasonAlertFiring,
+}
+
+// reasonCode returns the reason code of the trigger that produced a result
+func (t *triggerResult) reasonCode() operatorv1alpha1.RestartReasonCode {
+	if t.Code != "" {
+		return t.Code
+	}
+	return triggerReasonCodes[t.Trigger]
+}
+
+// terminationReasonCode returns the reason code of a container termination
+// matched by an exit code condition. Exit code 137 alone only means SIGKILL,
+// which failed liveness probes and expired grace periods end in as well, so
+// only the kubelet's OOMKilled reason counts.
+func terminationReasonCode(terminated *corev1.ContainerStateTerminated) operatorv1alpha1.RestartReasonCode {
+	if terminated.Reason == oomKilledReason {
+		return operatorv1alpha1.ReasonOOMKilled
+	}
+	return operatorv1alpha1.ReasonContainerExitCode
+}
@@ -0,0 +1,237 @@
+// remotecluster.go
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	operatorv1alpha1 "github.com/example/pod-restart-operator/api/v1alpha1"
+)
+
+// defaultKubeconfigKey is used when spec.cluster.key is not set
+const defaultKubeconfigKey = "kubeconfig"
+
+// clusterTarget is the cluster whose pods a PodRestart evaluates and
+// restarts. reader, writer, clientset, config and secrets act on behalf of
+// the PodRestart and are impersonated when impersonation is configured.
+type clusterTarget struct {
+	// reader reads live from the API server, e.g. the pod checked right
+	// before it is deleted
+	reader client.Reader
+	// cachedPods lists the targeted pods of the local cluster from the
+	// manager's cache; when nil reader lists them a page at a time
+	cachedPods client.Reader
+	// writer deletes pods
+	writer client.Writer
+	// identity is the user pods are deleted as when it is not the operator's
+	identity string
+	// identityKey is identity together with its groups, scoping what is
+	// shared between PodRestarts to those acting as the same user
+	identityKey string
+	clientset   kubernetes.Interface
+	config      *rest.Config
+	// secrets reads the Secrets the PodRestart refers to in its own
+	// namespace of the local cluster, e.g. upload credentials
+	secrets client.Reader
+	// lookups reads the workloads, autoscalers, nodes and GitOps sources
+	// behind the operator's own safety checks, always as the operator
+	lookups client.Reader
+	// namespace is the namespace of the targeted pods
+	namespace string
+	remote    bool
+}
+
+// remoteCluster is a set of clients for a remote cluster, built from a
+// kubeconfig Secret
+type remoteCluster struct {
+	resourceVersion string
+	client          client.Client
+	clientset       kubernetes.Interface
+	config          *rest.Config
+}
+
+// remoteClusters caches the clients of remote clusters per kubeconfig
+// Secret. Clients are rebuilt when the Secret changes, and dropped once the
+// Secret is gone or no PodRestart uses it, so tenant credentials do not
+// outlive them.
+type remoteClusters struct {
+	mu       sync.Mutex
+	clusters map[types.NamespacedName]*remoteCluster
+	// users maps each PodRestart to the kubeconfig Secret it last used
+	users map[types.NamespacedName]types.NamespacedName
+}
+
+func newRemoteClusters() *remoteClusters {
+	return &remoteClusters{
+		clusters: map[types.NamespacedName]*remoteCluster{},
+		users:    map[types.NamespacedName]types.NamespacedName{},
+	}
+}
+
+// clusterFor returns the cluster targeted by the PodRestart: the local one
+// unless spec.cluster is set
+func (r *PodRestartReconciler) clusterFor(ctx context.Context, pr *operatorv1alpha1.PodRestart) (*clusterTarget, error) {
+	user := types.NamespacedName{Namespace: pr.Namespace, Name: pr.Name}
+	ref := pr.Spec.Cluster
+	if ref == nil {
+		r.remotes.forget(user)
+		return &clusterTarget{
+			reader:     r.apiReader,
+			cachedPods: r.Client,
+			writer:     r.Client,
+			clientset:  r.clientset,
+			config:     r.restConfig,
+			secrets:    r.Client,
+			lookups:    r.apiReader,
+			namespace:  pr.Namespace,
+		}, nil
+	}
+	if !r.featureEnabled(MultiCluster) {
+		return nil, fmt.Errorf("spec.cluster requires the %s feature gate", MultiCluster)
+	}
+
+	// The kubeconfig is only ever read from the PodRestart's own namespace
+	key := types.NamespacedName{Namespace: pr.Namespace, Name: ref.SecretName}
+	secret := &corev1.Secret{}
+	if err := r.Get(ctx, key, secret); err != nil {
+		if errors.IsNotFound(err) {
+			r.remotes.evict(key)
+		}
+		return nil, fmt.Errorf("getting kubeconfig secret %s: %w", key, err)
+	}
+	remote, err := r.remotes.get(user, key, secret, ref, r.restConfig, r.ClusterServers)
+	if err != nil {
+		return nil, err
+	}
+
+	return &clusterTarget{
+		reader:    remote.client,
+		writer:    remote.client,
+		clientset: remote.clientset,
+		config:    remote.config,
+		secrets:   r.Client,
+		lookups:   remote.client,
+		namespace: defaultTargetNamespace(pr),
+		remote:    true,
+	}, nil
+}
+
+// get returns the clients for the kubeconfig in secret used by the
+// PodRestart user, building them when the Secret is new or changed. The
+// kubeconfig's server must match one of servers.
+func (c *remoteClusters) get(user, key types.NamespacedName, secret *corev1.Secret, ref *operatorv1alpha1.ClusterReference, local *rest.Config, servers []string) (*remoteCluster, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if previous, ok := c.users[user]; ok && previous != key {
+		delete(c.users, user)
+		c.dropUnusedLocked(previous)
+	}
+	c.users[user] = key
+	if remote, ok := c.clusters[key]; ok && remote.resourceVersion == secret.ResourceVersion {
+		return remote, nil
+	}
+
+	dataKey := ref.Key
+	if dataKey == "" {
+		dataKey = defaultKubeconfigKey
+	}
+	kubeconfig, ok := secret.Data[dataKey]
+	if !ok {
+		return nil, fmt.Errorf("kubeconfig secret %s has no key %q", key, dataKey)
+	}
+	parsed, err := clientcmd.Load(kubeconfig)
+	if err != nil {
+		return nil, fmt.Errorf("parsing kubeconfig from secret %s: %w", key, err)
+	}
+	if err := checkKubeconfig(parsed); err != nil {
+		return nil, fmt.Errorf("kubeconfig from secret %s: %w", key, err)
+	}
+	config, err := clientcmd.NewDefaultClientConfig(*parsed, &clientcmd.ConfigOverrides{}).ClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("parsing kubeconfig from secret %s: %w", key, err)
+	}
+	if !matchesAny(config.Host, servers) {
+		return nil, fmt.Errorf("kubeconfig from secret %s: server %s is not allowed by the operator's --cluster-servers", key, config.Host)
+	}
+	// Remote clusters get the same client side rate limits as the local one
+	config.QPS, config.Burst = local.QPS, local.Burst
+
+	cl, err := client.New(config, client.Options{Scheme: clientgoscheme.Scheme})
+	if err != nil {
+		return nil, fmt.Errorf("building client for secret %s: %w", key, err)
+	}
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("building clientset for secret %s: %w", key, err)
+	}
+	remote := &remoteCluster{
+		resourceVersion: secret.ResourceVersion,
+		client:          cl,
+		clientset:       clientset,
+		config:          config,
+	}
+	c.clusters[key] = remote
+	return remote, nil
+}
+
+// evict drops the clients built from a kubeconfig Secret, e.g. once it was deleted
+func (c *remoteClusters) evict(key types.NamespacedName) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.clusters, key)
+}
+
+// forget drops the PodRestart's use of its kubeconfig Secret, and the
+// clients of that Secret when no other PodRestart uses them
+func (c *remoteClusters) forget(user types.NamespacedName) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if key, ok := c.users[user]; ok {
+		delete(c.users, user)
+		c.dropUnusedLocked(key)
+	}
+}
+
+func (c *remoteClusters) dropUnusedLocked(key types.NamespacedName) {
+	for _, used := range c.users {
+		if used == key {
+			return
+		}
+	}
+	delete(c.clusters, key)
+}
+
+// checkKubeconfig rejects kubeconfigs that make the operator do more than
+// send inline credentials. Tenants control the Secret: exec plugins and
+// auth providers would run code or fetch tokens inside the operator pod,
+// and file references would read its local files, such as its own
+// ServiceAccount token.
+func checkKubeconfig(config *clientcmdapi.Config) error {
+	for name, auth := range config.AuthInfos {
+		switch {
+		case auth.Exec != nil:
+			return fmt.Errorf("user %q uses an exec credential plugin, only inline credentials are supported", name)
+		case auth.AuthProvider != nil:
+			return fmt.Errorf("user %q uses an auth provider, only inline credentials are supported", name)
+		case auth.TokenFile != "" || auth.ClientCertificate != "" || auth.ClientKey != "":
+			return fmt.Errorf("user %q references credential files, only inline credentials are supported", name)
+		}
+	}
+	for name, cluster := range config.Clusters {
+		if cluster.CertificateAuthority != "" {
+			return fmt.Errorf("cluster %q references a certificate authority file, use certificate-authority-data", name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+// restartrequest.go
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	operatorv1alpha1 "github.com/example/pod-restart-operator/api/v1alpha1"
+)
+
+const (
+	// AnnotationRestartRequests holds the restarts requested for the pods of
+	// a PodRestart through the inbound trigger API, as a JSON list. They are
+	// kept on the PodRestart rather than in memory, so the leader acts on
+	// requests whichever replica received them.
+	AnnotationRestartRequests = "operator.example.com/restart-requests"
+
+	// TriggerManual is recorded when a restart was requested through the
+	// inbound trigger API
+	TriggerManual = "Manual"
+
+	// TriggerAlert is recorded when a firing Alertmanager alert requested the restart
+	TriggerAlert = "Alert"
+
+	// restartRequestExpiry is how long a request for a pod not restarted,
+	// e.g. because it is not selected, is kept
+	restartRequestExpiry = 10 * time.Minute
+
+	// maxRestartRequests bounds the requests kept on a PodRestart
+	maxRestartRequests = 50
+)
+
+// RestartRequest asks for a pod selected by a PodRestart to be restarted.
+// It applies to the pod as long as the pod was created before the request,
+// so a StatefulSet pod keeping its name is not restarted again.
+type RestartRequest struct {
+	// Namespace is the namespace of the pod. Defaults to the namespace the
+	// PodRestart targets without spec.namespaces.
+	Namespace string `json:"namespace,omitempty"`
+	Pod       string `json:"pod"`
+	// Trigger is TriggerManual or TriggerAlert, Name the caller or alert
+	Trigger string    `json:"trigger"`
+	Name    string    `json:"name,omitempty"`
+	Reason  string    `json:"reason,omitempty"`
+	Time    time.Time `json:"time"`
+}
+
+// restartRequested passes updates of a PodRestart's restart requests, which
+// leave its generation as it is
+var restartRequested = predicate.Funcs{
+	UpdateFunc: func(e event.UpdateEvent) bool {
+		return e.ObjectOld.GetAnnotations()[AnnotationRestartRequests] != e.ObjectNew.GetAnnotations()[AnnotationRestartRequests]
+	},
+}
+
+// RequestRestarts records restart requests on a PodRestart. The caller is
+// expected to be authorized to trigger the PodRestart already. Requests for
+// pods of namespaces the PodRestart does not target are dropped.
+func (r *PodRestartReconciler) RequestRestarts(ctx context.Context, key types.NamespacedName, requests []RestartRequest) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		pr := &operatorv1alpha1.PodRestart{}
+		if err := r.apiReader.Get(ctx, key, pr); err != nil {
+			return err
+		}
+		targeted := map[string]bool{}
+		for _, namespace := range targetNamespaces(pr, &clusterTarget{namespace: defaultTargetNamespace(pr)}) {
+			targeted[namespace] = true
+		}
+		now := time.Now()
+		var pending []RestartRequest
+		for _, req := range requests {
+			if req.Namespace == "" {
+				req.Namespace = defaultTargetNamespace(pr)
+			}
+			if targeted[req.Namespace] {
+				pending = append(pending, req)
+			}
+		}
+		if len(pending) == 0 {
+			return nil
+		}
+		added := len(pending)
+		for _, req := range restartRequests(pr) {
+			if now.Sub(req.Time) < restartRequestExpiry && !requestsPod(pending[:added], requestNamespace(pr, req), req.Pod) {
+				pending = append(pending, req)
+			}
+		}
+		if len(pending) > maxRestartRequests {
+			pending = pending[:maxRestartRequests]
+		}
+		data, err := json.Marshal(pending)
+		if err != nil {
+			return err
+		}
+
+		patch := client.MergeFromWithOptions(pr.DeepCopy(), client.MergeFromWithOptimisticLock{})
+		if pr.Annotations == nil {
+			pr.Annotations = map[string]string{}
+		}
+		pr.Annotations[AnnotationRestartRequests] = string(data)
+		return r.Patch(ctx, pr, patch, client.FieldOwner(FieldManager))
+	})
+}
+
+func requestsPod(requests []RestartRequest, namespace, pod string) bool {
+	for _, req := range requests {
+		if req.Namespace == namespace && req.Pod == pod {
+			return true
+		}
+	}
+	return false
+}
+
+// defaultTargetNamespace is the namespace a PodRestart targets without
+// spec.namespaces: that of spec.cluster, or its own
+func defaultTargetNamespace(pr *operatorv1alpha1.PodRestart) string {
+	if ref := pr.Spec.Cluster; ref != nil && ref.Namespace != "" {
+		return ref.Namespace
+	}
+	return pr.Namespace
+}
+
+// requestNamespace is the namespace of the pod a request applies to.
+// Requests recorded without one apply to the default target namespace.
+func requestNamespace(pr *operatorv1alpha1.PodRestart, req RestartRequest) string {
+	if req.Namespace == "" {
+		return defaultTargetNamespace(pr)
+	}
+	return req.Namespace
+}
+
+// restartRequests returns the restart requests recorded on a PodRestart.
+// An annotation that does not parse holds no requests.
+func restartRequests(pr *operatorv1alpha1.PodRestart) []RestartRequest {
+	data, ok := pr.Annotations[AnnotationRestartRequests]
+	if !ok {
+		return nil
+	}
+	var requests []RestartRequest
+	if err := json.Unmarshal([]byte(data), &requests); err != nil {
+		return nil
+	}
+	return requests
+}
+
+// requestedRestart returns a result for a pending restart request for the
+// pod, or nil when there is none
+func requestedRestart(pr *operatorv1alpha1.PodRestart, pod *corev1.Pod, now time.Time) *triggerResult {
+	for _, req := range restartRequests(pr) {
+		if req.Pod != pod.Name || requestNamespace(pr, req) != pod.Namespace ||
+			now.Sub(req.Time) >= restartRequestExpiry || !pod.CreationTimestamp.Time.Before(req.Time) {
+			continue
+		}
+		reason := req.Reason
+		if reason == "" {
+			reason = fmt.Sprintf("Restart requested by %s", req.Name)
+		}
+		return &triggerResult{Trigger: req.Trigger, Name: req.Name, Reason: reason}
+	}
+	return nil
+}
@@ -0,0 +1,201 @@
+// restartretry.go
+package controllers
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	operatorv1alpha1 "github.com/example/pod-restart-operator/api/v1alpha1"
+)
+
+const (
+	// restartRetryBase is the wait before the first retry of a failed restart
+	restartRetryBase = 10 * time.Second
+	// restartRetryMax caps the wait between retries, and is used right away
+	// for failures that will not go away on their own
+	restartRetryMax = 5 * time.Minute
+	// persistentRestartFailures is the number of consecutive failed attempts
+	// after which the PodRestart reports itself Degraded between retries
+	persistentRestartFailures = 3
+	// restartRetryForget drops failures of pods not retried for this long,
+	// which are gone or no longer trigger
+	restartRetryForget = 30 * time.Minute
+)
+
+// restartFailure is the retry state of a pod whose restart failed
+type restartFailure struct {
+	attempts  int
+	permanent bool
+	last      time.Time
+	next      time.Time
+	err       string
+}
+
+// persistent reports whether the failure needs a human to look at it
+func (f *restartFailure) persistent() bool {
+	return f.permanent || f.attempts >= persistentRestartFailures
+}
+
+// restartRetries tracks failed restarts per PodRestart and pod, so a pod
+// whose delete failed is retried with exponential backoff instead of on
+// every pass
+type restartRetries struct {
+	mu       sync.Mutex
+	failures map[types.NamespacedName]map[types.UID]*restartFailure
+}
+
+func newRestartRetries() *restartRetries {
+	return &restartRetries{failures: map[types.NamespacedName]map[types.UID]*restartFailure{}}
+}
+
+// permanentRestartError reports whether a failed restart will fail the same
+// way until someone changes RBAC, admission policy or the pod itself
+func permanentRestartError(err error) bool {
+	return errors.IsForbidden(err) || errors.IsUnauthorized(err) || errors.IsInvalid(err) ||
+		errors.IsBadRequest(err) || errors.IsMethodNotSupported(err)
+}
+
+// failed records a failed restart attempt and returns the pod's retry state
+func (r *restartRetries) failed(key types.NamespacedName, uid types.UID, err error, now time.Time) *restartFailure {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	pods := r.failures[key]
+	if pods == nil {
+		pods = map[types.UID]*restartFailure{}
+		r.failures[key] = pods
+	}
+	f := pods[uid]
+	if f == nil {
+		f = &restartFailure{}
+		pods[uid] = f
+	}
+	f.attempts++
+	f.permanent = permanentRestartError(err)
+	f.last = now
+	f.err = err.Error()
+
+	wait := restartRetryMax
+	if !f.permanent && f.attempts < 16 {
+		if d := restartRetryBase << (f.attempts - 1); d < wait {
+			wait = d
+		}
+	}
+	f.next = now.Add(wait)
+	return f
+}
+
+// waiting returns the retry state of a pod whose next attempt is not due yet
+func (r *restartRetries) waiting(key types.NamespacedName, uid types.UID, now time.Time) *restartFailure {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	f := r.failures[key][uid]
+	if f == nil || !now.Before(f.next) {
+		return nil
+	}
+	copied := *f
+	return &copied
+}
+
+// succeeded clears the retry state of a restarted pod
+func (r *restartRetries) succeeded(key types.NamespacedName, uid types.UID) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.failures[key], uid)
+	if len(r.failures[key]) == 0 {
+		delete(r.failures, key)
+	}
+}
+
+// nextRetry returns when the earliest pending retry of a PodRestart is due,
+// dropping failures that were not retried for too long
+func (r *restartRetries) nextRetry(key types.NamespacedName, now time.Time) (time.Time, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var next time.Time
+	for uid, f := range r.failures[key] {
+		if now.Sub(f.last) > restartRetryForget {
+			delete(r.failures[key], uid)
+			continue
+		}
+		if next.IsZero() || f.next.Before(next) {
+			next = f.next
+		}
+	}
+	if len(r.failures[key]) == 0 {
+		delete(r.failures, key)
+	}
+	return next, !next.IsZero()
+}
+
+// snapshot returns the retry state of every failed restart of a PodRestart,
+// earliest retry first
+func (r *restartRetries) snapshot(key types.NamespacedName) []debugRestartRetry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	retries := []debugRestartRetry{}
+	for uid, f := range r.failures[key] {
+		retries = append(retries, debugRestartRetry{
+			PodUID:      uid,
+			Attempts:    f.attempts,
+			Permanent:   f.permanent,
+			LastAttempt: f.last,
+			NextAttempt: f.next,
+			Error:       f.err,
+		})
+	}
+	sort.Slice(retries, func(i, j int) bool {
+		return retries[i].NextAttempt.Before(retries[j].NextAttempt)
+	})
+	return retries
+}
+
+// checkpoint returns the retry state of a PodRestart for its status
+func (r *restartRetries) checkpoint(key types.NamespacedName) []operatorv1alpha1.RestartRetryCheckpoint {
+	var retries []operatorv1alpha1.RestartRetryCheckpoint
+	for _, f := range r.snapshot(key) {
+		retries = append(retries, operatorv1alpha1.RestartRetryCheckpoint{
+			PodUID:      string(f.PodUID),
+			Attempts:    int32(f.Attempts),
+			Permanent:   f.Permanent,
+			LastAttempt: metav1.NewTime(f.LastAttempt),
+			NextAttempt: metav1.NewTime(f.NextAttempt),
+			Error:       f.Error,
+		})
+	}
+	return retries
+}
+
+// restore loads the retry state of a PodRestart from a checkpoint, keeping
+// any failure recorded since
+func (r *restartRetries) restore(key types.NamespacedName, retries []operatorv1alpha1.RestartRetryCheckpoint) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, c := range retries {
+		if r.failures[key] == nil {
+			r.failures[key] = map[types.UID]*restartFailure{}
+		}
+		uid := types.UID(c.PodUID)
+		if _, ok := r.failures[key][uid]; ok {
+			continue
+		}
+		r.failures[key][uid] = &restartFailure{
+			attempts:  int(c.Attempts),
+			permanent: c.Permanent,
+			last:      c.LastAttempt.Time,
+			next:      c.NextAttempt.Time,
+			err:       c.Error,
+		}
+	}
+}
+
+// forget drops the retry state of a deleted PodRestart
+func (r *restartRetries) forget(key types.NamespacedName) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.failures, key)
+}
@@ -0,0 +1,73 @@
+// restartstamp.go
+package controllers
+
+import (
+	"context"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	operatorv1alpha1 "github.com/example/pod-restart-operator/api/v1alpha1"
+)
+
+const (
+	// FieldManager is the field manager of every write the operator makes to
+	// pods, workloads and its own objects
+	FieldManager = "pod-restart-operator"
+
+	// AnnotationRestartedBy names the PodRestart that restarted the pod, as namespace/name
+	AnnotationRestartedBy = "operator.example.com/restarted-by"
+	// AnnotationRestartTrigger is the trigger that fired, with its name when set
+	AnnotationRestartTrigger = "operator.example.com/restart-trigger"
+	// AnnotationRestartedAt is when the restart was requested, in RFC3339
+	AnnotationRestartedAt = "operator.example.com/restarted-at"
+	// AnnotationRestartReason is the human readable reason of the trigger
+	AnnotationRestartReason = "operator.example.com/restart-reason"
+	// AnnotationRestartEvidence is the observed metric value. Matched log
+	// lines are only recorded by hash, since they may hold secrets and the
+	// annotation is readable by everyone who can read the pod.
+	AnnotationRestartEvidence = "operator.example.com/restart-evidence"
+	// AnnotationRestartEvidenceHash is the evidence hash also written to the audit log
+	AnnotationRestartEvidenceHash = "operator.example.com/restart-evidence-hash"
+
+	// maxEvidenceAnnotationLength bounds the reason annotation
+	maxEvidenceAnnotationLength = 1024
+)
+
+// stampRestart annotates a pod with the PodRestart and trigger about to
+// restart it, and the evidence the trigger fired on. The patch lands in the
+// API server audit log and every watcher of the pod, so a post-mortem can
+// tell why the pod went away without the diagnostics bundle.
+func stampRestart(ctx context.Context, writer client.Writer, pr *operatorv1alpha1.PodRestart, pod *corev1.Pod, result *triggerResult) error {
+	trigger := result.Trigger
+	if result.Name != "" {
+		trigger += "/" + result.Name
+	}
+	annotations := map[string]string{
+		AnnotationRestartedBy:         pr.Namespace + "/" + pr.Name,
+		AnnotationRestartTrigger:      trigger,
+		AnnotationRestartedAt:         time.Now().UTC().Format(time.RFC3339),
+		AnnotationRestartReason:       truncateAnnotation(result.Reason),
+		AnnotationRestartEvidenceHash: evidenceHash(result),
+	}
+	if result.MetricValue != nil {
+		annotations[AnnotationRestartEvidence] = strconv.FormatFloat(*result.MetricValue, 'g', -1, 64)
+	}
+	return applyPodAnnotations(ctx, writer, pod, annotations)
+}
+
+// truncateAnnotation shortens s to maxEvidenceAnnotationLength bytes without
+// splitting a UTF-8 sequence
+func truncateAnnotation(s string) string {
+	if len(s) <= maxEvidenceAnnotationLength {
+		return s
+	}
+	cut := maxEvidenceAnnotationLength - len("…")
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut] + "…"
+}
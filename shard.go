@@ -0,0 +1,87 @@
+// shard.go
+package controllers
+
+import (
+	"context"
+	"hash/fnv"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	operatorv1alpha1 "github.com/example/pod-restart-operator/api/v1alpha1"
+)
+
+// Sharder decides which namespaces an operator replica is responsible for.
+// Replicas of different shards run side by side, each shard electing its own
+// leader, so evaluation scales horizontally across shards.
+type Sharder interface {
+	// Owns reports whether this replica handles PodRestarts in the namespace
+	Owns(ctx context.Context, namespace string) bool
+}
+
+// hashSharder assigns namespaces to shards by the FNV-1a hash of their name
+type hashSharder struct {
+	count uint32
+	id    uint32
+}
+
+// NewHashSharder returns a Sharder owning the namespaces whose hash modulo
+// count equals id
+func NewHashSharder(count, id int) Sharder {
+	return &hashSharder{count: uint32(count), id: uint32(id)}
+}
+
+// Owns implements Sharder
+func (s *hashSharder) Owns(_ context.Context, namespace string) bool {
+	h := fnv.New32a()
+	h.Write([]byte(namespace))
+	return h.Sum32()%s.count == s.id
+}
+
+// labelSharder owns the namespaces matching a label selector
+type labelSharder struct {
+	reader   client.Reader
+	selector labels.Selector
+}
+
+// NewLabelSharder returns a Sharder owning the namespaces whose labels match
+// the selector
+func NewLabelSharder(reader client.Reader, selector labels.Selector) Sharder {
+	return &labelSharder{reader: reader, selector: selector}
+}
+
+// Owns implements Sharder. Namespaces that cannot be read are not owned, a
+// replica of their shard will pick them up.
+func (s *labelSharder) Owns(ctx context.Context, namespace string) bool {
+	ns := &corev1.Namespace{}
+	if err := s.reader.Get(ctx, types.NamespacedName{Name: namespace}, ns); err != nil {
+		return false
+	}
+	return s.selector.Matches(labels.Set(ns.Labels))
+}
+
+// ownsNamespace reports whether the reconciler handles the namespace. Without
+// a Sharder every namespace is owned.
+func (r *PodRestartReconciler) ownsNamespace(ctx context.Context, namespace string) bool {
+	return r.Shard == nil || r.Shard.Owns(ctx, namespace)
+}
+
+// podRestartsInNamespace enqueues every PodRestart of a namespace, so a
+// namespace moving into this shard is evaluated right away
+func (r *PodRestartReconciler) podRestartsInNamespace(ctx context.Context, obj client.Object) []reconcile.Request {
+	list := &operatorv1alpha1.PodRestartList{}
+	if err := r.List(ctx, list, client.InNamespace(obj.GetName())); err != nil {
+		r.Log.Error(err, "Failed to list PodRestarts for namespace", "namespace", obj.GetName())
+		return nil
+	}
+	requests := make([]reconcile.Request, 0, len(list.Items))
+	for _, pr := range list.Items {
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{Namespace: pr.Namespace, Name: pr.Name},
+		})
+	}
+	return requests
+}
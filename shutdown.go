@@ -0,0 +1,52 @@
+// shutdown.go
+package controllers
+
+import (
+	"context"
+	"time"
+)
+
+// defaultShutdownGracePeriod is used when ShutdownGracePeriod is not set
+const defaultShutdownGracePeriod = 20 * time.Second
+
+// detachedContext carries the values of its parent but not its cancellation
+type detachedContext struct {
+	parent context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}       { return nil }
+func (detachedContext) Err() error                  { return nil }
+
+func (c detachedContext) Value(key interface{}) interface{} {
+	return c.parent.Value(key)
+}
+
+// gracefulContext returns a context that stays alive for grace after ctx is
+// cancelled, so a restart sequence or status write begun before shutdown can
+// finish instead of leaving a pod deleted without a record
+func gracefulContext(ctx context.Context, grace time.Duration) (context.Context, context.CancelFunc) {
+	if grace <= 0 {
+		grace = defaultShutdownGracePeriod
+	}
+	detached, cancel := context.WithCancel(detachedContext{parent: ctx})
+	stop := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-stop:
+			return
+		}
+		timer := time.NewTimer(grace)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+			cancel()
+		case <-stop:
+		}
+	}()
+	return detached, func() {
+		close(stop)
+		cancel()
+	}
+}
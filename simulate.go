@@ -0,0 +1,165 @@
+// simulate.go
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	operatorv1alpha1 "github.com/example/pod-restart-operator/api/v1alpha1"
+)
+
+// SimulatedPod is the outcome of evaluating a single pod in a simulation
+type SimulatedPod struct {
+	Name      string          `json:"name"`
+	Namespace string          `json:"namespace"`
+	Phase     corev1.PodPhase `json:"phase"`
+
+	// Restart is true when the pod would be restarted
+	Restart bool `json:"restart"`
+
+	// NotifyOnly is true when the trigger only sends a notification
+	NotifyOnly bool `json:"notifyOnly,omitempty"`
+
+	// Trigger, TriggerName and Reason describe the trigger that fired
+	Trigger     string   `json:"trigger,omitempty"`
+	TriggerName string   `json:"triggerName,omitempty"`
+	Reason      string   `json:"reason,omitempty"`
+	MatchedLine string   `json:"matchedLine,omitempty"`
+	MetricValue *float64 `json:"metricValue,omitempty"`
+
+	// Skip and SkipMessage explain why a triggered restart would be suppressed
+	Skip        operatorv1alpha1.SkipReason `json:"skip,omitempty"`
+	SkipMessage string                      `json:"skipMessage,omitempty"`
+}
+
+// Simulate runs the evaluation pipeline of a PodRestart against the live pods
+// it selects and reports which of them would be restarted and why. Nothing is
+// written to the cluster: no pod is deleted, no event is emitted and no status
+// is updated. The PodRestart's status is taken as the starting point for the
+// cooldown and budget checks, and each simulated restart is applied to a copy
+// of it so later pods see the earlier ones.
+func Simulate(ctx context.Context, c client.Client, config *rest.Config, pr *operatorv1alpha1.PodRestart, features *FeatureGates) ([]SimulatedPod, error) {
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	r := &PodRestartReconciler{
+		Client:     c,
+		Scheme:     c.Scheme(),
+		Log:        log.FromContext(ctx),
+		Recorder:   &record.FakeRecorder{},
+		Features:   features,
+		restConfig: config,
+		clientset:  clientset,
+		apiReader:  c,
+		patterns:   newPatternCache(),
+		rounds:     newEvaluationRounds(),
+		cursors:    newLogCursors(),
+		logShare:   newLogShare(),
+		remotes:    newRemoteClusters(),
+		queriers:   newQuerierCache(),
+		probes:     newProbeFailures(),
+		scheduler:  newEvaluationScheduler(defaultEvaluationWorkers),
+	}
+	// Simulations read kubeconfig Secrets with the caller's own credentials,
+	// so any server they point at is the caller's to reach
+	r.ClusterServers = []string{"https://*", "http://*"}
+	pr = pr.DeepCopy()
+	requested := pr.Spec.DeepCopy()
+	applied, err := r.applyPolicy(ctx, pr)
+	if err != nil {
+		return nil, fmt.Errorf("resolving PodRestartPolicy: %w", err)
+	}
+	pr.Status.Policy = applied
+
+	selector, err := metav1.LabelSelectorAsSelector(&pr.Spec.PodSelector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pod selector: %w", err)
+	}
+	owners, err := newOwnerMatcher(pr)
+	if err != nil {
+		return nil, err
+	}
+	cluster, err := r.clusterFor(ctx, pr)
+	if err != nil {
+		return nil, err
+	}
+	namespaces := targetNamespaces(pr, cluster)
+	for _, namespace := range namespaces {
+		if err := r.checkNamespace(namespace); err != nil {
+			return nil, err
+		}
+	}
+	logs, err := r.logSourceFor(pr, cluster)
+	if err != nil {
+		return nil, err
+	}
+	patterns, err := r.patterns.get(pr)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx = withBurnRatePass(ctx)
+	var simulated []SimulatedPod
+	ownSpec := pr.Spec
+	for _, namespace := range namespaces {
+		target := cluster.inNamespace(namespace)
+		pr.Spec = ownSpec
+		if namespace != pr.Namespace {
+			spec, err := r.namespaceSpec(ctx, pr, requested, namespace)
+			if err != nil {
+				return nil, fmt.Errorf("resolving PodRestartPolicy of %s: %w", namespace, err)
+			}
+			pr.Spec = *spec
+		}
+		guard := newRestartGuard(pr, target)
+		listOpts := []client.ListOption{
+			client.InNamespace(namespace),
+			client.MatchingLabelsSelector{Selector: selector},
+			client.Limit(podListPageSize),
+		}
+		for {
+			podList := &corev1.PodList{}
+			if err := target.reader.List(ctx, podList, listOpts...); err != nil {
+				return nil, fmt.Errorf("listing pods in %s: %w", namespace, err)
+			}
+			pods := owners.filter(podList.Items)
+			results, _ := r.evaluatePods(ctx, target, logs, pods, pr, patterns, nil)
+			for i, pod := range pods {
+				sim := SimulatedPod{Name: pod.Name, Namespace: pod.Namespace, Phase: pod.Status.Phase}
+				if result := results[i]; result != nil {
+					sim.Trigger = result.Trigger
+					sim.TriggerName = result.Name
+					sim.Reason = result.Reason
+					sim.MatchedLine = result.MatchedLine
+					sim.MetricValue = result.MetricValue
+					sim.NotifyOnly = result.NotifyOnly
+					if !sim.NotifyOnly {
+						sim.Skip, sim.SkipMessage = r.restartBlocked(ctx, pr, &pod, result, guard)
+					}
+					if !sim.NotifyOnly && sim.Skip == "" {
+						sim.Restart = true
+						now := metav1.Now()
+						pr.Status.LastRestartTime = &now
+						recordTriggerRestart(pr, result, now)
+						consumeBudget(pr, now)
+					}
+				}
+				simulated = append(simulated, sim)
+			}
+			if podList.Continue == "" {
+				break
+			}
+			listOpts = append(listOpts[:3:3], client.Continue(podList.Continue))
+		}
+	}
+	return simulated, nil
+}
@@ -0,0 +1,138 @@
+// slack.go
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	operatorv1alpha1 "github.com/example/pod-restart-operator/api/v1alpha1"
+)
+
+// slackNotifier posts notifications to a Slack incoming webhook
+type slackNotifier struct {
+	webhookURL string
+	channel    string
+	username   string
+	iconEmoji  string
+	payload    *payloadTemplate
+	httpClient *http.Client
+}
+
+// slackMessage is the incoming webhook payload
+type slackMessage struct {
+	Channel     string            `json:"channel,omitempty"`
+	Username    string            `json:"username,omitempty"`
+	IconEmoji   string            `json:"icon_emoji,omitempty"`
+	Text        string            `json:"text"`
+	Attachments []slackAttachment `json:"attachments,omitempty"`
+}
+
+type slackAttachment struct {
+	Color  string       `json:"color,omitempty"`
+	Fields []slackField `json:"fields,omitempty"`
+	Ts     int64        `json:"ts,omitempty"`
+}
+
+type slackField struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+	Short bool   `json:"short"`
+}
+
+// newSlackNotifier builds a Slack notifier from a channel and the per-PodRestart overrides
+func (r *PodRestartReconciler) newSlackNotifier(ctx context.Context, channel *operatorv1alpha1.NotificationChannel, pr *operatorv1alpha1.PodRestart) (Notifier, error) {
+	if channel.Spec.SecretRef == nil {
+		return nil, fmt.Errorf("slack channel %s has no secretRef", channel.Name)
+	}
+	url, err := r.secretValue(ctx, channel.Spec.SecretRef)
+	if err != nil {
+		return nil, err
+	}
+
+	payload, err := newPayloadTemplate(channel, pr)
+	if err != nil {
+		return nil, err
+	}
+
+	n := &slackNotifier{
+		webhookURL: strings.TrimSpace(string(url)),
+		payload:    payload,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+	if cfg := channel.Spec.Slack; cfg != nil {
+		n.channel = cfg.Channel
+		n.username = cfg.Username
+		n.iconEmoji = cfg.IconEmoji
+	}
+	if spec := pr.Spec.Notifications; spec != nil && spec.SlackChannel != "" {
+		n.channel = spec.SlackChannel
+	}
+	return n, nil
+}
+
+// Notify implements Notifier
+func (n *slackNotifier) Notify(ctx context.Context, notification Notification) error {
+	if n.payload != nil {
+		body, err := n.payload.render(notification)
+		if err != nil {
+			return err
+		}
+		return postJSON(ctx, n.httpClient, n.webhookURL, body)
+	}
+	return postJSON(ctx, n.httpClient, n.webhookURL, n.message(notification))
+}
+
+// message formats a notification as a Slack message
+func (n *slackNotifier) message(notification Notification) slackMessage {
+	var title, color string
+	switch notification.Event {
+	case operatorv1alpha1.EventRestarted:
+		title, color = fmt.Sprintf(":recycle: Pod *%s* restarted", notification.Pod), "good"
+	case operatorv1alpha1.EventRestartSkipped:
+		title, color = fmt.Sprintf(":pause_button: Restart of pod *%s* skipped", notification.Pod), "#439FE0"
+	case operatorv1alpha1.EventRestartFailed:
+		title, color = fmt.Sprintf(":x: Failed to restart pod *%s*", notification.Pod), "danger"
+	case operatorv1alpha1.EventBudgetExhausted:
+		title, color = ":rotating_light: Restart budget exhausted, further restarts are blocked", "danger"
+	case operatorv1alpha1.EventCircuitOpen:
+		title, color = ":rotating_light: Restarts keep failing, circuit breaker opened", "danger"
+	case operatorv1alpha1.EventRecovered:
+		title, color = ":white_check_mark: PodRestart recovered", "good"
+	default:
+		title, color = string(notification.Event), "warning"
+	}
+
+	fields := []slackField{
+		{Title: "PodRestart", Value: notification.PodRestart.String(), Short: true},
+	}
+	if notification.Trigger != "" {
+		fields = append(fields, slackField{Title: "Trigger", Value: notification.Trigger, Short: true})
+	}
+	if notification.ReasonCode != "" {
+		fields = append(fields, slackField{Title: "Code", Value: string(notification.ReasonCode), Short: true})
+	}
+	if notification.Reason != "" {
+		fields = append(fields, slackField{Title: "Reason", Value: notification.Reason})
+	}
+	if notification.Message != "" && notification.Message != notification.Reason {
+		fields = append(fields, slackField{Title: "Details", Value: notification.Message})
+	}
+	if notification.MatchedLine != "" {
+		fields = append(fields, slackField{Title: "Matched line", Value: "```" + notification.MatchedLine + "```"})
+	}
+
+	return slackMessage{
+		Channel:   n.channel,
+		Username:  n.username,
+		IconEmoji: n.iconEmoji,
+		Text:      title,
+		Attachments: []slackAttachment{{
+			Color:  color,
+			Fields: fields,
+			Ts:     notification.Time.Unix(),
+		}},
+	}
+}
@@ -0,0 +1,128 @@
+// statuspatch.go
+package controllers
+
+import (
+	"context"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	operatorv1alpha1 "github.com/example/pod-restart-operator/api/v1alpha1"
+)
+
+// patchStatus writes all status changes of a pass in one apply, guarded by
+// the resourceVersion the pass started from. When another writer, such as a
+// second operator instance during a leader handover, changed the status
+// meanwhile, the pass's restarts are replayed onto the latest status instead
+// of overwriting the restarts recorded there.
+func (r *PodRestartReconciler) patchStatus(ctx context.Context, pr, base *operatorv1alpha1.PodRestart) error {
+	err := r.applyStatus(ctx, pr, &pr.Status)
+	if !errors.IsConflict(err) {
+		return err
+	}
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		latest := &operatorv1alpha1.PodRestart{}
+		if err := r.apiReader.Get(ctx, client.ObjectKeyFromObject(pr), latest); err != nil {
+			return err
+		}
+		merged := latest.DeepCopy()
+		merged.Status = mergeStatus(&latest.Status, &base.Status, &pr.Status, historyLimit(pr))
+		refreshBudget(merged, time.Now())
+		if err := r.applyStatus(ctx, merged, &merged.Status); err != nil {
+			return err
+		}
+		pr.ResourceVersion = merged.ResourceVersion
+		pr.Status = merged.Status
+		return nil
+	})
+}
+
+// mergeStatus combines the status a pass computed from base (ours) with the
+// latest one written by someone else. What describes the last evaluation is
+// taken from ours; restart counters and history add this pass's restarts to
+// the latest values.
+func mergeStatus(latest, base, ours *operatorv1alpha1.PodRestartStatus, limit int) operatorv1alpha1.PodRestartStatus {
+	merged := *ours.DeepCopy()
+
+	merged.RestartCount = latest.RestartCount + ours.RestartCount - base.RestartCount
+	merged.LastRestartTime = laterTime(latest.LastRestartTime, ours.LastRestartTime)
+
+	// The budget counts this pass's restarts on top of the latest ones;
+	// refreshBudget drops those that left the window
+	budgetTimes := append([]metav1.Time(nil), latest.BudgetRestartTimes...)
+	for _, t := range ours.BudgetRestartTimes {
+		if !containsTime(base.BudgetRestartTimes, t) {
+			budgetTimes = append(budgetTimes, t)
+		}
+	}
+	merged.BudgetRestartTimes = budgetTimes
+
+	// Records are prepended, so this pass's are the ones base did not have
+	var added []operatorv1alpha1.RestartRecord
+	for _, record := range ours.RecentRestarts {
+		if containsRecord(base.RecentRestarts, record) {
+			break
+		}
+		added = append(added, record)
+	}
+	history := append(added, latest.RecentRestarts...)
+	if len(history) > limit {
+		history = history[:limit]
+	}
+	merged.RecentRestarts = history
+
+	triggers := append([]operatorv1alpha1.TriggerRestartTime(nil), latest.TriggerRestarts...)
+	for _, t := range ours.TriggerRestarts {
+		found := false
+		for i := range triggers {
+			if triggers[i].Trigger == t.Trigger && triggers[i].Name == t.Name {
+				found = true
+				if t.Time.After(triggers[i].Time.Time) {
+					triggers[i].Time = t.Time
+				}
+			}
+		}
+		if !found {
+			triggers = append(triggers, t)
+		}
+	}
+	merged.TriggerRestarts = triggers
+	return merged
+}
+
+// historyLimit returns the number of restart records kept in status
+func historyLimit(pr *operatorv1alpha1.PodRestart) int {
+	if pr.Spec.HistoryLimit != nil {
+		return int(*pr.Spec.HistoryLimit)
+	}
+	return defaultHistoryLimit
+}
+
+func containsRecord(records []operatorv1alpha1.RestartRecord, record operatorv1alpha1.RestartRecord) bool {
+	for _, r := range records {
+		if r.PodName == record.PodName && r.Time.Equal(&record.Time) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsTime(times []metav1.Time, t metav1.Time) bool {
+	for i := range times {
+		if times[i].Equal(&t) {
+			return true
+		}
+	}
+	return false
+}
+
+func laterTime(a, b *metav1.Time) *metav1.Time {
+	if a == nil || (b != nil && b.After(a.Time)) {
+		return b
+	}
+	return a
+}
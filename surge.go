@@ -0,0 +1,291 @@
+// surge.go
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	operatorv1alpha1 "github.com/example/pod-restart-operator/api/v1alpha1"
+)
+
+const (
+	// AnnotationSurgeReplicas records the replica count of a Deployment
+	// before a surge restart scaled it up, so it can be restored by the next
+	// surge if the operator stopped before scaling back
+	AnnotationSurgeReplicas = "operator.example.com/surge-from-replicas"
+	// AnnotationSurgePod is the UID of the pod a surge makes room for
+	AnnotationSurgePod = "operator.example.com/surge-pod"
+	// AnnotationSurgeReady is the number of Ready replicas completing a surge
+	AnnotationSurgeReady = "operator.example.com/surge-ready-replicas"
+	// AnnotationSurgeDeadline is when a surge that is not complete is given
+	// up, in RFC3339
+	AnnotationSurgeDeadline = "operator.example.com/surge-deadline"
+
+	defaultSurgeReadyTimeout = 5 * time.Minute
+
+	// surgeRecheck is how soon a PodRestart waiting for a surge is
+	// evaluated again
+	surgeRecheck = 10 * time.Second
+)
+
+// surges tracks the Deployments each PodRestart surged, so surges whose
+// pod went away or stopped triggering are scaled back once they expire
+type surges struct {
+	mu     sync.Mutex
+	surged map[types.NamespacedName]map[types.NamespacedName]bool
+}
+
+func newSurges() *surges {
+	return &surges{surged: map[types.NamespacedName]map[types.NamespacedName]bool{}}
+}
+
+func (s *surges) add(key, deployment types.NamespacedName) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.surged[key] == nil {
+		s.surged[key] = map[types.NamespacedName]bool{}
+	}
+	s.surged[key][deployment] = true
+}
+
+func (s *surges) remove(key, deployment types.NamespacedName) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.surged[key], deployment)
+	if len(s.surged[key]) == 0 {
+		delete(s.surged, key)
+	}
+}
+
+func (s *surges) of(key types.NamespacedName) []types.NamespacedName {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	deployments := make([]types.NamespacedName, 0, len(s.surged[key]))
+	for d := range s.surged[key] {
+		deployments = append(deployments, d)
+	}
+	return deployments
+}
+
+// forget drops the surges of a deleted PodRestart
+func (s *surges) forget(key types.NamespacedName) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.surged, key)
+}
+
+// surgePending prepares the surge restart of a pod: it adds one replica to
+// the pod's Deployment and reports the restart as pending until the extra
+// pod is Ready, so the workload never runs below its replica count. The
+// wait spans passes rather than blocking the reconcile. Pods of other
+// workloads, of Deployments scaled by a HorizontalPodAutoscaler, which would
+// fight over the replica count, and of Deployments applied by Flux, which
+// would revert it, are restarted without a surge. An error means the surge
+// failed or timed out and was scaled back.
+func (r *PodRestartReconciler) surgePending(ctx context.Context, cluster *clusterTarget, pr *operatorv1alpha1.PodRestart, pod *corev1.Pod) (bool, error) {
+	logger := log.FromContext(ctx)
+	workload := workloadFor(pod)
+	if workload.Kind != "Deployment" {
+		logger.Info("Pod is not owned by a Deployment, deleting it without a surge",
+			"pod", pod.Name,
+			"workload", workload.String())
+		return false, nil
+	}
+
+	deployment := &appsv1.Deployment{}
+	key := types.NamespacedName{Namespace: pod.Namespace, Name: workload.Name}
+	if err := cluster.reader.Get(ctx, key, deployment); err != nil {
+		return false, err
+	}
+	if reason, err := surgeUnsafe(ctx, cluster, deployment); err != nil {
+		return false, err
+	} else if reason != "" {
+		logger.Info("Deleting pod without a surge", "pod", pod.Name, "deployment", workload.Name, "reason", reason)
+		return false, nil
+	}
+
+	ownKey := types.NamespacedName{Namespace: pr.Namespace, Name: pr.Name}
+	now := time.Now()
+	if _, ok := deployment.Annotations[AnnotationSurgeReplicas]; ok {
+		deadline, _ := time.Parse(time.RFC3339, deployment.Annotations[AnnotationSurgeDeadline])
+		if deployment.Annotations[AnnotationSurgePod] != string(pod.UID) {
+			// Another pod's surge, or one left behind by an interrupted operator
+			if now.Before(deadline) {
+				return true, nil
+			}
+			logger.Info("Restoring replicas of an expired surge", "deployment", workload.Name)
+			if err := endSurge(ctx, cluster, deployment); err != nil {
+				return false, err
+			}
+			r.surges.remove(ownKey, key)
+			// This pod's own surge starts on the next pass
+			return true, nil
+		}
+		ready, _ := strconv.ParseInt(deployment.Annotations[AnnotationSurgeReady], 10, 32)
+		if deployment.Status.ObservedGeneration >= deployment.Generation && deployment.Status.ReadyReplicas >= int32(ready) {
+			return false, nil
+		}
+		if now.Before(deadline) {
+			return true, nil
+		}
+		readyReplicas := deployment.Status.ReadyReplicas
+		if err := endSurge(ctx, cluster, deployment); err != nil {
+			logger.Error(err, "Failed to scale deployment back after surge timeout", "deployment", workload.Name)
+		}
+		r.surges.remove(ownKey, key)
+		return false, fmt.Errorf("surge pod of deployment %s not ready in time, %d of %d replicas ready", workload.Name, readyReplicas, ready)
+	}
+
+	replicas := int32(1)
+	if deployment.Spec.Replicas != nil {
+		replicas = *deployment.Spec.Replicas
+	}
+	// The unhealthy pod may already be failing its readiness probe, so the
+	// surge is complete once one more pod than now is Ready
+	ready := deployment.Status.ReadyReplicas + 1
+	if ready > replicas+1 {
+		ready = replicas + 1
+	}
+	timeout := defaultSurgeReadyTimeout
+	if s := pr.Spec.Surge; s != nil && s.ReadyTimeout != nil {
+		timeout = s.ReadyTimeout.Duration
+	}
+	patch := client.MergeFrom(deployment.DeepCopy())
+	surged := replicas + 1
+	deployment.Spec.Replicas = &surged
+	if deployment.Annotations == nil {
+		deployment.Annotations = map[string]string{}
+	}
+	deployment.Annotations[AnnotationSurgeReplicas] = strconv.Itoa(int(replicas))
+	deployment.Annotations[AnnotationSurgePod] = string(pod.UID)
+	deployment.Annotations[AnnotationSurgeReady] = strconv.Itoa(int(ready))
+	deployment.Annotations[AnnotationSurgeDeadline] = now.Add(timeout).UTC().Format(time.RFC3339)
+	if err := cluster.writer.Patch(ctx, deployment, patch, client.FieldOwner(FieldManager)); err != nil {
+		return false, fmt.Errorf("scaling up deployment %s: %w", workload.Name, err)
+	}
+	r.surges.add(ownKey, key)
+	return true, nil
+}
+
+// surgeUnsafe returns why the replica count of a Deployment must not be
+// changed, or an empty string when it may
+func surgeUnsafe(ctx context.Context, cluster *clusterTarget, deployment *appsv1.Deployment) (string, error) {
+	for _, owner := range fluxOwners {
+		if name := deployment.Labels[owner.nameLabel]; name != "" {
+			return fmt.Sprintf("applied by %s %s", owner.gvk.Kind, name), nil
+		}
+	}
+	var hpas autoscalingv2.HorizontalPodAutoscalerList
+	if err := cluster.lookups.List(ctx, &hpas, client.InNamespace(deployment.Namespace)); err != nil {
+		return "", fmt.Errorf("listing HorizontalPodAutoscalers: %w", err)
+	}
+	for _, hpa := range hpas.Items {
+		if ref := hpa.Spec.ScaleTargetRef; ref.Kind == "Deployment" && ref.Name == deployment.Name {
+			return "scaled by HorizontalPodAutoscaler " + hpa.Name, nil
+		}
+	}
+	return "", nil
+}
+
+// surgeRestart deletes a pod whose surge completed and scales its
+// Deployment back. Pods restarted without a surge are only deleted.
+func (r *PodRestartReconciler) surgeRestart(ctx context.Context, cluster *clusterTarget, pr *operatorv1alpha1.PodRestart, pod *corev1.Pod, result *triggerResult) error {
+	err := r.deletePod(ctx, cluster, pr, pod, result)
+
+	workload := workloadFor(pod)
+	if workload.Kind != "Deployment" {
+		return err
+	}
+	deployment := &appsv1.Deployment{}
+	key := types.NamespacedName{Namespace: pod.Namespace, Name: workload.Name}
+	if getErr := cluster.reader.Get(ctx, key, deployment); getErr != nil {
+		if err == nil {
+			err = getErr
+		}
+		return err
+	}
+	if deployment.Annotations[AnnotationSurgePod] != string(pod.UID) {
+		return err
+	}
+	// Scale back even when the reconcile is being cancelled
+	scaleCtx, cancel := gracefulContext(ctx, r.ShutdownGracePeriod)
+	defer cancel()
+	if scaleErr := endSurge(scaleCtx, cluster, deployment); scaleErr != nil {
+		log.FromContext(ctx).Error(scaleErr, "Failed to scale deployment back after surge restart", "deployment", workload.Name)
+	}
+	r.surges.remove(types.NamespacedName{Namespace: pr.Namespace, Name: pr.Name}, key)
+	return err
+}
+
+// expireSurges scales back the surges of a PodRestart past their deadline,
+// e.g. because their pod went away or its trigger stopped firing
+func (r *PodRestartReconciler) expireSurges(ctx context.Context, pr *operatorv1alpha1.PodRestart, cluster *clusterTarget) error {
+	ownKey := types.NamespacedName{Namespace: pr.Namespace, Name: pr.Name}
+	now := time.Now()
+	for _, key := range r.surges.of(ownKey) {
+		deployment := &appsv1.Deployment{}
+		if err := cluster.reader.Get(ctx, key, deployment); err != nil {
+			if errors.IsNotFound(err) {
+				r.surges.remove(ownKey, key)
+				continue
+			}
+			return err
+		}
+		if _, ok := deployment.Annotations[AnnotationSurgeReplicas]; !ok {
+			r.surges.remove(ownKey, key)
+			continue
+		}
+		deadline, _ := time.Parse(time.RFC3339, deployment.Annotations[AnnotationSurgeDeadline])
+		if now.Before(deadline) {
+			continue
+		}
+		if err := endSurge(ctx, cluster, deployment); err != nil {
+			return err
+		}
+		r.surges.remove(ownKey, key)
+	}
+	return nil
+}
+
+// endSurge restores the replica count a surge started from and removes its
+// annotations
+func endSurge(ctx context.Context, cluster *clusterTarget, deployment *appsv1.Deployment) error {
+	original, err := strconv.ParseInt(deployment.Annotations[AnnotationSurgeReplicas], 10, 32)
+	if err != nil {
+		return fmt.Errorf("invalid %s annotation %q on deployment %s", AnnotationSurgeReplicas,
+			deployment.Annotations[AnnotationSurgeReplicas], deployment.Name)
+	}
+	return scaleDeployment(ctx, cluster.writer, deployment, int32(original))
+}
+
+// scaleDeployment sets the replica count of a Deployment and removes the
+// surge annotations. This is a merge patch rather than an apply: owning
+// spec.replicas after the surge would make the operator a co-owner of the
+// field its users manage, and releasing it would reset it.
+func scaleDeployment(ctx context.Context, writer client.Writer, deployment *appsv1.Deployment, replicas int32) error {
+	patch := client.MergeFrom(deployment.DeepCopy())
+	deployment.Spec.Replicas = &replicas
+	for _, key := range []string{AnnotationSurgeReplicas, AnnotationSurgePod, AnnotationSurgeReady, AnnotationSurgeDeadline} {
+		delete(deployment.Annotations, key)
+	}
+	return writer.Patch(ctx, deployment, patch, client.FieldOwner(FieldManager))
+}
+
+// restartAction returns the action of a PodRestart, defaulting to Delete
+func restartAction(pr *operatorv1alpha1.PodRestart) operatorv1alpha1.RestartAction {
+	if pr.Spec.Action == "" {
+		return operatorv1alpha1.ActionDelete
+	}
+	return pr.Spec.Action
+}
@@ -0,0 +1,126 @@
+// teams.go
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	operatorv1alpha1 "github.com/example/pod-restart-operator/api/v1alpha1"
+)
+
+// teamsNotifier posts connector cards to a Microsoft Teams incoming webhook
+type teamsNotifier struct {
+	webhookURL string
+	payload    *payloadTemplate
+	httpClient *http.Client
+}
+
+// teamsMessageCard is the legacy actionable message card accepted by Teams connectors
+type teamsMessageCard struct {
+	Type       string         `json:"@type"`
+	Context    string         `json:"@context"`
+	ThemeColor string         `json:"themeColor"`
+	Summary    string         `json:"summary"`
+	Title      string         `json:"title"`
+	Sections   []teamsSection `json:"sections"`
+}
+
+type teamsSection struct {
+	ActivitySubtitle string      `json:"activitySubtitle,omitempty"`
+	Facts            []teamsFact `json:"facts"`
+	Text             string      `json:"text,omitempty"`
+}
+
+type teamsFact struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// newTeamsNotifier builds a Teams notifier from a channel
+func (r *PodRestartReconciler) newTeamsNotifier(ctx context.Context, channel *operatorv1alpha1.NotificationChannel, pr *operatorv1alpha1.PodRestart) (Notifier, error) {
+	if channel.Spec.SecretRef == nil {
+		return nil, fmt.Errorf("teams channel %s has no secretRef", channel.Name)
+	}
+	url, err := r.secretValue(ctx, channel.Spec.SecretRef)
+	if err != nil {
+		return nil, err
+	}
+	payload, err := newPayloadTemplate(channel, pr)
+	if err != nil {
+		return nil, err
+	}
+	return &teamsNotifier{
+		webhookURL: strings.TrimSpace(string(url)),
+		payload:    payload,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+// Notify implements Notifier
+func (n *teamsNotifier) Notify(ctx context.Context, notification Notification) error {
+	if n.payload != nil {
+		body, err := n.payload.render(notification)
+		if err != nil {
+			return err
+		}
+		return postJSON(ctx, n.httpClient, n.webhookURL, body)
+	}
+	return postJSON(ctx, n.httpClient, n.webhookURL, n.card(notification))
+}
+
+// card formats a notification as a Teams connector card
+func (n *teamsNotifier) card(notification Notification) teamsMessageCard {
+	var title, color string
+	switch notification.Event {
+	case operatorv1alpha1.EventRestarted:
+		title, color = fmt.Sprintf("Pod %s restarted", notification.Pod), "2EB886"
+	case operatorv1alpha1.EventRestartSkipped:
+		title, color = fmt.Sprintf("Restart of pod %s skipped", notification.Pod), "439FE0"
+	case operatorv1alpha1.EventRestartFailed:
+		title, color = fmt.Sprintf("Failed to restart pod %s", notification.Pod), "A30200"
+	case operatorv1alpha1.EventBudgetExhausted:
+		title, color = "Restart budget exhausted, further restarts are blocked", "A30200"
+	case operatorv1alpha1.EventCircuitOpen:
+		title, color = "Restarts keep failing, circuit breaker opened", "A30200"
+	case operatorv1alpha1.EventRecovered:
+		title, color = "PodRestart recovered", "2EB886"
+	default:
+		title, color = string(notification.Event), "DAA038"
+	}
+
+	facts := []teamsFact{
+		{Name: "PodRestart", Value: notification.PodRestart.String()},
+	}
+	if notification.Trigger != "" {
+		facts = append(facts, teamsFact{Name: "Trigger", Value: notification.Trigger})
+	}
+	if notification.ReasonCode != "" {
+		facts = append(facts, teamsFact{Name: "Code", Value: string(notification.ReasonCode)})
+	}
+	if notification.Reason != "" {
+		facts = append(facts, teamsFact{Name: "Reason", Value: notification.Reason})
+	}
+	if notification.MatchedLine != "" {
+		facts = append(facts, teamsFact{Name: "Matched line", Value: notification.MatchedLine})
+	}
+
+	section := teamsSection{
+		ActivitySubtitle: notification.Time.UTC().Format(time.RFC3339),
+		Facts:            facts,
+	}
+	if notification.Message != "" && notification.Message != notification.Reason {
+		section.Text = notification.Message
+	}
+
+	return teamsMessageCard{
+		Type:       "MessageCard",
+		Context:    "https://schema.org/extensions",
+		ThemeColor: color,
+		Summary:    title,
+		Title:      title,
+		Sections:   []teamsSection{section},
+	}
+}
@@ -0,0 +1,83 @@
+// terminating.go
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// recentStopWindow is how far back a pod event counts as the pod being
+// stopped by someone else
+const recentStopWindow = 2 * time.Minute
+
+// stopEventReasons are the event reasons with which kubelet, the scheduler
+// and the taint manager announce they are stopping a pod. Only events about
+// the pod itself count: kubelet also emits Killing for a single container it
+// restarts after a failed liveness probe, which leaves the pod in place.
+var stopEventReasons = map[string]bool{
+	"Killing":              true,
+	"Evicted":              true,
+	"Preempting":           true,
+	"TaintManagerEviction": true,
+}
+
+// alreadyTerminating returns why a pod is already going away without the
+// operator's help, or an empty string when it is not. The pod is read again
+// from the cache, where available, because the listed copy may predate a
+// delete issued while it was evaluated.
+func alreadyTerminating(ctx context.Context, cluster *clusterTarget, pod *corev1.Pod) (string, error) {
+	if pod.DeletionTimestamp != nil {
+		return fmt.Sprintf("Pod has been terminating since %s", pod.DeletionTimestamp.UTC().Format(time.RFC3339)), nil
+	}
+
+	reader := cluster.cachedPods
+	if reader == nil {
+		reader = cluster.reader
+	}
+	current := &corev1.Pod{}
+	if err := reader.Get(ctx, client.ObjectKeyFromObject(pod), current); err != nil {
+		if errors.IsNotFound(err) {
+			return "Pod was already deleted", nil
+		}
+		return "", fmt.Errorf("getting pod %s: %w", pod.Name, err)
+	}
+	if current.UID != pod.UID {
+		return "Pod was already replaced", nil
+	}
+	if current.DeletionTimestamp != nil {
+		return fmt.Sprintf("Pod has been terminating since %s", current.DeletionTimestamp.UTC().Format(time.RFC3339)), nil
+	}
+	// Evictions, preemptions and taint manager deletions mark the pod first
+	for _, cond := range current.Status.Conditions {
+		if cond.Type == corev1.DisruptionTarget && cond.Status == corev1.ConditionTrue {
+			return fmt.Sprintf("Pod is being disrupted (%s): %s", cond.Reason, cond.Message), nil
+		}
+	}
+
+	events, err := cluster.clientset.CoreV1().Events(pod.Namespace).List(ctx, metav1.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("involvedObject.uid", string(pod.UID)).String(),
+	})
+	if err != nil {
+		return "", fmt.Errorf("listing events of pod %s: %w", pod.Name, err)
+	}
+	for _, event := range events.Items {
+		if !stopEventReasons[event.Reason] || event.InvolvedObject.FieldPath != "" {
+			continue
+		}
+		last := event.LastTimestamp.Time
+		if event.EventTime.After(last) {
+			last = event.EventTime.Time
+		}
+		if time.Since(last) < recentStopWindow {
+			return fmt.Sprintf("Pod is being stopped (%s): %s", event.Reason, event.Message), nil
+		}
+	}
+	return "", nil
+}
@@ -0,0 +1,10 @@
+// tracing.go
+package controllers
+
+import (
+	"go.opentelemetry.io/otel"
+)
+
+// tracer creates the spans of the reconcile pipeline. It uses the global
+// tracer provider, which is a no-op unless tracing is enabled in main.
+var tracer = otel.Tracer("github.com/example/pod-restart-operator/controllers")
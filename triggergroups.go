@@ -0,0 +1,74 @@
+// triggergroups.go
+package controllers
+
+import (
+	"fmt"
+	"strings"
+
+	operatorv1alpha1 "github.com/example/pod-restart-operator/api/v1alpha1"
+)
+
+// selects reports whether a trigger reference matches a result
+func selects(ref operatorv1alpha1.TriggerReference, result *triggerResult) bool {
+	return ref.Trigger == result.Trigger && (ref.Name == "" || ref.Name == result.Name)
+}
+
+// inTriggerGroup reports whether any trigger group references the condition
+// that produced a result
+func inTriggerGroup(pr *operatorv1alpha1.PodRestart, result *triggerResult) bool {
+	for _, group := range pr.Spec.TriggerGroups {
+		for _, ref := range group.AllOf {
+			if selects(ref, result) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchTriggerGroups returns a result for the first group all of whose
+// triggers are among the fired results, or nil when no group is satisfied
+func (r *PodRestartReconciler) matchTriggerGroups(pr *operatorv1alpha1.PodRestart, results []*triggerResult) *triggerResult {
+	for _, group := range pr.Spec.TriggerGroups {
+		members := groupMembers(group, results)
+		if len(members) == 0 {
+			continue
+		}
+
+		conditionMatchesTotal.WithLabelValues(pr.Namespace, pr.Name, group.Name).Inc()
+		combined := &triggerResult{Trigger: TriggerGroup, Name: group.Name}
+		reasons := make([]string, 0, len(members))
+		for _, member := range members {
+			reasons = append(reasons, member.Reason)
+			if combined.MatchedLine == "" {
+				combined.MatchedLine = member.MatchedLine
+			}
+			if combined.MetricValue == nil {
+				combined.MetricValue = member.MetricValue
+			}
+		}
+		combined.Reason = fmt.Sprintf("All triggers of group %s fired: %s", group.Name, strings.Join(reasons, "; "))
+		return combined
+	}
+	return nil
+}
+
+// groupMembers returns the result matching each reference of the group, or
+// nil when a reference has none
+func groupMembers(group operatorv1alpha1.TriggerGroup, results []*triggerResult) []*triggerResult {
+	members := make([]*triggerResult, 0, len(group.AllOf))
+	for _, ref := range group.AllOf {
+		var member *triggerResult
+		for _, result := range results {
+			if selects(ref, result) {
+				member = result
+				break
+			}
+		}
+		if member == nil {
+			return nil
+		}
+		members = append(members, member)
+	}
+	return members
+}
@@ -0,0 +1,215 @@
+// triggerserver.go
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/example/pod-restart-operator/controllers"
+)
+
+const (
+	// triggerPrefix is the path restarts are requested below
+	triggerPrefix = "/trigger/podrestarts/"
+
+	// alertmanagerPrefix is the path Alertmanager webhook receivers post to
+	alertmanagerPrefix = "/alertmanager/podrestarts/"
+
+	// maxTriggerBody bounds the request bodies the trigger server reads
+	maxTriggerBody = 1 << 20
+)
+
+// triggerServer is the inbound trigger API. Callers request restarts of the
+// pods a PodRestart selects at /trigger/podrestarts/{namespace}/{name}, and
+// Alertmanager webhook receivers post firing alerts to
+// /alertmanager/podrestarts/{namespace}/{name}, which restart the pods named
+// by their namespace and pod labels. Every request must present a verified client
+// certificate or a Kubernetes bearer token and is authorized as the
+// trigger verb on the PodRestart, see kubeAuth. It runs on every replica;
+// requests are recorded on the PodRestart, where the leader picks them up.
+type triggerServer struct {
+	addr     string
+	tls      *inboundTLS
+	auth     *kubeAuth
+	requests func(ctx context.Context, key types.NamespacedName, requests []controllers.RestartRequest) error
+}
+
+// triggerBody is the body of a request to the trigger API. Pods are given
+// by name, or as namespace/name for PodRestarts targeting several namespaces.
+type triggerBody struct {
+	Pods   []string `json:"pods"`
+	Reason string   `json:"reason,omitempty"`
+}
+
+// alertmanagerBody is the subset of the Alertmanager webhook payload used here
+type alertmanagerBody struct {
+	Alerts []struct {
+		Status      string            `json:"status"`
+		Labels      map[string]string `json:"labels"`
+		Annotations map[string]string `json:"annotations"`
+	} `json:"alerts"`
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable
+func (s *triggerServer) NeedLeaderElection() bool {
+	return false
+}
+
+// Start implements manager.Runnable
+func (s *triggerServer) Start(ctx context.Context) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc(triggerPrefix, s.handleTrigger)
+	mux.HandleFunc(alertmanagerPrefix, s.handleAlertmanager)
+
+	ln, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return err
+	}
+	s.tls.start(ctx)
+	ln = tls.NewListener(ln, s.tls.config())
+	srv := &http.Server{Handler: s.auth.wrap(mux), ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shutdownCtx)
+	}()
+	setupLog.Info("Serving trigger API", "address", ln.Addr().String())
+	if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+func (s *triggerServer) handleTrigger(w http.ResponseWriter, req *http.Request) {
+	key, ok := s.podRestart(w, req)
+	if !ok {
+		return
+	}
+	var body triggerBody
+	if err := json.NewDecoder(io.LimitReader(req.Body, maxTriggerBody)).Decode(&body); err != nil {
+		http.Error(w, "invalid body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(body.Pods) == 0 {
+		http.Error(w, "pods must not be empty", http.StatusBadRequest)
+		return
+	}
+	user := callerFrom(req.Context())
+	now := time.Now()
+	requests := make([]controllers.RestartRequest, 0, len(body.Pods))
+	for _, pod := range body.Pods {
+		var namespace string
+		if i := strings.IndexByte(pod, '/'); i >= 0 {
+			namespace, pod = pod[:i], pod[i+1:]
+		}
+		if pod == "" {
+			http.Error(w, "pods must not contain empty names", http.StatusBadRequest)
+			return
+		}
+		requests = append(requests, controllers.RestartRequest{
+			Namespace: namespace,
+			Pod:       pod,
+			Trigger:   controllers.TriggerManual,
+			Name:      user,
+			Reason:    body.Reason,
+			Time:      now,
+		})
+	}
+	s.record(w, req, key, requests)
+}
+
+func (s *triggerServer) handleAlertmanager(w http.ResponseWriter, req *http.Request) {
+	key, ok := s.podRestart(w, req)
+	if !ok {
+		return
+	}
+	var body alertmanagerBody
+	if err := json.NewDecoder(io.LimitReader(req.Body, maxTriggerBody)).Decode(&body); err != nil {
+		http.Error(w, "invalid body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	now := time.Now()
+	var requests []controllers.RestartRequest
+	for _, alert := range body.Alerts {
+		// Alerts about pods of namespaces the PodRestart does not target are
+		// dropped when they are recorded
+		if alert.Status != "firing" || alert.Labels["pod"] == "" || alert.Labels["namespace"] == "" {
+			continue
+		}
+		reason := alert.Annotations["summary"]
+		if reason == "" {
+			reason = fmt.Sprintf("Alert %s is firing", alert.Labels["alertname"])
+		}
+		requests = append(requests, controllers.RestartRequest{
+			Namespace: alert.Labels["namespace"],
+			Pod:       alert.Labels["pod"],
+			Trigger:   controllers.TriggerAlert,
+			Name:      alert.Labels["alertname"],
+			Reason:    reason,
+			Time:      now,
+		})
+	}
+	// Alertmanager retries on errors, resolved alerts are acknowledged as well
+	if len(requests) == 0 {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	s.record(w, req, key, requests)
+}
+
+// podRestart returns the PodRestart a POST refers to
+func (s *triggerServer) podRestart(w http.ResponseWriter, req *http.Request) (types.NamespacedName, bool) {
+	if req.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return types.NamespacedName{}, false
+	}
+	key, ok := triggerPath(req.URL.Path)
+	if !ok {
+		http.Error(w, "expected {namespace}/{name} below "+triggerPrefix+" or "+alertmanagerPrefix, http.StatusBadRequest)
+	}
+	return key, ok
+}
+
+func (s *triggerServer) record(w http.ResponseWriter, req *http.Request, key types.NamespacedName, requests []controllers.RestartRequest) {
+	if err := s.requests(req.Context(), key, requests); err != nil {
+		if apierrors.IsNotFound(err) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		setupLog.Error(err, "Failed to record restart requests", "podrestart", key)
+		http.Error(w, "failed to record restart requests", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// triggerPath returns the PodRestart a path of the trigger API refers to
+func triggerPath(path string) (types.NamespacedName, bool) {
+	var rest string
+	switch {
+	case strings.HasPrefix(path, triggerPrefix):
+		rest = strings.TrimPrefix(path, triggerPrefix)
+	case strings.HasPrefix(path, alertmanagerPrefix):
+		rest = strings.TrimPrefix(path, alertmanagerPrefix)
+	default:
+		return types.NamespacedName{}, false
+	}
+	parts := strings.Split(rest, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return types.NamespacedName{}, false
+	}
+	return types.NamespacedName{Namespace: parts[0], Name: parts[1]}, true
+}
@@ -2,7 +2,22 @@
 package v1alpha1
 
 import (
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	// DefaultCheckInterval is used when spec.checkInterval is not set
+	DefaultCheckInterval = 30 * time.Second
+	// MinCheckInterval is the shortest allowed spec.checkInterval
+	MinCheckInterval = 10 * time.Second
+	// MaxCheckInterval is the longest allowed spec.checkInterval
+	MaxCheckInterval = time.Hour
+	// MaxLogFollowStreams is the largest allowed spec.logFollow.maxStreams
+	MaxLogFollowStreams = 100
 )
 
 // PodRestartSpec defines the desired state of PodRestart
@@ -10,15 +25,695 @@ type PodRestartSpec struct {
 	// PodSelector is a label selector to target pods
 	PodSelector metav1.LabelSelector `json:"podSelector"`
 
+	// Namespaces lists the namespaces whose pods are targeted instead of the
+	// PodRestart's own. Other namespaces are only targeted while the
+	// PodRestart's creator, or whoever last changed its spec, may delete pods
+	// and read their logs there, and exec, proxy or patch them as far as the
+	// spec does. Their pods get the defaults of their own namespace's policy.
+	// Namespaces that fail these checks, that the operator may not list pods
+	// in, or that are targeted while the webhooks are disabled, are reported
+	// in the Degraded condition and skipped.
+	// +optional
+	Namespaces []string `json:"namespaces,omitempty"`
+
+	// OwnerFilter narrows the pods of the selector to those owned by a
+	// specific workload, for selectors that span several
+	// +optional
+	OwnerFilter *OwnerFilter `json:"ownerFilter,omitempty"`
+
+	// Cluster targets the pods of a remote cluster instead of the local one.
+	// Requires the MultiCluster feature gate.
+	// +optional
+	Cluster *ClusterReference `json:"cluster,omitempty"`
+
 	// ErrorPatterns is a list of regex patterns to match against pod logs
 	ErrorPatterns []string `json:"errorPatterns,omitempty"`
 
+	// LogReadBudget limits the logs read for this PodRestart in a single
+	// reconcile. Pods left over once it is used up are evaluated on the next
+	// pass. The operator-wide budget applies as well, the lower limit wins.
+	// +optional
+	LogReadBudget *LogReadBudget `json:"logReadBudget,omitempty"`
+
+	// LogSampling limits how much of a single container's logs is read and
+	// matched, for containers logging so much that they would use up the
+	// log read budget on their own. Truncated containers are listed in
+	// status.lastEvaluation.
+	// +optional
+	LogSampling *LogSampling `json:"logSampling,omitempty"`
+
+	// LogFollow keeps the log streams of the selected pods open, with the
+	// LogFollow feature gate, and evaluates the PodRestart within seconds of
+	// an error pattern match, instead of at the next check interval. Matches
+	// request a pass at most once per minTimeBetweenRestarts or check interval.
+	// +optional
+	LogFollow *LogFollowSpec `json:"logFollow,omitempty"`
+
+	// Sampling evaluates a rotating subset of the selected pods on each
+	// pass instead of all of them, for selectors matching thousands of pods.
+	// Every pod is still evaluated at least once per period.
+	// +optional
+	Sampling *FleetSampling `json:"sampling,omitempty"`
+
+	// EvaluationDeadline bounds how late a pass may start after it is due.
+	// A PodRestart waiting for evaluation workers past it is served before
+	// PodRestarts that are not overdue. Defaults to the check interval.
+	// +kubebuilder:validation:Format=duration
+	// +optional
+	EvaluationDeadline *metav1.Duration `json:"evaluationDeadline,omitempty"`
+
+	// NamedErrorPatterns are error patterns reported under a name instead of
+	// the regex itself in metrics, events and status
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	NamedErrorPatterns []ErrorPattern `json:"namedErrorPatterns,omitempty"`
+
+	// LogSource names the log backend used to evaluate ErrorPatterns.
+	// Defaults to kubeAPI, which reads logs through the Kubernetes API.
+	// +optional
+	LogSource string `json:"logSource,omitempty"`
+
 	// MetricConditions defines metric-based conditions that trigger restarts
 	MetricConditions []MetricCondition `json:"metricConditions,omitempty"`
 
+	// ZombieProcesses restarts pods whose containers accumulate zombie
+	// (defunct) processes, which broken child process handling leaves
+	// behind without logging anything
+	// +optional
+	ZombieProcesses *ZombieProcessCondition `json:"zombieProcesses,omitempty"`
+
+	// Connections restarts pods whose number of established connections
+	// leaves the configured bounds, recycling pods that leak sockets or
+	// silently stopped accepting traffic
+	// +optional
+	Connections *ConnectionCountCondition `json:"connections,omitempty"`
+
+	// HeartbeatFiles restarts pods whose heartbeat file was not touched
+	// recently, for workers that cannot expose HTTP probes
+	// +optional
+	HeartbeatFiles []HeartbeatFileCondition `json:"heartbeatFiles,omitempty"`
+
+	// ExitCodes act on pods whose containers last terminated with specific
+	// exit codes, e.g. 139 for a segfault or an application's own codes
+	// +optional
+	ExitCodes []ExitCodeCondition `json:"exitCodes,omitempty"`
+
+	// ForbiddenImages act on pods running images that should no longer run,
+	// e.g. ones affected by a CVE, so they are recreated from the updated
+	// workload. Restarting only helps once the workload refers to a fixed
+	// image or its tag was moved to one with imagePullPolicy Always.
+	// +optional
+	ForbiddenImages []ForbiddenImageCondition `json:"forbiddenImages,omitempty"`
+
+	// BurnRates evaluate multi-window error budget burn rates against
+	// Prometheus recording rules and restart pods, or only notify, while the
+	// budget of the selected workload burns too fast
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	BurnRates []BurnRateCondition `json:"burnRates,omitempty"`
+
+	// JVM restarts Java pods whose heap exhaustion is imminent, read from
+	// their Jolokia agent before the container is OOM killed
+	// +optional
+	JVM *JVMCondition `json:"jvm,omitempty"`
+
+	// TriggerGroups combine triggers: a group restarts a pod when every
+	// trigger it references fires, and any satisfied group is enough. The
+	// triggers referenced by a group no longer restart pods on their own;
+	// the other triggers keep doing so.
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	TriggerGroups []TriggerGroup `json:"triggerGroups,omitempty"`
+
 	// MinTimeBetweenRestarts is the minimum time to wait between pod restarts
 	// +kubebuilder:validation:Format=duration
 	MinTimeBetweenRestarts *metav1.Duration `json:"minTimeBetweenRestarts,omitempty"`
+
+	// CheckInterval is how often the selected pods are evaluated. It must be
+	// between MinCheckInterval and MaxCheckInterval; defaults to 30s.
+	// +kubebuilder:validation:Format=duration
+	// +optional
+	CheckInterval *metav1.Duration `json:"checkInterval,omitempty"`
+
+	// MessageTemplate is a Go template used for restart condition, event and
+	// notification messages. It receives .Pod, .Namespace, .PodRestart, .Trigger,
+	// .Reason, .MatchedLine and .MetricValue.
+	// +optional
+	MessageTemplate string `json:"messageTemplate,omitempty"`
+
+	// HistoryLimit is the maximum number of entries kept in status.recentRestarts
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:default=10
+	// +optional
+	HistoryLimit *int32 `json:"historyLimit,omitempty"`
+
+	// AllowBarePods permits restarting pods without a controller. Such a
+	// pod is deleted for good rather than restarted, so they are refused
+	// unless this is set.
+	// +optional
+	AllowBarePods bool `json:"allowBarePods,omitempty"`
+
+	// ReplacementDeadline is how long the owning controller of a restarted
+	// pod has to schedule a replacement. A workload that misses it, because
+	// it is paused or scaled to zero, is reported as orphaned and its pods
+	// are not restarted until a replacement shows up.
+	// +kubebuilder:validation:Format=duration
+	// +kubebuilder:default="5m"
+	// +optional
+	ReplacementDeadline *metav1.Duration `json:"replacementDeadline,omitempty"`
+
+	// Action is how a pod is restarted once a trigger fires
+	// +kubebuilder:validation:Enum=Delete;Surge
+	// +kubebuilder:default=Delete
+	// +optional
+	Action RestartAction `json:"action,omitempty"`
+
+	// Surge configures the Surge action
+	// +optional
+	Surge *SurgeSpec `json:"surge,omitempty"`
+
+	// Suspend stops the operator from restarting any pods selected by this PodRestart
+	// +optional
+	Suspend bool `json:"suspend,omitempty"`
+
+	// DryRun evaluates the triggers as usual but deletes no pod. Each
+	// restart that would have happened is reported as skipped with reason
+	// DryRun.
+	// +optional
+	DryRun bool `json:"dryRun,omitempty"`
+
+	// BlackoutWindows are recurring periods in which no pod is restarted,
+	// e.g. business hours or a nightly batch run
+	// +optional
+	BlackoutWindows []BlackoutWindow `json:"blackoutWindows,omitempty"`
+
+	// IgnoreDisruptionBudgets restarts pods even when a PodDisruptionBudget
+	// selecting them allows no disruption. By default such restarts are
+	// skipped until the budget allows one again.
+	// +optional
+	IgnoreDisruptionBudgets bool `json:"ignoreDisruptionBudgets,omitempty"`
+
+	// RestartBudget caps how many restarts may be performed within a rolling window
+	// +optional
+	RestartBudget *RestartBudget `json:"restartBudget,omitempty"`
+
+	// Diagnostics captures the state of a pod into a ConfigMap or Secret before it is restarted
+	// +optional
+	Diagnostics *DiagnosticsSpec `json:"diagnostics,omitempty"`
+
+	// ContainerCheckpoint checkpoints containers through the kubelet before
+	// the pod is restarted, preserving their process state for forensics.
+	// Requires the ContainerCheckpoint feature on the nodes.
+	// +optional
+	ContainerCheckpoint *ContainerCheckpointSpec `json:"containerCheckpoint,omitempty"`
+
+	// EvaluationReports writes the per-pod results of each pass to
+	// EvaluationReport objects, for detail that does not fit in status
+	// +optional
+	EvaluationReports *EvaluationReportsSpec `json:"evaluationReports,omitempty"`
+
+	// Notifications selects the NotificationChannels that receive restart notifications
+	// +optional
+	Notifications *NotificationSpec `json:"notifications,omitempty"`
+
+	// MemoryRecommendation suggests a larger memory limit for workloads that
+	// keep being restarted after their containers are OOM killed
+	// +optional
+	MemoryRecommendation *MemoryRecommendationSpec `json:"memoryRecommendation,omitempty"`
+
+	// Autoscaling controls how restarts cooperate with the HorizontalPodAutoscalers
+	// and KEDA ScaledObjects scaling the pod's workload
+	// +optional
+	Autoscaling *AutoscalingSpec `json:"autoscaling,omitempty"`
+
+	// GitOps defers restarts of workloads whose Flux Kustomization or
+	// HelmRelease is suspended or being reconciled
+	// +optional
+	GitOps *GitOpsSpec `json:"gitOps,omitempty"`
+
+	// CordonedNodes sets how pods on cordoned or draining nodes are treated
+	// when their triggers fire
+	// +kubebuilder:validation:Enum=Normal;Skip;Prefer
+	// +kubebuilder:default=Normal
+	// +optional
+	CordonedNodes CordonedNodePolicy `json:"cordonedNodes,omitempty"`
+}
+
+// BlackoutWindowTimeFormat is the layout of BlackoutWindow.Start
+const BlackoutWindowTimeFormat = "15:04"
+
+// BlackoutWindowDays maps the day names of BlackoutWindow.Days to weekdays
+var BlackoutWindowDays = map[string]time.Weekday{
+	"Mon": time.Monday,
+	"Tue": time.Tuesday,
+	"Wed": time.Wednesday,
+	"Thu": time.Thursday,
+	"Fri": time.Friday,
+	"Sat": time.Saturday,
+	"Sun": time.Sunday,
+}
+
+// BlackoutWindow is a recurring period in which no pod is restarted
+type BlackoutWindow struct {
+	// Start is the time of day the window opens, as HH:MM
+	// +kubebuilder:validation:Pattern=`^([01][0-9]|2[0-3]):[0-5][0-9]$`
+	Start string `json:"start"`
+
+	// Duration is how long the window stays open
+	// +kubebuilder:validation:Format=duration
+	Duration metav1.Duration `json:"duration"`
+
+	// Days are the weekdays the window opens on, e.g. Mon or Sat. Every day
+	// when empty.
+	// +kubebuilder:validation:items:Enum=Mon;Tue;Wed;Thu;Fri;Sat;Sun
+	// +optional
+	Days []string `json:"days,omitempty"`
+
+	// TimeZone is the IANA time zone Start is in. Defaults to UTC.
+	// +optional
+	TimeZone string `json:"timeZone,omitempty"`
+}
+
+// CordonedNodePolicy is the treatment of triggered pods on cordoned or draining nodes
+type CordonedNodePolicy string
+
+const (
+	// CordonedNodesNormal restarts pods on cordoned nodes like any other pod
+	CordonedNodesNormal CordonedNodePolicy = "Normal"
+	// CordonedNodesSkip leaves pods on cordoned nodes to the drain
+	CordonedNodesSkip CordonedNodePolicy = "Skip"
+	// CordonedNodesPrefer restarts pods on cordoned nodes before the others,
+	// so they are rescheduled onto healthy nodes first
+	CordonedNodesPrefer CordonedNodePolicy = "Prefer"
+)
+
+// OwnerFilter matches pods by the workload owning them. Pods of Deployments
+// are matched by their Deployment rather than their ReplicaSet, and pods
+// without a controller by kind Pod and their own name.
+type OwnerFilter struct {
+	// Kind is the owning workload's kind, e.g. Deployment or StatefulSet
+	// +optional
+	Kind string `json:"kind,omitempty"`
+
+	// NameRegex must match the owning workload's whole name
+	// +optional
+	NameRegex string `json:"nameRegex,omitempty"`
+}
+
+// NotificationSpec selects NotificationChannels by name or by label
+type NotificationSpec struct {
+	// Channels lists NotificationChannels by name
+	// +optional
+	Channels []string `json:"channels,omitempty"`
+
+	// ChannelSelector selects NotificationChannels by their labels
+	// +optional
+	ChannelSelector *metav1.LabelSelector `json:"channelSelector,omitempty"`
+
+	// EscalationChannels lists NotificationChannels that only receive
+	// critical escalations, e.g. an on-call pager
+	// +optional
+	EscalationChannels []string `json:"escalationChannels,omitempty"`
+
+	// SlackChannel overrides the Slack channel used by Slack NotificationChannels
+	// +optional
+	SlackChannel string `json:"slackChannel,omitempty"`
+}
+
+// RestartAction is the mechanism used to restart a pod
+type RestartAction string
+
+const (
+	// ActionDelete deletes the pod and lets its owning controller recreate it
+	ActionDelete RestartAction = "Delete"
+	// ActionSurge scales the owning Deployment up by one replica, waits for the
+	// new pod to be Ready, deletes the pod and scales the Deployment back.
+	// Deployments scaled by a HorizontalPodAutoscaler or applied by Flux are
+	// not scaled; their pods are deleted.
+	ActionSurge RestartAction = "Surge"
+)
+
+// SurgeSpec configures surge restarts
+type SurgeSpec struct {
+	// ReadyTimeout is how long to wait for the extra pod to become Ready
+	// before giving up on the restart. Defaults to 5m.
+	// +optional
+	ReadyTimeout *metav1.Duration `json:"readyTimeout,omitempty"`
+}
+
+// DiagnosticsStorage selects the kind of object diagnostics bundles are stored in
+type DiagnosticsStorage string
+
+const (
+	// DiagnosticsConfigMap stores bundles in ConfigMaps
+	DiagnosticsConfigMap DiagnosticsStorage = "ConfigMap"
+	// DiagnosticsSecret stores bundles in Secrets, for logs that may contain sensitive data
+	DiagnosticsSecret DiagnosticsStorage = "Secret"
+)
+
+// DiagnosticsSpec configures the pre-restart diagnostics bundle
+type DiagnosticsSpec struct {
+	// Enabled turns on diagnostics capture
+	Enabled bool `json:"enabled"`
+
+	// LogLines is the number of trailing log lines captured per container
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:default=200
+	// +optional
+	LogLines *int32 `json:"logLines,omitempty"`
+
+	// TTL is how long a bundle is kept before it is deleted
+	// +kubebuilder:validation:Format=duration
+	// +kubebuilder:default="24h"
+	// +optional
+	TTL *metav1.Duration `json:"ttl,omitempty"`
+
+	// StoreAs selects whether bundles are stored in ConfigMaps or Secrets
+	// +kubebuilder:validation:Enum=ConfigMap;Secret
+	// +kubebuilder:default=ConfigMap
+	// +optional
+	StoreAs DiagnosticsStorage `json:"storeAs,omitempty"`
+
+	// Dumps are commands executed in the pod before it is restarted whose
+	// output is stored in the bundle, e.g. heap dumps or pprof profiles
+	// +optional
+	Dumps []DumpCommand `json:"dumps,omitempty"`
+
+	// DebugContainer adds an ephemeral container to the pod before it is
+	// restarted and stores its output in the bundle. It shares the process
+	// namespace of the target container, so tools missing from the
+	// application image, such as ps or netstat, can inspect it.
+	// +optional
+	DebugContainer *DebugContainerSpec `json:"debugContainer,omitempty"`
+
+	// Upload additionally writes the untruncated bundle to object storage
+	// +optional
+	Upload *DiagnosticsUpload `json:"upload,omitempty"`
+}
+
+// ContainerCheckpointSpec configures checkpoints taken before a restart
+type ContainerCheckpointSpec struct {
+	// Enabled turns on checkpoints
+	Enabled bool `json:"enabled"`
+
+	// Containers to checkpoint. Defaults to every running container.
+	// +optional
+	Containers []string `json:"containers,omitempty"`
+
+	// Timeout bounds the checkpoint of a single container
+	// +kubebuilder:validation:Format=duration
+	// +kubebuilder:default="1m"
+	// +optional
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+}
+
+// EvaluationReportsSpec configures EvaluationReport objects
+type EvaluationReportsSpec struct {
+	// Enabled turns on reports
+	Enabled bool `json:"enabled"`
+
+	// History is the number of reports kept; older ones are deleted
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:default=3
+	// +optional
+	History *int32 `json:"history,omitempty"`
+
+	// MaxLineLength truncates matched log lines to this many bytes
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:default=256
+	// +optional
+	MaxLineLength *int32 `json:"maxLineLength,omitempty"`
+
+	// RedactPatterns are regular expressions whose matches are replaced
+	// in matched log lines before they are written, e.g. tokens or emails
+	// +optional
+	RedactPatterns []string `json:"redactPatterns,omitempty"`
+}
+
+// DumpCommand captures an artifact by executing a command in a container
+type DumpCommand struct {
+	// Name identifies the artifact in the bundle, e.g. heap
+	// +kubebuilder:validation:Pattern=`^[-._a-zA-Z0-9]+$`
+	Name string `json:"name"`
+
+	// Container to execute the command in. Defaults to the first container
+	// +optional
+	Container string `json:"container,omitempty"`
+
+	// Command is executed without a shell and the artifact is read from its
+	// stdout, e.g. ["curl", "-s", "http://localhost:6060/debug/pprof/heap"].
+	// Output beyond 8MiB fails the dump, so large artifacts such as JVM heap
+	// dumps should be compressed or summarized by the command.
+	// +kubebuilder:validation:MinItems=1
+	Command []string `json:"command"`
+
+	// Timeout bounds the command
+	// +kubebuilder:validation:Format=duration
+	// +kubebuilder:default="60s"
+	// +optional
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+}
+
+// DebugContainerSpec configures the ephemeral debug container of a bundle
+type DebugContainerSpec struct {
+	// Image is the debug image, e.g. busybox or nicolaka/netshoot. It must
+	// match the operator's --debug-container-images.
+	Image string `json:"image"`
+
+	// Command is the entrypoint of the debug container, whose output is
+	// stored in the bundle, e.g. ["sh", "-c", "ps aux; netstat -tanp"].
+	// Defaults to the image's entrypoint.
+	// +optional
+	Command []string `json:"command,omitempty"`
+
+	// Args are the arguments of the command
+	// +optional
+	Args []string `json:"args,omitempty"`
+
+	// TargetContainer is the container whose process namespace is shared.
+	// Defaults to the first container.
+	// +optional
+	TargetContainer string `json:"targetContainer,omitempty"`
+
+	// Timeout bounds the wait for the command to finish. The restart is
+	// held back on later passes until then.
+	// +kubebuilder:validation:Format=duration
+	// +kubebuilder:default="2m"
+	// +optional
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+}
+
+// UploadProvider is an object storage service
+type UploadProvider string
+
+const (
+	// UploadS3 uploads to Amazon S3 or an S3 compatible service
+	UploadS3 UploadProvider = "S3"
+	// UploadGCS uploads to Google Cloud Storage
+	UploadGCS UploadProvider = "GCS"
+	// UploadAzureBlob uploads to Azure Blob Storage
+	UploadAzureBlob UploadProvider = "AzureBlob"
+)
+
+// DiagnosticsUpload configures uploading diagnostics bundles to object storage
+type DiagnosticsUpload struct {
+	// Provider is the object storage service
+	// +kubebuilder:validation:Enum=S3;GCS;AzureBlob
+	Provider UploadProvider `json:"provider"`
+
+	// Bucket is the bucket, or the container for Azure Blob Storage
+	Bucket string `json:"bucket"`
+
+	// Prefix is prepended to every object key
+	// +optional
+	Prefix string `json:"prefix,omitempty"`
+
+	// Endpoint overrides the service endpoint. Required for Azure when no
+	// connection string is provided, e.g. https://account.blob.core.windows.net
+	// +optional
+	Endpoint string `json:"endpoint,omitempty"`
+
+	// Region of the S3 bucket
+	// +optional
+	Region string `json:"region,omitempty"`
+
+	// CredentialsSecretRef references a Secret with static credentials:
+	// AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY for S3, credentials.json for
+	// GCS or connectionString for Azure. Workload identity is used when unset.
+	// The Secret must be in the PodRestart's namespace.
+	// +optional
+	CredentialsSecretRef *SecretReference `json:"credentialsSecretRef,omitempty"`
+
+	// FullLogs uploads the complete container logs instead of the last
+	// logLines, up to 32MiB per container
+	// +optional
+	FullLogs bool `json:"fullLogs,omitempty"`
+}
+
+// RestartBudget limits the number of restarts within a time window
+type RestartBudget struct {
+	// MaxRestarts is the maximum number of restarts allowed within Window
+	// +kubebuilder:validation:Minimum=1
+	MaxRestarts int32 `json:"maxRestarts"`
+
+	// Window is the length of the sliding budget window: a restart counts
+	// against the budget until Window after it was performed
+	// +kubebuilder:validation:Format=duration
+	Window metav1.Duration `json:"window"`
+}
+
+// LogReadBudget limits log fetching in a single reconcile
+type LogReadBudget struct {
+	// MaxBytes is the number of log bytes read per reconcile, e.g. 64Mi
+	// +optional
+	MaxBytes *resource.Quantity `json:"maxBytes,omitempty"`
+
+	// MaxDuration is the time spent reading logs per reconcile
+	// +kubebuilder:validation:Format=duration
+	// +optional
+	MaxDuration *metav1.Duration `json:"maxDuration,omitempty"`
+}
+
+// LogSampling caps and thins out the logs read per container
+type LogSampling struct {
+	// MaxBytesPerContainer is the number of log bytes read per container
+	// and pass, e.g. 4Mi. With a log cursor the rest is read on later passes.
+	// +optional
+	MaxBytesPerContainer *resource.Quantity `json:"maxBytesPerContainer,omitempty"`
+
+	// SampleEvery matches only every Nth log line against the error
+	// patterns. 1 matches every line.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	SampleEvery *int32 `json:"sampleEvery,omitempty"`
+}
+
+// LogFollowSpec configures streaming of container logs
+type LogFollowSpec struct {
+	// Enabled turns on streaming
+	Enabled bool `json:"enabled"`
+
+	// MaxStreams is the number of container log streams kept open for this
+	// PodRestart. Pods beyond it are only evaluated at the check interval.
+	// The operator may keep fewer open to share its streams between every
+	// following PodRestart.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=100
+	// +kubebuilder:default=10
+	// +optional
+	MaxStreams *int32 `json:"maxStreams,omitempty"`
+}
+
+// FleetSampling configures the evaluation of pod subsets
+type FleetSampling struct {
+	// Period within which every pod is evaluated once. Each pass evaluates
+	// about checkInterval/period of the pods. Which pods follows the wall
+	// clock, so passes triggered by pod events evaluate the current subset
+	// again.
+	// +kubebuilder:validation:Format=duration
+	Period metav1.Duration `json:"period"`
+
+	// MinPods is the number of targeted pods from which on sampling applies.
+	// Smaller fleets are evaluated in full.
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:default=1000
+	// +optional
+	MinPods *int32 `json:"minPods,omitempty"`
+}
+
+// AutoscalingSpec configures restart deferral while a workload is being scaled
+type AutoscalingSpec struct {
+	// DeferDuringScaling defers restarts while an autoscaler is scaling the
+	// workload or its pods are still catching up with the new replica count,
+	// so restarts do not deepen a capacity dip or skew the autoscaler's
+	// metrics. Defaults to true.
+	// +optional
+	DeferDuringScaling *bool `json:"deferDuringScaling,omitempty"`
+
+	// SettleTime is how long restarts stay deferred after a scale event
+	// +kubebuilder:validation:Format=duration
+	// +kubebuilder:default="3m"
+	// +optional
+	SettleTime *metav1.Duration `json:"settleTime,omitempty"`
+}
+
+// GitOpsSpec configures restart deferral for workloads managed by Flux
+type GitOpsSpec struct {
+	// DeferWhenSuspended defers restarts while the workload's Kustomization or
+	// HelmRelease is suspended, so remediation does not mask a paused rollout
+	// +optional
+	DeferWhenSuspended bool `json:"deferWhenSuspended,omitempty"`
+
+	// DeferWhileReconciling defers restarts while the workload's Kustomization
+	// or HelmRelease is applying a change
+	// +optional
+	DeferWhileReconciling bool `json:"deferWhileReconciling,omitempty"`
+}
+
+// MemoryRecommendationSpec configures memory limit suggestions for workloads
+// restarted repeatedly after OOM kills
+type MemoryRecommendationSpec struct {
+	// MinOOMRestarts is the number of OOM-killed restarts of a workload within
+	// status.recentRestarts before a suggestion is made
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:default=3
+	// +optional
+	MinOOMRestarts int32 `json:"minOOMRestarts,omitempty"`
+
+	// Provider is the MetricProvider queried for the observed working set.
+	// Without one the suggestion is derived from the current memory limit.
+	// +optional
+	Provider string `json:"provider,omitempty"`
+
+	// Query overrides the working set query. It receives .Namespace, .Pod and .Container.
+	// +optional
+	Query string `json:"query,omitempty"`
+
+	// HeadroomPercent is added on top of the observed peak working set
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:default=25
+	// +optional
+	HeadroomPercent *int32 `json:"headroomPercent,omitempty"`
+
+	// CreateVerticalPodAutoscaler creates or updates a VerticalPodAutoscaler
+	// named <workload>-podrestart in recommendation-only mode for the
+	// workload, owned by the PodRestart and annotated with the suggestion.
+	// VerticalPodAutoscalers the operator did not create are never changed.
+	// +optional
+	CreateVerticalPodAutoscaler bool `json:"createVerticalPodAutoscaler,omitempty"`
+}
+
+// ClusterReference points to the kubeconfig of a remote cluster
+type ClusterReference struct {
+	// SecretName is the Secret in the PodRestart's namespace holding the
+	// kubeconfig. Only inline tokens and certificates are accepted; exec
+	// plugins, auth providers and file references are rejected, as are
+	// servers not listed in the operator's --cluster-servers.
+	SecretName string `json:"secretName"`
+
+	// Key is the Secret key holding the kubeconfig. Defaults to "kubeconfig".
+	// +optional
+	Key string `json:"key,omitempty"`
+
+	// Namespace is the remote namespace whose pods are targeted. Defaults to
+	// the PodRestart's namespace.
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// ErrorPattern is a named regex matched against pod logs
+type ErrorPattern struct {
+	// Name identifies the pattern in metrics, events and status
+	// +kubebuilder:validation:Pattern=`^[a-zA-Z0-9]([-._a-zA-Z0-9]*[a-zA-Z0-9])?$`
+	Name string `json:"name"`
+
+	// Pattern is the regex matched against each log chunk
+	Pattern string `json:"pattern"`
+
+	TriggerOptions `json:",inline"`
 }
 
 // MetricCondition defines a metric-based condition for pod restart
@@ -31,23 +726,958 @@ type MetricCondition struct {
 
 	// Operator is the comparison operator (>, <, >=, <=, ==)
 	Operator string `json:"operator"`
+
+	// Provider is the name of the MetricProvider used to evaluate this condition
+	// +optional
+	Provider string `json:"provider,omitempty"`
+
+	// Query overrides the provider's query template for this condition
+	// +optional
+	Query string `json:"query,omitempty"`
+
+	TriggerOptions `json:",inline"`
 }
 
+// Condition types maintained on PodRestart status
+const (
+	// ConditionReady is True when the PodRestart is being evaluated without errors
+	ConditionReady = "Ready"
+	// ConditionProgressing is True while pods are being restarted
+	ConditionProgressing = "Progressing"
+	// ConditionDegraded is True when the last evaluation hit errors
+	ConditionDegraded = "Degraded"
+	// ConditionBudgetExhausted is True when the restart budget has been used up
+	ConditionBudgetExhausted = "BudgetExhausted"
+	// ConditionSuspended is True when spec.suspend is set
+	ConditionSuspended = "Suspended"
+)
+
+// ZombieProcessCondition counts the zombie processes of a container by
+// running a command in it, which needs the ExecTriggers feature gate
+type ZombieProcessCondition struct {
+	// Containers are the containers checked. Defaults to every container of the pod.
+	// +optional
+	Containers []string `json:"containers,omitempty"`
+
+	// Threshold is the number of zombie processes a container may hold before
+	// the pod is restarted
+	// +kubebuilder:validation:Minimum=0
+	Threshold int32 `json:"threshold"`
+
+	// Command prints the number of zombie processes. Defaults to counting the
+	// processes in state Z under /proc, which needs sh, grep and wc in the image.
+	// A custom command needs its creator to be allowed to exec into the pods.
+	// +optional
+	Command []string `json:"command,omitempty"`
+
+	// Timeout bounds the command. Defaults to 10s.
+	// +kubebuilder:validation:Format=duration
+	// +optional
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+
+	TriggerOptions `json:",inline"`
+}
+
+// ConnectionCountCondition bounds the number of established connections of
+// a pod, counted by a metric query or by running a command in it, which
+// needs the ExecTriggers feature gate
+type ConnectionCountCondition struct {
+	// Max restarts the pod when more connections are established
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	Max *int32 `json:"max,omitempty"`
+
+	// Min restarts the pod when fewer connections are established
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	Min *int32 `json:"min,omitempty"`
+
+	// Container is the container the command runs in. Containers of a pod
+	// share its network namespace, so any running container will do.
+	// Defaults to the first container.
+	// +optional
+	Container string `json:"container,omitempty"`
+
+	// Command prints the connection count, either as a number or in the
+	// format of `ss -s`, which is the default. A custom command needs its
+	// creator to be allowed to exec into the pods.
+	// +optional
+	Command []string `json:"command,omitempty"`
+
+	// Provider is a MetricProvider to query the count from instead of running a command
+	// +optional
+	Provider string `json:"provider,omitempty"`
+
+	// Query is the query run against Provider. It receives .Namespace and .Pod.
+	// +optional
+	Query string `json:"query,omitempty"`
+
+	// Timeout bounds the command. Defaults to 10s.
+	// +kubebuilder:validation:Format=duration
+	// +optional
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+
+	TriggerOptions `json:",inline"`
+}
+
+// HeartbeatFileCondition checks the modification time of a file a container
+// touches while it is healthy by running stat in it, which needs the
+// ExecTriggers feature gate
+type HeartbeatFileCondition struct {
+	// Path is the absolute path of the heartbeat file inside the container
+	Path string `json:"path"`
+
+	// MaxAge is how old the file may get before the pod is restarted. A
+	// missing file counts as stale once the container has run this long.
+	// +kubebuilder:validation:Format=duration
+	MaxAge metav1.Duration `json:"maxAge"`
+
+	// Container is the container holding the file. Defaults to the first container.
+	// +optional
+	Container string `json:"container,omitempty"`
+
+	// Timeout bounds the stat command. Defaults to 10s.
+	// +kubebuilder:validation:Format=duration
+	// +optional
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+
+	TriggerOptions `json:",inline"`
+}
+
+// ExitCodeAction is what happens when an exit code condition fires
+type ExitCodeAction string
+
+const (
+	// ExitCodeRestart restarts the pod
+	ExitCodeRestart ExitCodeAction = "Restart"
+	// ExitCodeQuarantine takes a pod of a ReplicaSet out of it by removing
+	// the ReplicaSet's selector labels, and leaves it running for
+	// investigation until quarantineTTL passed. The workload replaces it like
+	// a restarted pod.
+	ExitCodeQuarantine ExitCodeAction = "Quarantine"
+	// ExitCodeNotify sends a notification without restarting
+	ExitCodeNotify ExitCodeAction = "Notify"
+)
+
+// ExitCodeCondition fires when a container's last termination, as reported
+// in its lastState, ended with one of the exit codes
+type ExitCodeCondition struct {
+	// Name identifies the condition in metrics, events and status
+	// +kubebuilder:validation:Pattern=`^[a-zA-Z0-9]([-._a-zA-Z0-9]*[a-zA-Z0-9])?$`
+	Name string `json:"name"`
+
+	// Codes are the exit codes the condition fires for, e.g. 137 or 139
+	// +kubebuilder:validation:MinItems=1
+	Codes []int32 `json:"codes"`
+
+	// Containers limits the condition to these containers. Every container is checked when empty.
+	// +optional
+	Containers []string `json:"containers,omitempty"`
+
+	// Within only counts terminations that finished this recently. Any
+	// termination still reported in lastState counts when not set.
+	// +kubebuilder:validation:Format=duration
+	// +optional
+	Within *metav1.Duration `json:"within,omitempty"`
+
+	// Action is what happens when the condition fires
+	// +kubebuilder:validation:Enum=Restart;Quarantine;Notify
+	// +kubebuilder:default=Restart
+	// +optional
+	Action ExitCodeAction `json:"action,omitempty"`
+
+	// QuarantineTTL is how long a quarantined pod is kept before it is deleted
+	// +kubebuilder:validation:Format=duration
+	// +kubebuilder:default="24h"
+	// +optional
+	QuarantineTTL *metav1.Duration `json:"quarantineTTL,omitempty"`
+
+	// Diagnostics captures a diagnostics bundle before acting on the pod,
+	// with the settings of spec.diagnostics if set, even when capture is not
+	// enabled there
+	// +optional
+	Diagnostics bool `json:"diagnostics,omitempty"`
+
+	TriggerOptions `json:",inline"`
+}
+
+// ImageAction is what happens when a forbidden image condition fires
+type ImageAction string
+
+const (
+	// ImageRestart restarts the pod
+	ImageRestart ImageAction = "Restart"
+	// ImageNotify sends a notification without restarting
+	ImageNotify ImageAction = "Notify"
+)
+
+// ForbiddenImageCondition fires when a container runs an image matching one
+// of the patterns or digests
+type ForbiddenImageCondition struct {
+	// Name identifies the condition in metrics, events and status
+	// +kubebuilder:validation:Pattern=`^[a-zA-Z0-9]([-._a-zA-Z0-9]*[a-zA-Z0-9])?$`
+	Name string `json:"name"`
+
+	// Images are glob patterns matched against the image references of the
+	// pod spec, e.g. "registry.example.com/payments/*:1.4.*". As in
+	// path.Match, * does not match a slash.
+	// +optional
+	Images []string `json:"images,omitempty"`
+
+	// Digests match the image digests the containers actually run, as
+	// reported in their imageID, e.g. "sha256:3f1a...". These also catch
+	// tags that were moved.
+	// +optional
+	Digests []string `json:"digests,omitempty"`
+
+	// Containers limits the condition to these containers. Every container is checked when empty.
+	// +optional
+	Containers []string `json:"containers,omitempty"`
+
+	// Action is what happens when the condition fires
+	// +kubebuilder:validation:Enum=Restart;Notify
+	// +kubebuilder:default=Restart
+	// +optional
+	Action ImageAction `json:"action,omitempty"`
+
+	TriggerOptions `json:",inline"`
+}
+
+// TriggerGroup is a set of triggers that must all fire to restart a pod
+type TriggerGroup struct {
+	// Name identifies the group in metrics, events and status
+	// +kubebuilder:validation:Pattern=`^[a-zA-Z0-9]([-._a-zA-Z0-9]*[a-zA-Z0-9])?$`
+	Name string `json:"name"`
+
+	// AllOf are the triggers that must all fire
+	// +kubebuilder:validation:MinItems=1
+	AllOf []TriggerReference `json:"allOf"`
+}
+
+// TriggerReference selects a trigger condition of the PodRestart
+type TriggerReference struct {
+	// Trigger is the kind of condition
+	// +kubebuilder:validation:Enum=ErrorPattern;MetricCondition;BurnRate;JVM;ZombieProcesses;ConnectionCount;HeartbeatFile;ExitCode;ForbiddenImage
+	Trigger string `json:"trigger"`
+
+	// Name selects a single condition of the kind: the error pattern, metric
+	// condition, burn rate, exit code or forbidden image name, or the
+	// heartbeat file path. It must name a condition of the PodRestart. Any
+	// condition of the kind matches when empty.
+	// +optional
+	Name string `json:"name,omitempty"`
+}
+
+// TriggerOptions are the settings every trigger condition carries
+type TriggerOptions struct {
+	// MinTimeBetweenRestarts replaces the PodRestart's minTimeBetweenRestarts
+	// for restarts triggered by this condition
+	// +kubebuilder:validation:Format=duration
+	// +optional
+	MinTimeBetweenRestarts *metav1.Duration `json:"minTimeBetweenRestarts,omitempty"`
+
+	// Severity is Critical to act on the pod when the condition fires, or
+	// Warn to only emit events, notifications and metrics
+	// +kubebuilder:validation:Enum=Warn;Critical
+	// +kubebuilder:default=Critical
+	// +optional
+	Severity TriggerSeverity `json:"severity,omitempty"`
+}
+
+// TriggerSeverity decides whether a firing trigger restarts the pod
+type TriggerSeverity string
+
+const (
+	// TriggerSeverityWarn triggers only report that they fired
+	TriggerSeverityWarn TriggerSeverity = "Warn"
+	// TriggerSeverityCritical triggers restart the pod
+	TriggerSeverityCritical TriggerSeverity = "Critical"
+)
+
+// BurnRateAction is what happens when a burn rate condition fires
+type BurnRateAction string
+
+const (
+	// BurnRateRestart restarts the pod
+	BurnRateRestart BurnRateAction = "Restart"
+	// BurnRateNotify sends a BurnRateExceeded notification without restarting
+	BurnRateNotify BurnRateAction = "Notify"
+)
+
+// BurnRateCondition fires when the error budget of an SLO burns faster than
+// a threshold over both the long and the short window of any window pair
+type BurnRateCondition struct {
+	// Name identifies the condition in metrics, events and status
+	// +kubebuilder:validation:Pattern=`^[a-zA-Z0-9]([-._a-zA-Z0-9]*[a-zA-Z0-9])?$`
+	Name string `json:"name"`
+
+	// Provider is the MetricProvider queried. Defaults to the operator's default provider.
+	// +optional
+	Provider string `json:"provider,omitempty"`
+
+	// Objective is the SLO target in percent, e.g. "99.9"
+	Objective string `json:"objective"`
+
+	// ErrorRatioQuery returns the ratio of failed to total requests of the
+	// workload over a window, typically an SLO recording rule. It receives
+	// .Window (e.g. 5m), .Namespace and .Workload. It is evaluated once per
+	// workload and pass, and restarts at most one pod of the workload per pass.
+	ErrorRatioQuery string `json:"errorRatioQuery"`
+
+	// Windows are the window pairs evaluated. Defaults to the fast and slow
+	// burn pairs of the SRE workbook: 1h/5m at 14.4 and 6h/30m at 6.
+	// +optional
+	Windows []BurnRateWindow `json:"windows,omitempty"`
+
+	// Action is what happens when the condition fires
+	// +kubebuilder:validation:Enum=Restart;Notify
+	// +kubebuilder:default=Restart
+	// +optional
+	Action BurnRateAction `json:"action,omitempty"`
+
+	TriggerOptions `json:",inline"`
+}
+
+// BurnRateWindow is a long and a short window that must both burn faster than Factor
+type BurnRateWindow struct {
+	// +kubebuilder:validation:Format=duration
+	Long metav1.Duration `json:"long"`
+
+	// +kubebuilder:validation:Format=duration
+	Short metav1.Duration `json:"short"`
+
+	// Factor is the burn rate threshold, e.g. "14.4"
+	Factor string `json:"factor"`
+}
+
+// JVMCondition reads JVM MBeans from a Jolokia agent in the pod. The agent is
+// reached through the API server's pod proxy.
+type JVMCondition struct {
+	// Port is the Jolokia agent port. Defaults to 8778.
+	// +optional
+	Port int32 `json:"port,omitempty"`
+
+	// Path is the Jolokia endpoint path. Defaults to /jolokia.
+	// +optional
+	Path string `json:"path,omitempty"`
+
+	// Scheme is http or https. Defaults to http.
+	// +kubebuilder:validation:Enum=http;https
+	// +optional
+	Scheme string `json:"scheme,omitempty"`
+
+	// OldGenAfterGCPercent restarts the pod when the old generation is still
+	// fuller than this percentage of its maximum after the last collection
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=100
+	// +optional
+	OldGenAfterGCPercent *int32 `json:"oldGenAfterGCPercent,omitempty"`
+
+	// MaxGCPause restarts the pod when the last collection of any garbage
+	// collector took longer
+	// +kubebuilder:validation:Format=duration
+	// +optional
+	MaxGCPause *metav1.Duration `json:"maxGCPause,omitempty"`
+
+	// OldGenPool is the memory pool treated as the old generation. Defaults
+	// to the heap pool whose name contains "Old Gen" or "Tenured".
+	// +optional
+	OldGenPool string `json:"oldGenPool,omitempty"`
+
+	// Timeout bounds each Jolokia request. Defaults to 10s.
+	// +kubebuilder:validation:Format=duration
+	// +optional
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+
+	TriggerOptions `json:",inline"`
+}
+
+// PodRestartPhase is a high level summary of the PodRestart state
+type PodRestartPhase string
+
+const (
+	// PhaseActive means pods are being evaluated and restarted as needed
+	PhaseActive PodRestartPhase = "Active"
+	// PhaseSuspended means spec.suspend is set
+	PhaseSuspended PodRestartPhase = "Suspended"
+	// PhaseDegraded means the last evaluation hit errors
+	PhaseDegraded PodRestartPhase = "Degraded"
+	// PhaseBudgetExhausted means no further restarts are allowed in the current window
+	PhaseBudgetExhausted PodRestartPhase = "BudgetExhausted"
+)
+
 // PodRestartStatus defines the observed state of PodRestart
 type PodRestartStatus struct {
+	// ObservedGeneration is the most recent generation observed by the controller
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Phase summarizes the current state of the PodRestart
+	// +optional
+	Phase PodRestartPhase `json:"phase,omitempty"`
+
+	// TargetedPods is the number of pods matched by the pod selector on the last evaluation
+	// +optional
+	TargetedPods int32 `json:"targetedPods"`
+
+	// MatchingPods is the number of targeted pods whose restart triggers fired on the last evaluation
+	// +optional
+	MatchingPods int32 `json:"matchingPods"`
+
 	// LastRestartTime is the last time a pod was restarted
 	LastRestartTime *metav1.Time `json:"lastRestartTime,omitempty"`
 
 	// RestartCount is the number of restarts performed
 	RestartCount int `json:"restartCount"`
 
+	// BudgetRestartTimes are the times of the restarts within the last
+	// restart budget window, newest first
+	// +optional
+	BudgetRestartTimes []metav1.Time `json:"budgetRestartTimes,omitempty"`
+
+	// RestartsInWindow is the number of restarts performed within the last budget window
+	// +optional
+	RestartsInWindow int32 `json:"restartsInWindow,omitempty"`
+
+	// BudgetRemaining is the number of restarts the budget allows right
+	// now. Unset when the PodRestart has no restart budget.
+	// +optional
+	BudgetRemaining *int32 `json:"budgetRemaining,omitempty"`
+
+	// ConsecutiveRestartFailures is the number of restarts that failed in a
+	// row, across all pods
+	// +optional
+	ConsecutiveRestartFailures int32 `json:"consecutiveRestartFailures,omitempty"`
+
+	// CircuitOpenUntil is set while the circuit breaker blocks restarts
+	// after too many restarts failed in a row
+	// +optional
+	CircuitOpenUntil *metav1.Time `json:"circuitOpenUntil,omitempty"`
+
+	// TriggerRestarts holds the last restart time of every trigger with its
+	// own minTimeBetweenRestarts
+	// +optional
+	TriggerRestarts []TriggerRestartTime `json:"triggerRestarts,omitempty"`
+
+	// Policy records the PodRestartPolicy defaults in effect
+	// +optional
+	Policy *AppliedPolicy `json:"policy,omitempty"`
+
+	// LastEvaluation summarizes the most recent evaluation pass
+	// +optional
+	LastEvaluation *EvaluationSummary `json:"lastEvaluation,omitempty"`
+
 	// Conditions represent the latest available observations of the PodRestart state
+	// +listType=map
+	// +listMapKey=type
 	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// RecentRestarts holds the most recent restart attempts, newest first
+	// +optional
+	RecentRestarts []RestartRecord `json:"recentRestarts,omitempty"`
+
+	// SkippedRestarts lists pods whose triggers fired on the last evaluation but
+	// were not restarted, along with the reason
+	// +optional
+	SkippedRestarts []SkippedRestart `json:"skippedRestarts,omitempty"`
+
+	// DeferredPods is the number of pods whose evaluation was deferred to a
+	// later pass on the last evaluation because the log read budget ran out
+	// +optional
+	DeferredPods int32 `json:"deferredPods,omitempty"`
+
+	// NotificationDeliveries reports the delivery state of each notification channel
+	// +optional
+	NotificationDeliveries []NotificationDeliveryStatus `json:"notificationDeliveries,omitempty"`
+
+	// Checkpoint persists the controller's in-memory evaluation state so an
+	// operator restart does not reset it
+	// +optional
+	Checkpoint *StateCheckpoint `json:"checkpoint,omitempty"`
+
+	// MemoryRecommendations are the memory limits suggested for workloads
+	// restarted repeatedly after OOM kills
+	// +optional
+	MemoryRecommendations []MemoryRecommendation `json:"memoryRecommendations,omitempty"`
+
+	// PendingReplacements are the restarted pods whose replacement has not
+	// been scheduled yet
+	// +optional
+	PendingReplacements []PendingReplacement `json:"pendingReplacements,omitempty"`
+
+	// OrphanedWorkloads are the workloads that did not replace a restarted
+	// pod within spec.replacementDeadline
+	// +optional
+	OrphanedWorkloads []PendingReplacement `json:"orphanedWorkloads,omitempty"`
+}
+
+// MemoryRecommendation is a suggested memory limit for one container of a workload
+type MemoryRecommendation struct {
+	// Workload is the owning workload as Kind/name
+	Workload string `json:"workload"`
+
+	// Container is the container that was OOM killed
+	Container string `json:"container"`
+
+	// CurrentLimit is the container's memory limit when the suggestion was made
+	// +optional
+	CurrentLimit *resource.Quantity `json:"currentLimit,omitempty"`
+
+	// ObservedPeak is the peak working set reported by the metric provider
+	// +optional
+	ObservedPeak *resource.Quantity `json:"observedPeak,omitempty"`
+
+	// SuggestedLimit is the recommended memory limit
+	SuggestedLimit resource.Quantity `json:"suggestedLimit"`
+
+	// OOMRestarts is the number of OOM-killed restarts that led to the suggestion
+	OOMRestarts int32 `json:"oomRestarts"`
+
+	// Time is when the suggestion was last updated
+	Time metav1.Time `json:"time"`
+}
+
+// AppliedPolicy describes the PodRestartPolicy a PodRestart inherits from
+type AppliedPolicy struct {
+	// Name of the PodRestartPolicy
+	Name string `json:"name"`
+
+	// InheritedFields are the spec fields taken from the policy
+	// +optional
+	InheritedFields []string `json:"inheritedFields,omitempty"`
+
+	// Effective holds the values of the fields a policy can default that
+	// the PodRestart is evaluated with, whether inherited or its own
+	// +optional
+	Effective PodRestartDefaults `json:"effective,omitempty"`
+
+	// EvaluationWeight is the policy's evaluationWeight
+	// +optional
+	EvaluationWeight *int32 `json:"evaluationWeight,omitempty"`
+}
+
+// PendingReplacement is a restarted pod awaiting its replacement
+type PendingReplacement struct {
+	// Workload is the pod's owning workload, as Kind/Name
+	Workload string `json:"workload"`
+
+	// Namespace is the pod's namespace
+	Namespace string `json:"namespace"`
+
+	// PodName is the restarted pod
+	PodName string `json:"podName"`
+
+	// PodUID is the UID of the restarted pod, which a replacement with the
+	// same name does not share
+	PodUID types.UID `json:"podUID"`
+
+	// RestartTime is when the pod was deleted
+	RestartTime metav1.Time `json:"restartTime"`
+}
+
+// TriggerRestartTime records when a trigger last restarted a pod
+type TriggerRestartTime struct {
+	// Trigger is the kind of condition, e.g. ErrorPattern
+	Trigger string `json:"trigger"`
+
+	// Name identifies the condition within its kind
+	// +optional
+	Name string `json:"name,omitempty"`
+
+	// Time is when the trigger last restarted a pod
+	Time metav1.Time `json:"time"`
+}
+
+// EvaluationSummary describes what an evaluation pass saw
+type EvaluationSummary struct {
+	// Time is when the pass finished
+	Time metav1.Time `json:"time"`
+
+	// PodsChecked is the number of pods evaluated. Pods deferred by the log
+	// read budget are not counted.
+	PodsChecked int32 `json:"podsChecked"`
+
+	// Matches counts the pods each trigger fired for
+	// +optional
+	Matches []TriggerMatchCount `json:"matches,omitempty"`
+
+	// ProviderErrors counts the failed queries per MetricProvider
+	// +optional
+	ProviderErrors []ProviderErrorSummary `json:"providerErrors,omitempty"`
+
+	// SampleRound is the round of spec.sampling evaluated by the pass, e.g.
+	// 3/12. Empty when every pod was evaluated.
+	// +optional
+	SampleRound string `json:"sampleRound,omitempty"`
+
+	// TruncatedContainers is the number of containers whose logs were cut
+	// off at spec.logSampling.maxBytesPerContainer
+	// +optional
+	TruncatedContainers int32 `json:"truncatedContainers,omitempty"`
+
+	// TruncatedLogs lists the first of those containers
+	// +optional
+	TruncatedLogs []TruncatedLog `json:"truncatedLogs,omitempty"`
+}
+
+// TruncatedLog notes a container whose logs were not read in full
+type TruncatedLog struct {
+	// PodName is the name of the pod
+	PodName string `json:"podName"`
+
+	// Container is the name of the container
+	Container string `json:"container"`
+
+	// BytesRead is the number of log bytes read before the cap was hit
+	BytesRead int64 `json:"bytesRead"`
+}
+
+// TriggerMatchCount counts the pods a trigger fired for
+type TriggerMatchCount struct {
+	// Trigger is the kind of condition, e.g. ErrorPattern
+	Trigger string `json:"trigger"`
+
+	// Name identifies the condition within its kind
+	// +optional
+	Name string `json:"name,omitempty"`
+
+	// Count is the number of pods the condition fired for
+	Count int32 `json:"count"`
+}
+
+// ProviderErrorSummary counts the failed queries to a MetricProvider
+type ProviderErrorSummary struct {
+	// Provider is the name of the MetricProvider
+	Provider string `json:"provider"`
+
+	// Count is the number of failed queries
+	Count int32 `json:"count"`
+
+	// LastError is the error of the last failed query
+	LastError string `json:"lastError"`
 }
 
+// StateCheckpoint is the evaluation state kept by the controller between passes
+type StateCheckpoint struct {
+	// DegradedPasses is the number of consecutive degraded passes, which
+	// drives the requeue backoff
+	// +optional
+	DegradedPasses int32 `json:"degradedPasses,omitempty"`
+
+	// LogCursors holds the timestamp of the last log line evaluated per
+	// container, newest first
+	// +optional
+	LogCursors []LogCursor `json:"logCursors,omitempty"`
+
+	// Notifications holds the last time each channel was notified per event type
+	// +optional
+	Notifications []NotificationCheckpoint `json:"notifications,omitempty"`
+
+	// RateLimits holds the send times per channel within its rateLimit window
+	// +optional
+	RateLimits []RateLimitCheckpoint `json:"rateLimits,omitempty"`
+
+	// Deduplicated holds the last send time of each distinct notification,
+	// newest first, so dedupWindow keeps applying
+	// +optional
+	Deduplicated []DedupCheckpoint `json:"deduplicated,omitempty"`
+
+	// RestartRetries holds the failed restarts waiting for their retry
+	// +optional
+	RestartRetries []RestartRetryCheckpoint `json:"restartRetries,omitempty"`
+}
+
+// RateLimitCheckpoint records the recent sends of a channel
+type RateLimitCheckpoint struct {
+	// Channel is the name of the NotificationChannel
+	Channel string `json:"channel"`
+
+	// Sent are the send times, oldest first
+	Sent []metav1.Time `json:"sent"`
+}
+
+// DedupCheckpoint records when a distinct notification was last sent
+type DedupCheckpoint struct {
+	// Channel is the name of the NotificationChannel
+	Channel string `json:"channel"`
+
+	// Event is the notification event type
+	Event NotificationEventType `json:"event"`
+
+	// Pod is the pod the notification was about
+	// +optional
+	Pod string `json:"pod,omitempty"`
+
+	// Trigger is the kind of trigger that fired
+	// +optional
+	Trigger string `json:"trigger,omitempty"`
+
+	// TriggerName is the name of the trigger that fired
+	// +optional
+	TriggerName string `json:"triggerName,omitempty"`
+
+	// Time is when the notification was sent
+	Time metav1.Time `json:"time"`
+}
+
+// RestartRetryCheckpoint records a failed restart of a pod
+type RestartRetryCheckpoint struct {
+	// PodUID is the UID of the pod
+	PodUID string `json:"podUID"`
+
+	// Attempts is the number of consecutive failed attempts
+	Attempts int32 `json:"attempts"`
+
+	// Permanent is set when the failure will not go away on its own
+	// +optional
+	Permanent bool `json:"permanent,omitempty"`
+
+	// LastAttempt is when the restart last failed
+	LastAttempt metav1.Time `json:"lastAttempt"`
+
+	// NextAttempt is when the restart is retried
+	NextAttempt metav1.Time `json:"nextAttempt"`
+
+	// Error is the error of the last attempt
+	// +optional
+	Error string `json:"error,omitempty"`
+}
+
+// LogCursor records where the log scan of a container stopped
+type LogCursor struct {
+	// PodUID is the UID of the pod
+	PodUID string `json:"podUID"`
+
+	// Container is the name of the container
+	Container string `json:"container"`
+
+	// Time is the timestamp of the last log line evaluated
+	Time metav1.MicroTime `json:"time"`
+}
+
+// NotificationCheckpoint records when a channel was last notified of an event
+type NotificationCheckpoint struct {
+	// Channel is the name of the NotificationChannel
+	Channel string `json:"channel"`
+
+	// Event is the notification event type
+	Event NotificationEventType `json:"event"`
+
+	// Time is when the notification was sent
+	Time metav1.Time `json:"time"`
+}
+
+// NotificationDeliveryStatus records the outcome of notification deliveries to a channel
+type NotificationDeliveryStatus struct {
+	// Channel is the name of the NotificationChannel
+	Channel string `json:"channel"`
+
+	// LastEvent is the event type of the last delivery attempt
+	// +optional
+	LastEvent NotificationEventType `json:"lastEvent,omitempty"`
+
+	// LastAttemptTime is when delivery was last attempted
+	// +optional
+	LastAttemptTime *metav1.Time `json:"lastAttemptTime,omitempty"`
+
+	// LastSuccessTime is when a notification was last delivered successfully
+	// +optional
+	LastSuccessTime *metav1.Time `json:"lastSuccessTime,omitempty"`
+
+	// ConsecutiveFailures counts failed deliveries since the last success
+	// +optional
+	ConsecutiveFailures int32 `json:"consecutiveFailures,omitempty"`
+
+	// LastError is the error of the last failed delivery
+	// +optional
+	LastError string `json:"lastError,omitempty"`
+}
+
+// RestartReasonCode is the stable, machine readable cause of a restart. It
+// is used in restart records, condition reasons, event annotations, metric
+// labels and notifications, where the free-form reason cannot be aggregated.
+// +kubebuilder:validation:Enum=LogPatternMatched;MetricThresholdBreached;OOMKilled;ProbeFailure;Manual;Scheduled;AlertFiring;ZombieProcesses;ConnectionCountOutOfBounds;HeartbeatStale;ErrorBudgetBurn;JVMHeapExhausted;TriggerGroupMatched;ContainerExitCode;ForbiddenImage
+type RestartReasonCode string
+
+const (
+	// ReasonLogPatternMatched means an error pattern matched the pod's logs
+	ReasonLogPatternMatched RestartReasonCode = "LogPatternMatched"
+	// ReasonMetricThresholdBreached means a metric condition was breached
+	ReasonMetricThresholdBreached RestartReasonCode = "MetricThresholdBreached"
+	// ReasonOOMKilled means a container matched by an exit code condition
+	// was OOM killed, i.e. terminated with reason OOMKilled
+	ReasonOOMKilled RestartReasonCode = "OOMKilled"
+	// ReasonProbeFailure means a container failed its health probe. It is
+	// reserved: failed exec probes are reported as ProbeFailed events but do
+	// not restart pods yet
+	ReasonProbeFailure RestartReasonCode = "ProbeFailure"
+	// ReasonManual means a user requested the restart
+	ReasonManual RestartReasonCode = "Manual"
+	// ReasonScheduled means the restart was due on a schedule. It is
+	// reserved for scheduled restarts, which no trigger performs yet
+	ReasonScheduled RestartReasonCode = "Scheduled"
+	// ReasonAlertFiring means a firing Alertmanager alert requested the restart
+	ReasonAlertFiring RestartReasonCode = "AlertFiring"
+	// ReasonZombieProcesses means a container held too many zombie processes
+	ReasonZombieProcesses RestartReasonCode = "ZombieProcesses"
+	// ReasonConnectionCountOutOfBounds means the established connections left their bounds
+	ReasonConnectionCountOutOfBounds RestartReasonCode = "ConnectionCountOutOfBounds"
+	// ReasonHeartbeatStale means a heartbeat file was not touched in time
+	ReasonHeartbeatStale RestartReasonCode = "HeartbeatStale"
+	// ReasonErrorBudgetBurn means an SLO error budget burned too fast
+	ReasonErrorBudgetBurn RestartReasonCode = "ErrorBudgetBurn"
+	// ReasonJVMHeapExhausted means a JVM was about to exhaust its heap
+	ReasonJVMHeapExhausted RestartReasonCode = "JVMHeapExhausted"
+	// ReasonTriggerGroupMatched means every trigger of a trigger group fired
+	ReasonTriggerGroupMatched RestartReasonCode = "TriggerGroupMatched"
+	// ReasonContainerExitCode means a container terminated with a watched exit code
+	ReasonContainerExitCode RestartReasonCode = "ContainerExitCode"
+	// ReasonForbiddenImage means a container ran an image that should no longer run
+	ReasonForbiddenImage RestartReasonCode = "ForbiddenImage"
+)
+
+// SkipReason explains why a triggered restart was suppressed
+type SkipReason string
+
+const (
+	// SkipCooldown means minTimeBetweenRestarts has not elapsed
+	SkipCooldown SkipReason = "Cooldown"
+	// SkipBudgetExhausted means the restart budget for the window is used up
+	SkipBudgetExhausted SkipReason = "BudgetExhausted"
+	// SkipScaling means an autoscaler is scaling the pod's workload
+	SkipScaling SkipReason = "Scaling"
+	// SkipGitOps means the workload's GitOps source is suspended or reconciling
+	SkipGitOps SkipReason = "GitOps"
+	// SkipNodeCordoned means the pod's node is cordoned or draining and the
+	// drain is left to move it
+	SkipNodeCordoned SkipReason = "NodeCordoned"
+	// SkipTerminating means the pod is already being deleted or stopped by
+	// kubelet, a drain or another controller
+	SkipTerminating SkipReason = "Terminating"
+	// SkipOrphaned means the pod's workload did not replace a pod restarted
+	// earlier
+	SkipOrphaned SkipReason = "WorkloadOrphaned"
+	// SkipBarePod means the pod has no controller to recreate it and
+	// spec.allowBarePods is not set
+	SkipBarePod SkipReason = "BarePod"
+	// SkipCircuitOpen means too many restarts failed in a row and the
+	// circuit breaker holds restarts back for a while
+	SkipCircuitOpen SkipReason = "CircuitOpen"
+	// SkipBlackoutWindow means the restart fell into one of spec.blackoutWindows
+	SkipBlackoutWindow SkipReason = "BlackoutWindow"
+	// SkipDisruptionBudget means a PodDisruptionBudget selecting the pod
+	// allows no disruption right now
+	SkipDisruptionBudget SkipReason = "DisruptionBudget"
+	// SkipDryRun means spec.dryRun is set and the pod would have been restarted
+	SkipDryRun SkipReason = "DryRun"
+)
+
+// SkippedRestart records a restart that was suppressed
+type SkippedRestart struct {
+	// PodName is the name of the pod that was not restarted
+	PodName string `json:"podName"`
+
+	// Reason is why the restart was suppressed
+	Reason SkipReason `json:"reason"`
+
+	// Message gives additional detail about the skip
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// Time is when the restart was suppressed
+	Time metav1.Time `json:"time"`
+}
+
+// RestartOutcome describes the result of a restart attempt
+type RestartOutcome string
+
+const (
+	// RestartSucceeded means the pod was deleted successfully
+	RestartSucceeded RestartOutcome = "Succeeded"
+	// RestartFailed means the delete call for the pod returned an error
+	RestartFailed RestartOutcome = "Failed"
+)
+
+// RestartRecord is a single entry in the restart history
+type RestartRecord struct {
+	// PodName is the name of the pod that was restarted
+	PodName string `json:"podName"`
+
+	// Namespace is the pod's namespace when it is not the PodRestart's own
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+
+	// Trigger is the kind of condition that fired (e.g. ErrorPattern)
+	Trigger string `json:"trigger"`
+
+	// ReasonCode is the machine readable cause of the restart
+	// +optional
+	ReasonCode RestartReasonCode `json:"reasonCode,omitempty"`
+
+	// Reason is a human readable description of why the pod was restarted
+	Reason string `json:"reason"`
+
+	// Time is when the restart was attempted
+	Time metav1.Time `json:"time"`
+
+	// Outcome is the result of the restart attempt
+	Outcome RestartOutcome `json:"outcome"`
+
+	// Message holds error details when the restart failed
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// DiagnosticsRef is the name of the ConfigMap or Secret holding the
+	// diagnostics bundle captured before the restart
+	// +optional
+	DiagnosticsRef string `json:"diagnosticsRef,omitempty"`
+
+	// DiagnosticsURL is the object storage location of the uploaded diagnostics bundle
+	// +optional
+	DiagnosticsURL string `json:"diagnosticsURL,omitempty"`
+
+	// Workload is the workload owning the pod, as Kind/name
+	// +optional
+	Workload string `json:"workload,omitempty"`
+
+	// OOMKilledContainers lists the containers whose last termination was an OOM kill
+	// +optional
+	OOMKilledContainers []string `json:"oomKilledContainers,omitempty"`
+
+	// Quarantined is set when the pod was quarantined by an exit code
+	// condition rather than deleted
+	// +optional
+	Quarantined bool `json:"quarantined,omitempty"`
+
+	// Checkpoints lists the container checkpoints taken before the restart
+	// +optional
+	Checkpoints []ContainerCheckpointRecord `json:"checkpoints,omitempty"`
+}
+
+// ContainerCheckpointRecord locates a checkpoint archive written by the kubelet
+type ContainerCheckpointRecord struct {
+	// Container is the name of the checkpointed container
+	Container string `json:"container"`
+
+	// Node is the node holding the archive
+	Node string `json:"node"`
+
+	// Location is the path of the archive on the node
+	Location string `json:"location"`
+}
+
+// +genclient
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
+// +kubebuilder:storageversion
+// +kubebuilder:resource:shortName=pr,categories=remediation
+// +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="Action",type=string,JSONPath=`.spec.action`
+// +kubebuilder:printcolumn:name="Suspended",type=boolean,JSONPath=`.spec.suspend`
+// +kubebuilder:printcolumn:name="TargetedPods",type=integer,JSONPath=`.status.targetedPods`
+// +kubebuilder:printcolumn:name="MatchingPods",type=integer,JSONPath=`.status.matchingPods`,priority=1
 // +kubebuilder:printcolumn:name="RestartCount",type=integer,JSONPath=`.status.restartCount`
+// +kubebuilder:printcolumn:name="BudgetRemaining",type=integer,JSONPath=`.status.budgetRemaining`,priority=1
 // +kubebuilder:printcolumn:name="LastRestart",type=date,JSONPath=`.status.lastRestartTime`
 // +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 
@@ -69,6 +1699,22 @@ type PodRestartList struct {
 	Items           []PodRestart `json:"items"`
 }
 
+// CheckIntervalDuration returns the effective evaluation interval, clamped to
+// the allowed bounds in case the webhook was bypassed
+func (pr *PodRestart) CheckIntervalDuration() time.Duration {
+	if pr.Spec.CheckInterval == nil {
+		return DefaultCheckInterval
+	}
+	interval := pr.Spec.CheckInterval.Duration
+	if interval < MinCheckInterval {
+		return MinCheckInterval
+	}
+	if interval > MaxCheckInterval {
+		return MaxCheckInterval
+	}
+	return interval
+}
+
 func init() {
 	SchemeBuilder.Register(&PodRestart{}, &PodRestartList{})
 }
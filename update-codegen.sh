@@ -0,0 +1,31 @@
+#!/usr/bin/env bash
+# update-codegen.sh
+# Generates the typed clientset, listers, informers and apply configurations
+# for the PodRestart API into pkg/generated.
+
+set -o errexit
+set -o nounset
+set -o pipefail
+
+SCRIPT_ROOT=$(dirname "${BASH_SOURCE[0]}")
+CODEGEN_PKG=${CODEGEN_PKG:-$(cd "${SCRIPT_ROOT}"; go list -m -f '{{.Dir}}' k8s.io/code-generator)}
+MODULE=github.com/example/pod-restart-operator
+
+# The generators walk <input>/<version>, so the input is the parent of the
+# directory the v1alpha1 package resolves to, wherever the module keeps it.
+API_PKG_DIR=$(cd "${SCRIPT_ROOT}"; go list -f '{{.Dir}}' "${MODULE}/api/v1alpha1")
+if [[ "$(basename "${API_PKG_DIR}")" != "v1alpha1" ]]; then
+    echo "package ${MODULE}/api/v1alpha1 must live in a v1alpha1 directory, found ${API_PKG_DIR}" >&2
+    exit 1
+fi
+API_DIR=$(dirname "${API_PKG_DIR}")
+
+source "${CODEGEN_PKG}/kube_codegen.sh"
+
+kube::codegen::gen_client \
+    --with-watch \
+    --with-applyconfig \
+    --output-dir "${SCRIPT_ROOT}/pkg/generated" \
+    --output-pkg "${MODULE}/pkg/generated" \
+    --boilerplate "${SCRIPT_ROOT}/boilerplate.go.txt" \
+    "${API_DIR}"
@@ -0,0 +1,118 @@
+// v1beta1_conversion.go
+package v1beta1
+
+import (
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+
+	"github.com/example/pod-restart-operator/api/v1alpha1"
+)
+
+var _ conversion.Convertible = &PodRestart{}
+
+// ConvertTo converts this PodRestart to the v1alpha1 hub
+func (src *PodRestart) ConvertTo(dstRaw conversion.Hub) error {
+	dst := dstRaw.(*v1alpha1.PodRestart)
+	dst.ObjectMeta = src.ObjectMeta
+	dst.Status = src.Status
+
+	dst.Spec.PodSelector = src.Spec.PodSelector
+	dst.Spec.Namespaces = src.Spec.Namespaces
+	dst.Spec.OwnerFilter = src.Spec.OwnerFilter
+	dst.Spec.Cluster = src.Spec.Cluster
+	dst.Spec.ErrorPatterns = src.Spec.ErrorPatterns
+	dst.Spec.Sampling = src.Spec.Sampling
+	dst.Spec.EvaluationDeadline = src.Spec.EvaluationDeadline
+	dst.Spec.NamedErrorPatterns = src.Spec.NamedErrorPatterns
+	dst.Spec.MetricConditions = src.Spec.MetricConditions
+	dst.Spec.ZombieProcesses = src.Spec.ZombieProcesses
+	dst.Spec.Connections = src.Spec.Connections
+	dst.Spec.HeartbeatFiles = src.Spec.HeartbeatFiles
+	dst.Spec.ExitCodes = src.Spec.ExitCodes
+	dst.Spec.ForbiddenImages = src.Spec.ForbiddenImages
+	dst.Spec.BurnRates = src.Spec.BurnRates
+	dst.Spec.JVM = src.Spec.JVM
+	dst.Spec.TriggerGroups = src.Spec.TriggerGroups
+	dst.Spec.MinTimeBetweenRestarts = src.Spec.MinTimeBetweenRestarts
+	dst.Spec.CheckInterval = src.Spec.CheckInterval
+	dst.Spec.MessageTemplate = src.Spec.MessageTemplate
+	dst.Spec.HistoryLimit = src.Spec.HistoryLimit
+	dst.Spec.AllowBarePods = src.Spec.AllowBarePods
+	dst.Spec.ReplacementDeadline = src.Spec.ReplacementDeadline
+	dst.Spec.Action = src.Spec.Action
+	dst.Spec.Surge = src.Spec.Surge
+	dst.Spec.Suspend = src.Spec.Suspend
+	dst.Spec.DryRun = src.Spec.DryRun
+	dst.Spec.BlackoutWindows = src.Spec.BlackoutWindows
+	dst.Spec.IgnoreDisruptionBudgets = src.Spec.IgnoreDisruptionBudgets
+	dst.Spec.RestartBudget = src.Spec.RestartBudget
+	dst.Spec.Diagnostics = src.Spec.Diagnostics
+	dst.Spec.ContainerCheckpoint = src.Spec.ContainerCheckpoint
+	dst.Spec.EvaluationReports = src.Spec.EvaluationReports
+	dst.Spec.Notifications = src.Spec.Notifications
+	dst.Spec.MemoryRecommendation = src.Spec.MemoryRecommendation
+	dst.Spec.Autoscaling = src.Spec.Autoscaling
+	dst.Spec.GitOps = src.Spec.GitOps
+	dst.Spec.CordonedNodes = src.Spec.CordonedNodes
+	if logs := src.Spec.Logs; logs != nil {
+		dst.Spec.LogSource = logs.Source
+		dst.Spec.LogReadBudget = logs.ReadBudget
+		dst.Spec.LogSampling = logs.Sampling
+		dst.Spec.LogFollow = logs.Follow
+	}
+	return nil
+}
+
+// ConvertFrom converts the v1alpha1 hub to this version
+func (dst *PodRestart) ConvertFrom(srcRaw conversion.Hub) error {
+	src := srcRaw.(*v1alpha1.PodRestart)
+	dst.ObjectMeta = src.ObjectMeta
+	dst.Status = src.Status
+
+	dst.Spec.PodSelector = src.Spec.PodSelector
+	dst.Spec.Namespaces = src.Spec.Namespaces
+	dst.Spec.OwnerFilter = src.Spec.OwnerFilter
+	dst.Spec.Cluster = src.Spec.Cluster
+	dst.Spec.ErrorPatterns = src.Spec.ErrorPatterns
+	dst.Spec.Sampling = src.Spec.Sampling
+	dst.Spec.EvaluationDeadline = src.Spec.EvaluationDeadline
+	dst.Spec.NamedErrorPatterns = src.Spec.NamedErrorPatterns
+	dst.Spec.MetricConditions = src.Spec.MetricConditions
+	dst.Spec.ZombieProcesses = src.Spec.ZombieProcesses
+	dst.Spec.Connections = src.Spec.Connections
+	dst.Spec.HeartbeatFiles = src.Spec.HeartbeatFiles
+	dst.Spec.ExitCodes = src.Spec.ExitCodes
+	dst.Spec.ForbiddenImages = src.Spec.ForbiddenImages
+	dst.Spec.BurnRates = src.Spec.BurnRates
+	dst.Spec.JVM = src.Spec.JVM
+	dst.Spec.TriggerGroups = src.Spec.TriggerGroups
+	dst.Spec.MinTimeBetweenRestarts = src.Spec.MinTimeBetweenRestarts
+	dst.Spec.CheckInterval = src.Spec.CheckInterval
+	dst.Spec.MessageTemplate = src.Spec.MessageTemplate
+	dst.Spec.HistoryLimit = src.Spec.HistoryLimit
+	dst.Spec.AllowBarePods = src.Spec.AllowBarePods
+	dst.Spec.ReplacementDeadline = src.Spec.ReplacementDeadline
+	dst.Spec.Action = src.Spec.Action
+	dst.Spec.Surge = src.Spec.Surge
+	dst.Spec.Suspend = src.Spec.Suspend
+	dst.Spec.DryRun = src.Spec.DryRun
+	dst.Spec.BlackoutWindows = src.Spec.BlackoutWindows
+	dst.Spec.IgnoreDisruptionBudgets = src.Spec.IgnoreDisruptionBudgets
+	dst.Spec.RestartBudget = src.Spec.RestartBudget
+	dst.Spec.Diagnostics = src.Spec.Diagnostics
+	dst.Spec.ContainerCheckpoint = src.Spec.ContainerCheckpoint
+	dst.Spec.EvaluationReports = src.Spec.EvaluationReports
+	dst.Spec.Notifications = src.Spec.Notifications
+	dst.Spec.MemoryRecommendation = src.Spec.MemoryRecommendation
+	dst.Spec.Autoscaling = src.Spec.Autoscaling
+	dst.Spec.GitOps = src.Spec.GitOps
+	dst.Spec.CordonedNodes = src.Spec.CordonedNodes
+	if src.Spec.LogSource != "" || src.Spec.LogReadBudget != nil || src.Spec.LogSampling != nil || src.Spec.LogFollow != nil {
+		dst.Spec.Logs = &LogsSpec{
+			Source:     src.Spec.LogSource,
+			ReadBudget: src.Spec.LogReadBudget,
+			Sampling:   src.Spec.LogSampling,
+			Follow:     src.Spec.LogFollow,
+		}
+	}
+	return nil
+}
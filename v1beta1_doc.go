@@ -0,0 +1,22 @@
+// v1beta1_doc.go
+// Package v1beta1 contains API Schema definitions for the operator v1beta1 API group.
+// Objects are stored as v1alpha1, the hub all versions convert through.
+// +kubebuilder:object:generate=true
+// +groupName=operator.example.com
+package v1beta1
+
+import (
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/scheme"
+)
+
+var (
+	// GroupVersion is group version used to register these objects
+	GroupVersion = schema.GroupVersion{Group: "operator.example.com", Version: "v1beta1"}
+
+	// SchemeBuilder is used to add go types to the GroupVersionKind scheme
+	SchemeBuilder = &scheme.Builder{GroupVersion: GroupVersion}
+
+	// AddToScheme adds the types in this group-version to the given scheme
+	AddToScheme = SchemeBuilder.AddToScheme
+)
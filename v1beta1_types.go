@@ -0,0 +1,293 @@
+// v1beta1_types.go
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/example/pod-restart-operator/api/v1alpha1"
+)
+
+// PodRestartSpec defines the desired state of PodRestart. It differs from
+// v1alpha1 in grouping the log settings under logs.
+type PodRestartSpec struct {
+	// PodSelector is a label selector to target pods
+	PodSelector metav1.LabelSelector `json:"podSelector"`
+
+	// Namespaces lists the namespaces whose pods are targeted instead of the
+	// PodRestart's own. Other namespaces are only targeted while the
+	// PodRestart's creator, or whoever last changed its spec, may delete pods
+	// and read their logs there, and exec, proxy or patch them as far as the
+	// spec does. Their pods get the defaults of their own namespace's policy.
+	// Namespaces that fail these checks, that the operator may not list pods
+	// in, or that are targeted while the webhooks are disabled, are reported
+	// in the Degraded condition and skipped.
+	// +optional
+	Namespaces []string `json:"namespaces,omitempty"`
+
+	// OwnerFilter narrows the pods of the selector to those owned by a
+	// specific workload, for selectors that span several
+	// +optional
+	OwnerFilter *v1alpha1.OwnerFilter `json:"ownerFilter,omitempty"`
+
+	// Cluster targets the pods of a remote cluster instead of the local one.
+	// Requires the MultiCluster feature gate.
+	// +optional
+	Cluster *v1alpha1.ClusterReference `json:"cluster,omitempty"`
+
+	// ErrorPatterns is a list of regex patterns to match against pod logs
+	ErrorPatterns []string `json:"errorPatterns,omitempty"`
+
+	// Logs configures how the logs matched against the error patterns are
+	// read: their backend, the read budget, sampling and following
+	// +optional
+	Logs *LogsSpec `json:"logs,omitempty"`
+
+	// Sampling evaluates a rotating subset of the selected pods on each
+	// pass instead of all of them, for selectors matching thousands of pods.
+	// Every pod is still evaluated at least once per period.
+	// +optional
+	Sampling *v1alpha1.FleetSampling `json:"sampling,omitempty"`
+
+	// EvaluationDeadline bounds how late a pass may start after it is due.
+	// A PodRestart waiting for evaluation workers past it is served before
+	// PodRestarts that are not overdue. Defaults to the check interval.
+	// +kubebuilder:validation:Format=duration
+	// +optional
+	EvaluationDeadline *metav1.Duration `json:"evaluationDeadline,omitempty"`
+
+	// NamedErrorPatterns are error patterns reported under a name instead of
+	// the regex itself in metrics, events and status
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	NamedErrorPatterns []v1alpha1.ErrorPattern `json:"namedErrorPatterns,omitempty"`
+
+	// MetricConditions defines metric-based conditions that trigger restarts
+	MetricConditions []v1alpha1.MetricCondition `json:"metricConditions,omitempty"`
+
+	// ZombieProcesses restarts pods whose containers accumulate zombie
+	// (defunct) processes, which broken child process handling leaves
+	// behind without logging anything
+	// +optional
+	ZombieProcesses *v1alpha1.ZombieProcessCondition `json:"zombieProcesses,omitempty"`
+
+	// Connections restarts pods whose number of established connections
+	// leaves the configured bounds, recycling pods that leak sockets or
+	// silently stopped accepting traffic
+	// +optional
+	Connections *v1alpha1.ConnectionCountCondition `json:"connections,omitempty"`
+
+	// HeartbeatFiles restarts pods whose heartbeat file was not touched
+	// recently, for workers that cannot expose HTTP probes
+	// +optional
+	HeartbeatFiles []v1alpha1.HeartbeatFileCondition `json:"heartbeatFiles,omitempty"`
+
+	// ExitCodes act on pods whose containers last terminated with specific
+	// exit codes, e.g. 139 for a segfault or an application's own codes
+	// +optional
+	ExitCodes []v1alpha1.ExitCodeCondition `json:"exitCodes,omitempty"`
+
+	// ForbiddenImages act on pods running images that should no longer run,
+	// e.g. ones affected by a CVE, so they are recreated from the updated
+	// workload. Restarting only helps once the workload refers to a fixed
+	// image or its tag was moved to one with imagePullPolicy Always.
+	// +optional
+	ForbiddenImages []v1alpha1.ForbiddenImageCondition `json:"forbiddenImages,omitempty"`
+
+	// BurnRates evaluate multi-window error budget burn rates against
+	// Prometheus recording rules and restart pods, or only notify, while the
+	// budget of the selected workload burns too fast
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	BurnRates []v1alpha1.BurnRateCondition `json:"burnRates,omitempty"`
+
+	// JVM restarts Java pods whose heap exhaustion is imminent, read from
+	// their Jolokia agent before the container is OOM killed
+	// +optional
+	JVM *v1alpha1.JVMCondition `json:"jvm,omitempty"`
+
+	// TriggerGroups combine triggers: a group restarts a pod when every
+	// trigger it references fires, and any satisfied group is enough. The
+	// triggers referenced by a group no longer restart pods on their own;
+	// the other triggers keep doing so.
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	TriggerGroups []v1alpha1.TriggerGroup `json:"triggerGroups,omitempty"`
+
+	// MinTimeBetweenRestarts is the minimum time to wait between pod restarts
+	// +kubebuilder:validation:Format=duration
+	MinTimeBetweenRestarts *metav1.Duration `json:"minTimeBetweenRestarts,omitempty"`
+
+	// CheckInterval is how often the selected pods are evaluated. It must be
+	// between MinCheckInterval and MaxCheckInterval; defaults to 30s.
+	// +kubebuilder:validation:Format=duration
+	// +optional
+	CheckInterval *metav1.Duration `json:"checkInterval,omitempty"`
+
+	// MessageTemplate is a Go template used for restart condition, event and
+	// notification messages. It receives .Pod, .Namespace, .PodRestart, .Trigger,
+	// .Reason, .MatchedLine and .MetricValue.
+	// +optional
+	MessageTemplate string `json:"messageTemplate,omitempty"`
+
+	// HistoryLimit is the maximum number of entries kept in status.recentRestarts
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:default=10
+	// +optional
+	HistoryLimit *int32 `json:"historyLimit,omitempty"`
+
+	// AllowBarePods permits restarting pods without a controller. Such a
+	// pod is deleted for good rather than restarted, so they are refused
+	// unless this is set.
+	// +optional
+	AllowBarePods bool `json:"allowBarePods,omitempty"`
+
+	// ReplacementDeadline is how long the owning controller of a restarted
+	// pod has to schedule a replacement. A workload that misses it, because
+	// it is paused or scaled to zero, is reported as orphaned and its pods
+	// are not restarted until a replacement shows up.
+	// +kubebuilder:validation:Format=duration
+	// +kubebuilder:default="5m"
+	// +optional
+	ReplacementDeadline *metav1.Duration `json:"replacementDeadline,omitempty"`
+
+	// Action is how a pod is restarted once a trigger fires
+	// +kubebuilder:validation:Enum=Delete;Surge
+	// +kubebuilder:default=Delete
+	// +optional
+	Action v1alpha1.RestartAction `json:"action,omitempty"`
+
+	// Surge configures the Surge action
+	// +optional
+	Surge *v1alpha1.SurgeSpec `json:"surge,omitempty"`
+
+	// Suspend stops the operator from restarting any pods selected by this PodRestart
+	// +optional
+	Suspend bool `json:"suspend,omitempty"`
+
+	// DryRun evaluates the triggers as usual but deletes no pod. Each
+	// restart that would have happened is reported as skipped with reason
+	// DryRun.
+	// +optional
+	DryRun bool `json:"dryRun,omitempty"`
+
+	// BlackoutWindows are recurring periods in which no pod is restarted,
+	// e.g. business hours or a nightly batch run
+	// +optional
+	BlackoutWindows []v1alpha1.BlackoutWindow `json:"blackoutWindows,omitempty"`
+
+	// IgnoreDisruptionBudgets restarts pods even when a PodDisruptionBudget
+	// selecting them allows no disruption. By default such restarts are
+	// skipped until the budget allows one again.
+	// +optional
+	IgnoreDisruptionBudgets bool `json:"ignoreDisruptionBudgets,omitempty"`
+
+	// RestartBudget caps how many restarts may be performed within a rolling window
+	// +optional
+	RestartBudget *v1alpha1.RestartBudget `json:"restartBudget,omitempty"`
+
+	// Diagnostics captures the state of a pod into a ConfigMap or Secret before it is restarted
+	// +optional
+	Diagnostics *v1alpha1.DiagnosticsSpec `json:"diagnostics,omitempty"`
+
+	// ContainerCheckpoint checkpoints containers through the kubelet before
+	// the pod is restarted, preserving their process state for forensics.
+	// Requires the ContainerCheckpoint feature on the nodes.
+	// +optional
+	ContainerCheckpoint *v1alpha1.ContainerCheckpointSpec `json:"containerCheckpoint,omitempty"`
+
+	// EvaluationReports writes the per-pod results of each pass to
+	// EvaluationReport objects, for detail that does not fit in status
+	// +optional
+	EvaluationReports *v1alpha1.EvaluationReportsSpec `json:"evaluationReports,omitempty"`
+
+	// Notifications selects the NotificationChannels that receive restart notifications
+	// +optional
+	Notifications *v1alpha1.NotificationSpec `json:"notifications,omitempty"`
+
+	// MemoryRecommendation suggests a larger memory limit for workloads that
+	// keep being restarted after their containers are OOM killed
+	// +optional
+	MemoryRecommendation *v1alpha1.MemoryRecommendationSpec `json:"memoryRecommendation,omitempty"`
+
+	// Autoscaling controls how restarts cooperate with the HorizontalPodAutoscalers
+	// and KEDA ScaledObjects scaling the pod's workload
+	// +optional
+	Autoscaling *v1alpha1.AutoscalingSpec `json:"autoscaling,omitempty"`
+
+	// GitOps defers restarts of workloads whose Flux Kustomization or
+	// HelmRelease is suspended or being reconciled
+	// +optional
+	GitOps *v1alpha1.GitOpsSpec `json:"gitOps,omitempty"`
+
+	// CordonedNodes sets how pods on cordoned or draining nodes are treated
+	// when their triggers fire
+	// +kubebuilder:validation:Enum=Normal;Skip;Prefer
+	// +kubebuilder:default=Normal
+	// +optional
+	CordonedNodes v1alpha1.CordonedNodePolicy `json:"cordonedNodes,omitempty"`
+}
+
+// LogsSpec configures how the logs of the selected pods are read
+type LogsSpec struct {
+	// Source names the log backend used to evaluate the error patterns.
+	// Defaults to kubeAPI, which reads logs through the Kubernetes API.
+	// +optional
+	Source string `json:"source,omitempty"`
+
+	// ReadBudget limits the logs read for this PodRestart in a single
+	// reconcile. Pods left over once it is used up are evaluated on the next
+	// pass. The operator-wide budget applies as well, the lower limit wins.
+	// +optional
+	ReadBudget *v1alpha1.LogReadBudget `json:"readBudget,omitempty"`
+
+	// Sampling limits how much of a single container's logs is read and
+	// matched, for containers logging so much that they would use up the
+	// read budget on their own
+	// +optional
+	Sampling *v1alpha1.LogSampling `json:"sampling,omitempty"`
+
+	// Follow keeps the log streams of the selected pods open, with the
+	// LogFollow feature gate, and evaluates the PodRestart within seconds of
+	// an error pattern match
+	// +optional
+	Follow *v1alpha1.LogFollowSpec `json:"follow,omitempty"`
+}
+
+// +genclient
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:shortName=pr,categories=remediation
+// +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="Action",type=string,JSONPath=`.spec.action`
+// +kubebuilder:printcolumn:name="Suspended",type=boolean,JSONPath=`.spec.suspend`
+// +kubebuilder:printcolumn:name="TargetedPods",type=integer,JSONPath=`.status.targetedPods`
+// +kubebuilder:printcolumn:name="MatchingPods",type=integer,JSONPath=`.status.matchingPods`,priority=1
+// +kubebuilder:printcolumn:name="RestartCount",type=integer,JSONPath=`.status.restartCount`
+// +kubebuilder:printcolumn:name="BudgetRemaining",type=integer,JSONPath=`.status.budgetRemaining`,priority=1
+// +kubebuilder:printcolumn:name="LastRestart",type=date,JSONPath=`.status.lastRestartTime`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// PodRestart is the Schema for the podrestarts API
+type PodRestart struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   PodRestartSpec            `json:"spec,omitempty"`
+	Status v1alpha1.PodRestartStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// PodRestartList contains a list of PodRestart
+type PodRestartList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []PodRestart `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&PodRestart{}, &PodRestartList{})
+}
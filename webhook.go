@@ -0,0 +1,222 @@
+// webhook.go
+package v1alpha1
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// SetupWebhookWithManager registers the PodRestart webhooks with the manager
+func (pr *PodRestart) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(pr).
+		WithDefaulter(&creatorRecorder{}).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/validate-operator-example-com-v1alpha1-podrestart,mutating=false,failurePolicy=fail,sideEffects=None,groups=operator.example.com,resources=podrestarts,verbs=create;update,versions=v1alpha1,matchPolicy=Equivalent,name=vpodrestart.kb.io,admissionReviewVersions=v1
+
+var _ webhook.Validator = &PodRestart{}
+
+// ValidateCreate implements webhook.Validator
+func (pr *PodRestart) ValidateCreate() (admission.Warnings, error) {
+	return pr.warnings(), pr.validate()
+}
+
+// ValidateUpdate implements webhook.Validator
+func (pr *PodRestart) ValidateUpdate(old runtime.Object) (admission.Warnings, error) {
+	return pr.warnings(), pr.validate()
+}
+
+// ValidateDelete implements webhook.Validator
+func (pr *PodRestart) ValidateDelete() (admission.Warnings, error) {
+	return nil, nil
+}
+
+// validate checks the fields that cannot be expressed as OpenAPI validation
+func (pr *PodRestart) validate() error {
+	allErrs := pr.validateSpec()
+	if len(allErrs) == 0 {
+		return nil
+	}
+	return allErrs.ToAggregate()
+}
+
+// validateSpec returns the problems found by validate, one per field
+func (pr *PodRestart) validateSpec() field.ErrorList {
+	var allErrs field.ErrorList
+	specPath := field.NewPath("spec")
+
+	if pr.Spec.CheckInterval != nil {
+		interval := pr.Spec.CheckInterval.Duration
+		if interval < MinCheckInterval || interval > MaxCheckInterval {
+			allErrs = append(allErrs, field.Invalid(specPath.Child("checkInterval"), interval.String(),
+				fmt.Sprintf("must be between %s and %s", MinCheckInterval, MaxCheckInterval)))
+		}
+	}
+
+	for i, pattern := range pr.Spec.ErrorPatterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			allErrs = append(allErrs, field.Invalid(specPath.Child("errorPatterns").Index(i), pattern, err.Error()))
+		}
+	}
+	for i, pattern := range pr.Spec.NamedErrorPatterns {
+		if _, err := regexp.Compile(pattern.Pattern); err != nil {
+			allErrs = append(allErrs, field.Invalid(specPath.Child("namedErrorPatterns").Index(i).Child("pattern"), pattern.Pattern, err.Error()))
+		}
+	}
+
+	for i, namespace := range pr.Spec.Namespaces {
+		for _, msg := range validation.IsDNS1123Label(namespace) {
+			allErrs = append(allErrs, field.Invalid(specPath.Child("namespaces").Index(i), namespace, msg))
+		}
+	}
+
+	// A zero maxAge would find every heartbeat stale and restart every pod
+	for i, cond := range pr.Spec.HeartbeatFiles {
+		hbPath := specPath.Child("heartbeatFiles").Index(i)
+		if !path.IsAbs(cond.Path) {
+			allErrs = append(allErrs, field.Invalid(hbPath.Child("path"), cond.Path, "must be an absolute path"))
+		}
+		if cond.MaxAge.Duration <= 0 {
+			allErrs = append(allErrs, field.Invalid(hbPath.Child("maxAge"), cond.MaxAge.Duration.String(), "must be positive"))
+		}
+	}
+
+	// With min above max every count is out of bounds and every pod restarts
+	if cond := pr.Spec.Connections; cond != nil {
+		connPath := specPath.Child("connections")
+		switch {
+		case cond.Max == nil && cond.Min == nil:
+			allErrs = append(allErrs, field.Required(connPath.Child("max"), "max or min must be set, the condition can never fire otherwise"))
+		case cond.Max != nil && cond.Min != nil && *cond.Min > *cond.Max:
+			allErrs = append(allErrs, field.Invalid(connPath.Child("min"), *cond.Min, fmt.Sprintf("must not be larger than max %d", *cond.Max)))
+		}
+	}
+
+	for i, cond := range pr.Spec.ExitCodes {
+		ecPath := specPath.Child("exitCodes").Index(i)
+		for j, code := range cond.Codes {
+			if code < 0 || code > 255 {
+				allErrs = append(allErrs, field.Invalid(ecPath.Child("codes").Index(j), code, "must be between 0 and 255"))
+			}
+		}
+		if cond.Within != nil && cond.Within.Duration <= 0 {
+			allErrs = append(allErrs, field.Invalid(ecPath.Child("within"), cond.Within.Duration.String(), "must be positive"))
+		}
+		if cond.QuarantineTTL != nil && cond.QuarantineTTL.Duration <= 0 {
+			allErrs = append(allErrs, field.Invalid(ecPath.Child("quarantineTTL"), cond.QuarantineTTL.Duration.String(), "must be positive"))
+		}
+	}
+
+	// A bad objective or factor would otherwise only surface while evaluating
+	for i, cond := range pr.Spec.BurnRates {
+		brPath := specPath.Child("burnRates").Index(i)
+		if objective, err := strconv.ParseFloat(cond.Objective, 64); err != nil || objective <= 0 || objective >= 100 {
+			allErrs = append(allErrs, field.Invalid(brPath.Child("objective"), cond.Objective, "must be a percentage between 0 and 100"))
+		}
+		if _, err := template.New("query").Parse(cond.ErrorRatioQuery); err != nil {
+			allErrs = append(allErrs, field.Invalid(brPath.Child("errorRatioQuery"), cond.ErrorRatioQuery, err.Error()))
+		} else if strings.Contains(cond.ErrorRatioQuery, ".Pod") {
+			allErrs = append(allErrs, field.Invalid(brPath.Child("errorRatioQuery"), cond.ErrorRatioQuery,
+				"must not refer to .Pod, burn rates are evaluated once per workload"))
+		}
+		for j, w := range cond.Windows {
+			wPath := brPath.Child("windows").Index(j)
+			if factor, err := strconv.ParseFloat(w.Factor, 64); err != nil || factor <= 0 {
+				allErrs = append(allErrs, field.Invalid(wPath.Child("factor"), w.Factor, "must be a positive number"))
+			}
+			if w.Short.Duration <= 0 || w.Short.Duration >= w.Long.Duration {
+				allErrs = append(allErrs, field.Invalid(wPath.Child("short"), w.Short.Duration.String(), "must be positive and shorter than long"))
+			}
+		}
+	}
+
+	// A mistyped name would leave its group unable to fire while the real
+	// condition's result is still held back as a group member
+	for i, group := range pr.Spec.TriggerGroups {
+		for j, ref := range group.AllOf {
+			if ref.Name != "" && !pr.hasTrigger(ref) {
+				allErrs = append(allErrs, field.Invalid(specPath.Child("triggerGroups").Index(i).Child("allOf").Index(j).Child("name"), ref.Name,
+					fmt.Sprintf("must name a %s condition of the PodRestart", ref.Trigger)))
+			}
+		}
+	}
+
+	for i, window := range pr.Spec.BlackoutWindows {
+		windowPath := specPath.Child("blackoutWindows").Index(i)
+		if _, err := time.Parse(BlackoutWindowTimeFormat, window.Start); err != nil {
+			allErrs = append(allErrs, field.Invalid(windowPath.Child("start"), window.Start, "must be a time of day as HH:MM"))
+		}
+		if window.Duration.Duration <= 0 {
+			allErrs = append(allErrs, field.Invalid(windowPath.Child("duration"), window.Duration.Duration.String(), "must be positive"))
+		}
+		for j, day := range window.Days {
+			if _, ok := BlackoutWindowDays[day]; !ok {
+				allErrs = append(allErrs, field.NotSupported(windowPath.Child("days").Index(j), day,
+					[]string{"Mon", "Tue", "Wed", "Thu", "Fri", "Sat", "Sun"}))
+			}
+		}
+		if _, err := time.LoadLocation(window.TimeZone); err != nil {
+			allErrs = append(allErrs, field.Invalid(windowPath.Child("timeZone"), window.TimeZone, err.Error()))
+		}
+	}
+
+	// With a zero window no restart would ever count against the budget
+	if budget := pr.Spec.RestartBudget; budget != nil && budget.Window.Duration <= 0 {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("restartBudget", "window"), budget.Window.Duration.String(), "must be positive"))
+	}
+
+	if s := pr.Spec.Sampling; s != nil && s.Period.Duration <= 0 {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("sampling", "period"), s.Period.Duration.String(), "must be positive"))
+	}
+
+	if f := pr.Spec.LogFollow; f != nil && f.MaxStreams != nil {
+		if n := *f.MaxStreams; n < 1 || n > MaxLogFollowStreams {
+			allErrs = append(allErrs, field.Invalid(specPath.Child("logFollow", "maxStreams"), n,
+				fmt.Sprintf("must be between 1 and %d", MaxLogFollowStreams)))
+		}
+	}
+
+	if filter := pr.Spec.OwnerFilter; filter != nil && filter.NameRegex != "" {
+		if _, err := regexp.Compile(filter.NameRegex); err != nil {
+			allErrs = append(allErrs, field.Invalid(specPath.Child("ownerFilter", "nameRegex"), filter.NameRegex, err.Error()))
+		}
+	}
+
+	if reports := pr.Spec.EvaluationReports; reports != nil {
+		for i, pattern := range reports.RedactPatterns {
+			if _, err := regexp.Compile(pattern); err != nil {
+				allErrs = append(allErrs, field.Invalid(specPath.Child("evaluationReports", "redactPatterns").Index(i), pattern, err.Error()))
+			}
+		}
+	}
+
+	// The operator may read Secrets the PodRestart's creator cannot
+	if d := pr.Spec.Diagnostics; d != nil && d.Upload != nil && d.Upload.CredentialsSecretRef != nil {
+		if ns := d.Upload.CredentialsSecretRef.Namespace; ns != pr.Namespace {
+			allErrs = append(allErrs, field.Invalid(specPath.Child("diagnostics", "upload", "credentialsSecretRef", "namespace"), ns,
+				"must be the namespace of the PodRestart"))
+		}
+	}
+
+	if pr.Spec.MessageTemplate != "" {
+		if _, err := template.New("message").Parse(pr.Spec.MessageTemplate); err != nil {
+			allErrs = append(allErrs, field.Invalid(specPath.Child("messageTemplate"), pr.Spec.MessageTemplate, err.Error()))
+		}
+	}
+
+	return allErrs
+}
@@ -0,0 +1,283 @@
+// webhooknotifier.go
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+
+	operatorv1alpha1 "github.com/example/pod-restart-operator/api/v1alpha1"
+)
+
+const (
+	// webhookSignatureHeader carries the hex encoded HMAC-SHA256 of the request body
+	webhookSignatureHeader = "X-PodRestart-Signature"
+
+	defaultWebhookRetries = 3
+	webhookInitialBackoff = time.Second
+	webhookMaxBackoff     = 8 * time.Second
+)
+
+// webhookNotifier POSTs a JSON description of each notification to a URL
+type webhookNotifier struct {
+	url        string
+	signingKey []byte
+	retries    int
+	payload    *payloadTemplate
+	httpClient *http.Client
+}
+
+// webhookPayload is the JSON document delivered to generic webhooks
+type webhookPayload struct {
+	Event      string          `json:"event"`
+	Severity   string          `json:"severity"`
+	PodRestart webhookObject   `json:"podRestart"`
+	Pod        string          `json:"pod,omitempty"`
+	Workload   string          `json:"workload,omitempty"`
+	Trigger    string          `json:"trigger,omitempty"`
+	ReasonCode string          `json:"reasonCode,omitempty"`
+	Reason     string          `json:"reason,omitempty"`
+	Message    string          `json:"message,omitempty"`
+	Evidence   webhookEvidence `json:"evidence"`
+	Time       time.Time       `json:"time"`
+}
+
+type webhookObject struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+}
+
+type webhookEvidence struct {
+	MatchedLine string   `json:"matchedLine,omitempty"`
+	MetricValue *float64 `json:"metricValue,omitempty"`
+}
+
+// newWebhookNotifier builds a generic webhook notifier from a channel
+func (r *PodRestartReconciler) newWebhookNotifier(ctx context.Context, channel *operatorv1alpha1.NotificationChannel, pr *operatorv1alpha1.PodRestart) (Notifier, error) {
+	cfg := channel.Spec.Webhook
+	if cfg == nil || cfg.URL == "" {
+		return nil, fmt.Errorf("webhook channel %s has no url", channel.Name)
+	}
+
+	payload, err := newPayloadTemplate(channel, pr)
+	if err != nil {
+		return nil, err
+	}
+
+	n := &webhookNotifier{
+		url:        cfg.URL,
+		retries:    defaultWebhookRetries,
+		payload:    payload,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+	if cfg.MaxRetries != nil {
+		n.retries = int(*cfg.MaxRetries)
+	}
+	if channel.Spec.SecretRef != nil {
+		key, err := r.secretValue(ctx, channel.Spec.SecretRef)
+		if err != nil {
+			return nil, err
+		}
+		n.signingKey = key
+	}
+	return n, nil
+}
+
+// Notify implements Notifier. Transient failures are retried with
+// exponential backoff; client errors other than 429 are not retried.
+func (n *webhookNotifier) Notify(ctx context.Context, notification Notification) error {
+	body, err := n.body(notification)
+	if err != nil {
+		return err
+	}
+
+	backoff := webhookInitialBackoff
+	for attempt := 0; ; attempt++ {
+		retry, err := n.deliver(ctx, notification, body)
+		if err == nil {
+			return nil
+		}
+		if !retry || attempt >= n.retries {
+			return fmt.Errorf("after %d attempts: %w", attempt+1, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > webhookMaxBackoff {
+			backoff = webhookMaxBackoff
+		}
+	}
+}
+
+// body renders the request body, using the channel's payload template if set
+func (n *webhookNotifier) body(notification Notification) ([]byte, error) {
+	if n.payload != nil {
+		return n.payload.render(notification)
+	}
+	return json.Marshal(webhookPayload{
+		Event:    string(notification.Event),
+		Severity: string(notification.Severity),
+		PodRestart: webhookObject{
+			Namespace: notification.PodRestart.Namespace,
+			Name:      notification.PodRestart.Name,
+		},
+		Pod:        notification.Pod,
+		Workload:   notification.Workload,
+		Trigger:    notification.Trigger,
+		ReasonCode: string(notification.ReasonCode),
+		Reason:     notification.Reason,
+		Message:    notification.Message,
+		Evidence: webhookEvidence{
+			MatchedLine: notification.MatchedLine,
+			MetricValue: notification.MetricValue,
+		},
+		Time: notification.Time.UTC(),
+	})
+}
+
+// deliver performs a single delivery attempt and reports whether a failure may be retried
+func (n *webhookNotifier) deliver(ctx context.Context, notification Notification, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-PodRestart-Event", string(notification.Event))
+	req.Header.Set("X-PodRestart-Timestamp", strconv.FormatInt(notification.Time.Unix(), 10))
+	if len(n.signingKey) > 0 {
+		mac := hmac.New(sha256.New, n.signingKey)
+		mac.Write(body)
+		req.Header.Set(webhookSignatureHeader, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	resp, err := n.httpClient.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return true, fmt.Errorf("webhook returned HTTP %d", resp.StatusCode)
+	default:
+		return false, unauthorized(resp.StatusCode, "webhook returned HTTP %d", resp.StatusCode)
+	}
+}
+
+const (
+	// webhookQueueSize bounds the webhook notifications waiting for delivery
+	webhookQueueSize = 256
+	// webhookWorkers is the number of webhook notifications delivered at once
+	webhookWorkers = 4
+	// webhookDeliveryTimeout bounds a single delivery including its retries
+	webhookDeliveryTimeout = time.Minute
+)
+
+// webhookQueue delivers webhook notifications in the background, so an
+// endpoint that is slow or retried with backoff does not hold back the
+// reconcile. Outcomes are kept until the PodRestart's next status update.
+// Notifications still queued when the operator stops are dropped.
+type webhookQueue struct {
+	jobs chan webhookJob
+
+	mu      sync.Mutex
+	results map[types.NamespacedName][]webhookResult
+}
+
+// webhookJob is a queued notification; deliver sends it
+type webhookJob struct {
+	podRestart   types.NamespacedName
+	channel      string
+	notification Notification
+	deliver      func(ctx context.Context) error
+}
+
+// webhookResult is the outcome of a delivered notification
+type webhookResult struct {
+	channel      string
+	notification Notification
+	err          error
+}
+
+func newWebhookQueue() *webhookQueue {
+	return &webhookQueue{
+		jobs:    make(chan webhookJob, webhookQueueSize),
+		results: map[types.NamespacedName][]webhookResult{},
+	}
+}
+
+// enqueue queues a notification and reports whether there was room for it
+func (q *webhookQueue) enqueue(job webhookJob) bool {
+	select {
+	case q.jobs <- job:
+		return true
+	default:
+		return false
+	}
+}
+
+// Start runs the delivery workers until ctx is done. It implements
+// manager.Runnable.
+func (q *webhookQueue) Start(ctx context.Context) error {
+	var wg sync.WaitGroup
+	for i := 0; i < webhookWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case job := <-q.jobs:
+					dctx, cancel := context.WithTimeout(ctx, webhookDeliveryTimeout)
+					err := job.deliver(dctx)
+					cancel()
+					q.mu.Lock()
+					q.results[job.podRestart] = append(q.results[job.podRestart], webhookResult{
+						channel:      job.channel,
+						notification: job.notification,
+						err:          err,
+					})
+					q.mu.Unlock()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	return nil
+}
+
+// record moves the outcomes of finished deliveries into the PodRestart's status
+func (q *webhookQueue) record(pr *operatorv1alpha1.PodRestart) {
+	key := types.NamespacedName{Namespace: pr.Namespace, Name: pr.Name}
+	q.mu.Lock()
+	results := q.results[key]
+	delete(q.results, key)
+	q.mu.Unlock()
+	for _, result := range results {
+		recordDelivery(pr, result.channel, result.notification, result.err)
+	}
+}
+
+// forget drops the outcomes kept for a deleted PodRestart
+func (q *webhookQueue) forget(key types.NamespacedName) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	delete(q.results, key)
+}
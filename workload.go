@@ -0,0 +1,50 @@
+// workload.go
+package controllers
+
+import (
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// workloadRef identifies the top level workload that owns a pod
+type workloadRef struct {
+	APIVersion string
+	Kind       string
+	Name       string
+}
+
+// String formats the workload as Kind/name
+func (w workloadRef) String() string {
+	return w.Kind + "/" + w.Name
+}
+
+// workloadFor derives the owning workload from the pod's controller reference.
+// Pods owned by a ReplicaSet are attributed to the Deployment that created it,
+// using the pod-template-hash label to strip the ReplicaSet suffix. Pods
+// without a controller are reported as bare pods.
+func workloadFor(pod *corev1.Pod) workloadRef {
+	owner := metav1.GetControllerOf(pod)
+	if owner == nil {
+		return workloadRef{APIVersion: "v1", Kind: "Pod", Name: pod.Name}
+	}
+
+	if owner.Kind == "ReplicaSet" {
+		if hash, ok := pod.Labels[appsv1.DefaultDeploymentUniqueLabelKey]; ok && strings.HasSuffix(owner.Name, "-"+hash) {
+			return workloadRef{APIVersion: "apps/v1", Kind: "Deployment", Name: strings.TrimSuffix(owner.Name, "-"+hash)}
+		}
+	}
+	return workloadRef{APIVersion: owner.APIVersion, Kind: owner.Kind, Name: owner.Name}
+}
+
+// objectReference returns a reference to the workload usable as an event target
+func (w workloadRef) objectReference(namespace string) *corev1.ObjectReference {
+	return &corev1.ObjectReference{
+		APIVersion: w.APIVersion,
+		Kind:       w.Kind,
+		Namespace:  namespace,
+		Name:       w.Name,
+	}
+}
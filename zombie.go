@@ -0,0 +1,75 @@
+// zombie.go
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	operatorv1alpha1 "github.com/example/pod-restart-operator/api/v1alpha1"
+)
+
+const defaultZombieCheckTimeout = 10 * time.Second
+
+// defaultZombieCommand counts the processes in state Z. grep prefixes every
+// match with its file name, so wc counts one line per zombie.
+var defaultZombieCommand = []string{"sh", "-c", "grep -s '^State:[[:space:]]*Z' /proc/[0-9]*/status | wc -l"}
+
+// checkZombieProcesses runs the zombie count command in the checked
+// containers and returns a result for the first one above the threshold
+func (r *PodRestartReconciler) checkZombieProcesses(ctx context.Context, cluster *clusterTarget, pod *corev1.Pod, pr *operatorv1alpha1.PodRestart, cond *operatorv1alpha1.ZombieProcessCondition) *triggerResult {
+	command := cond.Command
+	if len(command) == 0 {
+		command = defaultZombieCommand
+	}
+	timeout := defaultZombieCheckTimeout
+	if cond.Timeout != nil {
+		timeout = cond.Timeout.Duration
+	}
+
+	containers := cond.Containers
+	if len(containers) == 0 {
+		for _, c := range pod.Spec.Containers {
+			containers = append(containers, c.Name)
+		}
+	}
+	for _, container := range containers {
+		if !containerRunning(pod, container) {
+			continue
+		}
+		probe := "zombies/" + container
+		count, err := r.countZombies(ctx, cluster, pod, container, command, timeout)
+		if err != nil {
+			r.probeFailed(pr, pod, probe, err, "Failed to count zombie processes of pod %s container %s", pod.Name, container)
+			continue
+		}
+		r.probeSucceeded(pr, pod, probe)
+		if count > int(cond.Threshold) {
+			conditionMatchesTotal.WithLabelValues(pr.Namespace, pr.Name, TriggerZombieProcesses).Inc()
+			value := float64(count)
+			return &triggerResult{
+				Trigger:     TriggerZombieProcesses,
+				Name:        container,
+				Reason:      fmt.Sprintf("Container %s holds %d zombie processes (threshold %d)", container, count, cond.Threshold),
+				MetricValue: &value,
+			}
+		}
+	}
+	return nil
+}
+
+// countZombies runs the count command in a container and parses its output
+func (r *PodRestartReconciler) countZombies(ctx context.Context, cluster *clusterTarget, pod *corev1.Pod, container string, command []string, timeout time.Duration) (int, error) {
+	out, err := r.execProbe(ctx, cluster, pod, container, command, timeout)
+	if err != nil {
+		return 0, err
+	}
+	count, err := strconv.Atoi(out)
+	if err != nil {
+		return 0, fmt.Errorf("command printed %q, not a count", out)
+	}
+	return count, nil
+}